@@ -10,6 +10,15 @@ import (
 type PVCGroupSpec struct {
 	// Template defines the expansion configuration for the group
 	Template PVCGroupTemplate `json:"template"`
+
+	// Suspend pauses automatic expansion of all PVCs managed by this group
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// SuspendUntil pauses automatic expansion of all PVCs managed by this group
+	// until the given time
+	// +optional
+	SuspendUntil *metav1.Time `json:"suspendUntil,omitempty"`
 }
 
 // PVCGroupTemplate defines the expansion configuration template for groups
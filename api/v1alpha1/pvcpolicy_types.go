@@ -13,6 +13,15 @@ type PVCPolicySpec struct {
 
 	// Template defines the expansion configuration
 	Template PVCPolicyTemplate `json:"template"`
+
+	// Suspend pauses automatic expansion of all PVCs managed by this policy
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// SuspendUntil pauses automatic expansion of all PVCs managed by this policy
+	// until the given time
+	// +optional
+	SuspendUntil *metav1.Time `json:"suspendUntil,omitempty"`
 }
 
 // PVCPolicyTemplate defines the expansion configuration template
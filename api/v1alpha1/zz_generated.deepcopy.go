@@ -88,6 +88,10 @@ func (in *PVCGroupList) DeepCopyObject() runtime.Object {
 func (in *PVCGroupSpec) DeepCopyInto(out *PVCGroupSpec) {
 	*out = *in
 	in.Template.DeepCopyInto(&out.Template)
+	if in.SuspendUntil != nil {
+		in, out := &in.SuspendUntil, &out.SuspendUntil
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PVCGroupSpec.
@@ -242,6 +246,10 @@ func (in *PVCPolicySpec) DeepCopyInto(out *PVCPolicySpec) {
 	*out = *in
 	in.Selector.DeepCopyInto(&out.Selector)
 	in.Template.DeepCopyInto(&out.Template)
+	if in.SuspendUntil != nil {
+		in, out := &in.SuspendUntil, &out.SuspendUntil
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PVCPolicySpec.
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/logicIQ/pvc-chonker/pkg/agent"
+)
+
+type agentOptions struct {
+	rootDir      string
+	nodeName     string
+	bindAddress  string
+	pushURL      string
+	pushToken    string
+	pushCAFile   string
+	pushInterval time.Duration
+	scanTimeout  time.Duration
+}
+
+func newAgentCommand() *cobra.Command {
+	opts := &agentOptions{}
+	cmd := &cobra.Command{
+		Use:   "agent",
+		Short: "Report volume stats of the node's PVCs, run as a DaemonSet",
+		Long: "Walk the kubelet pod volume directories and statfs every mounted PVC. The stats are served in the\n" +
+			"kubelet volume stats format for the controller to scrape, pushed to the controller push endpoint, or both.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctrl.SetLogger(zap.New())
+			return runAgent(ctrl.SetupSignalHandler(), opts)
+		},
+	}
+	cmd.Flags().StringVar(&opts.rootDir, "kubelet-root-dir", agent.DefaultRootDir, "Kubelet root directory holding the pod volume directories")
+	cmd.Flags().StringVar(&opts.nodeName, "node-name", os.Getenv("NODE_NAME"), "Name of the node the agent runs on (defaults to NODE_NAME)")
+	cmd.Flags().StringVar(&opts.bindAddress, "bind-address", ":9847", "Address serving volume stats on "+agent.MetricsPath+" (empty disables)")
+	cmd.Flags().StringVar(&opts.pushURL, "push-url", "", "Controller push endpoint URL, e.g. https://pvc-chonker-push.pvc-chonker-system:8443, pushed to every address of its host (empty disables pushing)")
+	cmd.Flags().StringVar(&opts.pushToken, "push-token-file", "", "File holding the bearer token for the push endpoint, re-read on every push")
+	cmd.Flags().StringVar(&opts.pushCAFile, "push-ca-file", "", "CA bundle verifying the push endpoint certificate")
+	cmd.Flags().DurationVar(&opts.pushInterval, "push-interval", time.Minute, "Interval between pushes")
+	cmd.Flags().DurationVar(&opts.scanTimeout, "scan-timeout", 30*time.Second, "Timeout for reading the stats of all volumes, mounts that do not answer in time are skipped")
+	return cmd
+}
+
+func runAgent(ctx context.Context, opts *agentOptions) error {
+	log := ctrl.Log.WithName("agent")
+	if opts.bindAddress == "" && opts.pushURL == "" {
+		return fmt.Errorf("either bind-address or push-url is required")
+	}
+
+	config, err := ctrl.GetConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	// Resolve PersistentVolumes from an informer so scans never hit the API server
+	pvCache, err := cache.New(config, cache.Options{
+		Scheme:   scheme,
+		ByObject: map[client.Object]cache.ByObject{&corev1.PersistentVolume{}: {}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create cache: %w", err)
+	}
+
+	scanner := agent.NewScanner(opts.rootDir, &agent.PVResolver{Reader: pvCache})
+	log.Info("Starting agent", "node", opts.nodeName, "kubeletRootDir", opts.rootDir, "bindAddress", opts.bindAddress, "pushURL", opts.pushURL)
+
+	group, ctx := errgroup.WithContext(ctx)
+	group.Go(func() error {
+		return pvCache.Start(ctx)
+	})
+	if !pvCache.WaitForCacheSync(ctx) {
+		return fmt.Errorf("failed to sync cache")
+	}
+
+	if opts.bindAddress != "" {
+		exporter := &agent.Exporter{Scanner: scanner, Timeout: opts.scanTimeout}
+		group.Go(func() error {
+			return serveAgent(ctx, opts.bindAddress, exporter)
+		})
+	}
+	if opts.pushURL != "" {
+		pusher := &agent.Pusher{URL: opts.pushURL, TokenFile: opts.pushToken, NodeName: opts.nodeName}
+		if opts.pushCAFile != "" {
+			httpClient, err := newCAClient(opts.pushCAFile)
+			if err != nil {
+				return err
+			}
+			pusher.HTTPClient = httpClient
+		}
+		group.Go(func() error {
+			pushLoop(ctx, scanner, pusher, opts)
+			return nil
+		})
+	}
+	return group.Wait()
+}
+
+func serveAgent(ctx context.Context, address string, exporter *agent.Exporter) error {
+	mux := http.NewServeMux()
+	mux.Handle(agent.MetricsPath, exporter.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	server := &http.Server{Addr: address, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve volume stats: %w", err)
+	}
+	return nil
+}
+
+func pushLoop(ctx context.Context, scanner *agent.Scanner, pusher *agent.Pusher, opts *agentOptions) {
+	log := ctrl.Log.WithName("agent")
+	ticker := time.NewTicker(opts.pushInterval)
+	defer ticker.Stop()
+	for {
+		scanCtx, cancel := context.WithTimeout(ctx, opts.scanTimeout)
+		stats, err := scanner.Scan(scanCtx)
+		if err != nil {
+			log.Error(err, "Failed to read some volume stats", "volumes", len(stats))
+		}
+		if len(stats) > 0 {
+			if err := pusher.Push(scanCtx, stats); err != nil {
+				log.Error(err, "Failed to push volume stats", "volumes", len(stats))
+			} else {
+				log.V(1).Info("Pushed volume stats", "volumes", len(stats))
+			}
+		}
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func newCAClient(caFile string) (*http.Client, error) {
+	caData, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caData) {
+		return nil, fmt.Errorf("no certificates found in CA file %s", caFile)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	return &http.Client{Transport: transport, Timeout: 30 * time.Second}, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/logicIQ/pvc-chonker/pkg/annotations"
+)
+
+// globalDefaults holds the --default-* flags of subcommands that resolve PVC
+// configurations outside the operator, mirroring the operator flags.
+type globalDefaults struct {
+	threshold       float64
+	inodesThreshold float64
+	increase        string
+	cooldown        time.Duration
+	minScaleUp      string
+	maxSize         string
+}
+
+func (d *globalDefaults) addFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.Float64Var(&d.threshold, "default-threshold", 0, "Default storage threshold percentage")
+	flags.Float64Var(&d.inodesThreshold, "default-inodes-threshold", 0, "Default inode threshold percentage")
+	flags.StringVar(&d.increase, "default-increase", "", "Default expansion amount")
+	flags.DurationVar(&d.cooldown, "default-cooldown", 0, "Default cooldown period")
+	flags.StringVar(&d.minScaleUp, "default-min-scale-up", "", "Default minimum scale-up amount")
+	flags.StringVar(&d.maxSize, "default-max-size", "", "Default maximum size limit")
+}
+
+func (d *globalDefaults) globalConfig() (*annotations.GlobalConfig, error) {
+	var minScaleUp, maxSize resource.Quantity
+	var err error
+	if d.minScaleUp != "" {
+		if minScaleUp, err = resource.ParseQuantity(d.minScaleUp); err != nil {
+			return nil, fmt.Errorf("invalid default-min-scale-up: %w", err)
+		}
+	}
+	if d.maxSize != "" {
+		if maxSize, err = resource.ParseQuantity(d.maxSize); err != nil {
+			return nil, fmt.Errorf("invalid default-max-size: %w", err)
+		}
+	}
+	return annotations.NewGlobalConfig(d.threshold, d.inodesThreshold, d.increase, d.cooldown, minScaleUp, maxSize), nil
+}
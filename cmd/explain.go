@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/logicIQ/pvc-chonker/internal/controller"
+	"github.com/logicIQ/pvc-chonker/pkg/annotations"
+	"github.com/logicIQ/pvc-chonker/pkg/control"
+	"github.com/logicIQ/pvc-chonker/pkg/kubelet"
+)
+
+type explainOptions struct {
+	globalDefaults
+	controlNamespace string
+	controlConfigMap string
+	prometheusURL    string
+	output           string
+}
+
+func newExplainCommand() *cobra.Command {
+	opts := &explainOptions{}
+	cmd := &cobra.Command{
+		Use:   "explain namespace/name",
+		Short: "Explain the effective configuration of a PVC and the next expansion decision",
+		Long: "Resolve the configuration of a PVC against the live cluster and show every effective field with\n" +
+			"its source: an annotation, a PVCPolicy, a PVCGroup template applied by the webhook or a global\n" +
+			"default. The current volume metrics, the decision the next cycle would make, PVCPolicies shadowed\n" +
+			"by the effective configuration and ignored settings are shown as well. Nothing is modified.\n" +
+			"Pass the --default-* flags the operator runs with so global defaults match.",
+		Example: "  pvc-chonker explain default/data\n" +
+			"  kubectl pvc-chonker explain kafka/data-broker-0 --default-threshold 85 -o json",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runExplain(cmd.Context(), cmd.OutOrStdout(), opts, args[0])
+		},
+	}
+	opts.addFlags(cmd)
+	cmd.Flags().StringVar(&opts.controlNamespace, "control-namespace", control.DefaultNamespace, "Namespace of the control ConfigMap holding the kill switch")
+	cmd.Flags().StringVar(&opts.controlConfigMap, "control-configmap", control.DefaultConfigMapName, "Name of the control ConfigMap holding the kill switch")
+	cmd.Flags().StringVar(&opts.prometheusURL, "prometheus-url", "", "Read volume metrics from Prometheus with the default queries instead of the kubelet")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "table", "Output format: table or json")
+	return cmd
+}
+
+func runExplain(ctx context.Context, out io.Writer, opts *explainOptions, name string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if opts.output != "table" && opts.output != "json" {
+		return fmt.Errorf("unsupported output format %q, expected table or json", opts.output)
+	}
+	namespace, pvcName, ok := strings.Cut(name, "/")
+	if !ok || namespace == "" || pvcName == "" {
+		return fmt.Errorf("expected namespace/name, got %q", name)
+	}
+	global, err := opts.globalConfig()
+	if err != nil {
+		return err
+	}
+
+	config, err := ctrl.GetConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+
+	var collector kubelet.MetricsCollectorInterface
+	if opts.prometheusURL != "" {
+		if collector, err = kubelet.NewPrometheusMetricsCollector(kubelet.PrometheusConfig{URL: opts.prometheusURL, Queries: kubelet.DefaultPrometheusQueries}); err != nil {
+			return err
+		}
+	} else {
+		clientset, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			return fmt.Errorf("failed to create clientset: %w", err)
+		}
+		kubeletCollector, err := kubelet.NewMetricsCollector("")
+		if err != nil {
+			return err
+		}
+		kubeletCollector.SetClient(c, clientset)
+		collector = kubeletCollector
+	}
+
+	// The reconciler logs its reasoning, which the output already shows
+	ctx = log.IntoContext(ctx, zap.New(zap.WriteTo(io.Discard)))
+	reconciler := &controller.PersistentVolumeClaimReconciler{
+		Client:           c,
+		GlobalConfig:     global,
+		MetricsCollector: collector,
+		// Discards events, explaining a PVC does not change anything
+		EventRecorder: &record.FakeRecorder{},
+		ControlLoader: control.NewLoader(c, opts.controlNamespace, opts.controlConfigMap),
+	}
+	return explainPVC(ctx, out, reconciler, types.NamespacedName{Namespace: namespace, Name: pvcName}, opts.output)
+}
+
+type explainMetrics struct {
+	UsedBytes          int64     `json:"usedBytes"`
+	CapacityBytes      int64     `json:"capacityBytes"`
+	UsagePercent       float64   `json:"usagePercent"`
+	InodesUsed         int64     `json:"inodesUsed"`
+	InodesTotal        int64     `json:"inodesTotal"`
+	InodesUsagePercent float64   `json:"inodesUsagePercent"`
+	Source             string    `json:"source"`
+	Node               string    `json:"node,omitempty"`
+	Time               time.Time `json:"time,omitempty"`
+}
+
+type explainDecision struct {
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+	From    string `json:"from,omitempty"`
+	To      string `json:"to,omitempty"`
+}
+
+type explainResult struct {
+	PVC string `json:"pvc"`
+	*annotations.Explanation
+	Metrics  *explainMetrics  `json:"metrics,omitempty"`
+	Decision *explainDecision `json:"decision,omitempty"`
+}
+
+// explainPVC explains the configuration of the PVC and evaluates it with the
+// reconciler without modifying it.
+func explainPVC(ctx context.Context, out io.Writer, reconciler *controller.PersistentVolumeClaimReconciler, key types.NamespacedName, output string) error {
+	var pvc corev1.PersistentVolumeClaim
+	if err := reconciler.Get(ctx, key, &pvc); err != nil {
+		return fmt.Errorf("failed to get PVC %s: %w", key, err)
+	}
+
+	explanation, err := annotations.NewPolicyResolver(reconciler.Client).Explain(ctx, &pvc, reconciler.GlobalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to resolve the configuration of PVC %s: %w", key, err)
+	}
+	result := explainResult{PVC: key.String(), Explanation: explanation}
+
+	decision, err := reconciler.Evaluate(ctx, &pvc)
+	if err != nil {
+		return err
+	}
+	if decision != nil {
+		result.Decision = &explainDecision{Reason: decision.Reason, Message: describeDecision(decision)}
+		if !decision.NewSize.IsZero() {
+			result.Decision.From, result.Decision.To = decision.CurrentSize.String(), decision.NewSize.String()
+		}
+		if vm := decision.VolumeMetrics; vm != nil {
+			result.Metrics = &explainMetrics{
+				UsedBytes:          vm.UsedBytes,
+				CapacityBytes:      vm.CapacityBytes,
+				UsagePercent:       vm.UsagePercent,
+				InodesUsed:         vm.InodesUsed,
+				InodesTotal:        vm.InodesTotal,
+				InodesUsagePercent: vm.InodesUsagePercent,
+				Source:             vm.Source,
+				Node:               vm.NodeName,
+				Time:               vm.Timestamp.UTC(),
+			}
+		}
+	}
+
+	if output == "json" {
+		return writeJSON(out, result)
+	}
+	return printExplanation(out, result)
+}
+
+// describeDecision explains the reason of a decision in a sentence.
+func describeDecision(d *controller.Decision) string {
+	config := d.Config
+	switch d.Reason {
+	case controller.ReasonExpand:
+		if d.InodePressure {
+			return fmt.Sprintf("expand from %s to %s on inode pressure", d.CurrentSize.String(), d.NewSize.String())
+		}
+		return fmt.Sprintf("expand from %s to %s", d.CurrentSize.String(), d.NewSize.String())
+	case controller.ReasonBelowThreshold:
+		return fmt.Sprintf("usage %.1f%% is below the threshold of %g%%", d.VolumeMetrics.UsagePercent, config.Threshold)
+	case controller.ReasonCooldown:
+		return fmt.Sprintf("in cooldown until %s", config.LastExpansion.Add(config.Cooldown).UTC().Format(time.RFC3339))
+	case controller.ReasonSuspended:
+		if !config.IsSuspended() {
+			return "paused by the cluster-wide kill switch"
+		}
+		if config.SuspendedUntil != nil && !config.Suspended {
+			return fmt.Sprintf("suspended by %s until %s", config.SuspendedBy, config.SuspendedUntil.UTC().Format(time.RFC3339))
+		}
+		return "suspended by " + config.SuspendedBy
+	case controller.ReasonInvalidPausedUntil:
+		return fmt.Sprintf("suspended until the %s annotation is fixed: %v", annotations.AnnotationPausedUntil, config.PausedUntilErr)
+	case controller.ReasonMaxSizeReached:
+		return fmt.Sprintf("the next size would exceed the max size of %s", config.MaxSize.String())
+	case controller.ReasonInvalidConfig:
+		return fmt.Sprintf("the increase %q cannot be applied", config.Increase)
+	case controller.ReasonNotEligible:
+		return "the PVC is not bound, or is a block volume without pushed metrics"
+	case controller.ReasonStorageClassNotExpandable:
+		return "the StorageClass does not exist or does not allow volume expansion"
+	case controller.ReasonResizeError:
+		message, _ := annotations.ResizeError(d.PVC)
+		return "the last resize failed: " + message
+	case controller.ReasonResizeInProgress:
+		return "a resize is in progress"
+	case controller.ReasonMetricsNotFound:
+		return "no volume metrics, the volume is not mounted by a running pod"
+	case controller.ReasonMetricsUnavailable:
+		return "no volume metrics, scraping the node failed"
+	case controller.ReasonCircuitOpen:
+		return "paused by the circuit breaker of the provisioner"
+	}
+	return d.Reason
+}
+
+func printExplanation(out io.Writer, result explainResult) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "PVC:\t%s\n", result.PVC)
+	configuredBy := "not managed"
+	if result.Managed {
+		configuredBy = result.ConfiguredBy
+	}
+	fmt.Fprintf(w, "Configured by:\t%s\n", configuredBy)
+	if result.Group != "" {
+		fmt.Fprintf(w, "Group:\t%s\n", result.Group)
+	}
+	if m := result.Metrics; m != nil {
+		fmt.Fprintf(w, "Usage:\t%.1f%%, %d of %d bytes\n", m.UsagePercent, m.UsedBytes, m.CapacityBytes)
+		if m.InodesTotal > 0 {
+			fmt.Fprintf(w, "Inodes:\t%.1f%%, %d of %d\n", m.InodesUsagePercent, m.InodesUsed, m.InodesTotal)
+		}
+		sample := m.Source
+		if m.Node != "" {
+			sample += " from node " + m.Node
+		}
+		if !m.Time.IsZero() {
+			sample += " at " + m.Time.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "Sample:\t%s\n", sample)
+	}
+	if d := result.Decision; d != nil {
+		fmt.Fprintf(w, "Next cycle:\t%s: %s\n", d.Reason, d.Message)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(result.Fields) > 0 {
+		fmt.Fprintln(out)
+		w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "FIELD\tVALUE\tSOURCE\tNOTE")
+		for _, f := range result.Fields {
+			source := f.Source
+			if f.Object != "" {
+				source += " " + f.Object
+			}
+			note := f.Note
+			if note == "" {
+				note = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", f.Name, f.Value, source, note)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(result.Shadowed) > 0 {
+		fmt.Fprintln(out)
+		w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "SHADOWED POLICY\tSHADOWED BY\tCONFLICTS")
+		for _, s := range result.Shadowed {
+			conflicts := "-"
+			if len(s.Conflicts) > 0 {
+				conflicts = strings.Join(s.Conflicts, ", ")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", s.Name, s.ShadowedBy, conflicts)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(result.Warnings) > 0 {
+		fmt.Fprintln(out)
+		for _, warning := range result.Warnings {
+			fmt.Fprintf(out, "Warning: %s\n", warning)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/logicIQ/pvc-chonker/api/v1alpha1"
+	"github.com/logicIQ/pvc-chonker/internal/controller"
+	"github.com/logicIQ/pvc-chonker/pkg/annotations"
+	"github.com/logicIQ/pvc-chonker/pkg/kubelet"
+)
+
+func TestExplainPVC(t *testing.T) {
+	allowExpansion := true
+	scName := "standard"
+	threshold := "75%"
+	size := resource.MustParse("10Gi")
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: scName}, Provisioner: "ebs.csi.aws.com", AllowVolumeExpansion: &allowExpansion},
+			&corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "default", Labels: map[string]string{"app": "db"}},
+				Spec: corev1.PersistentVolumeClaimSpec{
+					StorageClassName: &scName,
+					Resources:        corev1.VolumeResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceStorage: size}},
+				},
+				Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound, Capacity: corev1.ResourceList{corev1.ResourceStorage: size}},
+			},
+			&v1alpha1.PVCPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+				Spec: v1alpha1.PVCPolicySpec{
+					Selector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+					Template: v1alpha1.PVCPolicyTemplate{Threshold: &threshold},
+				},
+			},
+		).
+		Build()
+
+	pushed := kubelet.NewPushStore(time.Hour)
+	used := int64(8 << 30)
+	if _, errs := pushed.Add([]kubelet.PushSample{{Namespace: "default", PersistentVolumeClaim: "data", CapacityBytes: 10 << 30, UsedBytes: &used}}); len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	collector, err := kubelet.NewPushMetricsCollector(nil, pushed, kubelet.PushModeOverride)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reconciler := &controller.PersistentVolumeClaimReconciler{
+		Client:           fakeClient,
+		GlobalConfig:     annotations.NewGlobalConfig(0, 0, "", 0, resource.Quantity{}, resource.Quantity{}),
+		MetricsCollector: collector,
+		EventRecorder:    &record.FakeRecorder{},
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "data"}
+
+	var out bytes.Buffer
+	if err := explainPVC(context.Background(), &out, reconciler, key, "table"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"Configured by:  PVCPolicy db",
+		"Usage:          80.0%, 8589934592 of 10737418240 bytes",
+		"Next cycle:     expand: expand from 10Gi to 11Gi",
+		"threshold         75%",
+		"PVCPolicy db",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := explainPVC(context.Background(), &out, reconciler, key, "json"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var result struct {
+		PVC          string              `json:"pvc"`
+		ConfiguredBy string              `json:"configuredBy"`
+		Fields       []annotations.Field `json:"fields"`
+		Decision     explainDecision     `json:"decision"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if result.PVC != "default/data" || result.ConfiguredBy != "PVCPolicy db" || result.Decision.Reason != controller.ReasonExpand || result.Decision.To != "11Gi" {
+		t.Errorf("unexpected JSON result: %s", out.String())
+	}
+
+	// The PVC is not modified
+	var pvc corev1.PersistentVolumeClaim
+	if err := fakeClient.Get(context.Background(), key, &pvc); err != nil {
+		t.Fatal(err)
+	}
+	if requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; requested.Cmp(size) != 0 {
+		t.Errorf("expected the PVC to keep its size, got %s", requested.String())
+	}
+
+	if err := explainPVC(context.Background(), &out, reconciler, types.NamespacedName{Namespace: "default", Name: "missing"}, "table"); err == nil {
+		t.Error("expected an error for a missing PVC")
+	}
+}
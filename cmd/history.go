@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/logicIQ/pvc-chonker/pkg/control"
+	"github.com/logicIQ/pvc-chonker/pkg/history"
+)
+
+const (
+	historyBackendConfigMap = "configmap"
+	historyBackendFile      = "file"
+	historyBackendNone      = "none"
+)
+
+// newHistoryBackend returns the backend persisting usage history, or nil when
+// history is kept in memory only.
+func newHistoryBackend(kind string, reader client.Reader, writer client.Writer, namespace, configMap, file string) (history.Backend, error) {
+	switch kind {
+	case historyBackendConfigMap:
+		return history.NewConfigMapBackend(reader, writer, namespace, configMap), nil
+	case historyBackendFile:
+		if file == "" {
+			return nil, fmt.Errorf("history-file is required when history-backend is %s", historyBackendFile)
+		}
+		return &history.FileBackend{Path: file}, nil
+	case historyBackendNone, "":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported history backend %q, expected %s, %s or %s", kind, historyBackendConfigMap, historyBackendFile, historyBackendNone)
+}
+
+type historyOptions struct {
+	backend   string
+	namespace string
+	configMap string
+	file      string
+	since     time.Duration
+	output    string
+}
+
+func newHistoryCommand() *cobra.Command {
+	opts := &historyOptions{}
+	cmd := &cobra.Command{
+		Use:   "history [namespace/name]",
+		Short: "Show the persisted per-PVC usage history",
+		Long: "Show the usage history persisted by the operator. Without arguments every PVC with history is\n" +
+			"listed with its latest usage and growth rate, otherwise the samples of the given PVC are printed.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runHistory(cmd.Context(), cmd.OutOrStdout(), opts, args)
+		},
+	}
+	cmd.Flags().StringVar(&opts.backend, "backend", historyBackendConfigMap, "Where the history is persisted: configmap or file")
+	cmd.Flags().StringVar(&opts.namespace, "namespace", control.DefaultNamespace, "Namespace of the history ConfigMap")
+	cmd.Flags().StringVar(&opts.configMap, "configmap", history.DefaultConfigMapName, "Name of the history ConfigMap")
+	cmd.Flags().StringVar(&opts.file, "file", "", "History file when backend is file")
+	cmd.Flags().DurationVar(&opts.since, "since", 0, "Only show samples newer than this (0 shows all)")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "table", "Output format: table or json")
+	return cmd
+}
+
+func runHistory(ctx context.Context, out io.Writer, opts *historyOptions, args []string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if opts.output != "table" && opts.output != "json" {
+		return fmt.Errorf("unsupported output format %q, expected table or json", opts.output)
+	}
+
+	var reader client.Client
+	if opts.backend == historyBackendConfigMap {
+		config, err := ctrl.GetConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load kubeconfig: %w", err)
+		}
+		if reader, err = client.New(config, client.Options{Scheme: scheme}); err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+	}
+	backend, err := newHistoryBackend(opts.backend, reader, reader, opts.namespace, opts.configMap, opts.file)
+	if err != nil {
+		return err
+	}
+	if backend == nil {
+		return fmt.Errorf("history backend %s does not persist history", opts.backend)
+	}
+
+	// Keep everything that was persisted, whatever the operator bounds are
+	store := history.NewStore(history.Config{RawSamples: 1 << 20, DownsampledSamples: 1 << 20})
+	if err := store.Load(ctx, backend); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var since time.Time
+	if opts.since > 0 {
+		since = now.Add(-opts.since)
+	}
+	if len(args) == 1 {
+		return printHistorySamples(out, store, args[0], since, opts.output)
+	}
+	return printHistorySummary(out, store, now, opts.output)
+}
+
+func printHistorySamples(out io.Writer, store *history.Store, key string, since time.Time, output string) error {
+	if !strings.Contains(key, "/") {
+		return fmt.Errorf("expected namespace/name, got %q", key)
+	}
+	samples := store.Query(key, since)
+	if len(samples) == 0 {
+		return fmt.Errorf("no history for PVC %s", key)
+	}
+
+	if output == "json" {
+		type jsonSample struct {
+			Time          time.Time `json:"time"`
+			UsedBytes     int64     `json:"usedBytes"`
+			CapacityBytes int64     `json:"capacityBytes"`
+			UsagePercent  float64   `json:"usagePercent"`
+			InodesUsed    int64     `json:"inodesUsed"`
+			InodesTotal   int64     `json:"inodesTotal"`
+		}
+		result := make([]jsonSample, 0, len(samples))
+		for _, s := range samples {
+			result = append(result, jsonSample{s.Time.UTC(), s.UsedBytes, s.CapacityBytes, s.UsagePercent(), s.InodesUsed, s.InodesTotal})
+		}
+		return writeJSON(out, result)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tUSED\tCAPACITY\tUSAGE\tINODES USED\tINODES TOTAL")
+	for _, s := range samples {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.1f%%\t%d\t%d\n", s.Time.UTC().Format(time.RFC3339), s.UsedBytes, s.CapacityBytes, s.UsagePercent(), s.InodesUsed, s.InodesTotal)
+	}
+	return w.Flush()
+}
+
+func printHistorySummary(out io.Writer, store *history.Store, now time.Time, output string) error {
+	type summary struct {
+		PVC                string    `json:"pvc"`
+		LastSeen           time.Time `json:"lastSeen"`
+		UsagePercent       float64   `json:"usagePercent"`
+		GrowthBytesPerHour *float64  `json:"growthBytesPerHour,omitempty"`
+	}
+	var result []summary
+	for _, key := range store.Keys() {
+		latest, ok := store.Latest(key)
+		if !ok {
+			continue
+		}
+		s := summary{PVC: key, LastSeen: latest.Time.UTC(), UsagePercent: latest.UsagePercent()}
+		// Measure growth over the hour before the last sample, so history of
+		// a stopped operator still reports a rate
+		if rate, ok := store.GrowthRate(key, time.Hour, latest.Time); ok {
+			perHour := rate * time.Hour.Seconds()
+			s.GrowthBytesPerHour = &perHour
+		}
+		result = append(result, s)
+	}
+
+	if output == "json" {
+		return writeJSON(out, result)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PVC\tLAST SEEN\tUSAGE\tGROWTH/HOUR")
+	for _, s := range result {
+		growth := "-"
+		if s.GrowthBytesPerHour != nil {
+			growth = fmt.Sprintf("%.0f", *s.GrowthBytesPerHour)
+		}
+		fmt.Fprintf(w, "%s\t%s ago\t%.1f%%\t%s\n", s.PVC, now.Sub(s.LastSeen).Round(time.Second), s.UsagePercent, growth)
+	}
+	return w.Flush()
+}
+
+func writeJSON(out io.Writer, v any) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/logicIQ/pvc-chonker/pkg/history"
+)
+
+func TestRunHistoryFromFile(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "history.json")
+	now := time.Now()
+
+	store := history.NewStore(history.Config{})
+	store.Record("default/data", history.Sample{Time: now.Add(-time.Hour), UsedBytes: 0, CapacityBytes: 1000})
+	store.Record("default/data", history.Sample{Time: now, UsedBytes: 500, CapacityBytes: 1000})
+	if err := store.Save(ctx, &history.FileBackend{Path: path}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	opts := &historyOptions{backend: historyBackendFile, file: path, output: "table"}
+	var out bytes.Buffer
+	if err := runHistory(ctx, &out, opts, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "default/data") || !strings.Contains(out.String(), "50.0%") || !strings.Contains(out.String(), "500") {
+		t.Errorf("expected summary with usage and growth of default/data, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	opts.output = "json"
+	if err := runHistory(ctx, &out, opts, []string{"default/data"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Count(out.String(), `"usedBytes"`) != 2 {
+		t.Errorf("expected 2 samples, got:\n%s", out.String())
+	}
+
+	if err := runHistory(ctx, &out, opts, []string{"default/missing"}); err == nil {
+		t.Error("expected an error for a PVC without history")
+	}
+}
+
+func TestNewHistoryBackend(t *testing.T) {
+	if backend, err := newHistoryBackend(historyBackendNone, nil, nil, "", "", ""); err != nil || backend != nil {
+		t.Errorf("expected no backend, got %v, %v", backend, err)
+	}
+	if _, err := newHistoryBackend(historyBackendFile, nil, nil, "", "", ""); err == nil {
+		t.Error("expected an error for the file backend without a path")
+	}
+	if _, err := newHistoryBackend("crd", nil, nil, "", "", ""); err == nil {
+		t.Error("expected an error for an unsupported backend")
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/logicIQ/pvc-chonker/pkg/lint"
+)
+
+type lintOptions struct {
+	files  []string
+	strict bool
+	output string
+}
+
+func newLintCommand() *cobra.Command {
+	opts := &lintOptions{}
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Check pvc-chonker annotations, PVCPolicies and PVCGroups in manifests",
+		Long: "Check manifests, e.g. kustomize build output, before they are applied. pvc-chonker annotations of\n" +
+			"PersistentVolumeClaims, StatefulSet volume claim templates and StorageClasses, and the fields of\n" +
+			"PVCPolicies and PVCGroups are parsed with the parsers of the operator. Overlapping PVCPolicy\n" +
+			"selectors and configurations that expand too often or never are reported as warnings.\n" +
+			"The exit code is 1 when errors, or warnings with --strict, are found.",
+		Example: "  pvc-chonker lint -f pvc.yaml -f policies.yaml\n" +
+			"  kustomize build overlays/prod | pvc-chonker lint -o json",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			code, err := runLint(cmd.InOrStdin(), cmd.OutOrStdout(), opts)
+			if err != nil {
+				return err
+			}
+			if code != 0 {
+				os.Exit(code)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVarP(&opts.files, "filename", "f", []string{"-"}, "Manifests to check (- reads stdin)")
+	cmd.Flags().BoolVar(&opts.strict, "strict", false, "Exit with 1 on warnings as well")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "table", "Output format: table or json")
+	return cmd
+}
+
+// lintJSON is the JSON output of lint.
+type lintJSON struct {
+	Issues   []lint.Issue `json:"issues"`
+	Errors   int          `json:"errors"`
+	Warnings int          `json:"warnings"`
+}
+
+// runLint prints the issues found in the manifests and returns the exit code.
+func runLint(in io.Reader, out io.Writer, opts *lintOptions) (int, error) {
+	if opts.output != "table" && opts.output != "json" {
+		return 0, fmt.Errorf("unsupported output format %q, expected table or json", opts.output)
+	}
+
+	var docs []lint.Document
+	for _, file := range opts.files {
+		name := file
+		if file == "-" {
+			name = "<stdin>"
+		}
+		err := forEachDocument(file, in, func(index int, data []byte) error {
+			docs = append(docs, lint.Document{File: name, Index: index, Data: data})
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	result := lintJSON{Issues: lint.Lint(docs)}
+	for _, issue := range result.Issues {
+		if issue.Severity == lint.SeverityError {
+			result.Errors++
+		} else {
+			result.Warnings++
+		}
+	}
+
+	if opts.output == "json" {
+		if err := writeJSON(out, result); err != nil {
+			return 0, err
+		}
+	} else {
+		printLintIssues(out, result, len(docs))
+	}
+
+	if result.Errors > 0 || (opts.strict && result.Warnings > 0) {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func printLintIssues(out io.Writer, result lintJSON, documents int) {
+	for _, issue := range result.Issues {
+		location := fmt.Sprintf("%s (document %d)", issue.File, issue.Document)
+		if issue.Kind != "" {
+			name := issue.Name
+			if issue.Namespace != "" {
+				name = issue.Namespace + "/" + name
+			}
+			location += " " + strings.TrimSpace(issue.Kind+" "+name)
+		}
+		if issue.Field != "" {
+			location += " " + issue.Field
+		}
+		fmt.Fprintf(out, "%s: %s: %s\n", location, issue.Severity, issue.Message)
+	}
+	fmt.Fprintf(out, "%d documents checked, %d errors, %d warnings\n", documents, result.Errors, result.Warnings)
+}
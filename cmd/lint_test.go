@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/logicIQ/pvc-chonker/pkg/lint"
+)
+
+const lintManifests = `
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+  namespace: default
+  annotations:
+    pvc-chonker.io/enabled: "true"
+    pvc-chonker.io/threshold: "80"
+---
+apiVersion: pvc-chonker.io/v1alpha1
+kind: PVCPolicy
+metadata:
+  name: all
+  namespace: default
+spec:
+  selector: {}
+`
+
+func TestRunLint(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "manifests.yaml")
+	if err := os.WriteFile(file, []byte(lintManifests), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	code, err := runLint(strings.NewReader(""), &out, &lintOptions{files: []string{file}, output: "table"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code != 1 {
+		t.Errorf("expected exit code 1 for errors, got %d", code)
+	}
+	for _, want := range []string{
+		file + " (document 1) PersistentVolumeClaim default/data metadata.annotations[pvc-chonker.io/threshold]: error: invalid value \"80\"",
+		file + " (document 2) PVCPolicy default/all spec.selector: warning: empty selector",
+		"2 documents checked, 1 errors, 1 warnings",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, out.String())
+		}
+	}
+
+	// Only the policy, read from stdin
+	stdin := lintManifests[strings.Index(lintManifests, "---"):]
+	out.Reset()
+	code, err = runLint(strings.NewReader(stdin), &out, &lintOptions{files: []string{"-"}, output: "json"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code != 0 {
+		t.Errorf("expected exit code 0 for warnings, got %d", code)
+	}
+	var result struct {
+		Issues   []lint.Issue `json:"issues"`
+		Errors   int          `json:"errors"`
+		Warnings int          `json:"warnings"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if result.Errors != 0 || result.Warnings != 1 || len(result.Issues) != 1 || result.Issues[0].File != "<stdin>" || result.Issues[0].Document != 1 {
+		t.Errorf("unexpected JSON result: %s", out.String())
+	}
+
+	code, err = runLint(strings.NewReader(stdin), &out, &lintOptions{files: []string{"-"}, strict: true, output: "json"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code != 1 {
+		t.Errorf("expected exit code 1 for warnings with strict, got %d", code)
+	}
+
+	if _, err := runLint(strings.NewReader(""), &out, &lintOptions{files: []string{"-"}, output: "yaml"}); err == nil {
+		t.Error("expected an error for an unsupported output format")
+	}
+}
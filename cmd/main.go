@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -13,9 +15,15 @@ import (
 	"github.com/logicIQ/pvc-chonker/internal/controller"
 	"github.com/logicIQ/pvc-chonker/internal/webhook"
 	"github.com/logicIQ/pvc-chonker/pkg/annotations"
+	"github.com/logicIQ/pvc-chonker/pkg/breaker"
+	"github.com/logicIQ/pvc-chonker/pkg/control"
+	"github.com/logicIQ/pvc-chonker/pkg/history"
 	"github.com/logicIQ/pvc-chonker/pkg/kubelet"
+	"github.com/logicIQ/pvc-chonker/pkg/metrics"
+	"github.com/logicIQ/pvc-chonker/pkg/throttle"
 	"github.com/logicIQ/pvc-chonker/pkg/utils"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
 
@@ -25,6 +33,8 @@ import (
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
@@ -48,13 +58,53 @@ func main() {
 		Short: "Kubernetes PVC auto-expansion operator",
 		Run:   run,
 	}
+	// Installed as the kubectl-pvc_chonker plugin, e.g. kubectl pvc-chonker explain
+	if strings.HasPrefix(filepath.Base(os.Args[0]), "kubectl-") {
+		rootCmd.Annotations = map[string]string{cobra.CommandDisplayNameAnnotation: "kubectl pvc-chonker"}
+	}
 
 	// Bind flags
 	rootCmd.Flags().String("metrics-bind-address", ":8080", "Metrics endpoint address")
 	rootCmd.Flags().String("health-probe-bind-address", ":8081", "Health probe endpoint address")
 	rootCmd.Flags().Bool("leader-elect", false, "Enable leader election")
-	rootCmd.Flags().String("kubelet-url", "", "Custom kubelet metrics URL (for e2e testing, e.g. http://mock-service:8080)")
+	rootCmd.Flags().String("metrics-source", "kubelet", "Volume metrics source: kubelet, prometheus, push (pushed samples only) or replay (recorded kubelet scrapes)")
+	rootCmd.Flags().String("prometheus-url", "", "Prometheus base URL when metrics-source is prometheus, e.g. http://prometheus.monitoring:9090")
+	rootCmd.Flags().String("prometheus-capacity-query", kubelet.DefaultPrometheusQueries.CapacityBytes, "PromQL returning volume capacity in bytes per PVC")
+	rootCmd.Flags().String("prometheus-available-query", kubelet.DefaultPrometheusQueries.AvailableBytes, "PromQL returning available volume bytes per PVC")
+	rootCmd.Flags().String("prometheus-inodes-query", kubelet.DefaultPrometheusQueries.InodesTotal, "PromQL returning total inodes per PVC (empty disables inode metrics)")
+	rootCmd.Flags().String("prometheus-inodes-used-query", kubelet.DefaultPrometheusQueries.InodesUsed, "PromQL returning used inodes per PVC (empty disables inode metrics)")
+	rootCmd.Flags().String("prometheus-timestamp-query", kubelet.DefaultPrometheusQueries.Timestamp, "PromQL returning the Unix time of the newest sample per PVC (empty uses the query time)")
+	rootCmd.Flags().String("prometheus-namespace-label", "namespace", "Series label holding the PVC namespace")
+	rootCmd.Flags().String("prometheus-pvc-label", "persistentvolumeclaim", "Series label holding the PVC name")
+	rootCmd.Flags().String("prometheus-bearer-token-file", "", "File containing a bearer token for Prometheus, re-read on every query")
+	rootCmd.Flags().String("prometheus-username", "", "Basic auth username for Prometheus")
+	rootCmd.Flags().String("prometheus-password", "", "Basic auth password for Prometheus (prefer PVC_CHONKER_PROMETHEUS_PASSWORD)")
+	rootCmd.Flags().Duration("prometheus-timeout", 30*time.Second, "Timeout for Prometheus queries")
+	rootCmd.Flags().String("kubelet-endpoint", kubelet.EndpointMetrics, "Kubelet endpoint to read volume stats from: metrics or stats/summary")
+	rootCmd.Flags().String("kubelet-url", "", "Custom kubelet URL template rendered per node with {{.NodeName}}, {{.NodeIP}}, {{.NodeHostname}} and {{.KubeletPort}} (e.g. https://{{.NodeIP}}:10250)")
+	rootCmd.Flags().Bool("kubelet-direct", false, "Connect directly to each kubelet's secure port instead of the API server node proxy")
+	rootCmd.Flags().String("kubelet-bearer-token-file", "", "Bearer token file for direct kubelet requests (defaults to the service account token in direct mode)")
+	rootCmd.Flags().String("kubelet-client-cert-file", "", "Client certificate file for mTLS to kubelets")
+	rootCmd.Flags().String("kubelet-client-key-file", "", "Client key file for mTLS to kubelets")
+	rootCmd.Flags().String("kubelet-ca-file", "", "CA bundle verifying kubelet serving certificates")
+	rootCmd.Flags().Bool("kubelet-insecure-skip-verify", false, "Skip kubelet serving certificate verification (development only)")
+	rootCmd.Flags().String("kubelet-record-dir", "", "Directory recording the raw payload of every kubelet scrape (empty disables)")
+	rootCmd.Flags().Int("kubelet-record-max", kubelet.DefaultMaxRecordings, "Number of recorded scrape cycles to keep")
+	rootCmd.Flags().String("replay-dir", "", "Directory of recorded kubelet scrapes when metrics-source is replay")
+	rootCmd.Flags().String("rwx-dedupe-strategy", kubelet.DefaultDedupeStrategy, "How to merge samples of volumes mounted on several nodes: freshest, pessimistic or same-node")
+	rootCmd.Flags().String("push-bind-address", "", "Address of the endpoint agents push usage samples to, e.g. :8443 (empty disables)")
+	rootCmd.Flags().String("push-token-file", "", "File listing the bearer tokens agents authenticate with, one per line with optional namespace scopes, re-read on every request")
+	rootCmd.Flags().String("push-cert-file", "", "TLS certificate file for the push endpoint")
+	rootCmd.Flags().String("push-key-file", "", "TLS key file for the push endpoint")
+	rootCmd.Flags().Bool("push-insecure", false, "Serve the push endpoint without TLS, sending bearer tokens in plain text (only behind a TLS-terminating proxy)")
+	rootCmd.Flags().Duration("push-ttl", kubelet.DefaultPushTTL, "How long a pushed usage sample is used after it was collected")
+	rootCmd.Flags().String("push-mode", kubelet.DefaultPushMode, "How pushed samples combine with collected ones: merge (freshest wins) or override (pushed wins)")
+	rootCmd.Flags().Duration("max-metrics-staleness", 0, "Skip PVCs whose volume metrics sample is older than this (0 disables)")
+	rootCmd.Flags().Duration("kubelet-timeout", kubelet.DefaultNodeTimeout, "Timeout for scraping a single node")
+	rootCmd.Flags().Int("kubelet-max-concurrent-scrapes", kubelet.DefaultMaxConcurrentScrapes, "Maximum number of nodes scraped at the same time")
 	rootCmd.Flags().Duration("watch-interval", 5*time.Minute, "Interval for checking PVC usage")
+	rootCmd.Flags().Duration("min-poll-interval", 0, "Minimum per-PVC check interval for adaptive polling (defaults to watch-interval)")
+	rootCmd.Flags().Duration("max-poll-interval", 0, "Maximum per-PVC check interval for adaptive polling (0 disables adaptive polling)")
 	rootCmd.Flags().Float64("default-threshold", 0, "Default storage threshold percentage")
 	rootCmd.Flags().Float64("default-inodes-threshold", 0, "Default inode threshold percentage")
 	rootCmd.Flags().String("default-increase", "", "Default expansion amount")
@@ -65,9 +115,36 @@ func main() {
 	rootCmd.Flags().String("log-format", "json", "Log format: json or console")
 	rootCmd.Flags().String("log-level", "info", "Log level: debug, info, warn, error")
 	rootCmd.Flags().Int("max-parallel", 4, "Maximum parallel PVC operations")
+	rootCmd.Flags().Int("max-expansions-per-cycle", 0, "Hold the expansion plan when it expands more PVCs than this in one cycle (0 disables)")
+	rootCmd.Flags().String("max-expansion-bytes-per-cycle", "", "Hold the expansion plan when it adds more storage than this in one cycle, e.g. 1Ti")
+	rootCmd.Flags().Float64("max-expansion-percent-per-cycle", 0, "Hold the expansion plan when it expands more than this percentage of managed PVCs in one cycle (0 disables)")
+	rootCmd.Flags().Int("breaker-failure-threshold", 5, "Resize failures per provisioner within the failure window that pause its expansions (0 disables the circuit breaker)")
+	rootCmd.Flags().Duration("breaker-failure-window", 10*time.Minute, "Window in which resize failures are counted by the circuit breaker")
+	rootCmd.Flags().Duration("breaker-cool-off", 15*time.Minute, "How long expansions stay paused before a single probe expansion is attempted")
+	rootCmd.Flags().Duration("stuck-resize-timeout", 30*time.Minute, "Count a resize still in progress after this long as a failure (0 disables)")
+	rootCmd.Flags().String("provisioner-max-in-flight", "", "Maximum PVCs resizing at once per provisioner, e.g. nfs.csi.k8s.io=2,rbd.csi.ceph.com=4")
+	rootCmd.Flags().String("provisioner-max-expansions-per-minute", "", "Maximum expansions started per minute per provisioner, e.g. nfs.csi.k8s.io=10")
+	rootCmd.Flags().Duration("expansion-jitter", 5*time.Second, "Upper bound of the random delay before each expansion (0 disables)")
+	rootCmd.Flags().String("control-namespace", "", "Namespace of the control ConfigMap (defaults to POD_NAMESPACE or pvc-chonker-system)")
+	rootCmd.Flags().String("control-configmap", control.DefaultConfigMapName, "Name of the control ConfigMap used to acknowledge held expansion plans")
+	rootCmd.Flags().String("history-backend", historyBackendConfigMap, "Where to persist per-PVC usage history: configmap, file or none")
+	rootCmd.Flags().String("history-configmap", history.DefaultConfigMapName, "Name of the ConfigMap in the control namespace holding usage history")
+	rootCmd.Flags().String("history-file", "", "File holding usage history when history-backend is file, e.g. on a mounted volume")
+	rootCmd.Flags().Int("history-raw-samples", history.DefaultRawSamples, "Raw usage samples kept per PVC")
+	rootCmd.Flags().Int("history-downsampled-samples", history.DefaultDownsampledSamples, "Downsampled usage samples kept per PVC")
+	rootCmd.Flags().Duration("history-resolution", history.DefaultResolution, "Bucket width of downsampled usage samples")
+	rootCmd.Flags().Duration("history-save-interval", 5*time.Minute, "Minimum interval between saves of the usage history")
 	rootCmd.Flags().String("webhook-port", "9443", "Webhook server port")
 	rootCmd.Flags().String("webhook-cert-dir", "/tmp/k8s-webhook-server/serving-certs", "Webhook certificate directory")
 	rootCmd.Flags().Bool("enable-webhook", false, "Enable admission webhook")
+	rootCmd.Flags().Bool("once", false, "Run a single reconciliation pass over policies, PVCs and groups, print a summary and exit, e.g. from a CronJob")
+	rootCmd.Flags().String("once-output", "table", "Summary format of --once: table or json")
+
+	rootCmd.AddCommand(newHistoryCommand())
+	rootCmd.AddCommand(newAgentCommand())
+	rootCmd.AddCommand(newSimulateCommand())
+	rootCmd.AddCommand(newExplainCommand())
+	rootCmd.AddCommand(newLintCommand())
 
 	// Bind viper to flags
 	if err := viper.BindPFlags(rootCmd.Flags()); err != nil {
@@ -91,6 +168,7 @@ func run(cmd *cobra.Command, args []string) {
 	logFormat := viper.GetString("log-format")
 	logLevel := viper.GetString("log-level")
 	dryRun := viper.GetBool("dry-run")
+	once := viper.GetBool("once")
 
 	var level zapcore.Level
 	switch logLevel {
@@ -118,18 +196,42 @@ func run(cmd *cobra.Command, args []string) {
 		setupLog.Info("Starting in DRY RUN mode - no PVC modifications will be made")
 	}
 
+	if once {
+		if output := viper.GetString("once-output"); output != "table" && output != "json" {
+			setupLog.Error(nil, "invalid once-output value, expected table or json", "value", utils.SanitizeForLogging(output))
+			os.Exit(1)
+		}
+		// Agents cannot push samples to a process that exits after one pass
+		if viper.GetString("push-bind-address") != "" || viper.GetString("metrics-source") == "push" {
+			setupLog.Error(nil, "pushed metrics are not supported with --once")
+			os.Exit(1)
+		}
+	}
+
 	cfg, err := ctrl.GetConfig()
 	if err != nil {
 		setupLog.Error(err, "unable to get kubernetes config")
 		os.Exit(1)
 	}
 
+	metricsBindAddress := utils.SanitizeForLogging(viper.GetString("metrics-bind-address"))
+	healthProbeBindAddress := utils.SanitizeForLogging(viper.GetString("health-probe-bind-address"))
+	if once {
+		// Nothing scrapes or probes a single pass
+		metricsBindAddress, healthProbeBindAddress = "0", "0"
+	}
+
 	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
 		Scheme:                 scheme,
-		Metrics:                server.Options{BindAddress: utils.SanitizeForLogging(viper.GetString("metrics-bind-address"))},
-		HealthProbeBindAddress: utils.SanitizeForLogging(viper.GetString("health-probe-bind-address")),
+		Metrics:                server.Options{BindAddress: metricsBindAddress},
+		HealthProbeBindAddress: healthProbeBindAddress,
 		LeaderElection:         viper.GetBool("leader-elect"),
 		LeaderElectionID:       "pvc-chonker-leader-election",
+		Cache: cache.Options{
+			ByObject: map[client.Object]cache.ByObject{
+				&corev1.Pod{}: {Transform: kubelet.TrimPod},
+			},
+		},
 	})
 	if err != nil {
 		setupLog.Error(nil, "unable to start manager", "error", utils.SanitizeError(err))
@@ -163,36 +265,159 @@ func run(cmd *cobra.Command, args []string) {
 		maxSizeQty,
 	)
 
-	// Use custom kubelet URL if provided via flag or env var (for e2e testing)
-	kubeletURL := viper.GetString("kubelet-url")
-	if kubeletURL != "" {
-		setupLog.Info("Using custom kubelet URL instead of K8s API proxy", "url", utils.SanitizeURL(kubeletURL))
-	} else {
-		setupLog.Info("Using Kubernetes API proxy for kubelet metrics")
+	cycleLimits := controller.CycleLimits{
+		MaxExpansions: viper.GetInt("max-expansions-per-cycle"),
+		MaxPercent:    viper.GetFloat64("max-expansion-percent-per-cycle"),
 	}
-	metricsCollector, err := kubelet.NewMetricsCollector(kubeletURL)
+	if maxBytes := viper.GetString("max-expansion-bytes-per-cycle"); maxBytes != "" {
+		if qty, err := resource.ParseQuantity(maxBytes); err != nil {
+			setupLog.Error(nil, "invalid max-expansion-bytes-per-cycle value", "value", utils.SanitizeForLogging(maxBytes), "error", utils.SanitizeError(err))
+			os.Exit(1)
+		} else {
+			cycleLimits.MaxBytes = qty
+		}
+	}
+
+	controlNamespace := viper.GetString("control-namespace")
+	if controlNamespace == "" {
+		controlNamespace = os.Getenv("POD_NAMESPACE")
+	}
+	// Read the control ConfigMap directly to avoid caching all ConfigMaps
+	controlLoader := control.NewLoader(mgr.GetAPIReader(), controlNamespace, viper.GetString("control-configmap"))
+
+	expansionBreaker := breaker.New(breaker.Settings{
+		FailureThreshold: viper.GetInt("breaker-failure-threshold"),
+		Window:           viper.GetDuration("breaker-failure-window"),
+		CoolOff:          viper.GetDuration("breaker-cool-off"),
+	})
+	metrics.RegisterCircuitBreakerDegraded(expansionBreaker.Tripped)
+
+	provisionerMaxInFlight, err := throttle.ParseLimits(viper.GetString("provisioner-max-in-flight"))
 	if err != nil {
-		setupLog.Error(nil, "unable to create metrics collector", "error", utils.SanitizeError(err))
+		setupLog.Error(nil, "invalid provisioner-max-in-flight value", "error", utils.SanitizeError(err))
 		os.Exit(1)
 	}
+	provisionerPerMinute, err := throttle.ParseLimits(viper.GetString("provisioner-max-expansions-per-minute"))
+	if err != nil {
+		setupLog.Error(nil, "invalid provisioner-max-expansions-per-minute value", "error", utils.SanitizeError(err))
+		os.Exit(1)
+	}
+	expansionThrottle := throttle.NewThrottle(throttle.MergeLimits(provisionerMaxInFlight, provisionerPerMinute), viper.GetDuration("expansion-jitter"))
 
-	// Set Kubernetes clients on metrics collector
-	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	historyStore := history.NewStore(history.Config{
+		RawSamples:         viper.GetInt("history-raw-samples"),
+		DownsampledSamples: viper.GetInt("history-downsampled-samples"),
+		Resolution:         viper.GetDuration("history-resolution"),
+	})
+	historyBackend, err := newHistoryBackend(viper.GetString("history-backend"), mgr.GetAPIReader(), mgr.GetClient(), controlLoader.Key.Namespace, viper.GetString("history-configmap"), viper.GetString("history-file"))
 	if err != nil {
-		setupLog.Error(nil, "unable to create clientset", "error", utils.SanitizeError(err))
+		setupLog.Error(nil, "invalid history configuration", "error", utils.SanitizeError(err))
 		os.Exit(1)
 	}
-	metricsCollector.SetClient(mgr.GetClient(), clientset)
+
+	var metricsCollector kubelet.MetricsCollectorInterface
+	source := viper.GetString("metrics-source")
+	switch source {
+	case "kubelet":
+		metricsCollector = newKubeletMetricsCollector(mgr)
+	case "prometheus":
+		metricsCollector = newPrometheusMetricsCollector()
+	case "push":
+		if viper.GetString("push-bind-address") == "" {
+			setupLog.Error(nil, "metrics-source push requires push-bind-address")
+			os.Exit(1)
+		}
+	case "replay":
+		metricsCollector = newReplayMetricsCollector()
+	default:
+		setupLog.Error(nil, "invalid metrics-source value, expected kubelet, prometheus, push or replay", "value", utils.SanitizeForLogging(source))
+		os.Exit(1)
+	}
+
+	var pushStore *kubelet.PushStore
+	if pushAddress := viper.GetString("push-bind-address"); pushAddress != "" {
+		pushStore = kubelet.NewPushStore(viper.GetDuration("push-ttl"))
+		pushCollector, err := kubelet.NewPushMetricsCollector(metricsCollector, pushStore, viper.GetString("push-mode"))
+		if err != nil {
+			setupLog.Error(nil, "invalid push-mode value", "error", utils.SanitizeError(err))
+			os.Exit(1)
+		}
+		metricsCollector = pushCollector
+
+		tokenFile := viper.GetString("push-token-file")
+		if tokenFile == "" {
+			setupLog.Error(nil, "push-token-file is required when push-bind-address is set")
+			os.Exit(1)
+		}
+		if viper.GetString("push-cert-file") == "" && !viper.GetBool("push-insecure") {
+			setupLog.Error(nil, "push-cert-file is required when push-bind-address is set, or set push-insecure to serve bearer tokens in plain text")
+			os.Exit(1)
+		}
+		pushServer := &kubelet.PushServer{
+			BindAddress: pushAddress,
+			Handler:     &kubelet.PushHandler{Store: pushStore, TokenFile: tokenFile},
+			CertFile:    viper.GetString("push-cert-file"),
+			KeyFile:     viper.GetString("push-key-file"),
+			Insecure:    viper.GetBool("push-insecure"),
+		}
+		if err := mgr.Add(pushServer); err != nil {
+			setupLog.Error(nil, "unable to add push endpoint", "error", utils.SanitizeError(err))
+			os.Exit(1)
+		}
+		setupLog.Info("Push endpoint enabled", "address", pushAddress, "mode", viper.GetString("push-mode"), "ttl", viper.GetDuration("push-ttl").String())
+	}
+
+	// A single pass reads the API server directly, so groups are coordinated
+	// on the sizes just written by the PVC cycle rather than a lagging cache
+	reconcilerClient := mgr.GetClient()
+	if once {
+		if reconcilerClient, err = client.New(cfg, client.Options{Scheme: scheme}); err != nil {
+			setupLog.Error(nil, "unable to create client", "error", utils.SanitizeError(err))
+			os.Exit(1)
+		}
+	}
 
 	pvcController := &controller.PersistentVolumeClaimReconciler{
-		Client:           mgr.GetClient(),
-		Scheme:           mgr.GetScheme(),
-		GlobalConfig:     globalConfig,
-		MetricsCollector: metricsCollector,
-		WatchInterval:    viper.GetDuration("watch-interval"),
-		EventRecorder:    mgr.GetEventRecorderFor("pvc-chonker"),
-		DryRun:           dryRun,
-		MaxParallel:      viper.GetInt("max-parallel"),
+		Client:              reconcilerClient,
+		Scheme:              mgr.GetScheme(),
+		GlobalConfig:        globalConfig,
+		MetricsCollector:    metricsCollector,
+		WatchInterval:       viper.GetDuration("watch-interval"),
+		EventRecorder:       mgr.GetEventRecorderFor("pvc-chonker"),
+		DryRun:              dryRun,
+		MaxParallel:         viper.GetInt("max-parallel"),
+		MinPollInterval:     viper.GetDuration("min-poll-interval"),
+		MaxPollInterval:     viper.GetDuration("max-poll-interval"),
+		CycleLimits:         cycleLimits,
+		ControlLoader:       controlLoader,
+		Breaker:             expansionBreaker,
+		StuckResizeTimeout:  viper.GetDuration("stuck-resize-timeout"),
+		Throttle:            expansionThrottle,
+		MaxMetricsStaleness: viper.GetDuration("max-metrics-staleness"),
+		History:             historyStore,
+		HistoryBackend:      historyBackend,
+		HistorySaveInterval: viper.GetDuration("history-save-interval"),
+		PushedMetrics:       pushStore,
+	}
+	policyController := &controller.PVCPolicyReconciler{
+		Client:        reconcilerClient,
+		Scheme:        mgr.GetScheme(),
+		EventRecorder: mgr.GetEventRecorderFor("pvc-chonker-policy"),
+	}
+	groupController := &controller.PVCGroupReconciler{
+		Client:        reconcilerClient,
+		Scheme:        mgr.GetScheme(),
+		EventRecorder: mgr.GetEventRecorderFor("pvc-chonker-group"),
+		ControlLoader: controlLoader,
+	}
+
+	if once {
+		os.Exit(runOnce(mgr, &controller.Once{
+			Client:   reconcilerClient,
+			PVCs:     pvcController,
+			Policies: policyController,
+			Groups:   groupController,
+		}, os.Stdout, viper.GetString("once-output")))
 	}
 
 	// Add the controller as a runnable for periodic reconciliation only
@@ -202,22 +427,12 @@ func run(cmd *cobra.Command, args []string) {
 	}
 
 	// Setup PVCPolicy controller
-	policyController := &controller.PVCPolicyReconciler{
-		Client:        mgr.GetClient(),
-		Scheme:        mgr.GetScheme(),
-		EventRecorder: mgr.GetEventRecorderFor("pvc-chonker-policy"),
-	}
 	if err = policyController.SetupWithManager(mgr); err != nil {
 		setupLog.Error(nil, "unable to create PVCPolicy controller", "error", utils.SanitizeError(err))
 		os.Exit(1)
 	}
 
 	// Setup PVCGroup controller
-	groupController := &controller.PVCGroupReconciler{
-		Client:        mgr.GetClient(),
-		Scheme:        mgr.GetScheme(),
-		EventRecorder: mgr.GetEventRecorderFor("pvc-chonker-group"),
-	}
 	if err = groupController.SetupWithManager(mgr); err != nil {
 		setupLog.Error(nil, "unable to create PVCGroup controller", "error", utils.SanitizeError(err))
 		os.Exit(1)
@@ -248,3 +463,129 @@ func run(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 }
+
+func newKubeletMetricsCollector(mgr ctrl.Manager) *kubelet.MetricsCollector {
+	// Use custom kubelet URL if provided via flag or env var (for e2e testing)
+	kubeletURL := viper.GetString("kubelet-url")
+	direct := viper.GetBool("kubelet-direct")
+	if direct && kubeletURL == "" {
+		kubeletURL = kubelet.DirectURLTemplate
+	}
+	if kubeletURL != "" {
+		setupLog.Info("Using custom kubelet URL instead of K8s API proxy", "url", utils.SanitizeURL(kubeletURL))
+	} else {
+		setupLog.Info("Using Kubernetes API proxy for kubelet metrics")
+	}
+	metricsCollector, err := kubelet.NewMetricsCollector(kubeletURL)
+	if err != nil {
+		setupLog.Error(nil, "unable to create metrics collector", "error", utils.SanitizeError(err))
+		os.Exit(1)
+	}
+
+	// Set Kubernetes clients on metrics collector
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(nil, "unable to create clientset", "error", utils.SanitizeError(err))
+		os.Exit(1)
+	}
+	metricsCollector.SetClient(mgr.GetClient(), clientset)
+
+	if err := metricsCollector.SetEndpoint(viper.GetString("kubelet-endpoint")); err != nil {
+		setupLog.Error(nil, "invalid kubelet-endpoint value", "error", utils.SanitizeError(err))
+		os.Exit(1)
+	}
+
+	if err := metricsCollector.SetDedupeStrategy(viper.GetString("rwx-dedupe-strategy")); err != nil {
+		setupLog.Error(nil, "invalid rwx-dedupe-strategy value", "error", utils.SanitizeError(err))
+		os.Exit(1)
+	}
+
+	if recordDir := viper.GetString("kubelet-record-dir"); recordDir != "" {
+		recorder, err := kubelet.NewRecorder(recordDir, viper.GetInt("kubelet-record-max"))
+		if err != nil {
+			setupLog.Error(nil, "unable to record kubelet scrapes", "error", utils.SanitizeError(err))
+			os.Exit(1)
+		}
+		metricsCollector.SetRecorder(recorder)
+		setupLog.Info("Recording kubelet scrapes", "dir", recordDir, "max", recorder.MaxRecordings)
+	}
+
+	if err := metricsCollector.SetMaxConcurrency(viper.GetInt("kubelet-max-concurrent-scrapes")); err != nil {
+		setupLog.Error(nil, "invalid kubelet-max-concurrent-scrapes value", "error", utils.SanitizeError(err))
+		os.Exit(1)
+	}
+
+	if err := metricsCollector.IndexPods(context.Background(), mgr.GetFieldIndexer()); err != nil {
+		setupLog.Error(nil, "unable to index pods", "error", utils.SanitizeError(err))
+		os.Exit(1)
+	}
+
+	if err := metricsCollector.SetNodeTimeout(viper.GetDuration("kubelet-timeout")); err != nil {
+		setupLog.Error(nil, "invalid kubelet-timeout value", "error", utils.SanitizeError(err))
+		os.Exit(1)
+	}
+
+	httpConfig := kubelet.HTTPConfig{
+		BearerTokenFile:    viper.GetString("kubelet-bearer-token-file"),
+		CertFile:           viper.GetString("kubelet-client-cert-file"),
+		KeyFile:            viper.GetString("kubelet-client-key-file"),
+		CAFile:             viper.GetString("kubelet-ca-file"),
+		InsecureSkipVerify: viper.GetBool("kubelet-insecure-skip-verify"),
+	}
+	if direct && httpConfig.BearerTokenFile == "" && httpConfig.CertFile == "" {
+		httpConfig.BearerTokenFile = kubelet.ServiceAccountTokenFile
+	}
+	if httpConfig.InsecureSkipVerify {
+		setupLog.Info("Kubelet serving certificates are not verified, do not use in production")
+	}
+	if err := metricsCollector.SetHTTPConfig(httpConfig); err != nil {
+		setupLog.Error(nil, "invalid kubelet TLS configuration", "error", utils.SanitizeError(err))
+		os.Exit(1)
+	}
+	return metricsCollector
+}
+
+func newReplayMetricsCollector() *kubelet.ReplayCollector {
+	replayDir := viper.GetString("replay-dir")
+	if replayDir == "" {
+		setupLog.Error(nil, "metrics-source replay requires replay-dir")
+		os.Exit(1)
+	}
+	metricsCollector, err := kubelet.NewReplayCollector(replayDir, false)
+	if err != nil {
+		setupLog.Error(nil, "unable to create replay collector", "error", utils.SanitizeError(err))
+		os.Exit(1)
+	}
+	if err := metricsCollector.SetDedupeStrategy(viper.GetString("rwx-dedupe-strategy")); err != nil {
+		setupLog.Error(nil, "invalid rwx-dedupe-strategy value", "error", utils.SanitizeError(err))
+		os.Exit(1)
+	}
+	setupLog.Info("Replaying recorded kubelet scrapes", "dir", replayDir, "recordings", metricsCollector.Len())
+	return metricsCollector
+}
+
+func newPrometheusMetricsCollector() *kubelet.PrometheusMetricsCollector {
+	prometheusURL := viper.GetString("prometheus-url")
+	setupLog.Info("Using Prometheus for volume metrics", "url", utils.SanitizeURL(prometheusURL))
+	metricsCollector, err := kubelet.NewPrometheusMetricsCollector(kubelet.PrometheusConfig{
+		URL: prometheusURL,
+		Queries: kubelet.PrometheusQueries{
+			CapacityBytes:  viper.GetString("prometheus-capacity-query"),
+			AvailableBytes: viper.GetString("prometheus-available-query"),
+			InodesTotal:    viper.GetString("prometheus-inodes-query"),
+			InodesUsed:     viper.GetString("prometheus-inodes-used-query"),
+			Timestamp:      viper.GetString("prometheus-timestamp-query"),
+		},
+		NamespaceLabel:  viper.GetString("prometheus-namespace-label"),
+		PVCLabel:        viper.GetString("prometheus-pvc-label"),
+		BearerTokenFile: viper.GetString("prometheus-bearer-token-file"),
+		Username:        viper.GetString("prometheus-username"),
+		Password:        viper.GetString("prometheus-password"),
+		Timeout:         viper.GetDuration("prometheus-timeout"),
+	})
+	if err != nil {
+		setupLog.Error(nil, "unable to create prometheus metrics collector", "error", utils.SanitizeError(err))
+		os.Exit(1)
+	}
+	return metricsCollector
+}
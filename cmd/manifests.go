@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// forEachDocument decodes the YAML or JSON documents of a manifest file, or
+// stdin for -, and calls fn with the 1-based index and JSON of each non-empty
+// document.
+func forEachDocument(file string, stdin io.Reader, fn func(index int, data []byte) error) error {
+	var reader io.Reader = stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		reader = f
+	}
+
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bufio.NewReader(reader), 4096)
+	for index := 1; ; {
+		var raw runtime.RawExtension
+		if err := decoder.Decode(&raw); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		if len(raw.Raw) == 0 {
+			continue
+		}
+		if err := fn(index, raw.Raw); err != nil {
+			return err
+		}
+		index++
+	}
+}
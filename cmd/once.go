@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/logicIQ/pvc-chonker/internal/controller"
+	"github.com/logicIQ/pvc-chonker/pkg/utils"
+)
+
+// runOnce starts the manager for the caches, leader election and event
+// recording, runs a single reconciliation pass and stops. It returns the exit
+// code, non-zero when anything failed.
+func runOnce(mgr ctrl.Manager, once *controller.Once, out io.Writer, output string) int {
+	ctx, cancel := context.WithCancel(ctrl.SetupSignalHandler())
+	defer cancel()
+
+	var summary *controller.CycleSummary
+	if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		summary = once.Run(ctx)
+		cancel()
+		return nil
+	})); err != nil {
+		setupLog.Error(nil, "unable to add reconciliation pass", "error", utils.SanitizeError(err))
+		return 1
+	}
+
+	setupLog.Info("running a single reconciliation pass", "dryRun", fmt.Sprintf("%t", once.PVCs.DryRun))
+	if err := mgr.Start(ctx); err != nil {
+		setupLog.Error(nil, "problem running manager", "error", utils.SanitizeError(err))
+		return 1
+	}
+	if summary == nil {
+		setupLog.Error(nil, "interrupted before the reconciliation pass completed")
+		return 1
+	}
+
+	if err := printCycleSummary(out, summary, output); err != nil {
+		setupLog.Error(nil, "unable to print summary", "error", utils.SanitizeError(err))
+		return 1
+	}
+	if summary.HasFailures() {
+		return 1
+	}
+	return 0
+}
+
+func printCycleSummary(out io.Writer, summary *controller.CycleSummary, output string) error {
+	if output == "json" {
+		type jsonSummary struct {
+			*controller.CycleSummary
+			Duration string `json:"duration"`
+		}
+		return writeJSON(out, jsonSummary{CycleSummary: summary, Duration: summary.Duration.String()})
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Started:\t%s\n", summary.StartedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(w, "Duration:\t%s\n", summary.Duration.Round(time.Millisecond))
+	fmt.Fprintf(w, "Dry run:\t%t\n", summary.DryRun)
+	fmt.Fprintf(w, "PVCs:\t%d total, %d managed, %d due\n", summary.TotalPVCs, summary.ManagedPVCs, summary.DuePVCs)
+	fmt.Fprintf(w, "PVCPolicies:\t%d\n", summary.Policies)
+	fmt.Fprintf(w, "PVCGroups:\t%d\n", summary.Groups)
+	if summary.PlanID != "" {
+		plan := summary.PlanID
+		if summary.PlanHeld {
+			plan += " (held, exceeds safety caps)"
+		}
+		fmt.Fprintf(w, "Plan:\t%s\n", plan)
+	}
+	fmt.Fprintf(w, "Expanded:\t%d\n", len(summary.Expanded))
+	fmt.Fprintf(w, "Skipped:\t%s\n", formatReasonCounts(summary.Skipped))
+	fmt.Fprintf(w, "Failed:\t%d\n", len(summary.Failed))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(summary.Expanded) > 0 {
+		fmt.Fprintln(out)
+		w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "PVC\tFROM\tTO")
+		for _, e := range summary.Expanded {
+			fmt.Fprintf(w, "%s/%s\t%s\t%s\n", e.Namespace, e.Name, e.From, e.To)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(summary.Failed) > 0 {
+		fmt.Fprintln(out)
+		w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "KIND\tOBJECT\tERROR")
+		for _, f := range summary.Failed {
+			object := "-"
+			if f.Name != "" {
+				object = f.Name
+				if f.Namespace != "" {
+					object = f.Namespace + "/" + f.Name
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", f.Kind, object, f.Error)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatReasonCounts formats counts per reason sorted by reason.
+func formatReasonCounts(counts map[string]int) string {
+	reasons := make([]string, 0, len(counts))
+	for reason, count := range counts {
+		reasons = append(reasons, fmt.Sprintf("%s=%d", reason, count))
+	}
+	if len(reasons) == 0 {
+		return "-"
+	}
+	sort.Strings(reasons)
+	return strings.Join(reasons, ", ")
+}
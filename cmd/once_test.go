@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/logicIQ/pvc-chonker/internal/controller"
+)
+
+func TestPrintCycleSummary(t *testing.T) {
+	summary := &controller.CycleSummary{
+		StartedAt:   time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Duration:    1500 * time.Millisecond,
+		TotalPVCs:   3,
+		ManagedPVCs: 2,
+		DuePVCs:     2,
+		PlanID:      "abc123",
+		Expanded:    []controller.ExpansionResult{{Namespace: "default", Name: "data", From: "10Gi", To: "11Gi"}},
+		Skipped:     map[string]int{"cooldown": 1, "below_threshold": 2},
+		Failed:      []controller.Failure{{Kind: "PVCGroup", Namespace: "default", Name: "kafka", Error: "conflict"}},
+		Policies:    1,
+	}
+
+	var out bytes.Buffer
+	if err := printCycleSummary(&out, summary, "table"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"PVCs:         3 total, 2 managed, 2 due",
+		"Plan:         abc123",
+		"Skipped:      below_threshold=2, cooldown=1",
+		"default/data  10Gi  11Gi",
+		"PVCGroup  default/kafka  conflict",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := printCycleSummary(&out, summary, "json"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if decoded["duration"] != "1.5s" || decoded["managedPVCs"] != float64(2) {
+		t.Errorf("unexpected JSON summary: %s", out.String())
+	}
+}
+
+func TestFormatReasonCounts(t *testing.T) {
+	if got := formatReasonCounts(nil); got != "-" {
+		t.Errorf("expected -, got %q", got)
+	}
+	if got := formatReasonCounts(map[string]int{"stale": 1, "cooldown": 3}); got != "cooldown=3, stale=1" {
+		t.Errorf("unexpected counts %q", got)
+	}
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/logicIQ/pvc-chonker/api/v1alpha1"
+	"github.com/logicIQ/pvc-chonker/pkg/annotations"
+	"github.com/logicIQ/pvc-chonker/pkg/simulate"
+)
+
+type simulateOptions struct {
+	files       []string
+	annotations map[string]string
+	labels      map[string]string
+	size        string
+
+	globalDefaults
+
+	trace               string
+	replayDir           string
+	pvc                 string
+	initialUsed         string
+	growthPerHour       string
+	growthPercentPerDay float64
+
+	start    string
+	interval time.Duration
+	duration time.Duration
+	output   string
+}
+
+func newSimulateCommand() *cobra.Command {
+	opts := &simulateOptions{}
+	cmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "Simulate the expansions of a PVC over a usage trace",
+		Long: "Replay a usage trace of a single PVC against the expansion logic of the operator, offline. The\n" +
+			"configuration is resolved from PVCPolicy and PVCGroup manifests, PVC annotations and the global\n" +
+			"defaults with the same precedence as in the cluster. The trace is a CSV or JSON file, recorded\n" +
+			"kubelet scrapes, or linear or exponential growth.",
+		Example: "  pvc-chonker simulate -f policy.yaml --labels app=db --size 50Gi --trace usage.csv\n" +
+			"  pvc-chonker simulate --annotations threshold=80%,increase=20% --size 10Gi --initial-used 5Gi --growth-per-hour 100Mi --duration 168h\n" +
+			"  pvc-chonker simulate -f pvc.yaml --replay-dir /var/lib/pvc-chonker/recordings --pvc default/data",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSimulate(cmd.Context(), cmd.InOrStdin(), cmd.OutOrStdout(), opts)
+		},
+	}
+	cmd.Flags().StringSliceVarP(&opts.files, "filename", "f", nil, "Manifests holding PVCPolicies, PVCGroups and at most one PersistentVolumeClaim (- reads stdin)")
+	cmd.Flags().StringToStringVar(&opts.annotations, "annotations", nil, "PVC annotations, the pvc-chonker.io/ prefix may be omitted, e.g. threshold=80%,increase=20%")
+	cmd.Flags().StringToStringVar(&opts.labels, "labels", nil, "PVC labels PVCPolicy selectors are matched against")
+	cmd.Flags().StringVar(&opts.size, "size", "", "Initial size of the PVC (defaults to the capacity of the PVC manifest)")
+	opts.addFlags(cmd)
+	cmd.Flags().StringVar(&opts.trace, "trace", "", "Usage trace file, JSON when it ends in .json and CSV with a time,usedBytes[,inodesUsed,inodesTotal] header otherwise")
+	cmd.Flags().StringVar(&opts.replayDir, "replay-dir", "", "Directory of recorded kubelet scrapes to read the usage of --pvc from")
+	cmd.Flags().StringVar(&opts.pvc, "pvc", "", "namespace/name of the PVC in the recorded scrapes (defaults to the PVC manifest)")
+	cmd.Flags().StringVar(&opts.initialUsed, "initial-used", "", "Used bytes at the start of a synthetic trace, e.g. 5Gi")
+	cmd.Flags().StringVar(&opts.growthPerHour, "growth-per-hour", "", "Linear growth of a synthetic trace, e.g. 100Mi")
+	cmd.Flags().Float64Var(&opts.growthPercentPerDay, "growth-percent-per-day", 0, "Exponential growth of a synthetic trace in percent per day")
+	cmd.Flags().StringVar(&opts.start, "start", "", "RFC3339 time of the first cycle (defaults to the start of the trace or now)")
+	cmd.Flags().DurationVar(&opts.interval, "interval", simulate.DefaultInterval, "Interval between simulated reconciliation cycles")
+	cmd.Flags().DurationVar(&opts.duration, "duration", 0, "Simulated duration (defaults to the length of the trace)")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "table", "Output format: table or json")
+	return cmd
+}
+
+func runSimulate(ctx context.Context, in io.Reader, out io.Writer, opts *simulateOptions) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if opts.output != "table" && opts.output != "json" {
+		return fmt.Errorf("unsupported output format %q, expected table or json", opts.output)
+	}
+
+	var objects simulationObjects
+	for _, file := range opts.files {
+		if err := objects.read(file, in); err != nil {
+			return err
+		}
+	}
+	pvc, err := simulatedPVC(objects.pvc, opts, len(objects.policies) == 0)
+	if err != nil {
+		return err
+	}
+	global, err := opts.globalConfig()
+	if err != nil {
+		return err
+	}
+	trace, traceStart, err := simulationTrace(opts, pvc)
+	if err != nil {
+		return err
+	}
+
+	start := traceStart
+	if opts.start != "" {
+		if start, err = time.Parse(time.RFC3339, opts.start); err != nil {
+			return fmt.Errorf("invalid start: %w", err)
+		}
+	}
+
+	result, err := simulate.Run(ctx, simulate.Config{
+		PVC:      pvc,
+		Policies: objects.policies,
+		Groups:   objects.groups,
+		Global:   global,
+		Start:    start,
+		Interval: opts.interval,
+		Duration: opts.duration,
+	}, trace)
+	if err != nil {
+		return err
+	}
+
+	if opts.output == "json" {
+		return writeJSON(out, newSimulationJSON(result))
+	}
+	return printSimulation(out, result)
+}
+
+// simulationObjects are the objects read from the manifests.
+type simulationObjects struct {
+	pvc      *corev1.PersistentVolumeClaim
+	policies []v1alpha1.PVCPolicy
+	groups   []v1alpha1.PVCGroup
+}
+
+func (o *simulationObjects) read(file string, stdin io.Reader) error {
+	return forEachDocument(file, stdin, func(_ int, data []byte) error {
+		if err := o.add(data); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		return nil
+	})
+}
+
+func (o *simulationObjects) add(data []byte) error {
+	var typeMeta metav1.TypeMeta
+	if err := json.Unmarshal(data, &typeMeta); err != nil {
+		return err
+	}
+	switch typeMeta.Kind {
+	case "PVCPolicy":
+		var policy v1alpha1.PVCPolicy
+		if err := json.Unmarshal(data, &policy); err != nil {
+			return fmt.Errorf("invalid PVCPolicy: %w", err)
+		}
+		o.policies = append(o.policies, policy)
+	case "PVCGroup":
+		var group v1alpha1.PVCGroup
+		if err := json.Unmarshal(data, &group); err != nil {
+			return fmt.Errorf("invalid PVCGroup: %w", err)
+		}
+		o.groups = append(o.groups, group)
+	case "PersistentVolumeClaim":
+		if o.pvc != nil {
+			return fmt.Errorf("only one PersistentVolumeClaim can be simulated")
+		}
+		o.pvc = &corev1.PersistentVolumeClaim{}
+		if err := json.Unmarshal(data, o.pvc); err != nil {
+			return fmt.Errorf("invalid PersistentVolumeClaim: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported kind %q, expected PVCPolicy, PVCGroup or PersistentVolumeClaim", typeMeta.Kind)
+	}
+	return nil
+}
+
+// simulatedPVC returns the PVC from the manifests, or a new one, with the
+// annotations, labels and size flags applied. Without policies a PVC with no
+// enabled annotation is enabled, so the global defaults can be simulated.
+func simulatedPVC(pvc *corev1.PersistentVolumeClaim, opts *simulateOptions, enableByDefault bool) (*corev1.PersistentVolumeClaim, error) {
+	if pvc == nil {
+		pvc = &corev1.PersistentVolumeClaim{}
+		pvc.Name = "simulated"
+	}
+	if pvc.Namespace == "" {
+		pvc.Namespace = corev1.NamespaceDefault
+	}
+	if len(opts.annotations) > 0 && pvc.Annotations == nil {
+		pvc.Annotations = map[string]string{}
+	}
+	for key, value := range opts.annotations {
+		if !strings.Contains(key, "/") {
+			key = "pvc-chonker.io/" + key
+		}
+		pvc.Annotations[key] = value
+	}
+	if len(opts.labels) > 0 && pvc.Labels == nil {
+		pvc.Labels = map[string]string{}
+	}
+	for key, value := range opts.labels {
+		pvc.Labels[key] = value
+	}
+	if _, ok := pvc.Annotations[annotations.AnnotationEnabled]; !ok && enableByDefault {
+		if pvc.Annotations == nil {
+			pvc.Annotations = map[string]string{}
+		}
+		pvc.Annotations[annotations.AnnotationEnabled] = "true"
+	}
+
+	if opts.size != "" {
+		size, err := resource.ParseQuantity(opts.size)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size: %w", err)
+		}
+		pvc.Status.Capacity = corev1.ResourceList{corev1.ResourceStorage: size}
+	}
+	capacity := pvc.Status.Capacity[corev1.ResourceStorage]
+	request := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	if capacity.IsZero() && request.IsZero() {
+		return nil, fmt.Errorf("size is required when no PersistentVolumeClaim with a storage request is given")
+	}
+	return pvc, nil
+}
+
+// simulationTrace returns the trace selected by the flags and the time of its
+// first sample, zero when the trace has no absolute times.
+func simulationTrace(opts *simulateOptions, pvc *corev1.PersistentVolumeClaim) (simulate.Trace, time.Time, error) {
+	sources := 0
+	for _, set := range []bool{opts.trace != "", opts.replayDir != "", opts.initialUsed != ""} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return nil, time.Time{}, fmt.Errorf("exactly one of trace, replay-dir or initial-used is required")
+	}
+
+	switch {
+	case opts.trace != "":
+		f, err := os.Open(opts.trace)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		defer f.Close()
+		if strings.EqualFold(filepath.Ext(opts.trace), ".json") {
+			return simulate.ParseJSON(f)
+		}
+		return simulate.ParseCSV(f)
+
+	case opts.replayDir != "":
+		key := types.NamespacedName{Namespace: pvc.Namespace, Name: pvc.Name}
+		if opts.pvc != "" {
+			namespace, name, ok := strings.Cut(opts.pvc, "/")
+			if !ok {
+				return nil, time.Time{}, fmt.Errorf("expected namespace/name, got %q", opts.pvc)
+			}
+			key = types.NamespacedName{Namespace: namespace, Name: name}
+		}
+		return simulate.LoadRecordings(opts.replayDir, key)
+	}
+
+	initial, err := resource.ParseQuantity(opts.initialUsed)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("invalid initial-used: %w", err)
+	}
+	if opts.growthPerHour != "" && opts.growthPercentPerDay != 0 {
+		return nil, time.Time{}, fmt.Errorf("growth-per-hour and growth-percent-per-day are mutually exclusive")
+	}
+	if opts.growthPercentPerDay != 0 {
+		return simulate.ExponentialGrowth{InitialBytes: initial.Value(), PercentPerDay: opts.growthPercentPerDay}, time.Time{}, nil
+	}
+	var growth resource.Quantity
+	if opts.growthPerHour != "" {
+		if growth, err = resource.ParseQuantity(opts.growthPerHour); err != nil {
+			return nil, time.Time{}, fmt.Errorf("invalid growth-per-hour: %w", err)
+		}
+	}
+	return simulate.LinearGrowth{InitialBytes: initial.Value(), BytesPerHour: growth.Value()}, time.Time{}, nil
+}
+
+type simulationExpansionJSON struct {
+	Offset             string    `json:"offset"`
+	Time               time.Time `json:"time"`
+	From               string    `json:"from"`
+	To                 string    `json:"to"`
+	UsedBytes          int64     `json:"usedBytes"`
+	UsagePercent       float64   `json:"usagePercent"`
+	InodesUsagePercent float64   `json:"inodesUsagePercent,omitempty"`
+	Trigger            string    `json:"trigger"`
+}
+
+type simulationJSON struct {
+	Start              time.Time                 `json:"start"`
+	Duration           string                    `json:"duration"`
+	Cycles             int                       `json:"cycles"`
+	InitialSize        string                    `json:"initialSize"`
+	FinalSize          string                    `json:"finalSize"`
+	Expansions         []simulationExpansionJSON `json:"expansions"`
+	Skipped            map[string]int            `json:"skipped"`
+	TimeAboveThreshold string                    `json:"timeAboveThreshold"`
+	PeakUsagePercent   float64                   `json:"peakUsagePercent"`
+	MaxSizeReachedAt   *time.Time                `json:"maxSizeReachedAt,omitempty"`
+	FullAt             *time.Time                `json:"fullAt,omitempty"`
+}
+
+func newSimulationJSON(result *simulate.Result) simulationJSON {
+	s := simulationJSON{
+		Start:              result.Start.UTC(),
+		Duration:           result.Duration.String(),
+		Cycles:             result.Cycles,
+		InitialSize:        result.InitialSize.String(),
+		FinalSize:          result.FinalSize.String(),
+		Expansions:         make([]simulationExpansionJSON, 0, len(result.Expansions)),
+		Skipped:            result.Skipped,
+		TimeAboveThreshold: result.TimeAboveThreshold.String(),
+		PeakUsagePercent:   result.PeakUsagePercent,
+	}
+	for _, e := range result.Expansions {
+		s.Expansions = append(s.Expansions, simulationExpansionJSON{
+			Offset:             e.Offset.String(),
+			Time:               e.Time.UTC(),
+			From:               e.From.String(),
+			To:                 e.To.String(),
+			UsedBytes:          e.UsedBytes,
+			UsagePercent:       e.UsagePercent,
+			InodesUsagePercent: e.InodesUsagePercent,
+			Trigger:            e.Trigger,
+		})
+	}
+	if result.MaxSizeReachedAt != nil {
+		t := result.Start.Add(*result.MaxSizeReachedAt).UTC()
+		s.MaxSizeReachedAt = &t
+	}
+	if result.FullAt != nil {
+		t := result.Start.Add(*result.FullAt).UTC()
+		s.FullAt = &t
+	}
+	return s
+}
+
+func printSimulation(out io.Writer, result *simulate.Result) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "OFFSET\tTIME\tFROM\tTO\tUSAGE\tTRIGGER")
+	for _, e := range result.Expansions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%.1f%%\t%s\n", e.Offset, e.Time.UTC().Format(time.RFC3339), e.From.String(), e.To.String(), e.UsagePercent, e.Trigger)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	at := func(offset *time.Duration) string {
+		if offset == nil {
+			return "never"
+		}
+		return fmt.Sprintf("after %s (%s)", *offset, result.Start.Add(*offset).UTC().Format(time.RFC3339))
+	}
+	fmt.Fprintln(out)
+	w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Simulated:\t%s in %d cycles from %s\n", result.Duration, result.Cycles, result.Start.UTC().Format(time.RFC3339))
+	fmt.Fprintf(w, "Expansions:\t%d\n", len(result.Expansions))
+	fmt.Fprintf(w, "Size:\t%s -> %s\n", result.InitialSize.String(), result.FinalSize.String())
+	fmt.Fprintf(w, "Time above threshold:\t%s\n", result.TimeAboveThreshold)
+	fmt.Fprintf(w, "Peak usage:\t%.1f%%\n", result.PeakUsagePercent)
+	fmt.Fprintf(w, "Max size reached:\t%s\n", at(result.MaxSizeReachedAt))
+	fmt.Fprintf(w, "Volume full:\t%s\n", at(result.FullAt))
+	fmt.Fprintf(w, "Skipped cycles:\t%s\n", formatReasonCounts(result.Skipped))
+	return w.Flush()
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunSimulate(t *testing.T) {
+	dir := t.TempDir()
+	manifests := filepath.Join(dir, "manifests.yaml")
+	if err := os.WriteFile(manifests, []byte(`apiVersion: pvc-chonker.io/v1alpha1
+kind: PVCPolicy
+metadata:
+  name: db
+spec:
+  selector:
+    matchLabels:
+      app: db
+  template:
+    threshold: "80%"
+    increase: "50%"
+    maxSize: 15Gi
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+  labels:
+    app: db
+spec:
+  resources:
+    requests:
+      storage: 10Gi
+`), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	trace := filepath.Join(dir, "usage.csv")
+	if err := os.WriteFile(trace, []byte("time,usedBytes\n2026-01-01T00:00:00Z,5Gi\n2026-01-01T01:00:00Z,9Gi\n2026-01-01T02:00:00Z,14Gi\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	opts := &simulateOptions{files: []string{manifests}, trace: trace, interval: 30 * time.Minute, output: "table"}
+	var out bytes.Buffer
+	if err := runSimulate(context.Background(), nil, &out, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"2026-01-01T01:00:00Z  10Gi  15Gi", "10Gi -> 15Gi", "after 2h0m0s", "MaxSizeReached=1"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	opts.output = "json"
+	if err := runSimulate(context.Background(), nil, &out, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var result simulationJSON
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	if len(result.Expansions) != 1 || result.FinalSize != "15Gi" || result.MaxSizeReachedAt == nil {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestRunSimulateGlobalDefaults(t *testing.T) {
+	opts := &simulateOptions{
+		annotations:    map[string]string{"increase": "1Gi"},
+		size:           "10Gi",
+		globalDefaults: globalDefaults{threshold: 50},
+		initialUsed:    "4Gi",
+		growthPerHour:  "1Gi",
+		interval:       time.Hour,
+		duration:       3 * time.Hour,
+		output:         "json",
+	}
+	var out bytes.Buffer
+	if err := runSimulate(context.Background(), nil, &out, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var result simulationJSON
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	// 5Gi reaches 50% after 1h and 1Gi increases keep usage above 50%
+	if len(result.Expansions) != 3 || result.FinalSize != "13Gi" {
+		t.Errorf("expected 3 expansions to 13Gi, got %+v", result)
+	}
+}
+
+func TestRunSimulateErrors(t *testing.T) {
+	for name, opts := range map[string]*simulateOptions{
+		"no trace":          {size: "10Gi", output: "table"},
+		"two traces":        {size: "10Gi", trace: "usage.csv", initialUsed: "1Gi", output: "table"},
+		"no size":           {initialUsed: "1Gi", duration: time.Minute, output: "table"},
+		"bad output":        {size: "10Gi", initialUsed: "1Gi", output: "yaml"},
+		"two growth models": {size: "10Gi", initialUsed: "1Gi", growthPerHour: "1Gi", growthPercentPerDay: 5, duration: time.Minute, output: "table"},
+	} {
+		if err := runSimulate(context.Background(), nil, &bytes.Buffer{}, opts); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	pvcchonkerv1alpha1 "github.com/logicIQ/pvc-chonker/api/v1alpha1"
+)
+
+// CycleSummary is the outcome of a reconciliation cycle.
+type CycleSummary struct {
+	StartedAt   time.Time         `json:"startedAt"`
+	Duration    time.Duration     `json:"duration"`
+	DryRun      bool              `json:"dryRun"`
+	TotalPVCs   int               `json:"totalPVCs"`
+	ManagedPVCs int               `json:"managedPVCs"`
+	DuePVCs     int               `json:"duePVCs"`
+	PlanID      string            `json:"planID,omitempty"`
+	PlanHeld    bool              `json:"planHeld"`
+	Expanded    []ExpansionResult `json:"expanded"`
+	// Skipped counts the PVCs that were not expanded by decision reason.
+	Skipped map[string]int `json:"skipped"`
+	Failed  []Failure      `json:"failed"`
+	// Policies and Groups count the PVCPolicies and PVCGroups reconciled in
+	// the same pass.
+	Policies int `json:"policies"`
+	Groups   int `json:"groups"`
+}
+
+// ExpansionResult is a PVC expanded in a cycle.
+type ExpansionResult struct {
+	Namespace     string `json:"namespace"`
+	Name          string `json:"name"`
+	From          string `json:"from"`
+	To            string `json:"to"`
+	InodePressure bool   `json:"inodePressure,omitempty"`
+}
+
+// Failure is an object that could not be reconciled, or a step of the cycle
+// that failed as a whole when Name is empty.
+type Failure struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Error     string `json:"error"`
+}
+
+func newCycleSummary(startedAt time.Time, dryRun bool) *CycleSummary {
+	return &CycleSummary{
+		StartedAt: startedAt,
+		DryRun:    dryRun,
+		Expanded:  []ExpansionResult{},
+		Skipped:   map[string]int{},
+		Failed:    []Failure{},
+	}
+}
+
+// HasFailures reports whether any object or step of the cycle failed.
+func (s *CycleSummary) HasFailures() bool {
+	return len(s.Failed) > 0
+}
+
+func (s *CycleSummary) addFailure(kind string, key types.NamespacedName, err error) {
+	s.Failed = append(s.Failed, Failure{Kind: kind, Namespace: key.Namespace, Name: key.Name, Error: err.Error()})
+}
+
+// addPlan records the decisions of an applied or held plan.
+func (s *CycleSummary) addPlan(plan *ExpansionPlan) {
+	for _, d := range plan.Decisions {
+		switch d.Reason {
+		case ReasonExpand:
+			s.Expanded = append(s.Expanded, ExpansionResult{
+				Namespace:     d.PVC.Namespace,
+				Name:          d.PVC.Name,
+				From:          d.CurrentSize.String(),
+				To:            d.NewSize.String(),
+				InodePressure: d.InodePressure,
+			})
+		case ReasonExpansionFailed:
+			s.addFailure("PersistentVolumeClaim", d.Key(), d.Err)
+		default:
+			s.Skipped[d.Reason]++
+		}
+	}
+}
+
+// Once runs a single reconciliation pass for deployments without a long-running
+// operator, such as a CronJob. Every PVCPolicy is reconciled first, then one
+// PVC reconciliation cycle runs, and every PVCGroup is reconciled last so group
+// members follow the expansions of the same pass.
+type Once struct {
+	client.Client
+	PVCs     *PersistentVolumeClaimReconciler
+	Policies *PVCPolicyReconciler
+	Groups   *PVCGroupReconciler
+}
+
+// Run performs the pass. Errors are reported in the summary.
+func (o *Once) Run(ctx context.Context) *CycleSummary {
+	log := log.FromContext(ctx).WithName("once")
+
+	var failures []Failure
+	fail := func(kind string, key types.NamespacedName, err error) {
+		log.Error(err, "Reconciliation failed", "kind", kind, "object", key)
+		failures = append(failures, Failure{Kind: kind, Namespace: key.Namespace, Name: key.Name, Error: err.Error()})
+	}
+
+	var policies pvcchonkerv1alpha1.PVCPolicyList
+	if err := o.List(ctx, &policies); err != nil {
+		fail("PVCPolicyList", types.NamespacedName{}, fmt.Errorf("failed to list PVCPolicies: %w", err))
+	}
+	for i := range policies.Items {
+		key := client.ObjectKeyFromObject(&policies.Items[i])
+		if _, err := o.Policies.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+			fail("PVCPolicy", key, err)
+		}
+	}
+
+	summary := o.PVCs.RunOnce(ctx)
+	summary.Policies = len(policies.Items)
+
+	var groups pvcchonkerv1alpha1.PVCGroupList
+	if err := o.List(ctx, &groups); err != nil {
+		fail("PVCGroupList", types.NamespacedName{}, fmt.Errorf("failed to list PVCGroups: %w", err))
+	}
+	for i := range groups.Items {
+		key := client.ObjectKeyFromObject(&groups.Items[i])
+		if _, err := o.Groups.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+			fail("PVCGroup", key, err)
+		}
+	}
+	summary.Groups = len(groups.Items)
+
+	summary.Failed = append(summary.Failed, failures...)
+	summary.Duration = o.PVCs.getClock().Since(summary.StartedAt)
+	return summary
+}
@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	pvcchonkerv1alpha1 "github.com/logicIQ/pvc-chonker/api/v1alpha1"
+	"github.com/logicIQ/pvc-chonker/pkg/annotations"
+	"github.com/logicIQ/pvc-chonker/pkg/kubelet"
+)
+
+func TestOnceRun(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = storagev1.AddToScheme(scheme)
+	_ = pvcchonkerv1alpha1.AddToScheme(scheme)
+
+	allowExpansion := true
+	scName := "standard"
+	newPVC := func(name string, pvcAnnotations map[string]string) *corev1.PersistentVolumeClaim {
+		size := resource.MustParse("10Gi")
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "kafka"}, Annotations: pvcAnnotations},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				StorageClassName: &scName,
+				Resources:        corev1.VolumeResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceStorage: size}},
+			},
+			Status: corev1.PersistentVolumeClaimStatus{
+				Phase:    corev1.ClaimBound,
+				Capacity: corev1.ResourceList{corev1.ResourceStorage: size},
+			},
+		}
+	}
+	member := map[string]string{annotations.AnnotationEnabled: "true", annotations.AnnotationGroup: "kafka"}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: scName}, Provisioner: "ebs.csi.aws.com", AllowVolumeExpansion: &allowExpansion},
+			newPVC("broker-0", member),
+			newPVC("broker-1", member),
+			newPVC("broken", map[string]string{annotations.AnnotationEnabled: "true"}),
+			&pvcchonkerv1alpha1.PVCPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default"},
+				Spec:       pvcchonkerv1alpha1.PVCPolicySpec{Selector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "kafka"}}},
+			},
+			&pvcchonkerv1alpha1.PVCGroup{ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default"}},
+		).
+		WithStatusSubresource(&pvcchonkerv1alpha1.PVCPolicy{}, &pvcchonkerv1alpha1.PVCGroup{}).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				if obj.GetName() == "broken" {
+					return errors.New("cloud API unavailable")
+				}
+				return c.Update(ctx, obj, opts...)
+			},
+		}).
+		Build()
+
+	pushed := kubelet.NewPushStore(time.Hour)
+	usage := map[string]int64{"broker-0": 9 << 30, "broker-1": 5 << 30, "broken": 9 << 30}
+	for name, used := range usage {
+		used := used
+		if _, errs := pushed.Add([]kubelet.PushSample{{Namespace: "default", PersistentVolumeClaim: name, CapacityBytes: 10 << 30, UsedBytes: &used}}); len(errs) > 0 {
+			t.Fatalf("unexpected errors: %v", errs)
+		}
+	}
+	collector, err := kubelet.NewPushMetricsCollector(nil, pushed, kubelet.PushModeOverride)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	once := &Once{
+		Client: fakeClient,
+		PVCs: &PersistentVolumeClaimReconciler{
+			Client:           fakeClient,
+			GlobalConfig:     annotations.NewGlobalConfig(0, 0, "", 0, resource.Quantity{}, resource.Quantity{}),
+			MetricsCollector: collector,
+			EventRecorder:    record.NewFakeRecorder(20),
+		},
+		Policies: &PVCPolicyReconciler{Client: fakeClient, EventRecorder: record.NewFakeRecorder(20)},
+		Groups:   &PVCGroupReconciler{Client: fakeClient, EventRecorder: record.NewFakeRecorder(20)},
+	}
+	ctx := context.Background()
+	summary := once.Run(ctx)
+
+	if summary.TotalPVCs != 3 || summary.ManagedPVCs != 3 || summary.Policies != 1 || summary.Groups != 1 {
+		t.Errorf("unexpected counts: %+v", summary)
+	}
+	if len(summary.Expanded) != 1 || summary.Expanded[0].Name != "broker-0" || summary.Expanded[0].To != "11Gi" {
+		t.Errorf("expected broker-0 expanded to 11Gi, got %+v", summary.Expanded)
+	}
+	if summary.Skipped[ReasonBelowThreshold] != 1 {
+		t.Errorf("expected broker-1 skipped below threshold, got %v", summary.Skipped)
+	}
+	if !summary.HasFailures() || len(summary.Failed) != 1 || summary.Failed[0].Name != "broken" {
+		t.Errorf("expected the expansion of broken to fail, got %+v", summary.Failed)
+	}
+
+	// The group coordinates broker-1 to the size broker-0 was expanded to
+	var pvc corev1.PersistentVolumeClaim
+	if err := fakeClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "broker-1"}, &pvc); err != nil {
+		t.Fatal(err)
+	}
+	if size := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; size.Cmp(resource.MustParse("11Gi")) != 0 {
+		t.Errorf("expected broker-1 coordinated to 11Gi, got %s", size.String())
+	}
+	var policy pvcchonkerv1alpha1.PVCPolicy
+	if err := fakeClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "kafka"}, &policy); err != nil {
+		t.Fatal(err)
+	}
+	if policy.Status.MatchedPVCs != 3 {
+		t.Errorf("expected the policy status to count 3 PVCs, got %d", policy.Status.MatchedPVCs)
+	}
+}
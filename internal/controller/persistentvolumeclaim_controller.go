@@ -7,15 +7,22 @@ import (
 	"time"
 
 	"github.com/logicIQ/pvc-chonker/pkg/annotations"
+	"github.com/logicIQ/pvc-chonker/pkg/breaker"
 	"github.com/logicIQ/pvc-chonker/pkg/cache"
+	"github.com/logicIQ/pvc-chonker/pkg/control"
+	"github.com/logicIQ/pvc-chonker/pkg/history"
 	"github.com/logicIQ/pvc-chonker/pkg/kubelet"
 	"github.com/logicIQ/pvc-chonker/pkg/metrics"
+	"github.com/logicIQ/pvc-chonker/pkg/scheduler"
+	"github.com/logicIQ/pvc-chonker/pkg/throttle"
 
 	corev1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -28,6 +35,11 @@ import (
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;get;list;watch
 // +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=nodes/proxy,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=nodes/metrics;nodes/stats,verbs=get
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",namespace=pvc-chonker-system,resources=configmaps,resourceNames=pvc-chonker-control;pvc-chonker-history,verbs=get
+// +kubebuilder:rbac:groups="",namespace=pvc-chonker-system,resources=configmaps,resourceNames=pvc-chonker-history,verbs=update
+// +kubebuilder:rbac:groups="",namespace=pvc-chonker-system,resources=configmaps,verbs=create
 
 type PersistentVolumeClaimReconciler struct {
 	client.Client
@@ -38,24 +50,49 @@ type PersistentVolumeClaimReconciler struct {
 	EventRecorder    record.EventRecorder
 	DryRun           bool
 	MaxParallel      int
-	storageCache     *cache.StorageClassCache
-	policyResolver   *annotations.PolicyResolver
+	// MinPollInterval and MaxPollInterval bound the per-PVC check interval.
+	// Adaptive polling is disabled when MaxPollInterval is zero.
+	MinPollInterval time.Duration
+	MaxPollInterval time.Duration
+	// CycleLimits caps the expansions applied in a single cycle. Plans above a
+	// cap are held until acknowledged through the ControlLoader ConfigMap.
+	CycleLimits   CycleLimits
+	ControlLoader *control.Loader
+	// Breaker pauses expansions per StorageClass provisioner when resizes keep
+	// failing. A resize still in progress after StuckResizeTimeout counts as a
+	// failure.
+	Breaker            *breaker.Breaker
+	StuckResizeTimeout time.Duration
+	// Throttle limits in-flight resizes and expansions per minute per
+	// StorageClass and provisioner.
+	Throttle *throttle.Throttle
+	// MaxMetricsStaleness skips PVCs whose volume metrics sample is older than
+	// this. Zero disables the check.
+	MaxMetricsStaleness time.Duration
+	// History records the usage of every checked PVC. It is loaded from
+	// HistoryBackend on start and saved at most every HistorySaveInterval.
+	History             *history.Store
+	HistoryBackend      history.Backend
+	HistorySaveInterval time.Duration
+	lastHistorySave     time.Time
+	// PushedMetrics holds usage samples pushed by agents. Block-mode PVCs are
+	// only eligible while they have a pushed sample, since the kubelet
+	// cannot measure them.
+	PushedMetrics *kubelet.PushStore
+	// Clock drives the reconciliation loop and every time-based decision.
+	// Real time is used when nil.
+	Clock          clock.WithTicker
+	storageCache   *cache.StorageClassCache
+	policyResolver *annotations.PolicyResolver
+	pollScheduler  *scheduler.AdaptiveScheduler
 }
 
 func (r *PersistentVolumeClaimReconciler) Start(ctx context.Context) error {
 	log := log.FromContext(ctx).WithName("pvcReconciler")
 	log.Info("Starting periodic reconciliation loop", "interval", r.WatchInterval, "dryRun", r.DryRun)
 
-	// Initialize storage class cache
-	r.storageCache = cache.NewStorageClassCache()
-	r.policyResolver = annotations.NewPolicyResolver(r.Client)
-
-	// Set default MaxParallel if not configured
-	if r.MaxParallel <= 0 {
-		r.MaxParallel = 4
-	}
-
-	ticker := time.NewTicker(r.WatchInterval)
+	interval := r.setup(ctx)
+	ticker := r.getClock().NewTicker(interval)
 	defer ticker.Stop()
 
 	r.reconcileAll(ctx)
@@ -65,20 +102,104 @@ func (r *PersistentVolumeClaimReconciler) Start(ctx context.Context) error {
 		case <-ctx.Done():
 			log.Info("Stopping periodic reconciliation loop")
 			return nil
-		case <-ticker.C:
+		case <-ticker.C():
 			r.reconcileAll(ctx)
 		}
 	}
 }
 
+// RunOnce runs a single reconciliation cycle and saves the usage history
+// afterwards, for deployments that do not run the reconciliation loop.
+func (r *PersistentVolumeClaimReconciler) RunOnce(ctx context.Context) *CycleSummary {
+	r.setup(ctx)
+	// Save the history at the end of the cycle, whatever the save interval
+	r.lastHistorySave = time.Time{}
+	return r.reconcileAll(ctx)
+}
+
+// Evaluate returns the decision the next cycle would make for the PVC without
+// modifying it, or nil when the PVC is not managed. VolumeMetrics is set
+// whenever metrics are available, also when the decision is made before they
+// are checked. Safety caps, throttling and the circuit breaker state of the
+// running operator apply to a whole cycle and are not evaluated.
+func (r *PersistentVolumeClaimReconciler) Evaluate(ctx context.Context, pvc *corev1.PersistentVolumeClaim) (*Decision, error) {
+	r.setup(ctx)
+
+	metricsCache, err := r.fetchVolumeMetrics(ctx, []corev1.PersistentVolumeClaim{*pvc})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch volume metrics: %w", err)
+	}
+	settings, err := r.ControlLoader.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	decision := r.planPVC(ctx, pvc, metricsCache, settings)
+	if decision != nil && decision.VolumeMetrics == nil {
+		decision.VolumeMetrics, _ = metricsCache.Get(decision.Key())
+	}
+	return decision, nil
+}
+
+// setup initializes the reconciler state and loads the usage history. It
+// returns the interval between cycles.
+func (r *PersistentVolumeClaimReconciler) setup(ctx context.Context) time.Duration {
+	log := log.FromContext(ctx).WithName("pvcReconciler")
+
+	// Initialize storage class cache
+	r.storageCache = cache.NewStorageClassCache()
+	r.policyResolver = annotations.NewPolicyResolver(r.Client)
+	r.policyResolver.Clock = r.getClock()
+
+	// Set default MaxParallel if not configured
+	if r.MaxParallel <= 0 {
+		r.MaxParallel = 4
+	}
+
+	interval := r.WatchInterval
+	if r.MaxPollInterval > 0 {
+		if r.MinPollInterval <= 0 {
+			r.MinPollInterval = r.WatchInterval
+		}
+		r.pollScheduler = scheduler.NewAdaptiveScheduler(r.MinPollInterval, r.MaxPollInterval)
+		interval = r.MinPollInterval
+		log.Info("Adaptive polling enabled", "minInterval", r.MinPollInterval, "maxInterval", r.pollScheduler.MaxInterval)
+	}
+
+	if r.History != nil && r.HistoryBackend != nil {
+		if err := r.History.Load(ctx, r.HistoryBackend); err != nil {
+			log.Error(err, "Failed to load usage history, starting with an empty history")
+		} else {
+			series, samples := r.History.Len()
+			log.Info("Loaded usage history", "pvcs", series, "samples", samples)
+		}
+		r.lastHistorySave = r.now()
+	}
+	return interval
+}
+
 func (r *PersistentVolumeClaimReconciler) NeedLeaderElection() bool {
 	return true
 }
 
-func (r *PersistentVolumeClaimReconciler) reconcileAll(ctx context.Context) {
+func (r *PersistentVolumeClaimReconciler) getClock() clock.WithTicker {
+	if r.Clock == nil {
+		return clock.RealClock{}
+	}
+	return r.Clock
+}
+
+func (r *PersistentVolumeClaimReconciler) now() time.Time {
+	return r.getClock().Now()
+}
+
+// reconcileAll runs a reconciliation cycle over every PVC and summarizes it.
+func (r *PersistentVolumeClaimReconciler) reconcileAll(ctx context.Context) *CycleSummary {
 	log := log.FromContext(ctx).WithName("reconcileAll")
-	startTime := time.Now()
+	startTime := r.now()
+	summary := newCycleSummary(startTime, r.DryRun)
 	defer func() {
+		summary.Duration = r.getClock().Since(startTime)
 		metrics.LastReconciliationTime.SetToCurrentTime()
 	}()
 
@@ -92,7 +213,8 @@ func (r *PersistentVolumeClaimReconciler) reconcileAll(ctx context.Context) {
 		metrics.RecordKubernetesClientRequest("list_pvcs", "failed")
 		metrics.ReconciliationStatus.WithLabelValues("failure").Set(1)
 		metrics.ReconciliationStatus.WithLabelValues("success").Set(0)
-		return
+		summary.addFailure("PersistentVolumeClaimList", types.NamespacedName{}, fmt.Errorf("failed to list PVCs: %w", err))
+		return summary
 	}
 	metrics.RecordKubernetesClientRequest("list_pvcs", "success")
 
@@ -105,51 +227,304 @@ func (r *PersistentVolumeClaimReconciler) reconcileAll(ctx context.Context) {
 	}
 
 	log.Info("Found PVCs", "total", totalPVCs, "managed", len(managedPVCs))
+	summary.TotalPVCs, summary.ManagedPVCs = totalPVCs, len(managedPVCs)
+	defer r.maintainHistory(ctx, managedPVCs)
+	r.countInFlightResizes(ctx, pvcs.Items)
+	metrics.ManagedPVCsTotal.Set(float64(len(managedPVCs)))
+
+	duePVCs := r.filterDuePVCs(managedPVCs, startTime)
+	metrics.SchedulerDuePVCs.Set(float64(len(duePVCs)))
+	summary.DuePVCs = len(duePVCs)
+	if len(duePVCs) == 0 {
+		log.V(1).Info("No PVCs due for a usage check")
+		metrics.ReconciliationStatus.WithLabelValues("success").Set(1)
+		metrics.ReconciliationStatus.WithLabelValues("failure").Set(0)
+		return summary
+	}
 
-	log.V(1).Info("Fetching kubelet metrics")
-	metricsCache, err := r.MetricsCollector.GetAllVolumeMetrics(ctx)
+	log.V(1).Info("Fetching kubelet metrics", "duePVCs", len(duePVCs))
+	metricsCache, err := r.fetchVolumeMetrics(ctx, duePVCs)
 	if err != nil {
 		log.Error(err, "Failed to fetch kubelet metrics")
 		metrics.RecordKubeletClientRequest("failed")
 		metrics.ReconciliationStatus.WithLabelValues("failure").Set(1)
 		metrics.ReconciliationStatus.WithLabelValues("success").Set(0)
-		return
+		summary.addFailure("VolumeMetrics", types.NamespacedName{}, fmt.Errorf("failed to fetch volume metrics: %w", err))
+		return summary
 	}
 	log.V(1).Info("Successfully fetched kubelet metrics", "volumeCount", len(metricsCache.GetAll()))
+	for _, failure := range metricsCache.FailedNodes() {
+		log.Info("Failed to scrape node, skipping its PVCs this cycle", "node", failure.Node, "reason", failure.Reason, "error", failure.Err)
+	}
 	for key, vm := range metricsCache.GetAll() {
 		if vm == nil {
 			log.V(1).Info("Skipping nil volume metrics", "pvc", key)
 			continue
 		}
-		log.V(1).Info("Found volume metrics", "pvc", key, "usage", vm.UsagePercent, "capacity", vm.CapacityBytes)
+		age := vm.Age(r.now())
+		if !vm.Timestamp.IsZero() {
+			metrics.ObserveSampleStaleness(vm.Source, age.Seconds())
+		}
+		log.V(1).Info("Found volume metrics", "pvc", key, "usage", vm.UsagePercent, "capacity", vm.CapacityBytes, "node", vm.NodeName, "mountedNodes", vm.MountedNodes, "pod", vm.PodName, "source", vm.Source, "age", age)
 	}
 	metrics.RecordKubeletClientRequest("success")
 
-	metrics.ManagedPVCsTotal.Set(float64(len(managedPVCs)))
+	settings, err := r.ControlLoader.Load(ctx)
+	if err != nil {
+		// Fail safe: the kill switch may be set but unreadable
+		log.Error(err, "Failed to load control settings, pausing expansions for this cycle")
+		metrics.RecordKubernetesClientRequest("get_control_configmap", "failed")
+		settings = &control.Settings{Paused: true}
+	}
+	if settings.IsPaused(startTime) {
+		log.Info("Automatic expansions paused by control ConfigMap", "configMap", r.controlConfigMapName())
+		metrics.GlobalPaused.Set(1)
+	} else {
+		metrics.GlobalPaused.Set(0)
+	}
+
+	plan := r.buildPlan(ctx, duePVCs, metricsCache, len(managedPVCs), settings)
+	expansions := plan.Expansions()
+	metrics.PlannedExpansions.Set(float64(len(expansions)))
+	log.Info("Built expansion plan", "planID", plan.ID(), "expansions", len(expansions), "bytesAdded", plan.TotalBytesAdded(), "decisions", plan.ReasonCounts())
+
+	summary.PlanID = plan.ID()
+	if r.allowPlan(ctx, plan, settings) {
+		r.applyPlan(ctx, plan)
+	} else {
+		summary.PlanHeld = true
+	}
+	metrics.DeferredPVCs.Set(float64(plan.ReasonCounts()[ReasonDeferred]))
+	summary.addPlan(plan)
+
+	duration := r.getClock().Since(startTime)
+	metrics.RecordLoopDuration(duration.Seconds())
+	metrics.ReconciliationStatus.WithLabelValues("success").Set(1)
+	metrics.ReconciliationStatus.WithLabelValues("failure").Set(0)
+	log.Info("Completed reconciliation cycle", "totalPVCs", totalPVCs, "managedPVCs", len(managedPVCs), "duePVCs", len(duePVCs), "duration", duration, "nextCycle", startTime.Add(r.cycleInterval()).Format(time.RFC3339))
+	return summary
+}
+
+// maintainHistory drops the history of PVCs no longer managed and persists
+// the history once HistorySaveInterval has elapsed since the last save.
+func (r *PersistentVolumeClaimReconciler) maintainHistory(ctx context.Context, managedPVCs []corev1.PersistentVolumeClaim) {
+	if r.History == nil {
+		return
+	}
+	log := log.FromContext(ctx).WithName("history")
+
+	keep := make(map[string]struct{}, len(managedPVCs))
+	for i := range managedPVCs {
+		keep[types.NamespacedName{Namespace: managedPVCs[i].Namespace, Name: managedPVCs[i].Name}.String()] = struct{}{}
+	}
+	r.History.Retain(keep)
+	metrics.UpdateHistorySize(r.History.Len())
+
+	if r.HistoryBackend == nil || r.getClock().Since(r.lastHistorySave) < r.HistorySaveInterval {
+		return
+	}
+	if err := r.History.Save(ctx, r.HistoryBackend); err != nil {
+		log.Error(err, "Failed to save usage history")
+		metrics.HistorySaveFailuresTotal.Inc()
+		return
+	}
+	r.lastHistorySave = r.now()
+}
 
+// recordHistory adds the sample to the usage history and updates the growth
+// rate of the PVC.
+func (r *PersistentVolumeClaimReconciler) recordHistory(pvc *corev1.PersistentVolumeClaim, volumeMetrics *kubelet.VolumeMetrics, now time.Time) {
+	if r.History == nil {
+		return
+	}
+	sampleTime := volumeMetrics.Timestamp
+	if sampleTime.IsZero() {
+		sampleTime = now
+	}
+	key := types.NamespacedName{Namespace: pvc.Namespace, Name: pvc.Name}.String()
+	r.History.Record(key, history.Sample{
+		Time:          sampleTime,
+		UsedBytes:     volumeMetrics.UsedBytes,
+		CapacityBytes: volumeMetrics.CapacityBytes,
+		InodesUsed:    volumeMetrics.InodesUsed,
+		InodesTotal:   volumeMetrics.InodesTotal,
+	})
+	if rate, ok := r.History.GrowthRate(key, time.Hour, now); ok {
+		metrics.UpdatePVCGrowthRate(pvc.Name, pvc.Namespace, rate*time.Hour.Seconds())
+	}
+}
+
+// countInFlightResizes tells the throttle which PVCs are still resizing from
+// earlier cycles, including PVCs not managed by pvc-chonker.
+func (r *PersistentVolumeClaimReconciler) countInFlightResizes(ctx context.Context, pvcs []corev1.PersistentVolumeClaim) {
+	if r.Throttle == nil {
+		return
+	}
+	r.Throttle.ResetInFlight()
+	for i := range pvcs {
+		pvc := &pvcs[i]
+		if !annotations.IsPvcResizing(pvc) || pvc.Spec.StorageClassName == nil {
+			continue
+		}
+		// Loads the StorageClass into the cache to resolve its provisioner
+		r.IsStorageClassExpandable(ctx, pvc)
+		r.Throttle.AddInFlight(*pvc.Spec.StorageClassName, r.getProvisioner(pvc))
+	}
+}
+
+func (r *PersistentVolumeClaimReconciler) cycleInterval() time.Duration {
+	if r.pollScheduler != nil {
+		return r.pollScheduler.MinInterval
+	}
+	return r.WatchInterval
+}
+
+// filterDuePVCs returns the PVCs whose next usage check is due. Without
+// adaptive polling every managed PVC is due on every cycle.
+func (r *PersistentVolumeClaimReconciler) filterDuePVCs(pvcs []corev1.PersistentVolumeClaim, now time.Time) []corev1.PersistentVolumeClaim {
+	if r.pollScheduler == nil {
+		return pvcs
+	}
+
+	managed := make(map[string]struct{}, len(pvcs))
+	due := make([]corev1.PersistentVolumeClaim, 0, len(pvcs))
+	for i := range pvcs {
+		key := types.NamespacedName{Namespace: pvcs[i].Namespace, Name: pvcs[i].Name}.String()
+		managed[key] = struct{}{}
+		if r.pollScheduler.IsDue(key, now) {
+			due = append(due, pvcs[i])
+		}
+	}
+	r.pollScheduler.Retain(managed)
+	return due
+}
+
+// fetchVolumeMetrics limits kubelet scraping to the nodes hosting the given
+// PVCs when the collector supports it.
+func (r *PersistentVolumeClaimReconciler) fetchVolumeMetrics(ctx context.Context, pvcs []corev1.PersistentVolumeClaim) (*kubelet.MetricsCache, error) {
+	scoped, ok := r.MetricsCollector.(kubelet.NodeScopedMetricsCollector)
+	if !ok {
+		return r.MetricsCollector.GetAllVolumeMetrics(ctx)
+	}
+
+	names := make([]types.NamespacedName, 0, len(pvcs))
+	for i := range pvcs {
+		names = append(names, types.NamespacedName{Namespace: pvcs[i].Namespace, Name: pvcs[i].Name})
+	}
+	return scoped.GetVolumeMetricsForPVCs(ctx, names)
+}
+
+// scheduleNextCheck records the usage of the resource closest to its threshold
+// so the next check of the PVC can be scheduled.
+func (r *PersistentVolumeClaimReconciler) scheduleNextCheck(pvc *corev1.PersistentVolumeClaim, volumeMetrics *kubelet.VolumeMetrics, config *annotations.PVCConfig) {
+	if r.pollScheduler == nil {
+		return
+	}
+
+	usage, threshold := volumeMetrics.UsagePercent, config.Threshold
+	if volumeMetrics.InodesTotal > 0 && config.InodesThreshold-volumeMetrics.InodesUsagePercent < threshold-usage {
+		usage, threshold = volumeMetrics.InodesUsagePercent, config.InodesThreshold
+	}
+
+	key := types.NamespacedName{Namespace: pvc.Namespace, Name: pvc.Name}.String()
+	delay := r.pollScheduler.Observe(key, usage, threshold, r.now())
+	metrics.UpdatePVCNextCheck(pvc.Name, pvc.Namespace, delay.Seconds())
+}
+
+// buildPlan evaluates every due PVC without modifying any of them.
+func (r *PersistentVolumeClaimReconciler) buildPlan(ctx context.Context, pvcs []corev1.PersistentVolumeClaim, metricsCache *kubelet.MetricsCache, managedPVCs int, settings *control.Settings) *ExpansionPlan {
 	semaphore := make(chan struct{}, r.MaxParallel)
 	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	decisions := make([]*Decision, 0, len(pvcs))
 
-	for i := range managedPVCs {
+	for i := range pvcs {
 		wg.Add(1)
 		go func(pvc corev1.PersistentVolumeClaim) {
 			defer wg.Done()
 			semaphore <- struct{}{}
 			defer func() { <-semaphore }()
-			r.reconcilePVC(ctx, &pvc, metricsCache)
-		}(managedPVCs[i])
+			if decision := r.planPVC(ctx, &pvc, metricsCache, settings); decision != nil {
+				mutex.Lock()
+				decisions = append(decisions, decision)
+				mutex.Unlock()
+			}
+		}(pvcs[i])
 	}
 
 	wg.Wait()
 
-	duration := time.Since(startTime)
-	metrics.RecordLoopDuration(duration.Seconds())
-	metrics.ReconciliationStatus.WithLabelValues("success").Set(1)
-	metrics.ReconciliationStatus.WithLabelValues("failure").Set(0)
-	log.Info("Completed reconciliation cycle", "totalPVCs", totalPVCs, "managedPVCs", len(managedPVCs), "duration", duration, "nextCycle", startTime.Add(r.WatchInterval).Format(time.RFC3339))
+	return NewExpansionPlan(decisions, managedPVCs)
+}
+
+// allowPlan enforces the per-cycle safety caps. A plan exceeding any cap is
+// held until an operator acknowledges its ID in the control ConfigMap.
+func (r *PersistentVolumeClaimReconciler) allowPlan(ctx context.Context, plan *ExpansionPlan, settings *control.Settings) bool {
+	log := log.FromContext(ctx)
+
+	violations := r.CycleLimits.Check(plan)
+	if len(violations) == 0 {
+		metrics.PlanHeld.Set(0)
+		return true
+	}
+
+	planID := plan.ID()
+	if settings != nil && settings.AcknowledgedPlan == planID {
+		log.Info("Applying acknowledged expansion plan that exceeds safety caps", "planID", planID, "violations", formatViolations(violations))
+		metrics.PlanHeld.Set(0)
+		return true
+	}
+
+	metrics.PlanHeld.Set(1)
+	for _, v := range violations {
+		metrics.RecordPlanHeld(v.Cap)
+	}
+
+	message := formatViolations(violations)
+	log.Info("Holding expansion plan that exceeds safety caps", "planID", planID, "violations", message, "expansions", len(plan.Expansions()))
+	for _, d := range plan.Expansions() {
+		d.Reason = ReasonPlanHeld
+		r.EventRecorder.Eventf(d.PVC, corev1.EventTypeWarning, "ExpansionPlanHeld",
+			"Expansion from %s to %s held: plan %s exceeds safety caps (%s). Set %s=%s in ConfigMap %s to apply it",
+			d.CurrentSize.String(), d.NewSize.String(), planID, message, control.KeyAcknowledgedPlan, planID, r.controlConfigMapName())
+	}
+	return false
+}
+
+func (r *PersistentVolumeClaimReconciler) controlConfigMapName() string {
+	if r.ControlLoader == nil {
+		return control.DefaultNamespace + "/" + control.DefaultConfigMapName
+	}
+	return r.ControlLoader.Key.String()
+}
+
+func (r *PersistentVolumeClaimReconciler) applyPlan(ctx context.Context, plan *ExpansionPlan) {
+	semaphore := make(chan struct{}, r.MaxParallel)
+	var wg sync.WaitGroup
+
+	for _, decision := range plan.Expansions() {
+		wg.Add(1)
+		go func(d *Decision) {
+			defer wg.Done()
+			// Spread expansions so they do not reach the backends at once
+			if delay := r.Throttle.Delay(); delay > 0 {
+				select {
+				case <-ctx.Done():
+					return
+				case <-r.getClock().After(delay):
+				}
+			}
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			r.applyDecision(ctx, d)
+		}(decision)
+	}
+
+	wg.Wait()
 }
 
-func (r *PersistentVolumeClaimReconciler) reconcilePVC(ctx context.Context, pvc *corev1.PersistentVolumeClaim, metricsCache *kubelet.MetricsCache) {
+// planPVC decides what should happen to a single PVC in this cycle.
+func (r *PersistentVolumeClaimReconciler) planPVC(ctx context.Context, pvc *corev1.PersistentVolumeClaim, metricsCache *kubelet.MetricsCache, settings *control.Settings) *Decision {
 	log := log.FromContext(ctx).WithValues("pvc", pvc.Name, "namespace", pvc.Namespace)
 
 	log.V(1).Info("Processing PVC", "phase", pvc.Status.Phase, "size", pvc.Status.Capacity[corev1.ResourceStorage])
@@ -157,60 +532,109 @@ func (r *PersistentVolumeClaimReconciler) reconcilePVC(ctx context.Context, pvc
 	config, err := r.policyResolver.ResolvePVCConfig(ctx, pvc, r.GlobalConfig)
 	if err != nil {
 		log.V(2).Info("PVC not managed", "reason", err.Error())
-		return
+		return nil
+	}
+
+	decision := &Decision{
+		PVC:         pvc,
+		Config:      config,
+		CurrentSize: pvc.Status.Capacity[corev1.ResourceStorage],
+		Provisioner: "unknown",
 	}
 
 	if !r.IsPVCEligible(pvc) {
 		log.V(2).Info("PVC not eligible for expansion")
-		return
+		return r.decide(decision, ReasonNotEligible)
 	}
 
 	if !r.IsStorageClassExpandable(ctx, pvc) {
 		log.V(2).Info("Storage class does not allow volume expansion")
 		metrics.RecordFailedResize(pvc.Name, pvc.Namespace, "storage_class_not_expandable")
-		return
+		return r.decide(decision, ReasonStorageClassNotExpandable)
+	}
+	decision.Provisioner = r.getProvisioner(pvc)
+
+	if message, hasError := annotations.ResizeError(pvc); hasError {
+		log.Info("PVC reports a resize error, skipping", "error", message)
+		metrics.RecordFailedResize(pvc.Name, pvc.Namespace, "resize_error")
+		r.recordPersistentFailure(ctx, pvc, decision.Provisioner, "resize_error")
+		return r.decide(decision, ReasonResizeError)
 	}
 
 	if annotations.IsPvcResizing(pvc) {
+		if r.StuckResizeTimeout > 0 && config.LastExpansion != nil && r.getClock().Since(*config.LastExpansion) > r.StuckResizeTimeout {
+			log.Info("PVC resize appears stuck", "lastExpansion", config.LastExpansion.Format(time.RFC3339), "timeout", r.StuckResizeTimeout)
+			r.recordPersistentFailure(ctx, pvc, decision.Provisioner, "stuck_resize")
+		}
 		log.V(1).Info("PVC is currently resizing, skipping")
 		metrics.RecordResizeInProgress(pvc.Name, pvc.Namespace)
-		return
+		return r.decide(decision, ReasonResizeInProgress)
 	}
 
 	if config.IsInCooldown() {
 		log.V(2).Info("PVC is in cooldown period")
 		metrics.RecordCooldownSkipped(pvc.Name, pvc.Namespace)
-		return
+		if r.pollScheduler != nil {
+			r.pollScheduler.DeferUntil(decision.Key().String(), config.LastExpansion.Add(config.Cooldown), r.now())
+		}
+		return r.decide(decision, ReasonCooldown)
 	}
 
-	namespacedName := types.NamespacedName{Namespace: pvc.Namespace, Name: pvc.Name}
-	volumeMetrics, exists := metricsCache.Get(namespacedName)
+	volumeMetrics, exists := metricsCache.Get(decision.Key())
+	if failure, unavailable := metricsCache.MetricsUnavailable(decision.Key()); !exists && unavailable {
+		log.V(1).Info("Volume metrics unavailable, node scrape failed", "node", failure.Node, "reason", failure.Reason)
+		metrics.RecordFailedResize(pvc.Name, pvc.Namespace, "metrics_unavailable")
+		return r.decide(decision, ReasonMetricsUnavailable)
+	}
 	if !exists {
 		log.V(1).Info("Volume metrics not found in cache", "availableMetrics", len(metricsCache.GetAll()))
 		metrics.RecordFailedResize(pvc.Name, pvc.Namespace, "metrics_not_found")
-		return
+		return r.decide(decision, ReasonMetricsNotFound)
+	}
+	decision.VolumeMetrics = volumeMetrics
+	r.recordHistory(pvc, volumeMetrics, r.now())
+
+	if age := volumeMetrics.Age(r.now()); r.MaxMetricsStaleness > 0 && age > r.MaxMetricsStaleness {
+		log.V(1).Info("Volume metrics are stale, skipping PVC this cycle", "age", age, "maxStaleness", r.MaxMetricsStaleness, "source", volumeMetrics.Source, "node", volumeMetrics.NodeName)
+		return r.decide(decision, ReasonMetricsStale)
 	}
 
 	log.V(1).Info("Found volume metrics", "storageUsage", volumeMetrics.UsagePercent, "inodesUsage", volumeMetrics.InodesUsagePercent, "storageThreshold", config.Threshold, "inodesThreshold", config.InodesThreshold)
 
-	currentSize := pvc.Status.Capacity[corev1.ResourceStorage]
-	metrics.UpdatePVCMetrics(pvc.Name, pvc.Namespace, volumeMetrics.UsagePercent, currentSize.Value())
+	metrics.UpdatePVCMetrics(pvc.Name, pvc.Namespace, volumeMetrics.UsagePercent, decision.CurrentSize.Value())
 	metrics.UpdatePVCInodesMetrics(pvc.Name, pvc.Namespace, volumeMetrics.InodesUsagePercent, volumeMetrics.InodesTotal)
+	r.scheduleNextCheck(pvc, volumeMetrics, config)
+
+	if settings.IsPaused(r.now()) {
+		log.V(1).Info("PVC suspended by cluster-wide kill switch")
+		return r.decide(decision, ReasonSuspended)
+	}
+	if config.PausedUntilErr != nil {
+		// An invalid paused-until suspends the PVC rather than unmanaging it
+		log.Info("PVC suspended by an invalid paused-until annotation", "error", config.PausedUntilErr)
+		r.EventRecorder.Eventf(pvc, corev1.EventTypeWarning, "InvalidPausedUntil",
+			"Expansion suspended until the %s annotation is fixed: %v", annotations.AnnotationPausedUntil, config.PausedUntilErr)
+		return r.decide(decision, ReasonInvalidPausedUntil)
+	}
+	if config.IsSuspended() {
+		log.V(1).Info("PVC suspended", "suspendedBy", config.SuspendedBy, "suspendedUntil", config.SuspendedUntil)
+		return r.decide(decision, ReasonSuspended)
+	}
 
 	thresholdReached := volumeMetrics.UsagePercent >= config.Threshold
-	var fsType string
 	if volumeMetrics.InodesTotal > 0 {
 		if volumeMetrics.InodesUsagePercent >= config.InodesThreshold {
-			fsType = r.getFilesystemType(ctx, pvc)
+			decision.FsType = r.getFilesystemType(ctx, pvc)
+			decision.InodePressure = true
 			thresholdReached = true
-			if fsType == "ext3" || fsType == "ext4" {
+			if decision.FsType == "ext3" || decision.FsType == "ext4" {
 				log.Info("Inode threshold reached on fixed-inode filesystem - expansion will not resolve inode pressure",
-					"filesystem", fsType,
+					"filesystem", decision.FsType,
 					"inodesUsage", volumeMetrics.InodesUsagePercent,
 					"inodesThreshold", config.InodesThreshold)
 			} else {
 				log.Info("Inode threshold reached",
-					"filesystem", fsType,
+					"filesystem", decision.FsType,
 					"inodesUsage", volumeMetrics.InodesUsagePercent,
 					"inodesThreshold", config.InodesThreshold)
 			}
@@ -219,36 +643,175 @@ func (r *PersistentVolumeClaimReconciler) reconcilePVC(ctx context.Context, pvc
 
 	if !thresholdReached {
 		log.V(3).Info("Threshold not reached", "storageUsage", volumeMetrics.UsagePercent, "inodesUsage", volumeMetrics.InodesUsagePercent, "storageThreshold", config.Threshold, "inodesThreshold", config.InodesThreshold)
-		return
+		return r.decide(decision, ReasonBelowThreshold)
 	}
 
 	metrics.RecordThresholdReached(pvc.Name, pvc.Namespace)
-	log.Info("Threshold reached - initiating expansion",
+	log.Info("Threshold reached - planning expansion",
 		"storageUsage", volumeMetrics.UsagePercent,
 		"inodesUsage", volumeMetrics.InodesUsagePercent,
 		"storageThreshold", config.Threshold,
 		"inodesThreshold", config.InodesThreshold,
 		"dryRun", r.DryRun)
 
-	if err := r.ExpandPVC(ctx, pvc, config); err != nil {
+	newSize, err := r.calculateExpansion(pvc, config)
+	if err != nil {
+		metrics.RecordFailedResize(pvc.Name, pvc.Namespace, "expansion_failed")
+		r.EventRecorder.Eventf(pvc, corev1.EventTypeWarning, "ExpansionFailed", "Failed to expand PVC: %v", err)
+		log.Error(err, "PVC expansion failed")
+		if config.ExceedsMaxSize(newSize) {
+			return r.decide(decision, ReasonMaxSizeReached)
+		}
+		return r.decide(decision, ReasonInvalidConfig)
+	}
+	decision.NewSize = newSize
+
+	if r.Breaker.Blocked(decision.Provisioner, r.now()) {
+		log.Info("Expansion paused by circuit breaker", "provisioner", decision.Provisioner)
+		r.EventRecorder.Eventf(pvc, corev1.EventTypeWarning, "ExpansionPaused",
+			"Expansion from %s to %s paused: circuit breaker for provisioner %s is open after repeated resize failures",
+			decision.CurrentSize.String(), newSize.String(), decision.Provisioner)
+		return r.decide(decision, ReasonCircuitOpen)
+	}
+
+	return r.decide(decision, ReasonExpand)
+}
+
+func (r *PersistentVolumeClaimReconciler) recordBreakerFailure(ctx context.Context, pvc *corev1.PersistentVolumeClaim, provisioner, source string) {
+	if r.Breaker == nil {
+		return
+	}
+	metrics.RecordCircuitBreakerFailure(provisioner, source)
+	if state, changed := r.Breaker.RecordFailure(provisioner, r.now()); changed {
+		r.recordBreakerTransition(ctx, pvc, provisioner, state)
+	}
+}
+
+// recordPersistentFailure counts a failure read from the state of the PVC,
+// which is seen again on every cycle until it clears, once per episode. An
+// episode ends with the next expansion or a new resize error condition.
+func (r *PersistentVolumeClaimReconciler) recordPersistentFailure(ctx context.Context, pvc *corev1.PersistentVolumeClaim, provisioner, source string) {
+	if r.Breaker == nil {
+		return
+	}
+	episode := fmt.Sprintf("%s/%s/%s/%s/%s", pvc.Namespace, pvc.Name, pvc.UID, source, pvc.Annotations[annotations.AnnotationLastExpansion])
+	for _, condition := range pvc.Status.Conditions {
+		if condition.Type == corev1.PersistentVolumeClaimControllerResizeError || condition.Type == corev1.PersistentVolumeClaimNodeResizeError {
+			episode += "/" + condition.LastTransitionTime.UTC().Format(time.RFC3339)
+		}
+	}
+	if !r.Breaker.FirstSeen(episode, r.now()) {
+		log.FromContext(ctx).V(1).Info("Resize failure already counted by the circuit breaker", "source", source)
+		return
+	}
+	r.recordBreakerFailure(ctx, pvc, provisioner, source)
+}
+
+func (r *PersistentVolumeClaimReconciler) recordBreakerTransition(ctx context.Context, pvc *corev1.PersistentVolumeClaim, provisioner string, state breaker.State) {
+	log.FromContext(ctx).Info("Circuit breaker state changed", "provisioner", provisioner, "state", state.String())
+	metrics.RecordCircuitBreakerTransition(provisioner, state.String(), int(state))
+
+	switch state {
+	case breaker.StateOpen:
+		r.EventRecorder.Eventf(pvc, corev1.EventTypeWarning, "CircuitBreakerOpened",
+			"Expansions for provisioner %s paused for %s after repeated resize failures", provisioner, r.Breaker.CoolOff)
+	case breaker.StateHalfOpen:
+		r.EventRecorder.Eventf(pvc, corev1.EventTypeNormal, "CircuitBreakerProbing",
+			"Probing provisioner %s with a single expansion after cool-off", provisioner)
+	case breaker.StateClosed:
+		r.EventRecorder.Eventf(pvc, corev1.EventTypeNormal, "CircuitBreakerClosed",
+			"Expansions for provisioner %s resumed after a successful probe", provisioner)
+	}
+}
+
+func (r *PersistentVolumeClaimReconciler) decide(decision *Decision, reason string) *Decision {
+	decision.Reason = reason
+	metrics.RecordDecision(reason)
+	return decision
+}
+
+// applyDecision resizes a PVC planned for expansion and reports the outcome.
+func (r *PersistentVolumeClaimReconciler) applyDecision(ctx context.Context, d *Decision) {
+	log := log.FromContext(ctx).WithValues("pvc", d.PVC.Name, "namespace", d.PVC.Namespace)
+	pvc := d.PVC
+	volumeMetrics := d.VolumeMetrics
+	currentSize, newSize := d.CurrentSize, d.NewSize
+
+	storageClass := ""
+	if pvc.Spec.StorageClassName != nil {
+		storageClass = *pvc.Spec.StorageClassName
+	}
+	acquired, reason := false, ""
+	if r.DryRun {
+		// Nothing is sent, so the throttle is only consulted
+		acquired, reason = r.Throttle.Check(storageClass, d.Provisioner, r.now())
+	} else {
+		acquired, reason = r.Throttle.Acquire(d.Key().String(), storageClass, d.Provisioner, r.now())
+	}
+	if !acquired {
+		log.Info("Expansion deferred by throttle", "storageClass", storageClass, "provisioner", d.Provisioner, "reason", reason)
+		metrics.RecordDeferred(reason)
+		r.EventRecorder.Eventf(pvc, corev1.EventTypeNormal, "ExpansionDeferred",
+			"Expansion from %s to %s deferred to a later cycle: %s limit reached", currentSize.String(), newSize.String(), reason)
+		d.Reason = ReasonDeferred
+		return
+	}
+
+	if r.DryRun {
+		// A half-open circuit must not be closed by a probe that is never sent
+		if r.Breaker.Blocked(d.Provisioner, r.now()) {
+			log.Info("Expansion paused by circuit breaker", "provisioner", d.Provisioner, "state", r.Breaker.State(d.Provisioner).String())
+			d.Reason = ReasonCircuitOpen
+			return
+		}
+		// Later PVCs of this cycle see the expansion against the in-flight
+		// limit, the count is reset at the start of the next cycle
+		r.Throttle.AddInFlight(storageClass, d.Provisioner)
+	} else {
+		allowed, state := r.Breaker.Allow(d.Provisioner, r.now())
+		if !allowed {
+			log.Info("Expansion paused by circuit breaker", "provisioner", d.Provisioner, "state", state.String())
+			r.Throttle.Release(d.Key().String(), storageClass, d.Provisioner)
+			d.Reason = ReasonCircuitOpen
+			return
+		}
+		if state == breaker.StateHalfOpen {
+			r.recordBreakerTransition(ctx, pvc, d.Provisioner, state)
+		}
+	}
+
+	log.Info("Initiating expansion", "from", currentSize.String(), "to", newSize.String(), "dryRun", r.DryRun)
+
+	if err := r.resizePVC(ctx, pvc, newSize); err != nil {
 		metrics.RecordFailedResize(pvc.Name, pvc.Namespace, "expansion_failed")
 		r.EventRecorder.Eventf(pvc, corev1.EventTypeWarning, "ExpansionFailed", "Failed to expand PVC: %v", err)
 		log.Error(err, "PVC expansion failed")
+		r.recordBreakerFailure(ctx, pvc, d.Provisioner, "update_failed")
+		r.Throttle.Release(d.Key().String(), storageClass, d.Provisioner)
+		d.Reason = ReasonExpansionFailed
+		d.Err = err
 		return
 	}
 
 	metrics.RecordSuccessfulResize(pvc.Name, pvc.Namespace)
-	newSize := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	if !r.DryRun {
+		if state, changed := r.Breaker.RecordSuccess(d.Provisioner); changed {
+			r.recordBreakerTransition(ctx, pvc, d.Provisioner, state)
+		}
+	}
+	if r.pollScheduler != nil {
+		r.pollScheduler.Forget(d.Key().String())
+	}
 	if volumeMetrics.InodesTotal > 0 {
-		if volumeMetrics.InodesUsagePercent >= config.InodesThreshold {
-			if fsType == "ext3" || fsType == "ext4" {
+		if d.InodePressure {
+			if d.FsType == "ext3" || d.FsType == "ext4" {
 				r.EventRecorder.Eventf(pvc, corev1.EventTypeWarning, "ExpandedInodePressure",
 					"PVC expanded from %s to %s due to inode pressure (storage: %.1f%%, inodes: %.1f%%) - WARNING: %s filesystem has fixed inode count, expansion will not resolve inode pressure",
-					currentSize.String(), newSize.String(), volumeMetrics.UsagePercent, volumeMetrics.InodesUsagePercent, fsType)
+					currentSize.String(), newSize.String(), volumeMetrics.UsagePercent, volumeMetrics.InodesUsagePercent, d.FsType)
 			} else {
 				r.EventRecorder.Eventf(pvc, corev1.EventTypeNormal, "ExpandedInodePressure",
 					"PVC expanded from %s to %s due to inode pressure (storage: %.1f%%, inodes: %.1f%%) - %s filesystem",
-					currentSize.String(), newSize.String(), volumeMetrics.UsagePercent, volumeMetrics.InodesUsagePercent, fsType)
+					currentSize.String(), newSize.String(), volumeMetrics.UsagePercent, volumeMetrics.InodesUsagePercent, d.FsType)
 			}
 		} else {
 			r.EventRecorder.Eventf(pvc, corev1.EventTypeNormal, "Expanded",
@@ -265,7 +828,9 @@ func (r *PersistentVolumeClaimReconciler) reconcilePVC(ctx context.Context, pvc
 
 func (r *PersistentVolumeClaimReconciler) IsPVCEligible(pvc *corev1.PersistentVolumeClaim) bool {
 	if pvc.Spec.VolumeMode != nil && *pvc.Spec.VolumeMode != corev1.PersistentVolumeFilesystem {
-		return false
+		if !r.PushedMetrics.Has(types.NamespacedName{Namespace: pvc.Namespace, Name: pvc.Name}) {
+			return false
+		}
 	}
 	if pvc.Status.Phase != corev1.ClaimBound {
 		return false
@@ -294,6 +859,15 @@ func (r *PersistentVolumeClaimReconciler) IsStorageClassExpandable(ctx context.C
 
 	expandable := sc.AllowVolumeExpansion != nil && *sc.AllowVolumeExpansion
 	r.storageCache.Set(scName, expandable)
+	r.storageCache.SetProvisioner(scName, sc.Provisioner)
+
+	if r.Throttle != nil {
+		maxInFlight, perMinute, err := annotations.ParseStorageClassLimits(sc.Annotations)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "Ignoring invalid StorageClass limit", "storageClass", scName, "maxInFlight", maxInFlight, "perMinute", perMinute)
+		}
+		r.Throttle.SetStorageClassLimits(scName, throttle.Limits{MaxInFlight: maxInFlight, PerMinute: perMinute})
+	}
 
 	// Cache filesystem type while we have the StorageClass
 	if fsType, exists := sc.Parameters["fsType"]; exists {
@@ -320,27 +894,58 @@ func (r *PersistentVolumeClaimReconciler) getFilesystemType(ctx context.Context,
 	return "ext4"
 }
 
+func (r *PersistentVolumeClaimReconciler) getProvisioner(pvc *corev1.PersistentVolumeClaim) string {
+	if pvc.Spec.StorageClassName == nil {
+		return "unknown"
+	}
+
+	if provisioner, exists := r.storageCache.GetProvisioner(*pvc.Spec.StorageClassName); exists && provisioner != "" {
+		return provisioner
+	}
+
+	return "unknown"
+}
+
 func (r *PersistentVolumeClaimReconciler) ExpandPVC(ctx context.Context, pvc *corev1.PersistentVolumeClaim, config *annotations.PVCConfig) error {
-	log := log.FromContext(ctx).WithValues("pvc", pvc.Name, "namespace", pvc.Namespace)
+	newSize, err := r.calculateExpansion(pvc, config)
+	if err != nil {
+		return err
+	}
+	return r.resizePVC(ctx, pvc, newSize)
+}
+
+// calculateExpansion returns the size the PVC should be expanded to. When the
+// size exceeds the configured maximum it is returned together with an error.
+func (r *PersistentVolumeClaimReconciler) calculateExpansion(pvc *corev1.PersistentVolumeClaim, config *annotations.PVCConfig) (resource.Quantity, error) {
 	currentSize := pvc.Status.Capacity[corev1.ResourceStorage]
 	newSize, err := config.CalculateNewSize(currentSize)
 	if err != nil {
-		return fmt.Errorf("failed to calculate new size: %w", err)
+		return resource.Quantity{}, fmt.Errorf("failed to calculate new size: %w", err)
 	}
 
 	if config.ExceedsMaxSize(newSize) {
 		metrics.RecordLimitReached(pvc.Name, pvc.Namespace)
-		return fmt.Errorf("new size %s exceeds max size %s", newSize.String(), config.MaxSize.String())
+		return newSize, fmt.Errorf("new size %s exceeds max size %s", newSize.String(), config.MaxSize.String())
 	}
 
+	return newSize, nil
+}
+
+func (r *PersistentVolumeClaimReconciler) resizePVC(ctx context.Context, pvc *corev1.PersistentVolumeClaim, newSize resource.Quantity) error {
+	log := log.FromContext(ctx).WithValues("pvc", pvc.Name, "namespace", pvc.Namespace)
+	currentSize := pvc.Status.Capacity[corev1.ResourceStorage]
+
 	if r.DryRun {
 		log.Info("DRY RUN: Would expand PVC", "currentSize", currentSize.String(), "newSize", newSize.String())
 		return nil
 	}
 
 	pvcCopy := pvc.DeepCopy()
+	if pvcCopy.Spec.Resources.Requests == nil {
+		pvcCopy.Spec.Resources.Requests = corev1.ResourceList{}
+	}
 	pvcCopy.Spec.Resources.Requests[corev1.ResourceStorage] = newSize
-	annotations.UpdateLastExpansion(pvcCopy)
+	annotations.UpdateLastExpansionAt(pvcCopy, r.now())
 
 	if err := r.Update(ctx, pvcCopy); err != nil {
 		metrics.RecordKubernetesClientRequest("update_pvc", "failed")
@@ -2,10 +2,19 @@ package controller
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/logicIQ/pvc-chonker/pkg/annotations"
+	"github.com/logicIQ/pvc-chonker/pkg/breaker"
 	"github.com/logicIQ/pvc-chonker/pkg/cache"
+	"github.com/logicIQ/pvc-chonker/pkg/control"
+	"github.com/logicIQ/pvc-chonker/pkg/history"
+	"github.com/logicIQ/pvc-chonker/pkg/kubelet"
+	"github.com/logicIQ/pvc-chonker/pkg/scheduler"
+	"github.com/logicIQ/pvc-chonker/pkg/throttle"
 
 	corev1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
@@ -13,12 +22,20 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 )
 
 func TestIsPVCEligible(t *testing.T) {
-	reconciler := &PersistentVolumeClaimReconciler{}
+	pushed := kubelet.NewPushStore(time.Minute)
+	used := int64(10)
+	if _, errs := pushed.Add([]kubelet.PushSample{{Namespace: "default", PersistentVolumeClaim: "db", CapacityBytes: 100, UsedBytes: &used}}); len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	reconciler := &PersistentVolumeClaimReconciler{PushedMetrics: pushed}
 
 	tests := []struct {
 		name     string
@@ -55,6 +72,22 @@ func TestIsPVCEligible(t *testing.T) {
 			},
 			expected: false,
 		},
+		{
+			name: "block volume mode with pushed metrics",
+			pvc: &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+				Spec: corev1.PersistentVolumeClaimSpec{
+					VolumeMode: func() *corev1.PersistentVolumeMode {
+						mode := corev1.PersistentVolumeBlock
+						return &mode
+					}(),
+				},
+				Status: corev1.PersistentVolumeClaimStatus{
+					Phase: corev1.ClaimBound,
+				},
+			},
+			expected: true,
+		},
 		{
 			name: "not bound",
 			pvc: &corev1.PersistentVolumeClaim{
@@ -285,8 +318,8 @@ func TestExpandPVC(t *testing.T) {
 
 func TestReconcilePVCLogic(t *testing.T) {
 	// This test focuses on the individual components that can be easily unit tested
-	// The full reconcilePVC method would be better tested with integration tests
-	t.Log("ReconcilePVC logic is tested through individual component tests")
+	// The full planPVC and applyDecision methods would be better tested with integration tests
+	t.Log("Planning logic is tested through individual component tests")
 	t.Log("Full integration testing would require a more complex test setup")
 }
 
@@ -296,3 +329,481 @@ func TestNeedLeaderElection(t *testing.T) {
 		t.Error("NeedLeaderElection() should return true")
 	}
 }
+
+func TestFilterDuePVCs(t *testing.T) {
+	pvcs := []corev1.PersistentVolumeClaim{
+		{ObjectMeta: metav1.ObjectMeta{Name: "checked", Namespace: "default"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "new", Namespace: "default"}},
+	}
+	now := time.Now()
+
+	reconciler := &PersistentVolumeClaimReconciler{}
+	if due := reconciler.filterDuePVCs(pvcs, now); len(due) != 2 {
+		t.Errorf("expected every PVC to be due without adaptive polling, got %d", len(due))
+	}
+
+	reconciler.pollScheduler = scheduler.NewAdaptiveScheduler(time.Minute, time.Hour)
+	reconciler.pollScheduler.Observe("default/checked", 10, 80, now)
+	reconciler.pollScheduler.Observe("default/deleted", 10, 80, now)
+
+	due := reconciler.filterDuePVCs(pvcs, now)
+	if len(due) != 1 || due[0].Name != "new" {
+		t.Errorf("expected only the unchecked PVC to be due, got %v", due)
+	}
+	if _, exists := reconciler.pollScheduler.NextCheck("default/deleted"); exists {
+		t.Error("expected state of unmanaged PVC to be dropped")
+	}
+}
+
+func TestHistoryRecordAndMaintain(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	pvcs := []corev1.PersistentVolumeClaim{
+		{ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "default"}},
+	}
+	backend := &history.FileBackend{Path: t.TempDir() + "/history.json"}
+	reconciler := &PersistentVolumeClaimReconciler{
+		History:             history.NewStore(history.Config{}),
+		HistoryBackend:      backend,
+		HistorySaveInterval: time.Hour,
+		lastHistorySave:     now,
+	}
+
+	reconciler.recordHistory(&pvcs[0], &kubelet.VolumeMetrics{UsedBytes: 100, CapacityBytes: 1000, Timestamp: now.Add(-time.Minute)}, now)
+	// Samples without a kubelet timestamp are recorded at the cycle time
+	reconciler.recordHistory(&pvcs[0], &kubelet.VolumeMetrics{UsedBytes: 200, CapacityBytes: 1000}, now)
+	reconciler.History.Record("default/deleted", history.Sample{Time: now, UsedBytes: 1})
+
+	if rate, ok := reconciler.History.GrowthRate("default/data", time.Hour, now); !ok || rate <= 0 {
+		t.Errorf("expected a positive growth rate, got %v", rate)
+	}
+
+	reconciler.maintainHistory(ctx, pvcs)
+	if keys := reconciler.History.Keys(); len(keys) != 1 || keys[0] != "default/data" {
+		t.Errorf("expected history of unmanaged PVCs to be dropped, got %v", keys)
+	}
+	if data, _ := backend.Load(ctx); data != nil {
+		t.Error("expected no save before the save interval elapsed")
+	}
+
+	reconciler.lastHistorySave = now.Add(-2 * time.Hour)
+	reconciler.maintainHistory(ctx, pvcs)
+	restored := history.NewStore(history.Config{})
+	if err := restored.Load(ctx, backend); err != nil {
+		t.Fatalf("unexpected error loading saved history: %v", err)
+	}
+	if samples := restored.Query("default/data", time.Time{}); len(samples) != 2 {
+		t.Errorf("expected 2 saved samples, got %d", len(samples))
+	}
+}
+
+func TestApplyDecisionCircuitBreaker(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+
+	var pvcs []client.Object
+	var decisions []*Decision
+	for _, name := range []string{"a", "b", "c"} {
+		pvc := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Status: corev1.PersistentVolumeClaimStatus{
+				Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+			},
+		}
+		pvcs = append(pvcs, pvc)
+		decision := newTestDecision(name, "10Gi", "12Gi")
+		decision.PVC = pvc
+		decision.Provisioner = "ebs.csi.aws.com"
+		decision.VolumeMetrics = &kubelet.VolumeMetrics{UsagePercent: 90}
+		decisions = append(decisions, decision)
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(pvcs...).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				return errors.New("cloud API unavailable")
+			},
+		}).
+		Build()
+
+	reconciler := &PersistentVolumeClaimReconciler{
+		Client:        fakeClient,
+		EventRecorder: record.NewFakeRecorder(20),
+		Breaker:       breaker.New(breaker.Settings{FailureThreshold: 2, Window: time.Minute, CoolOff: time.Hour}),
+	}
+
+	ctx := context.Background()
+	for _, d := range decisions {
+		reconciler.applyDecision(ctx, d)
+	}
+
+	if state := reconciler.Breaker.State("ebs.csi.aws.com"); state != breaker.StateOpen {
+		t.Fatalf("expected circuit to be open after repeated failures, got %s", state)
+	}
+	if decisions[2].Reason != ReasonCircuitOpen {
+		t.Errorf("expected expansion after the circuit opened to be paused, got %s", decisions[2].Reason)
+	}
+}
+
+func TestApplyDecisionThrottle(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = storagev1.AddToScheme(scheme)
+
+	scName := "ceph-rbd"
+	storageClass := &storagev1.StorageClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        scName,
+			Annotations: map[string]string{annotations.AnnotationMaxInFlightResizes: "2"},
+		},
+		Provisioner: "rbd.csi.ceph.com",
+	}
+	newPVC := func(name string, resizing bool) *corev1.PersistentVolumeClaim {
+		pvc := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       corev1.PersistentVolumeClaimSpec{StorageClassName: &scName},
+			Status: corev1.PersistentVolumeClaimStatus{
+				Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+			},
+		}
+		if resizing {
+			pvc.Status.Conditions = []corev1.PersistentVolumeClaimCondition{{
+				Type:   corev1.PersistentVolumeClaimResizing,
+				Status: corev1.ConditionTrue,
+			}}
+		}
+		return pvc
+	}
+
+	resizing := newPVC("resizing", true)
+	first, second := newPVC("first", false), newPVC("second", false)
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(storageClass, resizing, first, second).
+		Build()
+
+	reconciler := &PersistentVolumeClaimReconciler{
+		Client:        fakeClient,
+		EventRecorder: record.NewFakeRecorder(20),
+		Throttle:      throttle.NewThrottle(nil, 0),
+		storageCache:  cache.NewStorageClassCache(),
+	}
+
+	ctx := context.Background()
+	reconciler.countInFlightResizes(ctx, []corev1.PersistentVolumeClaim{*resizing, *first, *second})
+
+	var decisions []*Decision
+	for _, pvc := range []*corev1.PersistentVolumeClaim{first, second} {
+		decision := newTestDecision(pvc.Name, "10Gi", "12Gi")
+		decision.PVC = pvc
+		decision.Provisioner = reconciler.getProvisioner(pvc)
+		decision.VolumeMetrics = &kubelet.VolumeMetrics{UsagePercent: 90}
+		decisions = append(decisions, decision)
+		reconciler.applyDecision(ctx, decision)
+	}
+
+	if decisions[0].Reason != ReasonExpand {
+		t.Errorf("expected first expansion to proceed, got %s", decisions[0].Reason)
+	}
+	if decisions[1].Reason != ReasonDeferred {
+		t.Errorf("expected second expansion to be deferred by the StorageClass limit, got %s", decisions[1].Reason)
+	}
+}
+
+func TestApplyDecisionDryRun(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+
+	provisioner := "ebs.csi.aws.com"
+	var pvcs []client.Object
+	newDecision := func(name string) *Decision {
+		pvc := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Status: corev1.PersistentVolumeClaimStatus{
+				Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+			},
+		}
+		pvcs = append(pvcs, pvc)
+		decision := newTestDecision(name, "10Gi", "12Gi")
+		decision.PVC = pvc
+		decision.Provisioner = provisioner
+		decision.VolumeMetrics = &kubelet.VolumeMetrics{UsagePercent: 90}
+		return decision
+	}
+	first, second := newDecision("first"), newDecision("second")
+
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	reconciler := &PersistentVolumeClaimReconciler{
+		Client:        fake.NewClientBuilder().WithScheme(scheme).WithObjects(pvcs...).Build(),
+		EventRecorder: record.NewFakeRecorder(20),
+		DryRun:        true,
+		Clock:         fakeClock,
+		Throttle:      throttle.NewThrottle(map[string]throttle.Limits{provisioner: {MaxInFlight: 1, PerMinute: 1}}, 0),
+		Breaker:       breaker.New(breaker.Settings{FailureThreshold: 1, Window: time.Minute, CoolOff: time.Minute}),
+	}
+	ctx := context.Background()
+
+	reconciler.Throttle.ResetInFlight()
+	reconciler.applyDecision(ctx, first)
+	reconciler.applyDecision(ctx, second)
+	if first.Reason != ReasonExpand || second.Reason != ReasonDeferred {
+		t.Fatalf("expected the would-be throttle result, got %s and %s", first.Reason, second.Reason)
+	}
+	reconciler.Throttle.ResetInFlight()
+	if ok, reason := reconciler.Throttle.Acquire("default/other", "", provisioner, fakeClock.Now()); !ok {
+		t.Errorf("expected the dry run to leave the rate slot free, got %s", reason)
+	}
+
+	// The probe of a circuit past its cool-off is reported but not sent, so
+	// the circuit is neither probed nor closed
+	reconciler.Breaker.RecordFailure(provisioner, fakeClock.Now())
+	reconciler.Throttle = nil
+	third := newDecision("third")
+	reconciler.applyDecision(ctx, third)
+	if third.Reason != ReasonCircuitOpen {
+		t.Errorf("expected the dry run to report the open circuit, got %s", third.Reason)
+	}
+	fakeClock.Step(2 * time.Minute)
+	fourth := newDecision("fourth")
+	reconciler.applyDecision(ctx, fourth)
+	if fourth.Reason != ReasonExpand {
+		t.Errorf("expected the dry run to report the probe, got %s", fourth.Reason)
+	}
+	if state := reconciler.Breaker.State(provisioner); state != breaker.StateOpen {
+		t.Errorf("expected the circuit to stay open, got %s", state)
+	}
+}
+
+func TestPlanPVCFastForward(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = storagev1.AddToScheme(scheme)
+
+	fakeClock := clocktesting.NewFakeClock(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	allowExpansion := true
+	scName := "standard"
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "data",
+			Namespace: "default",
+			Annotations: map[string]string{
+				annotations.AnnotationEnabled:       "true",
+				annotations.AnnotationCooldown:      "15m",
+				annotations.AnnotationLastExpansion: fakeClock.Now().Add(-10 * time.Minute).Format(time.RFC3339),
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{StorageClassName: &scName},
+		Status: corev1.PersistentVolumeClaimStatus{
+			Phase:    corev1.ClaimBound,
+			Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+		},
+	}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(pvc, &storagev1.StorageClass{
+			ObjectMeta:           metav1.ObjectMeta{Name: scName},
+			Provisioner:          "ebs.csi.aws.com",
+			AllowVolumeExpansion: &allowExpansion,
+		}).
+		Build()
+
+	pushed := kubelet.NewPushStore(time.Hour)
+	used := int64(9 << 30)
+	if _, errs := pushed.Add([]kubelet.PushSample{{Namespace: "default", PersistentVolumeClaim: "data", CapacityBytes: 10 << 30, UsedBytes: &used}}); len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	collector, err := kubelet.NewPushMetricsCollector(nil, pushed, kubelet.PushModeOverride)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metricsCache, err := collector.GetAllVolumeMetrics(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reconciler := &PersistentVolumeClaimReconciler{
+		Client:         fakeClient,
+		GlobalConfig:   annotations.NewGlobalConfig(0, 0, "", 0, resource.Quantity{}, resource.Quantity{}),
+		EventRecorder:  record.NewFakeRecorder(20),
+		Breaker:        breaker.New(breaker.Settings{FailureThreshold: 1, Window: time.Minute, CoolOff: 30 * time.Minute}),
+		Clock:          fakeClock,
+		storageCache:   cache.NewStorageClassCache(),
+		policyResolver: &annotations.PolicyResolver{Client: fakeClient, Clock: fakeClock},
+	}
+	ctx := context.Background()
+	settings := &control.Settings{}
+
+	if d := reconciler.planPVC(ctx, pvc, metricsCache, settings); d.Reason != ReasonCooldown {
+		t.Fatalf("expected PVC to be in cooldown, got %s", d.Reason)
+	}
+
+	// A failure opens the circuit, so the expansion waits for the cool-off
+	fakeClock.Step(6 * time.Minute)
+	reconciler.recordBreakerFailure(ctx, pvc, "ebs.csi.aws.com", "resize_error")
+	if d := reconciler.planPVC(ctx, pvc, metricsCache, settings); d.Reason != ReasonCircuitOpen {
+		t.Fatalf("expected expansion to be paused by the circuit breaker, got %s", d.Reason)
+	}
+
+	fakeClock.Step(30 * time.Minute)
+	d := reconciler.planPVC(ctx, pvc, metricsCache, settings)
+	if d.Reason != ReasonExpand {
+		t.Fatalf("expected expansion after the cool-off, got %s", d.Reason)
+	}
+
+	reconciler.applyDecision(ctx, d)
+	var updated corev1.PersistentVolumeClaim
+	if err := fakeClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "data"}, &updated); err != nil {
+		t.Fatal(err)
+	}
+	if got := updated.Annotations[annotations.AnnotationLastExpansion]; got != fakeClock.Now().Format(time.RFC3339) {
+		t.Errorf("expected last expansion at the fake clock time %s, got %s", fakeClock.Now().Format(time.RFC3339), got)
+	}
+}
+
+func TestPlanPVCPersistentFailureCountedOnce(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = storagev1.AddToScheme(scheme)
+
+	fakeClock := clocktesting.NewFakeClock(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	allowExpansion := true
+	scName := "standard"
+	newPVC := func(name string, conditions ...corev1.PersistentVolumeClaimCondition) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				UID:       types.UID(name + "-uid"),
+				Annotations: map[string]string{
+					annotations.AnnotationEnabled:       "true",
+					annotations.AnnotationLastExpansion: fakeClock.Now().Add(-time.Hour).Format(time.RFC3339),
+				},
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{StorageClassName: &scName},
+			Status: corev1.PersistentVolumeClaimStatus{
+				Phase:      corev1.ClaimBound,
+				Capacity:   corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+				Conditions: conditions,
+			},
+		}
+	}
+	stuck := newPVC("stuck", corev1.PersistentVolumeClaimCondition{Type: corev1.PersistentVolumeClaimResizing, Status: corev1.ConditionTrue})
+	failed := newPVC("failed", corev1.PersistentVolumeClaimCondition{
+		Type:               corev1.PersistentVolumeClaimControllerResizeError,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(fakeClock.Now().Add(-time.Hour)),
+	})
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(stuck, failed, &storagev1.StorageClass{
+			ObjectMeta:           metav1.ObjectMeta{Name: scName},
+			Provisioner:          "ebs.csi.aws.com",
+			AllowVolumeExpansion: &allowExpansion,
+		}).
+		Build()
+
+	reconciler := &PersistentVolumeClaimReconciler{
+		Client:             fakeClient,
+		GlobalConfig:       annotations.NewGlobalConfig(0, 0, "", 0, resource.Quantity{}, resource.Quantity{}),
+		EventRecorder:      record.NewFakeRecorder(20),
+		Breaker:            breaker.New(breaker.Settings{FailureThreshold: 3, Window: time.Hour, CoolOff: 30 * time.Minute}),
+		StuckResizeTimeout: 30 * time.Minute,
+		Clock:              fakeClock,
+		storageCache:       cache.NewStorageClassCache(),
+		policyResolver:     &annotations.PolicyResolver{Client: fakeClient, Clock: fakeClock},
+	}
+	ctx := context.Background()
+	metricsCache := kubelet.NewMetricsCache()
+
+	// The same stuck resize and resize error are seen on every cycle
+	for cycle := 0; cycle < 30; cycle++ {
+		if d := reconciler.planPVC(ctx, stuck, metricsCache, &control.Settings{}); d.Reason != ReasonResizeInProgress {
+			t.Fatalf("cycle %d: expected resize in progress, got %s", cycle, d.Reason)
+		}
+		if d := reconciler.planPVC(ctx, failed, metricsCache, &control.Settings{}); d.Reason != ReasonResizeError {
+			t.Fatalf("cycle %d: expected resize error, got %s", cycle, d.Reason)
+		}
+		if state := reconciler.Breaker.State("ebs.csi.aws.com"); state != breaker.StateClosed {
+			t.Fatalf("cycle %d: expected the circuit to stay closed, got %s", cycle, state)
+		}
+		fakeClock.Step(time.Minute)
+	}
+
+	// A new resize error on the same PVC is a new episode
+	failed.Status.Conditions[0].LastTransitionTime = metav1.NewTime(fakeClock.Now())
+	reconciler.planPVC(ctx, failed, metricsCache, &control.Settings{})
+	if state := reconciler.Breaker.State("ebs.csi.aws.com"); state != breaker.StateOpen {
+		t.Errorf("expected the circuit to open after three failure episodes, got %s", state)
+	}
+}
+
+func TestPlanPVCInvalidPausedUntil(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = storagev1.AddToScheme(scheme)
+
+	allowExpansion := true
+	scName := "standard"
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "data",
+			Namespace: "default",
+			Annotations: map[string]string{
+				annotations.AnnotationEnabled:     "true",
+				annotations.AnnotationPausedUntil: "tomorrow",
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{StorageClassName: &scName},
+		Status: corev1.PersistentVolumeClaimStatus{
+			Phase:    corev1.ClaimBound,
+			Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+		},
+	}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(pvc, &storagev1.StorageClass{
+			ObjectMeta:           metav1.ObjectMeta{Name: scName},
+			Provisioner:          "ebs.csi.aws.com",
+			AllowVolumeExpansion: &allowExpansion,
+		}).
+		Build()
+
+	pushed := kubelet.NewPushStore(time.Hour)
+	used := int64(9 << 30)
+	if _, errs := pushed.Add([]kubelet.PushSample{{Namespace: "default", PersistentVolumeClaim: "data", CapacityBytes: 10 << 30, UsedBytes: &used}}); len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	collector, err := kubelet.NewPushMetricsCollector(nil, pushed, kubelet.PushModeOverride)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metricsCache, err := collector.GetAllVolumeMetrics(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := record.NewFakeRecorder(20)
+	reconciler := &PersistentVolumeClaimReconciler{
+		Client:         fakeClient,
+		GlobalConfig:   annotations.NewGlobalConfig(0, 0, "", 0, resource.Quantity{}, resource.Quantity{}),
+		EventRecorder:  recorder,
+		storageCache:   cache.NewStorageClassCache(),
+		policyResolver: &annotations.PolicyResolver{Client: fakeClient},
+	}
+
+	// The PVC above its threshold stays managed but is not expanded
+	d := reconciler.planPVC(context.Background(), pvc, metricsCache, &control.Settings{})
+	if d == nil || d.Reason != ReasonInvalidPausedUntil {
+		t.Fatalf("expected the PVC to be suspended by the invalid annotation, got %+v", d)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "InvalidPausedUntil") {
+			t.Errorf("unexpected event %q", event)
+		}
+	default:
+		t.Error("expected an InvalidPausedUntil event")
+	}
+}
@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/logicIQ/pvc-chonker/pkg/annotations"
+	"github.com/logicIQ/pvc-chonker/pkg/kubelet"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Decision reasons recorded for every PVC evaluated in a reconciliation cycle.
+const (
+	ReasonExpand                    = "expand"
+	ReasonNotEligible               = "not_eligible"
+	ReasonStorageClassNotExpandable = "storage_class_not_expandable"
+	ReasonResizeInProgress          = "resize_in_progress"
+	ReasonResizeError               = "resize_error"
+	ReasonCooldown                  = "cooldown"
+	ReasonMetricsNotFound           = "metrics_not_found"
+	ReasonMetricsUnavailable        = "metrics_unavailable"
+	ReasonMetricsStale              = "metrics_stale"
+	ReasonSuspended                 = "suspended"
+	ReasonInvalidPausedUntil        = "invalid_paused_until"
+	ReasonBelowThreshold            = "below_threshold"
+	ReasonMaxSizeReached            = "max_size_reached"
+	ReasonInvalidConfig             = "invalid_config"
+	ReasonCircuitOpen               = "circuit_open"
+	ReasonDeferred                  = "deferred"
+	ReasonPlanHeld                  = "plan_held"
+	ReasonExpansionFailed           = "expansion_failed"
+)
+
+// Decision is the outcome of evaluating a single PVC during planning.
+type Decision struct {
+	PVC           *corev1.PersistentVolumeClaim
+	Config        *annotations.PVCConfig
+	Reason        string
+	VolumeMetrics *kubelet.VolumeMetrics
+	CurrentSize   resource.Quantity
+	NewSize       resource.Quantity
+	FsType        string
+	InodePressure bool
+	Provisioner   string
+	// Err is why the expansion failed when Reason is ReasonExpansionFailed.
+	Err error
+}
+
+func (d *Decision) Key() types.NamespacedName {
+	return types.NamespacedName{Namespace: d.PVC.Namespace, Name: d.PVC.Name}
+}
+
+func (d *Decision) BytesAdded() int64 {
+	return d.NewSize.Value() - d.CurrentSize.Value()
+}
+
+// ExpansionPlan holds the decisions of a reconciliation cycle before any PVC is
+// modified, so global safety caps can be enforced on the cycle as a whole.
+type ExpansionPlan struct {
+	Decisions   []*Decision
+	ManagedPVCs int
+}
+
+func NewExpansionPlan(decisions []*Decision, managedPVCs int) *ExpansionPlan {
+	sort.Slice(decisions, func(i, j int) bool {
+		return decisions[i].Key().String() < decisions[j].Key().String()
+	})
+	return &ExpansionPlan{Decisions: decisions, ManagedPVCs: managedPVCs}
+}
+
+func (p *ExpansionPlan) Expansions() []*Decision {
+	var expansions []*Decision
+	for _, d := range p.Decisions {
+		if d.Reason == ReasonExpand {
+			expansions = append(expansions, d)
+		}
+	}
+	return expansions
+}
+
+func (p *ExpansionPlan) TotalBytesAdded() int64 {
+	var total int64
+	for _, d := range p.Expansions() {
+		total += d.BytesAdded()
+	}
+	return total
+}
+
+// ID identifies the set of expansions in the plan. Operators acknowledge a held
+// plan by its ID, so the same PVCs expanded to the same sizes yield the same ID.
+func (p *ExpansionPlan) ID() string {
+	hash := sha256.New()
+	for _, d := range p.Expansions() {
+		fmt.Fprintf(hash, "%s=%d\n", d.Key(), d.NewSize.Value())
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:12]
+}
+
+// ReasonCounts returns the number of decisions per reason.
+func (p *ExpansionPlan) ReasonCounts() map[string]int {
+	counts := make(map[string]int)
+	for _, d := range p.Decisions {
+		counts[d.Reason]++
+	}
+	return counts
+}
+
+// CycleLimits caps how much a single reconciliation cycle may expand. Zero
+// values disable the corresponding cap.
+type CycleLimits struct {
+	MaxExpansions int
+	MaxBytes      resource.Quantity
+	MaxPercent    float64
+}
+
+// CapViolation describes a safety cap exceeded by a plan.
+type CapViolation struct {
+	Cap     string
+	Message string
+}
+
+// Check returns the caps exceeded by the plan.
+func (l CycleLimits) Check(plan *ExpansionPlan) []CapViolation {
+	var violations []CapViolation
+	expansions := len(plan.Expansions())
+	if expansions == 0 {
+		return nil
+	}
+
+	if l.MaxExpansions > 0 && expansions > l.MaxExpansions {
+		violations = append(violations, CapViolation{
+			Cap:     "max_expansions",
+			Message: fmt.Sprintf("%d expansions exceed the limit of %d per cycle", expansions, l.MaxExpansions),
+		})
+	}
+
+	if !l.MaxBytes.IsZero() {
+		if added := plan.TotalBytesAdded(); added > l.MaxBytes.Value() {
+			violations = append(violations, CapViolation{
+				Cap: "max_bytes",
+				Message: fmt.Sprintf("%s added exceeds the limit of %s per cycle",
+					resource.NewQuantity(added, resource.BinarySI).String(), l.MaxBytes.String()),
+			})
+		}
+	}
+
+	if l.MaxPercent > 0 && plan.ManagedPVCs > 0 {
+		if percent := float64(expansions) / float64(plan.ManagedPVCs) * 100; percent > l.MaxPercent {
+			violations = append(violations, CapViolation{
+				Cap:     "max_percent",
+				Message: fmt.Sprintf("%.1f%% of managed PVCs exceeds the limit of %.1f%% per cycle", percent, l.MaxPercent),
+			})
+		}
+	}
+
+	return violations
+}
+
+func formatViolations(violations []CapViolation) string {
+	messages := make([]string, 0, len(violations))
+	for _, v := range violations {
+		messages = append(messages, v.Message)
+	}
+	return strings.Join(messages, "; ")
+}
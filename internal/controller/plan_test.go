@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/logicIQ/pvc-chonker/pkg/control"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func newTestDecision(name, current, target string) *Decision {
+	return &Decision{
+		PVC: &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		},
+		Reason:      ReasonExpand,
+		CurrentSize: resource.MustParse(current),
+		NewSize:     resource.MustParse(target),
+	}
+}
+
+func TestExpansionPlanID(t *testing.T) {
+	a := NewExpansionPlan([]*Decision{
+		newTestDecision("b", "10Gi", "12Gi"),
+		newTestDecision("a", "10Gi", "12Gi"),
+	}, 10)
+	b := NewExpansionPlan([]*Decision{
+		newTestDecision("a", "10Gi", "12Gi"),
+		newTestDecision("b", "10Gi", "12Gi"),
+		{PVC: &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "c", Namespace: "default"}}, Reason: ReasonBelowThreshold},
+	}, 10)
+
+	if a.ID() != b.ID() {
+		t.Errorf("expected plans with the same expansions to share an ID, got %s and %s", a.ID(), b.ID())
+	}
+
+	c := NewExpansionPlan([]*Decision{
+		newTestDecision("a", "10Gi", "12Gi"),
+		newTestDecision("b", "10Gi", "14Gi"),
+	}, 10)
+	if a.ID() == c.ID() {
+		t.Error("expected plans with different target sizes to have different IDs")
+	}
+
+	if got := a.TotalBytesAdded(); got != 4*1024*1024*1024 {
+		t.Errorf("expected 4Gi added, got %d", got)
+	}
+}
+
+func TestCycleLimitsCheck(t *testing.T) {
+	plan := NewExpansionPlan([]*Decision{
+		newTestDecision("a", "10Gi", "20Gi"),
+		newTestDecision("b", "10Gi", "20Gi"),
+		newTestDecision("c", "10Gi", "20Gi"),
+	}, 10)
+
+	tests := []struct {
+		name     string
+		limits   CycleLimits
+		expected []string
+	}{
+		{
+			name:   "no limits",
+			limits: CycleLimits{},
+		},
+		{
+			name:   "within limits",
+			limits: CycleLimits{MaxExpansions: 3, MaxBytes: resource.MustParse("30Gi"), MaxPercent: 30},
+		},
+		{
+			name:     "too many expansions",
+			limits:   CycleLimits{MaxExpansions: 2},
+			expected: []string{"max_expansions"},
+		},
+		{
+			name:     "too many bytes",
+			limits:   CycleLimits{MaxBytes: resource.MustParse("20Gi")},
+			expected: []string{"max_bytes"},
+		},
+		{
+			name:     "all caps exceeded",
+			limits:   CycleLimits{MaxExpansions: 1, MaxBytes: resource.MustParse("1Gi"), MaxPercent: 10},
+			expected: []string{"max_expansions", "max_bytes", "max_percent"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := tt.limits.Check(plan)
+			if len(violations) != len(tt.expected) {
+				t.Fatalf("expected %d violations, got %v", len(tt.expected), violations)
+			}
+			for i, v := range violations {
+				if v.Cap != tt.expected[i] {
+					t.Errorf("expected cap %s, got %s", tt.expected[i], v.Cap)
+				}
+			}
+		})
+	}
+}
+
+func TestAllowPlan(t *testing.T) {
+	newPlan := func() *ExpansionPlan {
+		return NewExpansionPlan([]*Decision{
+			newTestDecision("a", "10Gi", "20Gi"),
+			newTestDecision("b", "10Gi", "20Gi"),
+		}, 2)
+	}
+
+	tests := []struct {
+		name         string
+		acknowledged string
+		limits       CycleLimits
+		expected     bool
+	}{
+		{
+			name:     "within caps",
+			limits:   CycleLimits{MaxExpansions: 2},
+			expected: true,
+		},
+		{
+			name:     "exceeds caps",
+			limits:   CycleLimits{MaxExpansions: 1},
+			expected: false,
+		},
+		{
+			name:         "exceeds caps with stale acknowledgement",
+			acknowledged: "0123456789ab",
+			limits:       CycleLimits{MaxExpansions: 1},
+			expected:     false,
+		},
+		{
+			name:         "exceeds caps but acknowledged",
+			acknowledged: newPlan().ID(),
+			limits:       CycleLimits{MaxExpansions: 1},
+			expected:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			reconciler := &PersistentVolumeClaimReconciler{
+				EventRecorder: recorder,
+				CycleLimits:   tt.limits,
+			}
+
+			plan := newPlan()
+			settings := &control.Settings{AcknowledgedPlan: tt.acknowledged}
+			if allowed := reconciler.allowPlan(context.Background(), plan, settings); allowed != tt.expected {
+				t.Fatalf("expected allowPlan() = %v, got %v", tt.expected, allowed)
+			}
+
+			if tt.expected {
+				if len(plan.Expansions()) != 2 {
+					t.Errorf("expected expansions to stay planned, got %v", plan.ReasonCounts())
+				}
+				return
+			}
+			if counts := plan.ReasonCounts(); counts[ReasonPlanHeld] != 2 {
+				t.Errorf("expected both decisions to be held, got %v", counts)
+			}
+			if len(recorder.Events) != 2 {
+				t.Errorf("expected an event per held PVC, got %d", len(recorder.Events))
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -12,6 +13,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -19,6 +21,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	pvcchonkerv1alpha1 "github.com/logicIQ/pvc-chonker/api/v1alpha1"
+	"github.com/logicIQ/pvc-chonker/pkg/annotations"
+	"github.com/logicIQ/pvc-chonker/pkg/control"
 )
 
 // PVCGroupReconciler reconciles a PVCGroup object
@@ -26,6 +30,12 @@ type PVCGroupReconciler struct {
 	client.Client
 	Scheme        *runtime.Scheme
 	EventRecorder record.EventRecorder
+	// Clock is the time source of status timestamps and pause checks. Real
+	// time is used when nil.
+	Clock clock.PassiveClock
+	// ControlLoader reads the cluster-wide kill switch, which pauses size
+	// coordination like automatic expansions.
+	ControlLoader *control.Loader
 	// Mutex to prevent concurrent status updates for the same PVCGroup
 	statusLocks sync.Map // map[string]*sync.Mutex
 }
@@ -77,22 +87,22 @@ func (r *PVCGroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	var activePVCs []corev1.PersistentVolumeClaim
 	for _, pvc := range pvcList.Items {
 		// Must have group annotation matching this group
-		if pvc.Annotations == nil || pvc.Annotations["pvc-chonker.io/group"] != pvcGroup.Name {
+		if pvc.Annotations == nil || pvc.Annotations[annotations.AnnotationGroup] != pvcGroup.Name {
 			continue
 		}
 
 		// Must be enabled
-		if enabled, exists := pvc.Annotations["pvc-chonker.io/enabled"]; !exists || enabled != "true" {
+		if enabled, exists := pvc.Annotations[annotations.AnnotationEnabled]; !exists || enabled != "true" {
 			logger.V(1).Info("PVC excluded from group", "pvc", pvc.Name, "enabled", enabled, "exists", exists)
 			continue
 		}
 
-		logger.V(1).Info("PVC included in group", "pvc", pvc.Name, "group", pvc.Annotations["pvc-chonker.io/group"], "enabled", pvc.Annotations["pvc-chonker.io/enabled"])
+		logger.V(1).Info("PVC included in group", "pvc", pvc.Name, "group", pvc.Annotations[annotations.AnnotationGroup], "enabled", pvc.Annotations[annotations.AnnotationEnabled])
 		activePVCs = append(activePVCs, pvc)
 	}
 
 	// Update status
-	now := metav1.Now()
+	now := metav1.NewTime(r.now())
 	pvcGroup.Status.MemberCount = int32(len(activePVCs))
 	pvcGroup.Status.LastUpdated = &now
 
@@ -101,7 +111,11 @@ func (r *PVCGroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		pvcGroup.Status.CurrentSize = &coordinatedSize
 
 		// Apply coordination if needed
-		if err := r.coordinatePVCSizes(ctx, activePVCs, coordinatedSize, &pvcGroup); err != nil {
+		if isGroupSuspended(&pvcGroup, now.Time) {
+			logger.Info("PVCGroup suspended, skipping size coordination")
+		} else if r.isClusterPaused(ctx) {
+			logger.Info("Size coordination paused by control ConfigMap")
+		} else if err := r.coordinatePVCSizes(ctx, activePVCs, coordinatedSize, &pvcGroup); err != nil {
 			logger.Error(err, "Failed to coordinate PVC sizes")
 			r.EventRecorder.Event(&pvcGroup, corev1.EventTypeWarning, "CoordinationFailed", err.Error())
 			return ctrl.Result{RequeueAfter: time.Minute * 5}, err
@@ -118,6 +132,13 @@ func (r *PVCGroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	return ctrl.Result{RequeueAfter: time.Minute * 10}, nil
 }
 
+func (r *PVCGroupReconciler) now() time.Time {
+	if r.Clock == nil {
+		return time.Now()
+	}
+	return r.Clock.Now()
+}
+
 func (r *PVCGroupReconciler) calculateLargestSize(pvcs []corev1.PersistentVolumeClaim) resource.Quantity {
 	var largest resource.Quantity
 	for _, pvc := range pvcs {
@@ -139,6 +160,11 @@ func (r *PVCGroupReconciler) coordinatePVCSizes(ctx context.Context, pvcs []core
 			continue
 		}
 
+		if suspended, suspendedBy := r.isMemberSuspended(ctx, &pvc); suspended {
+			logger.Info("PVC suspended, skipping size coordination", "pvc", pvc.Name, "suspendedBy", suspendedBy)
+			continue
+		}
+
 		// Update PVC size to match group coordination
 		pvcCopy := pvc.DeepCopy()
 		pvcCopy.Spec.Resources.Requests[corev1.ResourceStorage] = targetSize
@@ -155,6 +181,48 @@ func (r *PVCGroupReconciler) coordinatePVCSizes(ctx context.Context, pvcs []core
 	return nil
 }
 
+func isGroupSuspended(group *pvcchonkerv1alpha1.PVCGroup, now time.Time) bool {
+	if group.Spec.Suspend {
+		return true
+	}
+	return group.Spec.SuspendUntil != nil && now.Before(group.Spec.SuspendUntil.Time)
+}
+
+// isClusterPaused reports whether the kill switch of the control ConfigMap is
+// active. An unreadable ConfigMap pauses coordination, as it does expansions.
+func (r *PVCGroupReconciler) isClusterPaused(ctx context.Context) bool {
+	settings, err := r.ControlLoader.Load(ctx)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to load control settings, pausing size coordination")
+		return true
+	}
+	return settings.IsPaused(r.now())
+}
+
+// isMemberSuspended resolves the configuration of a member PVC like the PVC
+// reconciler does and reports whether it is suspended by a paused-until
+// annotation, a PVCPolicy or its PVCGroup. Members whose configuration cannot
+// be resolved are treated as suspended.
+func (r *PVCGroupReconciler) isMemberSuspended(ctx context.Context, pvc *corev1.PersistentVolumeClaim) (bool, string) {
+	resolver := &annotations.PolicyResolver{Client: r.Client, Clock: r.Clock}
+	// Only the suspension is used, so the global defaults do not matter
+	global := annotations.NewGlobalConfig(0, 0, "", 0, resource.Quantity{}, resource.Quantity{})
+	config, err := resolver.ResolvePVCConfig(ctx, pvc, global)
+	if errors.Is(err, annotations.ErrPVCNotManaged) {
+		return false, ""
+	}
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to resolve PVC configuration, skipping size coordination", "pvc", pvc.Name)
+		return true, "unresolvable configuration"
+	}
+	if config.PausedUntilErr != nil {
+		r.EventRecorder.Eventf(pvc, corev1.EventTypeWarning, "InvalidPausedUntil",
+			"Expansion suspended until the %s annotation is fixed: %v", annotations.AnnotationPausedUntil, config.PausedUntilErr)
+		return true, config.PausedUntilErr.Error()
+	}
+	return config.IsSuspended(), config.SuspendedBy
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *PVCGroupReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
@@ -176,7 +244,7 @@ func (r *PVCGroupReconciler) findPVCGroupForPVC(ctx context.Context, obj client.
 	}
 
 	// Only process PVCs with group annotation
-	groupName, exists := pvc.Annotations["pvc-chonker.io/group"]
+	groupName, exists := pvc.Annotations[annotations.AnnotationGroup]
 	if !exists || groupName == "" {
 		return nil
 	}
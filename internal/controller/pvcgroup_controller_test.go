@@ -13,10 +13,12 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	pvcchonkerv1alpha1 "github.com/logicIQ/pvc-chonker/api/v1alpha1"
+	"github.com/logicIQ/pvc-chonker/pkg/control"
 )
 
 func TestPVCGroupReconciler_Reconcile(t *testing.T) {
@@ -304,3 +306,199 @@ func TestPVCGroupReconciler_coordinatePVCSizes(t *testing.T) {
 func stringPtr(s string) *string {
 	return &s
 }
+
+func TestPVCGroupReconciler_suspension(t *testing.T) {
+	now := time.Now()
+
+	assert.False(t, isGroupSuspended(&pvcchonkerv1alpha1.PVCGroup{}, now))
+	assert.True(t, isGroupSuspended(&pvcchonkerv1alpha1.PVCGroup{
+		Spec: pvcchonkerv1alpha1.PVCGroupSpec{Suspend: true},
+	}, now))
+	assert.True(t, isGroupSuspended(&pvcchonkerv1alpha1.PVCGroup{
+		Spec: pvcchonkerv1alpha1.PVCGroupSpec{SuspendUntil: &metav1.Time{Time: now.Add(time.Hour)}},
+	}, now))
+	assert.False(t, isGroupSuspended(&pvcchonkerv1alpha1.PVCGroup{
+		Spec: pvcchonkerv1alpha1.PVCGroupSpec{SuspendUntil: &metav1.Time{Time: now.Add(-time.Hour)}},
+	}, now))
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	require.NoError(t, pvcchonkerv1alpha1.AddToScheme(scheme))
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &PVCGroupReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).Build(), EventRecorder: recorder}
+	paused := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "default",
+		Annotations: map[string]string{
+			"pvc-chonker.io/enabled":      "true",
+			"pvc-chonker.io/paused-until": now.Add(time.Hour).Format(time.RFC3339),
+		},
+	}}
+	suspended, suspendedBy := reconciler.isMemberSuspended(context.Background(), paused)
+	assert.True(t, suspended)
+	assert.Equal(t, "annotation pvc-chonker.io/paused-until", suspendedBy)
+	suspended, _ = reconciler.isMemberSuspended(context.Background(), &corev1.PersistentVolumeClaim{})
+	assert.False(t, suspended)
+
+	// An invalid paused-until suspends the member like it suspends expansions
+	invalid := paused.DeepCopy()
+	invalid.Annotations["pvc-chonker.io/paused-until"] = "tomorrow"
+	suspended, suspendedBy = reconciler.isMemberSuspended(context.Background(), invalid)
+	assert.True(t, suspended)
+	assert.Contains(t, suspendedBy, "invalid paused-until time")
+	require.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "InvalidPausedUntil")
+}
+
+func TestPVCGroupReconciler_killSwitch(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	require.NoError(t, pvcchonkerv1alpha1.AddToScheme(scheme))
+
+	member := func(name, size string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Annotations: map[string]string{
+				"pvc-chonker.io/group":   "test-group",
+				"pvc-chonker.io/enabled": "true",
+			}},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(size)},
+				},
+			},
+		}
+	}
+	controlConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: control.DefaultConfigMapName, Namespace: control.DefaultNamespace},
+		Data:       map[string]string{control.KeyPaused: "true"},
+	}
+	client := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRuntimeObjects(
+			&pvcchonkerv1alpha1.PVCGroup{ObjectMeta: metav1.ObjectMeta{Name: "test-group", Namespace: "default"}},
+			member("pvc-1", "200Gi"),
+			member("pvc-2", "100Gi"),
+			controlConfigMap).
+		WithStatusSubresource(&pvcchonkerv1alpha1.PVCGroup{}).
+		Build()
+	reconciler := &PVCGroupReconciler{
+		Client:        client,
+		Scheme:        scheme,
+		EventRecorder: record.NewFakeRecorder(10),
+		ControlLoader: control.NewLoader(client, "", ""),
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "test-group", Namespace: "default"}}
+	reconcile := func() resource.Quantity {
+		_, err := reconciler.Reconcile(context.Background(), req)
+		require.NoError(t, err)
+		var pvc corev1.PersistentVolumeClaim
+		require.NoError(t, client.Get(context.Background(), types.NamespacedName{Name: "pvc-2", Namespace: "default"}, &pvc))
+		return pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	}
+
+	size := reconcile()
+	assert.Equal(t, "100Gi", size.String(), "PVC must not be coordinated while the cluster is paused")
+
+	require.NoError(t, client.Get(context.Background(), types.NamespacedName{Name: control.DefaultConfigMapName, Namespace: control.DefaultNamespace}, controlConfigMap))
+	controlConfigMap.Data[control.KeyPaused] = "false"
+	require.NoError(t, client.Update(context.Background(), controlConfigMap))
+	size = reconcile()
+	assert.Equal(t, "200Gi", size.String(), "PVC must be coordinated once the cluster is resumed")
+}
+
+func TestPVCGroupReconciler_policySuspension(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	require.NoError(t, pvcchonkerv1alpha1.AddToScheme(scheme))
+
+	group := &pvcchonkerv1alpha1.PVCGroup{ObjectMeta: metav1.ObjectMeta{Name: "test-group", Namespace: "default"}}
+	pvc := func(name string, labels map[string]string) corev1.PersistentVolumeClaim {
+		return corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("100Gi")},
+				},
+			},
+		}
+	}
+	pvcs := []corev1.PersistentVolumeClaim{pvc("suspended", map[string]string{"app": "db"}), pvc("active", nil)}
+	policy := &pvcchonkerv1alpha1.PVCPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+		Spec: pvcchonkerv1alpha1.PVCPolicySpec{
+			Selector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+			Suspend:  true,
+		},
+	}
+	client := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(group, policy, &pvcs[0], &pvcs[1]).Build()
+	reconciler := &PVCGroupReconciler{Client: client, Scheme: scheme, EventRecorder: record.NewFakeRecorder(10)}
+
+	targetSize := resource.MustParse("200Gi")
+	require.NoError(t, reconciler.coordinatePVCSizes(context.Background(), pvcs, targetSize, group))
+
+	for name, want := range map[string]string{"suspended": "100Gi", "active": "200Gi"} {
+		var updated corev1.PersistentVolumeClaim
+		require.NoError(t, client.Get(context.Background(), types.NamespacedName{Name: name, Namespace: "default"}, &updated))
+		size := updated.Spec.Resources.Requests[corev1.ResourceStorage]
+		assert.Equal(t, want, size.String(), "PVC %s", name)
+	}
+}
+
+func TestPVCGroupReconciler_clock(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	require.NoError(t, pvcchonkerv1alpha1.AddToScheme(scheme))
+
+	fakeClock := clocktesting.NewFakePassiveClock(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	group := &pvcchonkerv1alpha1.PVCGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-group", Namespace: "default"},
+	}
+	member := func(name, size string, annotations map[string]string) *corev1.PersistentVolumeClaim {
+		annotations["pvc-chonker.io/group"] = "test-group"
+		annotations["pvc-chonker.io/enabled"] = "true"
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Annotations: annotations},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(size)},
+				},
+			},
+		}
+	}
+	pausedUntil := fakeClock.Now().Add(time.Hour).Format(time.RFC3339)
+
+	client := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRuntimeObjects(group,
+			member("pvc-1", "200Gi", map[string]string{}),
+			member("pvc-2", "100Gi", map[string]string{"pvc-chonker.io/paused-until": pausedUntil})).
+		WithStatusSubresource(&pvcchonkerv1alpha1.PVCGroup{}).
+		Build()
+	reconciler := &PVCGroupReconciler{
+		Client:        client,
+		Scheme:        scheme,
+		EventRecorder: record.NewFakeRecorder(10),
+		Clock:         fakeClock,
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "test-group", Namespace: "default"}}
+	reconcile := func() (pvcchonkerv1alpha1.PVCGroup, resource.Quantity) {
+		_, err := reconciler.Reconcile(context.Background(), req)
+		require.NoError(t, err)
+		var updatedGroup pvcchonkerv1alpha1.PVCGroup
+		require.NoError(t, client.Get(context.Background(), req.NamespacedName, &updatedGroup))
+		var pvc corev1.PersistentVolumeClaim
+		require.NoError(t, client.Get(context.Background(), types.NamespacedName{Name: "pvc-2", Namespace: "default"}, &pvc))
+		return updatedGroup, pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	}
+
+	updatedGroup, size := reconcile()
+	require.NotNil(t, updatedGroup.Status.LastUpdated)
+	assert.True(t, fakeClock.Now().Equal(updatedGroup.Status.LastUpdated.Time))
+	assert.Equal(t, "100Gi", size.String(), "paused PVC must not be coordinated")
+
+	fakeClock.SetTime(fakeClock.Now().Add(time.Hour))
+	updatedGroup, size = reconcile()
+	assert.True(t, fakeClock.Now().Equal(updatedGroup.Status.LastUpdated.Time))
+	assert.Equal(t, "200Gi", size.String(), "PVC must be coordinated once the pause has ended")
+}
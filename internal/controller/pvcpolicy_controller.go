@@ -2,12 +2,14 @@ package controller
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -23,10 +25,16 @@ type PVCPolicyReconciler struct {
 	client.Client
 	Scheme        *runtime.Scheme
 	EventRecorder record.EventRecorder
+	// Clock is the time source of status timestamps. Real time is used when
+	// nil.
+	Clock clock.PassiveClock
 	// Channel-based semaphore to limit concurrent reconciliations
-	semaphore chan struct{}
+	semaphore     chan struct{}
+	semaphoreOnce sync.Once
 }
 
+const maxConcurrentPolicyReconciles = 10
+
 //+kubebuilder:rbac:groups=pvc-chonker.io,resources=pvcpolicies,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=pvc-chonker.io,resources=pvcpolicies/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=pvc-chonker.io,resources=pvcpolicies/finalizers,verbs=update
@@ -34,7 +42,12 @@ type PVCPolicyReconciler struct {
 func (r *PVCPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 
-	// Acquire semaphore
+	// Acquire semaphore, initializing it if the reconciler was not set up through the manager
+	r.semaphoreOnce.Do(func() {
+		if r.semaphore == nil {
+			r.semaphore = make(chan struct{}, maxConcurrentPolicyReconciles)
+		}
+	})
 	r.semaphore <- struct{}{}
 	defer func() { <-r.semaphore }()
 
@@ -63,7 +76,7 @@ func (r *PVCPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	}
 
 	policy.Status.MatchedPVCs = matchedCount
-	now := metav1.Now()
+	now := metav1.NewTime(r.now())
 	policy.Status.LastUpdated = &now
 
 	if err := r.Status().Update(ctx, &policy); err != nil {
@@ -75,9 +88,16 @@ func (r *PVCPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	return ctrl.Result{RequeueAfter: 5 * time.Minute}, nil
 }
 
+func (r *PVCPolicyReconciler) now() time.Time {
+	if r.Clock == nil {
+		return time.Now()
+	}
+	return r.Clock.Now()
+}
+
 func (r *PVCPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	// Initialize semaphore before any reconciliation occurs
-	r.semaphore = make(chan struct{}, 10)
+	r.semaphore = make(chan struct{}, maxConcurrentPolicyReconciles)
 
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&pvcchonkerv1alpha1.PVCPolicy{}).
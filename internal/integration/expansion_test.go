@@ -0,0 +1,99 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/logicIQ/pvc-chonker/pkg/annotations"
+	"github.com/logicIQ/pvc-chonker/pkg/kubelet/kubelettest"
+)
+
+func TestExpansion_ThresholdReached(t *testing.T) {
+	ctx := context.Background()
+	ns := newNamespace(t)
+
+	createBoundPVC(t, &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "data",
+			Namespace: ns,
+			Annotations: map[string]string{
+				annotations.AnnotationEnabled:   "true",
+				annotations.AnnotationThreshold: "80%",
+				annotations.AnnotationIncrease:  "20%",
+				annotations.AnnotationCooldown:  "1h",
+			},
+		},
+	}, "10Gi")
+	mountVolume(t, ns, kubelettest.Volume{Namespace: ns, PVC: "data", CapacityBytes: 10 * gib, UsedBytes: 9 * gib})
+
+	requireRequestedSize(t, ns, "data", "12Gi")
+
+	var pvc corev1.PersistentVolumeClaim
+	require.NoError(t, k8sClient.Get(ctx, client.ObjectKey{Namespace: ns, Name: "data"}, &pvc))
+	assert.Contains(t, pvc.Annotations, annotations.AnnotationLastExpansion)
+
+	require.Eventually(t, func() bool {
+		var events corev1.EventList
+		if err := k8sClient.List(ctx, &events, client.InNamespace(ns)); err != nil {
+			return false
+		}
+		for _, event := range events.Items {
+			if event.InvolvedObject.Name == "data" && event.Reason == "Expanded" {
+				return true
+			}
+		}
+		return false
+	}, timeout, interval, "expected an Expanded event")
+
+	// The resize completes but usage stays above the threshold
+	pvc.Status.Capacity[corev1.ResourceStorage] = pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	require.NoError(t, k8sClient.Status().Update(ctx, &pvc))
+	kubeletServer.SetCapacity(client.ObjectKeyFromObject(&pvc), 12*gib)
+	kubeletServer.SetUsage(client.ObjectKeyFromObject(&pvc), 11*gib)
+
+	requireRequestedSizeStays(t, ns, "data", "12Gi")
+}
+
+func TestExpansion_BelowThreshold(t *testing.T) {
+	ns := newNamespace(t)
+
+	createBoundPVC(t, &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "data",
+			Namespace: ns,
+			Annotations: map[string]string{
+				annotations.AnnotationEnabled:   "true",
+				annotations.AnnotationThreshold: "80%",
+			},
+		},
+	}, "10Gi")
+	mountVolume(t, ns, kubelettest.Volume{Namespace: ns, PVC: "data", CapacityBytes: 10 * gib, UsedBytes: 5 * gib})
+
+	requireRequestedSizeStays(t, ns, "data", "10Gi")
+}
+
+func TestExpansion_MaxSize(t *testing.T) {
+	ns := newNamespace(t)
+
+	createBoundPVC(t, &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "data",
+			Namespace: ns,
+			Annotations: map[string]string{
+				annotations.AnnotationEnabled:   "true",
+				annotations.AnnotationThreshold: "80%",
+				annotations.AnnotationIncrease:  "50%",
+				annotations.AnnotationMaxSize:   "12Gi",
+			},
+		},
+	}, "10Gi")
+	mountVolume(t, ns, kubelettest.Volume{Namespace: ns, PVC: "data", CapacityBytes: 10 * gib, UsedBytes: 9 * gib})
+
+	requireRequestedSizeStays(t, ns, "data", "10Gi")
+}
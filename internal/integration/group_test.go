@@ -0,0 +1,142 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	pvcchonkerv1alpha1 "github.com/logicIQ/pvc-chonker/api/v1alpha1"
+	"github.com/logicIQ/pvc-chonker/pkg/annotations"
+)
+
+func TestGroup_WebhookAppliesTemplate(t *testing.T) {
+	ctx := context.Background()
+	ns := newNamespace(t)
+
+	maxSize := resource.MustParse("100Gi")
+	require.NoError(t, k8sClient.Create(ctx, &pvcchonkerv1alpha1.PVCGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: ns},
+		Spec: pvcchonkerv1alpha1.PVCGroupSpec{
+			Template: pvcchonkerv1alpha1.PVCGroupTemplate{
+				Threshold: stringPtr("70%"),
+				Increase:  stringPtr("25%"),
+				MaxSize:   &maxSize,
+			},
+		},
+	}))
+
+	pvc := createBoundPVC(t, &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "data",
+			Namespace: ns,
+			Annotations: map[string]string{
+				annotations.AnnotationGroup:    "web",
+				annotations.AnnotationIncrease: "5Gi",
+			},
+		},
+	}, "10Gi")
+
+	var created corev1.PersistentVolumeClaim
+	require.NoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(pvc), &created))
+	assert.Equal(t, "70%", created.Annotations[annotations.AnnotationThreshold])
+	assert.Equal(t, "100Gi", created.Annotations[annotations.AnnotationMaxSize])
+	assert.Equal(t, "5Gi", created.Annotations[annotations.AnnotationIncrease], "existing annotations must not be overridden")
+}
+
+func TestGroup_WebhookIgnoresMissingGroup(t *testing.T) {
+	ctx := context.Background()
+	ns := newNamespace(t)
+
+	pvc := createBoundPVC(t, &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "data",
+			Namespace:   ns,
+			Annotations: map[string]string{annotations.AnnotationGroup: "missing"},
+		},
+	}, "10Gi")
+
+	var created corev1.PersistentVolumeClaim
+	require.NoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(pvc), &created))
+	assert.Equal(t, map[string]string{annotations.AnnotationGroup: "missing"}, created.Annotations)
+}
+
+func TestGroup_CoordinatesSizes(t *testing.T) {
+	ctx := context.Background()
+	ns := newNamespace(t)
+
+	group := &pvcchonkerv1alpha1.PVCGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: ns},
+		Spec: pvcchonkerv1alpha1.PVCGroupSpec{
+			Template: pvcchonkerv1alpha1.PVCGroupTemplate{Threshold: stringPtr("80%")},
+		},
+	}
+	require.NoError(t, k8sClient.Create(ctx, group))
+
+	member := func(name string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: ns,
+				Annotations: map[string]string{
+					annotations.AnnotationGroup:   "kafka",
+					annotations.AnnotationEnabled: "true",
+				},
+			},
+		}
+	}
+	createBoundPVC(t, member("broker-0"), "10Gi")
+	createBoundPVC(t, member("broker-1"), "20Gi")
+	// Not enabled, so not a member of the group
+	createBoundPVC(t, &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "broker-2",
+			Namespace:   ns,
+			Annotations: map[string]string{annotations.AnnotationGroup: "kafka"},
+		},
+	}, "5Gi")
+
+	requireRequestedSize(t, ns, "broker-0", "20Gi")
+	requireRequestedSizeStays(t, ns, "broker-2", "5Gi")
+
+	require.Eventually(t, func() bool {
+		var current pvcchonkerv1alpha1.PVCGroup
+		if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(group), &current); err != nil {
+			return false
+		}
+		return current.Status.MemberCount == 2 &&
+			current.Status.CurrentSize != nil && current.Status.CurrentSize.Cmp(resource.MustParse("20Gi")) == 0
+	}, timeout, interval, "expected the group status to report 2 members of 20Gi")
+}
+
+func TestGroup_SuspendedSkipsCoordination(t *testing.T) {
+	ctx := context.Background()
+	ns := newNamespace(t)
+
+	require.NoError(t, k8sClient.Create(ctx, &pvcchonkerv1alpha1.PVCGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: ns},
+		Spec: pvcchonkerv1alpha1.PVCGroupSpec{
+			Template: pvcchonkerv1alpha1.PVCGroupTemplate{Threshold: stringPtr("80%")},
+			Suspend:  true,
+		},
+	}))
+	for name, size := range map[string]string{"broker-0": "10Gi", "broker-1": "20Gi"} {
+		createBoundPVC(t, &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: ns,
+				Annotations: map[string]string{
+					annotations.AnnotationGroup:   "kafka",
+					annotations.AnnotationEnabled: "true",
+				},
+			},
+		}, size)
+	}
+
+	requireRequestedSizeStays(t, ns, "broker-0", "10Gi")
+}
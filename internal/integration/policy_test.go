@@ -0,0 +1,128 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	pvcchonkerv1alpha1 "github.com/logicIQ/pvc-chonker/api/v1alpha1"
+	"github.com/logicIQ/pvc-chonker/pkg/annotations"
+	"github.com/logicIQ/pvc-chonker/pkg/kubelet/kubelettest"
+)
+
+func TestPolicy_Precedence(t *testing.T) {
+	ctx := context.Background()
+	ns := newNamespace(t)
+	labels := map[string]string{"app": "db"}
+
+	policy := &pvcchonkerv1alpha1.PVCPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: ns},
+		Spec: pvcchonkerv1alpha1.PVCPolicySpec{
+			Selector: metav1.LabelSelector{MatchLabels: labels},
+			Template: pvcchonkerv1alpha1.PVCPolicyTemplate{
+				Threshold: stringPtr("90%"),
+				Increase:  stringPtr("50%"),
+			},
+		},
+	}
+	require.NoError(t, k8sClient.Create(ctx, policy))
+
+	// Below the policy threshold
+	createBoundPVC(t, &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy-below", Namespace: ns, Labels: labels},
+	}, "10Gi")
+	// Above the policy threshold
+	createBoundPVC(t, &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy-above", Namespace: ns, Labels: labels},
+	}, "10Gi")
+	// Annotations take precedence over the matching policy
+	createBoundPVC(t, &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "annotated",
+			Namespace: ns,
+			Labels:    labels,
+			Annotations: map[string]string{
+				annotations.AnnotationEnabled:   "true",
+				annotations.AnnotationThreshold: "80%",
+				annotations.AnnotationIncrease:  "20%",
+			},
+		},
+	}, "10Gi")
+	mountVolume(t, ns, kubelettest.Volume{Namespace: ns, PVC: "policy-below", CapacityBytes: 10 * gib, UsedBytes: 85 * gib / 10})
+	mountVolume(t, ns, kubelettest.Volume{Namespace: ns, PVC: "policy-above", CapacityBytes: 10 * gib, UsedBytes: 95 * gib / 10})
+	mountVolume(t, ns, kubelettest.Volume{Namespace: ns, PVC: "annotated", CapacityBytes: 10 * gib, UsedBytes: 85 * gib / 10})
+
+	requireRequestedSize(t, ns, "policy-above", "15Gi")
+	requireRequestedSize(t, ns, "annotated", "12Gi")
+	requireRequestedSizeStays(t, ns, "policy-below", "10Gi")
+
+	require.Eventually(t, func() bool {
+		var current pvcchonkerv1alpha1.PVCPolicy
+		if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(policy), &current); err != nil {
+			return false
+		}
+		return current.Status.MatchedPVCs == 3 && current.Status.LastUpdated != nil
+	}, timeout, interval, "expected the policy status to count 3 matched PVCs")
+}
+
+func TestPolicy_Suspended(t *testing.T) {
+	ctx := context.Background()
+	ns := newNamespace(t)
+	labels := map[string]string{"app": "cache"}
+
+	require.NoError(t, k8sClient.Create(ctx, &pvcchonkerv1alpha1.PVCPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "cache", Namespace: ns},
+		Spec: pvcchonkerv1alpha1.PVCPolicySpec{
+			Selector: metav1.LabelSelector{MatchLabels: labels},
+			Template: pvcchonkerv1alpha1.PVCPolicyTemplate{Threshold: stringPtr("80%")},
+			Suspend:  true,
+		},
+	}))
+	createBoundPVC(t, &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: ns, Labels: labels},
+	}, "10Gi")
+	mountVolume(t, ns, kubelettest.Volume{Namespace: ns, PVC: "data", CapacityBytes: 10 * gib, UsedBytes: 9 * gib})
+
+	requireRequestedSizeStays(t, ns, "data", "10Gi")
+}
+
+func TestPolicy_CRDValidation(t *testing.T) {
+	ctx := context.Background()
+	ns := newNamespace(t)
+
+	tests := []struct {
+		name     string
+		template pvcchonkerv1alpha1.PVCPolicyTemplate
+		valid    bool
+	}{
+		{"valid template", pvcchonkerv1alpha1.PVCPolicyTemplate{Threshold: stringPtr("80%"), Increase: stringPtr("10Gi")}, true},
+		{"threshold without percent", pvcchonkerv1alpha1.PVCPolicyTemplate{Threshold: stringPtr("80")}, false},
+		{"inodes threshold without percent", pvcchonkerv1alpha1.PVCPolicyTemplate{InodesThreshold: stringPtr("90")}, false},
+		{"increase in decimal units", pvcchonkerv1alpha1.PVCPolicyTemplate{Increase: stringPtr("10GB")}, false},
+		{"empty template", pvcchonkerv1alpha1.PVCPolicyTemplate{}, false},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := &pvcchonkerv1alpha1.PVCPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "policy-" + string(rune('a'+i)), Namespace: ns},
+				Spec: pvcchonkerv1alpha1.PVCPolicySpec{
+					Selector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "none"}},
+					Template: tt.template,
+				},
+			}
+			err := k8sClient.Create(ctx, policy)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, apierrors.IsInvalid(err), "expected an invalid error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+// Package integration runs the controllers and the PVCGroup webhook together
+// against a local API server and etcd started by envtest. The suite is skipped
+// unless KUBEBUILDER_ASSETS points at the envtest binaries, see
+// `task go:test:integration`.
+package integration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	ctrlwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	pvcchonkerv1alpha1 "github.com/logicIQ/pvc-chonker/api/v1alpha1"
+	"github.com/logicIQ/pvc-chonker/internal/controller"
+	"github.com/logicIQ/pvc-chonker/internal/webhook"
+	"github.com/logicIQ/pvc-chonker/pkg/annotations"
+	"github.com/logicIQ/pvc-chonker/pkg/control"
+	"github.com/logicIQ/pvc-chonker/pkg/kubelet"
+	"github.com/logicIQ/pvc-chonker/pkg/kubelet/kubelettest"
+)
+
+const (
+	// storageClassName allows volume expansion, so the API server accepts
+	// larger storage requests on bound claims.
+	storageClassName = "expandable"
+
+	timeout  = 30 * time.Second
+	interval = 250 * time.Millisecond
+	// watchInterval is the reconciliation interval of the PVC reconciler.
+	watchInterval = time.Second
+
+	gib = int64(1 << 30)
+)
+
+var (
+	// k8sClient talks to the API server directly, bypassing the manager cache.
+	k8sClient client.Client
+	// kubeletServer serves the volume stats of every node.
+	kubeletServer *kubelettest.Server
+)
+
+func TestMain(m *testing.M) {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		// CI must run the suite, a skip there would let it rot unnoticed
+		if os.Getenv("CI") != "" {
+			fmt.Println("KUBEBUILDER_ASSETS is not set but CI is, run the suite with task go:test:integration")
+			os.Exit(1)
+		}
+		fmt.Println("Skipping envtest integration suite: KUBEBUILDER_ASSETS is not set")
+		os.Exit(0)
+	}
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	ctrl.SetLogger(zap.New(zap.WriteTo(os.Stderr), zap.UseDevMode(true)))
+
+	testEnv := &envtest.Environment{
+		CRDDirectoryPaths:     []string{filepath.Join("..", "..", "config", "crd", "bases")},
+		ErrorIfCRDPathMissing: true,
+		WebhookInstallOptions: envtest.WebhookInstallOptions{
+			Paths: []string{filepath.Join("..", "..", "config", "webhook", "mutating-webhook-configuration.yaml")},
+		},
+	}
+	cfg, err := testEnv.Start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start envtest: %v\n", err)
+		return 1
+	}
+	defer func() {
+		if err := testEnv.Stop(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to stop envtest: %v\n", err)
+		}
+	}()
+
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(pvcchonkerv1alpha1.AddToScheme(scheme))
+
+	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create client: %v\n", err)
+		return 1
+	}
+
+	kubeletServer = kubelettest.NewServer()
+	defer kubeletServer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mgr, err := newManager(ctx, cfg, scheme, &testEnv.WebhookInstallOptions)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to set up manager: %v\n", err)
+		return 1
+	}
+	if err := createStorageClass(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create StorageClass: %v\n", err)
+		return 1
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- mgr.Start(ctx)
+	}()
+	if err := waitForManager(ctx, mgr); err != nil {
+		fmt.Fprintf(os.Stderr, "manager did not become ready: %v\n", err)
+		cancel()
+		<-done
+		return 1
+	}
+
+	code := m.Run()
+
+	cancel()
+	if err := <-done; err != nil {
+		fmt.Fprintf(os.Stderr, "manager stopped with error: %v\n", err)
+	}
+	return code
+}
+
+// newManager wires the reconcilers and the webhook the same way cmd/main.go
+// does, with the fake kubelet as metrics source.
+func newManager(ctx context.Context, cfg *rest.Config, scheme *runtime.Scheme, webhookOptions *envtest.WebhookInstallOptions) (ctrl.Manager, error) {
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme:                 scheme,
+		Metrics:                server.Options{BindAddress: "0"},
+		HealthProbeBindAddress: "0",
+		WebhookServer: ctrlwebhook.NewServer(ctrlwebhook.Options{
+			Host:    webhookOptions.LocalServingHost,
+			Port:    webhookOptions.LocalServingPort,
+			CertDir: webhookOptions.LocalServingCertDir,
+		}),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	metricsCollector, err := kubelet.NewMetricsCollector(kubeletServer.URLTemplate(kubelettest.EndpointMetrics))
+	if err != nil {
+		return nil, err
+	}
+	metricsCollector.SetClient(mgr.GetClient(), nil)
+	if err := metricsCollector.IndexPods(ctx, mgr.GetFieldIndexer()); err != nil {
+		return nil, err
+	}
+
+	pvcController := &controller.PersistentVolumeClaimReconciler{
+		Client:           mgr.GetClient(),
+		Scheme:           mgr.GetScheme(),
+		GlobalConfig:     annotations.NewGlobalConfig(0, 0, "", 0, resource.Quantity{}, resource.Quantity{}),
+		MetricsCollector: metricsCollector,
+		WatchInterval:    watchInterval,
+		EventRecorder:    mgr.GetEventRecorderFor("pvc-chonker"),
+		ControlLoader:    control.NewLoader(mgr.GetAPIReader(), "", ""),
+	}
+	if err := pvcController.SetupWithManager(mgr); err != nil {
+		return nil, err
+	}
+
+	policyController := &controller.PVCPolicyReconciler{
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		EventRecorder: mgr.GetEventRecorderFor("pvc-chonker-policy"),
+	}
+	if err := policyController.SetupWithManager(mgr); err != nil {
+		return nil, err
+	}
+
+	groupController := &controller.PVCGroupReconciler{
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		EventRecorder: mgr.GetEventRecorderFor("pvc-chonker-group"),
+		ControlLoader: control.NewLoader(mgr.GetAPIReader(), "", ""),
+	}
+	if err := groupController.SetupWithManager(mgr); err != nil {
+		return nil, err
+	}
+
+	if err := webhook.SetupPVCGroupWebhook(mgr); err != nil {
+		return nil, err
+	}
+	return mgr, nil
+}
+
+// waitForManager blocks until the cache is synced and the webhook server
+// accepts connections.
+func waitForManager(ctx context.Context, mgr ctrl.Manager) error {
+	syncCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if !mgr.GetCache().WaitForCacheSync(syncCtx) {
+		return fmt.Errorf("cache did not sync")
+	}
+
+	started := mgr.GetWebhookServer().StartedChecker()
+	for {
+		if err := started(nil); err == nil {
+			return nil
+		}
+		select {
+		case <-syncCtx.Done():
+			return fmt.Errorf("webhook server did not start: %w", syncCtx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
+
+func createStorageClass(ctx context.Context) error {
+	allowExpansion := true
+	return k8sClient.Create(ctx, &storagev1.StorageClass{
+		ObjectMeta:           metav1.ObjectMeta{Name: storageClassName},
+		Provisioner:          "test.csi.k8s.io",
+		AllowVolumeExpansion: &allowExpansion,
+	})
+}
+
+// newNamespace creates a namespace for a single test. envtest runs no
+// namespace controller, so namespaces are never cleaned up and every test gets
+// its own.
+func newNamespace(t *testing.T) string {
+	t.Helper()
+	ctx := context.Background()
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "pvc-chonker-it-"}}
+	require.NoError(t, k8sClient.Create(ctx, ns))
+	// Pods are rejected without the default service account, which is
+	// normally created by the controller manager
+	require.NoError(t, k8sClient.Create(ctx, &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: ns.Name},
+	}))
+	return ns.Name
+}
+
+// createBoundPVC creates a PVC and marks it bound with the given capacity, as
+// the PV controller and CSI provisioner would.
+func createBoundPVC(t *testing.T, pvc *corev1.PersistentVolumeClaim, capacity string) *corev1.PersistentVolumeClaim {
+	t.Helper()
+	ctx := context.Background()
+	size := resource.MustParse(capacity)
+	scName := storageClassName
+	pvc.Spec.StorageClassName = &scName
+	pvc.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+	pvc.Spec.Resources.Requests = corev1.ResourceList{corev1.ResourceStorage: size}
+	require.NoError(t, k8sClient.Create(ctx, pvc))
+
+	pvc.Status.Phase = corev1.ClaimBound
+	pvc.Status.AccessModes = pvc.Spec.AccessModes
+	pvc.Status.Capacity = corev1.ResourceList{corev1.ResourceStorage: size}
+	require.NoError(t, k8sClient.Status().Update(ctx, pvc))
+	return pvc
+}
+
+// mountVolume reports the volume on the node through the fake kubelet and
+// creates the node and the pod mounting the PVC.
+func mountVolume(t *testing.T, nodeName string, volume kubelettest.Volume) {
+	t.Helper()
+	kubeletServer.AddVolume(nodeName, volume)
+	for _, obj := range kubeletServer.Objects() {
+		if err := k8sClient.Create(context.Background(), obj); err != nil && !apierrors.IsAlreadyExists(err) {
+			require.NoError(t, err)
+		}
+	}
+}
+
+// requestedSize returns the storage request of a PVC.
+func requestedSize(t *testing.T, namespace, name string) resource.Quantity {
+	t.Helper()
+	var pvc corev1.PersistentVolumeClaim
+	require.NoError(t, k8sClient.Get(context.Background(), client.ObjectKey{Namespace: namespace, Name: name}, &pvc))
+	return pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+}
+
+// requireRequestedSize waits until the storage request of a PVC equals size.
+func requireRequestedSize(t *testing.T, namespace, name, size string) {
+	t.Helper()
+	want := resource.MustParse(size)
+	require.Eventually(t, func() bool {
+		got := requestedSize(t, namespace, name)
+		return got.Cmp(want) == 0
+	}, timeout, interval, "expected %s/%s to request %s", namespace, name, size)
+}
+
+// requireRequestedSizeStays checks that the storage request of a PVC does not
+// change over several reconciliation cycles.
+func requireRequestedSizeStays(t *testing.T, namespace, name, size string) {
+	t.Helper()
+	want := resource.MustParse(size)
+	require.Never(t, func() bool {
+		got := requestedSize(t, namespace, name)
+		return got.Cmp(want) != 0
+	}, 4*watchInterval, interval, "expected %s/%s to keep requesting %s", namespace, name, size)
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -16,6 +16,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	pvcchonkerv1alpha1 "github.com/logicIQ/pvc-chonker/api/v1alpha1"
+	"github.com/logicIQ/pvc-chonker/pkg/annotations"
 )
 
 // PVCGroupMutator handles PVC mutations based on PVCGroup membership
@@ -126,48 +127,12 @@ func (m *PVCGroupMutator) Handle(ctx context.Context, req admission.Request) adm
 // getTemplateAnnotations returns a map of annotations to apply from the template
 // Only returns annotations that don't already exist in the provided annotations map
 func getTemplateAnnotations(template pvcchonkerv1alpha1.PVCGroupTemplate, existing map[string]string) map[string]string {
-	result := make(map[string]string)
-
-	if existing == nil {
-		existing = make(map[string]string)
-	}
-
-	if template.Threshold != nil {
-		if _, exists := existing["pvc-chonker.io/threshold"]; !exists {
-			result["pvc-chonker.io/threshold"] = *template.Threshold
-		}
-	}
-
-	if template.Increase != nil {
-		if _, exists := existing["pvc-chonker.io/increase"]; !exists {
-			result["pvc-chonker.io/increase"] = *template.Increase
+	result := annotations.GroupTemplateAnnotations(template)
+	for key := range result {
+		if _, exists := existing[key]; exists {
+			delete(result, key)
 		}
 	}
-
-	if template.MaxSize != nil {
-		if _, exists := existing["pvc-chonker.io/max-size"]; !exists {
-			result["pvc-chonker.io/max-size"] = template.MaxSize.String()
-		}
-	}
-
-	if template.Cooldown != nil {
-		if _, exists := existing["pvc-chonker.io/cooldown"]; !exists {
-			result["pvc-chonker.io/cooldown"] = template.Cooldown.Duration.String()
-		}
-	}
-
-	if template.InodesThreshold != nil {
-		if _, exists := existing["pvc-chonker.io/inodes-threshold"]; !exists {
-			result["pvc-chonker.io/inodes-threshold"] = *template.InodesThreshold
-		}
-	}
-
-	if template.MinScaleUp != nil {
-		if _, exists := existing["pvc-chonker.io/min-scale-up"]; !exists {
-			result["pvc-chonker.io/min-scale-up"] = template.MinScaleUp.String()
-		}
-	}
-
 	return result
 }
 
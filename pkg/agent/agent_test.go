@@ -0,0 +1,354 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/logicIQ/pvc-chonker/pkg/kubelet"
+)
+
+// fakeFilesystem reports fixed stats for mounted paths relative to root.
+type fakeFilesystem struct {
+	root   string
+	mounts map[string]FilesystemStats
+}
+
+func (f *fakeFilesystem) rel(path string) string {
+	rel, _ := filepath.Rel(f.root, path)
+	return filepath.ToSlash(rel)
+}
+
+func (f *fakeFilesystem) Statfs(path string) (FilesystemStats, error) {
+	stats, ok := f.mounts[f.rel(path)]
+	if !ok {
+		return FilesystemStats{}, fmt.Errorf("not mounted")
+	}
+	return stats, nil
+}
+
+func (f *fakeFilesystem) IsMountPoint(path string) (bool, error) {
+	if _, err := os.Stat(path); err != nil {
+		return false, err
+	}
+	_, ok := f.mounts[f.rel(path)]
+	return ok, nil
+}
+
+type staticResolver map[string]types.NamespacedName
+
+func (r staticResolver) Resolve(ctx context.Context, pvName string) (types.NamespacedName, bool, error) {
+	pvc, ok := r[pvName]
+	return pvc, ok, nil
+}
+
+// newFakeNode lays out a kubelet root directory:
+//
+//	pod-a: CSI volume pv-data, secret volume
+//	pod-b: CSI volume pv-data (shared with pod-a), NFS volume pv-shared,
+//	       CSI volume pv-unmounted, CSI volume pv-unbound
+//	pod-c: no volumes
+func newFakeNode(t *testing.T) *Scanner {
+	t.Helper()
+	root := t.TempDir()
+	dirs := []string{
+		"pods/pod-a/volumes/kubernetes.io~csi/pv-data/mount",
+		"pods/pod-a/volumes/kubernetes.io~secret/token",
+		"pods/pod-b/volumes/kubernetes.io~csi/pv-data/mount",
+		"pods/pod-b/volumes/kubernetes.io~nfs/pv-shared",
+		"pods/pod-b/volumes/kubernetes.io~csi/pv-unmounted/mount",
+		"pods/pod-b/volumes/kubernetes.io~csi/pv-unbound/mount",
+		"pods/pod-c",
+	}
+	for _, dir := range dirs {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dataStats := FilesystemStats{CapacityBytes: 1000, AvailableBytes: 300, UsedBytes: 700, InodesTotal: 100, InodesFree: 40}
+	fs := &fakeFilesystem{root: root, mounts: map[string]FilesystemStats{
+		"pods/pod-a/volumes/kubernetes.io~csi/pv-data/mount":    dataStats,
+		"pods/pod-b/volumes/kubernetes.io~csi/pv-data/mount":    dataStats,
+		"pods/pod-b/volumes/kubernetes.io~nfs/pv-shared":        {CapacityBytes: 2000, AvailableBytes: 1500, UsedBytes: 500},
+		"pods/pod-b/volumes/kubernetes.io~csi/pv-unbound/mount": dataStats,
+		"pods/pod-a/volumes/kubernetes.io~secret/token":         dataStats,
+	}}
+	resolver := staticResolver{
+		"pv-data":      {Namespace: "default", Name: "data"},
+		"pv-shared":    {Namespace: "team", Name: "shared"},
+		"pv-unmounted": {Namespace: "default", Name: "unmounted"},
+		"token":        {Namespace: "default", Name: "secret"},
+	}
+
+	scanner := NewScanner(root, resolver)
+	scanner.Filesystem = fs
+	return scanner
+}
+
+func TestScanner_Scan(t *testing.T) {
+	scanner := newFakeNode(t)
+
+	stats, err := scanner.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 volumes, got %+v", stats)
+	}
+
+	data := stats[0]
+	if data.PVC != (types.NamespacedName{Namespace: "default", Name: "data"}) || data.PVName != "pv-data" || data.PodUID != "pod-a" {
+		t.Errorf("unexpected first volume %+v", data)
+	}
+	if data.UsedBytes != 700 || data.InodesUsed() != 60 || data.Timestamp.IsZero() {
+		t.Errorf("unexpected stats %+v", data)
+	}
+	if shared := stats[1]; shared.PVC.Name != "shared" || shared.CapacityBytes != 2000 {
+		t.Errorf("expected NFS volume to be reported, got %+v", shared)
+	}
+}
+
+func TestScanner_ScanMissingRoot(t *testing.T) {
+	scanner := NewScanner(filepath.Join(t.TempDir(), "missing"), staticResolver{})
+	if _, err := scanner.Scan(context.Background()); err == nil {
+		t.Error("expected an error for a missing kubelet root directory")
+	}
+}
+
+func TestScanner_ScanReportsPartialFailures(t *testing.T) {
+	scanner := newFakeNode(t)
+	fs := scanner.Filesystem.(*fakeFilesystem)
+	scanner.Filesystem = &failingFilesystem{fakeFilesystem: fs, failing: "pv-shared"}
+
+	stats, err := scanner.Scan(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "pv-shared") {
+		t.Errorf("expected the failing volume to be reported, got %v", err)
+	}
+	if len(stats) != 1 || stats[0].PVC.Name != "data" {
+		t.Errorf("expected the other volumes to be reported, got %+v", stats)
+	}
+}
+
+type failingFilesystem struct {
+	*fakeFilesystem
+	failing string
+}
+
+func (f *failingFilesystem) Statfs(path string) (FilesystemStats, error) {
+	if strings.Contains(path, f.failing) {
+		return FilesystemStats{}, fmt.Errorf("stale file handle")
+	}
+	return f.fakeFilesystem.Statfs(path)
+}
+
+// hangingFilesystem blocks statfs of the hanging volume until release is
+// closed, like an unresponsive NFS mount.
+type hangingFilesystem struct {
+	*fakeFilesystem
+	hanging string
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (f *hangingFilesystem) Statfs(path string) (FilesystemStats, error) {
+	if strings.Contains(path, f.hanging) {
+		f.calls.Add(1)
+		<-f.release
+	}
+	return f.fakeFilesystem.Statfs(path)
+}
+
+func TestScanner_ScanSkipsHungMounts(t *testing.T) {
+	scanner := newFakeNode(t)
+	fs := &hangingFilesystem{fakeFilesystem: scanner.Filesystem.(*fakeFilesystem), hanging: "pv-shared", release: make(chan struct{})}
+	scanner.Filesystem = fs
+
+	for i := 1; i <= 2; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		stats, err := scanner.Scan(ctx)
+		cancel()
+		if !errors.Is(err, ErrMountTimeout) || !strings.Contains(err.Error(), "pv-shared") {
+			t.Errorf("scan %d: expected a timeout of the hung mount, got %v", i, err)
+		}
+		if len(stats) != 1 || stats[0].PVC.Name != "data" {
+			t.Errorf("scan %d: expected the other volumes to be reported, got %+v", i, stats)
+		}
+		if got := scanner.MountTimeouts(); got != int64(i) {
+			t.Errorf("scan %d: expected %d mount timeouts, got %d", i, i, got)
+		}
+	}
+	if calls := fs.calls.Load(); calls != 1 {
+		t.Errorf("expected a single call on the hung mount, got %d", calls)
+	}
+
+	close(fs.release)
+	stats, err := scanner.Scan(context.Background())
+	if err != nil || len(stats) != 2 {
+		t.Errorf("expected the mount to be read once it answers, got %+v, %v", stats, err)
+	}
+}
+
+func TestExporter_ServesKubeletFormat(t *testing.T) {
+	exporter := &Exporter{Scanner: newFakeNode(t), Timeout: time.Second}
+	server := httptest.NewServer(exporter.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + MetricsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	for _, expected := range []string{
+		`kubelet_volume_stats_capacity_bytes{namespace="default",persistentvolumeclaim="data"} 1000`,
+		`kubelet_volume_stats_available_bytes{namespace="default",persistentvolumeclaim="data"} 300`,
+		`kubelet_volume_stats_inodes_used{namespace="default",persistentvolumeclaim="data"} 60`,
+		`kubelet_volume_stats_capacity_bytes{namespace="team",persistentvolumeclaim="shared"} 2000`,
+		`pvcchonker_agent_mount_timeouts_total 0`,
+	} {
+		if !strings.Contains(string(body), expected) {
+			t.Errorf("expected %q in output:\n%s", expected, body)
+		}
+	}
+	if strings.Contains(string(body), `kubelet_volume_stats_inodes{namespace="team"`) {
+		t.Error("expected no inode metrics for a volume without inode stats")
+	}
+}
+
+func TestPusher_Push(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store := kubelet.NewPushStore(time.Minute)
+	mux := http.NewServeMux()
+	mux.Handle(kubelet.PushPath, &kubelet.PushHandler{Store: store, TokenFile: tokenFile})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	stats, err := newFakeNode(t).Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pusher := &Pusher{URL: server.URL, TokenFile: tokenFile, NodeName: "node-1"}
+	if err := pusher.Push(context.Background(), stats); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	vm, ok := store.Get(types.NamespacedName{Namespace: "default", Name: "data"})
+	if !ok || vm.UsedBytes != 700 || vm.InodesUsed != 60 || vm.NodeName != "node-1" {
+		t.Errorf("unexpected pushed sample %+v", vm)
+	}
+
+	wrongToken := filepath.Join(t.TempDir(), "wrong")
+	if err := os.WriteFile(wrongToken, []byte("guess"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pusher.TokenFile = wrongToken
+	if err := pusher.Push(context.Background(), stats); err == nil {
+		t.Error("expected an error for a rejected token")
+	}
+
+	pusher.URL = "ftp://controller"
+	if err := pusher.Push(context.Background(), stats); err == nil {
+		t.Error("expected an error for an unsupported scheme")
+	}
+}
+
+type fakeHostResolver map[string][]string
+
+func (r fakeHostResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	addresses, ok := r[host]
+	if !ok {
+		return nil, fmt.Errorf("no such host %s", host)
+	}
+	return addresses, nil
+}
+
+func TestPusher_PushEveryReplica(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two replicas listening on the same port of different loopback addresses
+	first, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := first.Addr().(*net.TCPAddr).Port
+	second, err := net.Listen("tcp", net.JoinHostPort("127.0.0.2", strconv.Itoa(port)))
+	if err != nil {
+		first.Close()
+		t.Skipf("127.0.0.2 not available: %v", err)
+	}
+	var stores []*kubelet.PushStore
+	for _, listener := range []net.Listener{first, second} {
+		store := kubelet.NewPushStore(time.Minute)
+		stores = append(stores, store)
+		mux := http.NewServeMux()
+		mux.Handle(kubelet.PushPath, &kubelet.PushHandler{Store: store, TokenFile: tokenFile})
+		server := &httptest.Server{Listener: listener, Config: &http.Server{Handler: mux}}
+		server.Start()
+		defer server.Close()
+	}
+
+	stats, err := newFakeNode(t).Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pusher := &Pusher{
+		URL:          fmt.Sprintf("http://pvc-chonker-push:%d", port),
+		TokenFile:    tokenFile,
+		NodeName:     "node-1",
+		HostResolver: fakeHostResolver{"pvc-chonker-push": {"127.0.0.1", "127.0.0.2"}},
+	}
+	if err := pusher.Push(context.Background(), stats); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, store := range stores {
+		if _, ok := store.Get(types.NamespacedName{Namespace: "default", Name: "data"}); !ok {
+			t.Errorf("replica %d did not receive the sample", i)
+		}
+	}
+
+	// A replica that is down fails the push, the others still get the sample
+	pusher.HostResolver = fakeHostResolver{"pvc-chonker-push": {"127.0.0.1", "127.0.0.3"}}
+	if err := pusher.Push(context.Background(), stats); err == nil || !strings.Contains(err.Error(), "127.0.0.3") {
+		t.Errorf("expected an error for the unreachable replica, got %v", err)
+	}
+
+	pusher.URL = "http://unknown"
+	if err := pusher.Push(context.Background(), stats); err == nil {
+		t.Error("expected an error for an unresolvable host")
+	}
+}
+
+func TestHostFilesystem(t *testing.T) {
+	dir := t.TempDir()
+	stats, err := hostFilesystem{}.Statfs(dir)
+	if err != nil {
+		t.Skipf("statfs not supported: %v", err)
+	}
+	if stats.CapacityBytes <= 0 || stats.UsedBytes < 0 || stats.AvailableBytes > stats.CapacityBytes {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if mounted, err := (hostFilesystem{}).IsMountPoint(filepath.Join(dir, "missing")); err == nil || mounted {
+		t.Error("expected an error for a missing path")
+	}
+}
@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// MetricsPath serves volume stats like the kubelet metrics endpoint.
+const MetricsPath = "/metrics"
+
+var volumeLabels = []string{"namespace", "persistentvolumeclaim"}
+
+// Descriptors of the kubelet volume stats families the agent reproduces.
+var (
+	capacityBytesDesc  = prometheus.NewDesc("kubelet_volume_stats_capacity_bytes", "Capacity in bytes of the volume", volumeLabels, nil)
+	availableBytesDesc = prometheus.NewDesc("kubelet_volume_stats_available_bytes", "Number of available bytes in the volume", volumeLabels, nil)
+	usedBytesDesc      = prometheus.NewDesc("kubelet_volume_stats_used_bytes", "Number of used bytes in the volume", volumeLabels, nil)
+	inodesDesc         = prometheus.NewDesc("kubelet_volume_stats_inodes", "Maximum number of inodes in the volume", volumeLabels, nil)
+	inodesFreeDesc     = prometheus.NewDesc("kubelet_volume_stats_inodes_free", "Number of free inodes in the volume", volumeLabels, nil)
+	inodesUsedDesc     = prometheus.NewDesc("kubelet_volume_stats_inodes_used", "Number of used inodes in the volume", volumeLabels, nil)
+)
+
+var mountTimeoutsDesc = prometheus.NewDesc("pvcchonker_agent_mount_timeouts_total", "Number of mounts skipped because they did not answer before the scan timeout", nil, nil)
+
+// Exporter scans the node on every scrape and exposes the results in the
+// kubelet volume stats format, so the controller can scrape agents with a
+// custom kubelet URL.
+type Exporter struct {
+	Scanner *Scanner
+	// Timeout bounds a scan. Mounts that do not answer in time, typically
+	// unresponsive network mounts, are skipped and counted in
+	// pvcchonker_agent_mount_timeouts_total.
+	Timeout time.Duration
+}
+
+var _ prometheus.Collector = (*Exporter)(nil)
+
+func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{capacityBytesDesc, availableBytesDesc, usedBytesDesc, inodesDesc, inodesFreeDesc, inodesUsedDesc, mountTimeoutsDesc} {
+		ch <- desc
+	}
+}
+
+func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	ctx := context.Background()
+	if e.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.Timeout)
+		defer cancel()
+	}
+
+	stats, err := e.Scanner.Scan(ctx)
+	if err != nil {
+		log.Log.WithName("agent").Error(err, "Failed to read some volume stats", "volumes", len(stats))
+	}
+	ch <- prometheus.MustNewConstMetric(mountTimeoutsDesc, prometheus.CounterValue, float64(e.Scanner.MountTimeouts()))
+	for i := range stats {
+		vs := &stats[i]
+		labels := []string{vs.PVC.Namespace, vs.PVC.Name}
+		gauge := func(desc *prometheus.Desc, value int64) {
+			// The timestamp tells the controller how fresh the sample is
+			ch <- prometheus.NewMetricWithTimestamp(vs.Timestamp, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(value), labels...))
+		}
+		gauge(capacityBytesDesc, vs.CapacityBytes)
+		gauge(availableBytesDesc, vs.AvailableBytes)
+		gauge(usedBytesDesc, vs.UsedBytes)
+		if vs.InodesTotal > 0 {
+			gauge(inodesDesc, vs.InodesTotal)
+			gauge(inodesFreeDesc, vs.InodesFree)
+			gauge(inodesUsedDesc, vs.InodesUsed())
+		}
+	}
+}
+
+// Handler serves the volume stats in the text or protobuf exposition format.
+func (e *Exporter) Handler() http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(e)
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
+}
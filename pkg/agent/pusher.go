@@ -0,0 +1,193 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/logicIQ/pvc-chonker/pkg/kubelet"
+)
+
+// HostResolver looks up the addresses of a host name.
+type HostResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// Pusher sends volume stats to the push endpoint of the controller. Every
+// controller replica serves the endpoint but only the leader uses the
+// samples, so the stats are pushed to every address the URL host resolves
+// to. With a headless Service that is every replica, and a new leader
+// already holds recent samples.
+type Pusher struct {
+	// URL is the controller push endpoint. The default path is appended
+	// when the URL has none.
+	URL string
+	// TokenFile holds the bearer token and is read on every push so rotated
+	// tokens are picked up.
+	TokenFile  string
+	NodeName   string
+	HTTPClient *http.Client
+	// HostResolver resolves the URL host. net.DefaultResolver is used when
+	// nil.
+	HostResolver HostResolver
+}
+
+func (p *Pusher) endpoint() (*url.URL, error) {
+	u, err := url.Parse(p.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid push URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid push URL %q: scheme must be http or https", p.URL)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = kubelet.PushPath
+	}
+	return u, nil
+}
+
+// addresses returns the host:port of every replica behind the endpoint.
+func (p *Pusher) addresses(ctx context.Context, endpoint *url.URL) ([]string, error) {
+	port := endpoint.Port()
+	if port == "" {
+		port = "80"
+		if endpoint.Scheme == "https" {
+			port = "443"
+		}
+	}
+	host := endpoint.Hostname()
+	if net.ParseIP(host) != nil {
+		return []string{net.JoinHostPort(host, port)}, nil
+	}
+
+	var resolver HostResolver = net.DefaultResolver
+	if p.HostResolver != nil {
+		resolver = p.HostResolver
+	}
+	hosts, err := resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve push endpoint %s: %w", host, err)
+	}
+	addresses := make([]string, 0, len(hosts))
+	for _, h := range hosts {
+		addresses = append(addresses, net.JoinHostPort(h, port))
+	}
+	return addresses, nil
+}
+
+// clientFor returns an HTTP client that connects to address whatever the URL
+// host is, so TLS is still verified against the host name. A client with a
+// custom transport is returned unchanged.
+func (p *Pusher) clientFor(address string) (*http.Client, func(), bool) {
+	httpClient := p.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	roundTripper := httpClient.Transport
+	if roundTripper == nil {
+		roundTripper = http.DefaultTransport
+	}
+	transport, ok := roundTripper.(*http.Transport)
+	if !ok {
+		return httpClient, func() {}, false
+	}
+
+	transport = transport.Clone()
+	dialer := &net.Dialer{}
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, address)
+	}
+	pinned := *httpClient
+	pinned.Transport = transport
+	return &pinned, transport.CloseIdleConnections, true
+}
+
+// Push sends the stats to every replica behind the endpoint. It fails when
+// any replica did not accept them.
+func (p *Pusher) Push(ctx context.Context, stats []VolumeStats) error {
+	endpoint, err := p.endpoint()
+	if err != nil {
+		return err
+	}
+	addresses, err := p.addresses(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+
+	request := kubelet.PushRequest{Samples: make([]kubelet.PushSample, 0, len(stats))}
+	for i := range stats {
+		vs := &stats[i]
+		used, timestamp := vs.UsedBytes, vs.Timestamp
+		request.Samples = append(request.Samples, kubelet.PushSample{
+			Namespace:             vs.PVC.Namespace,
+			PersistentVolumeClaim: vs.PVC.Name,
+			CapacityBytes:         vs.CapacityBytes,
+			UsedBytes:             &used,
+			InodesTotal:           vs.InodesTotal,
+			InodesUsed:            vs.InodesUsed(),
+			Timestamp:             &timestamp,
+			NodeName:              p.NodeName,
+		})
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode samples: %w", err)
+	}
+
+	var errs []error
+	for _, address := range addresses {
+		httpClient, closeIdle, pinned := p.clientFor(address)
+		err := p.push(ctx, httpClient, endpoint.String(), body, len(stats))
+		closeIdle()
+		if !pinned {
+			// Without control over dialing every push reaches the same host
+			return err
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", address, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *Pusher) push(ctx context.Context, httpClient *http.Client, endpoint string, body []byte, samples int) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create push request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.TokenFile != "" {
+		token, err := os.ReadFile(p.TokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read push token file: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push samples: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("push rejected with status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	var response kubelet.PushResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("invalid push response: %w", err)
+	}
+	if response.Rejected > 0 {
+		return fmt.Errorf("%d of %d samples rejected: %s", response.Rejected, samples, strings.Join(response.Errors, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultRootDir is the default kubelet root directory.
+const DefaultRootDir = "/var/lib/kubelet"
+
+// csiPlugin mounts volumes one level below the volume directory.
+const csiPlugin = "kubernetes.io~csi"
+
+// skippedPlugins never back a PersistentVolume.
+var skippedPlugins = map[string]struct{}{
+	"kubernetes.io~configmap":    {},
+	"kubernetes.io~downward-api": {},
+	"kubernetes.io~empty-dir":    {},
+	"kubernetes.io~projected":    {},
+	"kubernetes.io~secret":       {},
+}
+
+// ErrMountTimeout is returned for mounts that did not answer before the scan
+// context was done, typically unresponsive network mounts.
+var ErrMountTimeout = errors.New("mount did not answer in time")
+
+// FilesystemStats are the statfs results of a mounted volume.
+type FilesystemStats struct {
+	CapacityBytes  int64
+	AvailableBytes int64
+	UsedBytes      int64
+	InodesTotal    int64
+	InodesFree     int64
+}
+
+// Filesystem reads filesystem stats. It is an interface so scans can be
+// tested against a fake directory tree.
+type Filesystem interface {
+	Statfs(path string) (FilesystemStats, error)
+	IsMountPoint(path string) (bool, error)
+}
+
+// Resolver maps a PersistentVolume name to the PVC bound to it.
+type Resolver interface {
+	Resolve(ctx context.Context, pvName string) (types.NamespacedName, bool, error)
+}
+
+// PVResolver resolves PersistentVolumes through their claim reference.
+// Reader should be backed by an informer cache, as every volume of every
+// scan is resolved.
+type PVResolver struct {
+	Reader client.Reader
+}
+
+func (r *PVResolver) Resolve(ctx context.Context, pvName string) (types.NamespacedName, bool, error) {
+	var pv corev1.PersistentVolume
+	if err := r.Reader.Get(ctx, types.NamespacedName{Name: pvName}, &pv); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			return types.NamespacedName{}, false, nil
+		}
+		return types.NamespacedName{}, false, fmt.Errorf("failed to get PersistentVolume %s: %w", pvName, err)
+	}
+	if pv.Spec.ClaimRef == nil {
+		return types.NamespacedName{}, false, nil
+	}
+	return types.NamespacedName{Namespace: pv.Spec.ClaimRef.Namespace, Name: pv.Spec.ClaimRef.Name}, true, nil
+}
+
+// VolumeStats are the stats of a PVC mounted on the node.
+type VolumeStats struct {
+	PVC    types.NamespacedName
+	PVName string
+	PodUID string
+	Path   string
+	FilesystemStats
+	Timestamp time.Time
+}
+
+// InodesUsed returns the inodes in use.
+func (vs *VolumeStats) InodesUsed() int64 {
+	return vs.InodesTotal - vs.InodesFree
+}
+
+// Scanner walks the kubelet pod volume directories and reads the stats of
+// every mounted PVC.
+type Scanner struct {
+	RootDir    string
+	Filesystem Filesystem
+	Resolver   Resolver
+
+	mutex sync.Mutex
+	// inFlight holds the filesystem calls running per path. A call on a hung
+	// mount keeps running after its scan gave up on it.
+	inFlight      map[string]chan struct{}
+	mountTimeouts atomic.Int64
+}
+
+func NewScanner(rootDir string, resolver Resolver) *Scanner {
+	if rootDir == "" {
+		rootDir = DefaultRootDir
+	}
+	return &Scanner{RootDir: rootDir, Filesystem: hostFilesystem{}, Resolver: resolver}
+}
+
+// Scan returns the stats of every PVC mounted on the node, sorted by PVC. A
+// volume mounted by several pods is reported once. Volumes that cannot be
+// read are skipped and their errors returned alongside the other stats.
+func (s *Scanner) Scan(ctx context.Context) ([]VolumeStats, error) {
+	podsDir := filepath.Join(s.RootDir, "pods")
+	pods, err := os.ReadDir(podsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pod directory %s: %w", podsDir, err)
+	}
+
+	var errs []error
+	seen := make(map[types.NamespacedName]struct{})
+	var result []VolumeStats
+	for _, pod := range pods {
+		if !pod.IsDir() {
+			continue
+		}
+		volumesDir := filepath.Join(podsDir, pod.Name(), "volumes")
+		plugins, err := os.ReadDir(volumesDir)
+		if err != nil {
+			// Pods without volumes have no volumes directory
+			if !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, fmt.Errorf("failed to read volumes of pod %s: %w", pod.Name(), err))
+			}
+			continue
+		}
+		for _, plugin := range plugins {
+			if _, skip := skippedPlugins[plugin.Name()]; skip || !plugin.IsDir() {
+				continue
+			}
+			volumes, err := os.ReadDir(filepath.Join(volumesDir, plugin.Name()))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to read %s volumes of pod %s: %w", plugin.Name(), pod.Name(), err))
+				continue
+			}
+			for _, volume := range volumes {
+				if !volume.IsDir() {
+					continue
+				}
+				stats, ok, err := s.statVolume(ctx, pod.Name(), plugin.Name(), volume.Name())
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				if !ok {
+					continue
+				}
+				if _, dup := seen[stats.PVC]; dup {
+					continue
+				}
+				seen[stats.PVC] = struct{}{}
+				result = append(result, stats)
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].PVC.String() < result[j].PVC.String()
+	})
+	return result, errors.Join(errs...)
+}
+
+// MountTimeouts returns the number of mounts skipped because they did not
+// answer in time.
+func (s *Scanner) MountTimeouts() int64 {
+	return s.mountTimeouts.Load()
+}
+
+// call runs a filesystem call on path in a goroutine, as statfs and stat
+// ignore ctx and block on unresponsive network mounts. It gives up when ctx is
+// done. Calls on the same path are serialized, so a hung mount holds a single
+// goroutine however many scans skip it.
+func (s *Scanner) call(ctx context.Context, path string, fn func()) error {
+	timeout := func() error {
+		s.mountTimeouts.Add(1)
+		return fmt.Errorf("%w: %s", ErrMountTimeout, path)
+	}
+	if ctx.Err() != nil {
+		return timeout()
+	}
+	running := make(chan struct{})
+	for {
+		s.mutex.Lock()
+		pending, busy := s.inFlight[path]
+		if !busy {
+			if s.inFlight == nil {
+				s.inFlight = make(map[string]chan struct{})
+			}
+			s.inFlight[path] = running
+			s.mutex.Unlock()
+			break
+		}
+		s.mutex.Unlock()
+		select {
+		case <-pending:
+		case <-ctx.Done():
+			return timeout()
+		}
+	}
+
+	go func() {
+		defer func() {
+			s.mutex.Lock()
+			delete(s.inFlight, path)
+			s.mutex.Unlock()
+			close(running)
+		}()
+		fn()
+	}()
+	select {
+	case <-running:
+		return nil
+	case <-ctx.Done():
+		return timeout()
+	}
+}
+
+// statVolume reads the stats of a volume directory named after its
+// PersistentVolume. It reports false for volumes that are not mounted or not
+// bound to a PVC.
+func (s *Scanner) statVolume(ctx context.Context, podUID, plugin, pvName string) (VolumeStats, bool, error) {
+	path := filepath.Join(s.RootDir, "pods", podUID, "volumes", plugin, pvName)
+	if plugin == csiPlugin {
+		path = filepath.Join(path, "mount")
+	}
+
+	// An unmounted volume directory would report the kubelet root filesystem
+	// The results are only read when the call returned in time
+	var mounted bool
+	var mountErr error
+	if err := s.call(ctx, path, func() { mounted, mountErr = s.Filesystem.IsMountPoint(path) }); err != nil {
+		return VolumeStats{}, false, err
+	}
+	if mountErr != nil {
+		if errors.Is(mountErr, os.ErrNotExist) {
+			return VolumeStats{}, false, nil
+		}
+		return VolumeStats{}, false, fmt.Errorf("failed to check mount of %s: %w", path, mountErr)
+	}
+	if !mounted {
+		return VolumeStats{}, false, nil
+	}
+
+	pvc, found, err := s.Resolver.Resolve(ctx, pvName)
+	if err != nil || !found {
+		return VolumeStats{}, false, err
+	}
+
+	var fsStats FilesystemStats
+	var statErr error
+	if err := s.call(ctx, path, func() { fsStats, statErr = s.Filesystem.Statfs(path) }); err != nil {
+		return VolumeStats{}, false, err
+	}
+	if statErr != nil {
+		return VolumeStats{}, false, fmt.Errorf("failed to statfs %s: %w", path, statErr)
+	}
+	return VolumeStats{
+		PVC:             pvc,
+		PVName:          pvName,
+		PodUID:          podUID,
+		Path:            path,
+		FilesystemStats: fsStats,
+		Timestamp:       time.Now(),
+	}, true, nil
+}
@@ -0,0 +1,18 @@
+//go:build !linux && !darwin
+
+package agent
+
+import (
+	"fmt"
+	"runtime"
+)
+
+type hostFilesystem struct{}
+
+func (hostFilesystem) Statfs(path string) (FilesystemStats, error) {
+	return FilesystemStats{}, fmt.Errorf("statfs is not supported on %s", runtime.GOOS)
+}
+
+func (hostFilesystem) IsMountPoint(path string) (bool, error) {
+	return false, fmt.Errorf("mount detection is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,41 @@
+//go:build linux || darwin
+
+package agent
+
+import (
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// hostFilesystem reads stats of the filesystems mounted on the host.
+type hostFilesystem struct{}
+
+// Statfs computes usage the way the kubelet does, counting blocks reserved
+// for root as used.
+func (hostFilesystem) Statfs(path string) (FilesystemStats, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return FilesystemStats{}, err
+	}
+	blockSize := int64(st.Bsize)
+	return FilesystemStats{
+		CapacityBytes:  int64(st.Blocks) * blockSize,
+		AvailableBytes: int64(st.Bavail) * blockSize,
+		UsedBytes:      int64(st.Blocks-st.Bfree) * blockSize,
+		InodesTotal:    int64(st.Files),
+		InodesFree:     int64(st.Ffree),
+	}, nil
+}
+
+// IsMountPoint reports whether path is on a different device than its parent.
+func (hostFilesystem) IsMountPoint(path string) (bool, error) {
+	var st, parent unix.Stat_t
+	if err := unix.Stat(path, &st); err != nil {
+		return false, err
+	}
+	if err := unix.Stat(filepath.Dir(path), &parent); err != nil {
+		return false, err
+	}
+	return st.Dev != parent.Dev, nil
+}
@@ -1,6 +1,7 @@
 package annotations
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -8,6 +9,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/clock"
 )
 
 const (
@@ -19,6 +21,12 @@ const (
 	AnnotationCooldown        = "pvc-chonker.io/cooldown"
 	AnnotationMinScaleUp      = "pvc-chonker.io/min-scale-up"
 	AnnotationLastExpansion   = "pvc-chonker.io/last-expansion"
+	AnnotationPausedUntil     = "pvc-chonker.io/paused-until"
+	AnnotationGroup           = "pvc-chonker.io/group"
+
+	// StorageClass annotations limiting concurrent and per-minute expansions
+	AnnotationMaxInFlightResizes     = "pvc-chonker.io/max-in-flight-resizes"
+	AnnotationMaxExpansionsPerMinute = "pvc-chonker.io/max-expansions-per-minute"
 
 	DefaultThreshold       = 80.0
 	DefaultInodesThreshold = 80.0
@@ -48,6 +56,17 @@ type PVCConfig struct {
 	Cooldown        time.Duration
 	MinScaleUp      resource.Quantity
 	LastExpansion   *time.Time
+	// Suspended pauses expansion indefinitely, SuspendedUntil until the given
+	// time. SuspendedBy names the source of the suspension.
+	Suspended      bool
+	SuspendedUntil *time.Time
+	SuspendedBy    string
+	// PausedUntilErr is set when the paused-until annotation is invalid. The
+	// PVC is then suspended indefinitely until the annotation is fixed.
+	PausedUntilErr error
+	// Clock is the time source of cooldown and suspension checks. Real time
+	// is used when nil.
+	Clock clock.PassiveClock
 }
 
 func ParsePVCAnnotations(pvc *corev1.PersistentVolumeClaim, global *GlobalConfig) (*PVCConfig, error) {
@@ -128,6 +147,8 @@ func ParsePVCAnnotations(pvc *corev1.PersistentVolumeClaim, global *GlobalConfig
 		config.MinScaleUp = global.MinScaleUp
 	}
 
+	applyPausedUntil(pvc, config)
+
 	if lastExpansion, exists := pvc.Annotations[AnnotationLastExpansion]; exists {
 		t, err := time.Parse(time.RFC3339, lastExpansion)
 		if err != nil {
@@ -139,6 +160,56 @@ func ParsePVCAnnotations(pvc *corev1.PersistentVolumeClaim, global *GlobalConfig
 	return config, nil
 }
 
+// Suspend pauses expansion of the PVC until the given time, or indefinitely
+// when until is nil. The latest suspension wins.
+func (c *PVCConfig) Suspend(until *time.Time, source string) {
+	if c.Suspended {
+		return
+	}
+	if until == nil {
+		c.Suspended = true
+		c.SuspendedUntil = nil
+		c.SuspendedBy = source
+		return
+	}
+	if c.SuspendedUntil == nil || until.After(*c.SuspendedUntil) {
+		t := *until
+		c.SuspendedUntil = &t
+		c.SuspendedBy = source
+	}
+}
+
+func (c *PVCConfig) now() time.Time {
+	if c.Clock == nil {
+		return time.Now()
+	}
+	return c.Clock.Now()
+}
+
+func (c *PVCConfig) IsSuspended() bool {
+	if c.Suspended {
+		return true
+	}
+	return c.SuspendedUntil != nil && c.now().Before(*c.SuspendedUntil)
+}
+
+// applyPausedUntil suspends the PVC until the time of its paused-until
+// annotation. An invalid time suspends it indefinitely, as pausing was
+// intended, and is reported in PausedUntilErr.
+func applyPausedUntil(pvc *corev1.PersistentVolumeClaim, config *PVCConfig) {
+	pausedUntil, exists := pvc.Annotations[AnnotationPausedUntil]
+	if !exists {
+		return
+	}
+	t, err := time.Parse(time.RFC3339, pausedUntil)
+	if err != nil {
+		config.PausedUntilErr = fmt.Errorf("invalid paused-until time: %w", err)
+		config.Suspend(nil, "annotation "+AnnotationPausedUntil)
+		return
+	}
+	config.Suspend(&t, "annotation "+AnnotationPausedUntil)
+}
+
 func (c *PVCConfig) CalculateNewSize(currentSize resource.Quantity) (resource.Quantity, error) {
 	if c == nil {
 		return resource.Quantity{}, fmt.Errorf("PVCConfig is nil")
@@ -184,7 +255,7 @@ func (c *PVCConfig) IsInCooldown() bool {
 	if c.LastExpansion == nil {
 		return false
 	}
-	return time.Since(*c.LastExpansion) < c.Cooldown
+	return c.now().Sub(*c.LastExpansion) < c.Cooldown
 }
 
 func (c *PVCConfig) ExceedsMaxSize(newSize resource.Quantity) bool {
@@ -209,14 +280,64 @@ func IsPvcResizing(pvc *corev1.PersistentVolumeClaim) bool {
 	return false
 }
 
+// ResizeError returns the message of a resize error reported by the external
+// resizer or kubelet, and whether the PVC has one.
+func ResizeError(pvc *corev1.PersistentVolumeClaim) (string, bool) {
+	if pvc == nil {
+		return "", false
+	}
+	for _, condition := range pvc.Status.Conditions {
+		if condition.Type == corev1.PersistentVolumeClaimControllerResizeError ||
+			condition.Type == corev1.PersistentVolumeClaimNodeResizeError {
+			if condition.Status == corev1.ConditionTrue {
+				return condition.Message, true
+			}
+		}
+	}
+	if status, exists := pvc.Status.AllocatedResourceStatuses[corev1.ResourceStorage]; exists {
+		if status == corev1.PersistentVolumeClaimControllerResizeInfeasible ||
+			status == corev1.PersistentVolumeClaimNodeResizeInfeasible {
+			return string(status), true
+		}
+	}
+	return "", false
+}
+
+// ParseStorageClassLimits returns the in-flight and per-minute expansion
+// limits set on a StorageClass. Missing annotations yield zero (unlimited).
+// An invalid annotation also yields zero and is reported in the error, while
+// the other limit is still returned.
+func ParseStorageClassLimits(annotations map[string]string) (int, int, error) {
+	var limits [2]int
+	var errs []error
+	for i, key := range []string{AnnotationMaxInFlightResizes, AnnotationMaxExpansionsPerMinute} {
+		value, exists := annotations[key]
+		if !exists {
+			continue
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || limit < 0 {
+			errs = append(errs, fmt.Errorf("invalid %s annotation %q", key, value))
+			continue
+		}
+		limits[i] = limit
+	}
+	return limits[0], limits[1], errors.Join(errs...)
+}
+
 func UpdateLastExpansion(pvc *corev1.PersistentVolumeClaim) {
+	UpdateLastExpansionAt(pvc, time.Now())
+}
+
+// UpdateLastExpansionAt records now as the time of the last expansion.
+func UpdateLastExpansionAt(pvc *corev1.PersistentVolumeClaim, now time.Time) {
 	if pvc == nil {
 		return
 	}
 	if pvc.Annotations == nil {
 		pvc.Annotations = make(map[string]string)
 	}
-	pvc.Annotations[AnnotationLastExpansion] = time.Now().Format(time.RFC3339)
+	pvc.Annotations[AnnotationLastExpansion] = now.Format(time.RFC3339)
 }
 
 func parsePercentage(s string) (float64, error) {
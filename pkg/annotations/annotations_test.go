@@ -2,10 +2,12 @@ package annotations
 
 import (
 	"testing"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
 )
 
 func createTestGlobalConfig() *GlobalConfig {
@@ -109,3 +111,139 @@ func TestNewGlobalConfig_InodesThreshold(t *testing.T) {
 		t.Errorf("expected InodesThreshold %f, got %f", DefaultInodesThreshold, config.InodesThreshold)
 	}
 }
+
+func TestResizeError(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   corev1.PersistentVolumeClaimStatus
+		expected bool
+	}{
+		{
+			name:     "no conditions",
+			expected: false,
+		},
+		{
+			name: "controller resize error",
+			status: corev1.PersistentVolumeClaimStatus{
+				Conditions: []corev1.PersistentVolumeClaimCondition{{
+					Type:    corev1.PersistentVolumeClaimControllerResizeError,
+					Status:  corev1.ConditionTrue,
+					Message: "quota exceeded",
+				}},
+			},
+			expected: true,
+		},
+		{
+			name: "resolved node resize error",
+			status: corev1.PersistentVolumeClaimStatus{
+				Conditions: []corev1.PersistentVolumeClaimCondition{{
+					Type:   corev1.PersistentVolumeClaimNodeResizeError,
+					Status: corev1.ConditionFalse,
+				}},
+			},
+			expected: false,
+		},
+		{
+			name: "infeasible resize",
+			status: corev1.PersistentVolumeClaimStatus{
+				AllocatedResourceStatuses: map[corev1.ResourceName]corev1.ClaimResourceStatus{
+					corev1.ResourceStorage: corev1.PersistentVolumeClaimControllerResizeInfeasible,
+				},
+			},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, hasError := ResizeError(&corev1.PersistentVolumeClaim{Status: tt.status})
+			if hasError != tt.expected {
+				t.Errorf("expected ResizeError() = %v, got %v", tt.expected, hasError)
+			}
+		})
+	}
+}
+
+func TestParseStorageClassLimits(t *testing.T) {
+	tests := []struct {
+		name              string
+		annotations       map[string]string
+		expectedInFlight  int
+		expectedPerMinute int
+		wantErr           bool
+	}{
+		{
+			name: "no annotations",
+		},
+		{
+			name: "both limits",
+			annotations: map[string]string{
+				AnnotationMaxInFlightResizes:     "2",
+				AnnotationMaxExpansionsPerMinute: "10",
+			},
+			expectedInFlight:  2,
+			expectedPerMinute: 10,
+		},
+		{
+			name:        "invalid value",
+			annotations: map[string]string{AnnotationMaxInFlightResizes: "two"},
+			wantErr:     true,
+		},
+		{
+			name: "one invalid value keeps the other limit",
+			annotations: map[string]string{
+				AnnotationMaxInFlightResizes:     "-1",
+				AnnotationMaxExpansionsPerMinute: "10",
+			},
+			expectedPerMinute: 10,
+			wantErr:           true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inFlight, perMinute, err := ParseStorageClassLimits(tt.annotations)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseStorageClassLimits() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if inFlight != tt.expectedInFlight || perMinute != tt.expectedPerMinute {
+				t.Errorf("expected %d/%d, got %d/%d", tt.expectedInFlight, tt.expectedPerMinute, inFlight, perMinute)
+			}
+		})
+	}
+}
+
+func TestPVCConfig_Clock(t *testing.T) {
+	fakeClock := clocktesting.NewFakePassiveClock(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	lastExpansion := fakeClock.Now()
+	suspendedUntil := fakeClock.Now().Add(time.Hour)
+	config := &PVCConfig{
+		Cooldown:       15 * time.Minute,
+		LastExpansion:  &lastExpansion,
+		SuspendedUntil: &suspendedUntil,
+		Clock:          fakeClock,
+	}
+	if !config.IsInCooldown() || !config.IsSuspended() {
+		t.Fatal("expected config to be in cooldown and suspended")
+	}
+
+	fakeClock.SetTime(lastExpansion.Add(15 * time.Minute))
+	if config.IsInCooldown() {
+		t.Error("expected cooldown to end after 15 minutes")
+	}
+	if !config.IsSuspended() {
+		t.Error("expected suspension to last an hour")
+	}
+
+	fakeClock.SetTime(suspendedUntil)
+	if config.IsSuspended() {
+		t.Error("expected suspension to end after an hour")
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	UpdateLastExpansionAt(pvc, fakeClock.Now())
+	if got := pvc.Annotations[AnnotationLastExpansion]; got != "2026-01-01T13:00:00Z" {
+		t.Errorf("expected last expansion at the fake clock time, got %q", got)
+	}
+}
@@ -0,0 +1,345 @@
+package annotations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	pvcchonkerv1alpha1 "github.com/logicIQ/pvc-chonker/api/v1alpha1"
+)
+
+// Sources of an effective configuration field.
+const (
+	SourceAnnotation = "annotation"
+	SourcePVCPolicy  = "PVCPolicy"
+	SourcePVCGroup   = "PVCGroup"
+	SourceGlobal     = "global"
+)
+
+// configAnnotations are the annotations holding configuration fields, in the
+// order they are explained.
+var configAnnotations = []string{
+	AnnotationThreshold,
+	AnnotationInodesThreshold,
+	AnnotationIncrease,
+	AnnotationMaxSize,
+	AnnotationMinScaleUp,
+	AnnotationCooldown,
+}
+
+// Field is an effective configuration field and where its value came from.
+type Field struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Source is one of the Source constants. Object names the annotation,
+	// PVCPolicy or PVCGroup the value came from.
+	Source string `json:"source"`
+	Object string `json:"object,omitempty"`
+	Note   string `json:"note,omitempty"`
+}
+
+// ShadowedPolicy is a PVCPolicy selecting the PVC that does not configure it.
+type ShadowedPolicy struct {
+	Name       string `json:"name"`
+	ShadowedBy string `json:"shadowedBy"`
+	// Conflicts lists the template fields set to a different value than the
+	// effective one.
+	Conflicts []string `json:"conflicts,omitempty"`
+}
+
+// Explanation is the effective configuration of a PVC and its provenance.
+type Explanation struct {
+	// Managed is false when neither annotations nor a PVCPolicy configure the
+	// PVC. Config is nil then.
+	Managed bool       `json:"managed"`
+	Config  *PVCConfig `json:"-"`
+	// ConfiguredBy is "annotations", the enabled annotation disabling the PVC
+	// or the PVCPolicy providing the configuration.
+	ConfiguredBy string           `json:"configuredBy,omitempty"`
+	Group        string           `json:"group,omitempty"`
+	Fields       []Field          `json:"fields"`
+	Shadowed     []ShadowedPolicy `json:"shadowed"`
+	// Warnings lists settings that are ignored or refer to missing objects.
+	Warnings []string `json:"warnings"`
+}
+
+// Explain resolves the configuration of the PVC like ResolvePVCConfig and
+// reports where every effective field came from, the PVCPolicies that select
+// the PVC without configuring it and the settings that are ignored.
+func (r *PolicyResolver) Explain(ctx context.Context, pvc *corev1.PersistentVolumeClaim, globalConfig *GlobalConfig) (*Explanation, error) {
+	config, err := r.ResolvePVCConfig(ctx, pvc, globalConfig)
+	if err != nil && !errors.Is(err, ErrPVCNotManaged) {
+		return nil, err
+	}
+	e := &Explanation{
+		Managed:  err == nil,
+		Config:   config,
+		Group:    pvc.Annotations[AnnotationGroup],
+		Fields:   []Field{},
+		Shadowed: []ShadowedPolicy{},
+		Warnings: []string{},
+	}
+
+	var group *pvcchonkerv1alpha1.PVCGroup
+	if e.Group != "" {
+		group = &pvcchonkerv1alpha1.PVCGroup{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: pvc.Namespace, Name: e.Group}, group); err != nil {
+			if client.IgnoreNotFound(err) != nil {
+				return nil, err
+			}
+			group = nil
+			e.Warnings = append(e.Warnings, fmt.Sprintf("PVCGroup %s does not exist", e.Group))
+		}
+	}
+
+	var policies pvcchonkerv1alpha1.PVCPolicyList
+	if err := r.List(ctx, &policies, client.InNamespace(pvc.Namespace)); err != nil {
+		return nil, err
+	}
+	// The same order as the resolver, so the policy named here is the one
+	// the operator applies
+	sortPoliciesByName(policies.Items)
+	var matching []*pvcchonkerv1alpha1.PVCPolicy
+	for i := range policies.Items {
+		selector, err := metav1.LabelSelectorAsSelector(&policies.Items[i].Spec.Selector)
+		if err != nil {
+			e.Warnings = append(e.Warnings, fmt.Sprintf("PVCPolicy %s has an invalid selector: %v", policies.Items[i].Name, err))
+			continue
+		}
+		if selector.Matches(labels.Set(pvc.Labels)) {
+			matching = append(matching, &policies.Items[i])
+		}
+	}
+
+	enabled, hasEnabled := pvc.Annotations[AnnotationEnabled]
+	_, annotationErr := ParsePVCAnnotations(pvc, globalConfig)
+	shadowed := matching
+	switch {
+	case config == nil:
+		if hasEnabled {
+			e.Warnings = append(e.Warnings, ignoredAnnotationsWarning(enabled, annotationErr))
+		}
+	case hasEnabled && strings.ToLower(enabled) == "false":
+		e.ConfiguredBy = "annotation " + AnnotationEnabled
+		e.Fields = append(e.Fields, Field{Name: fieldName(AnnotationEnabled), Value: "false", Source: SourceAnnotation, Object: AnnotationEnabled})
+		e.Fields = append(e.Fields, explainGlobalFields(config)...)
+	case annotationErr == nil:
+		e.ConfiguredBy = "annotations"
+		e.Fields = append(e.Fields, Field{Name: fieldName(AnnotationEnabled), Value: "true", Source: SourceAnnotation, Object: AnnotationEnabled})
+		e.Fields = append(e.Fields, explainAnnotationFields(pvc, config, group)...)
+	case len(matching) > 0:
+		policy := matching[0]
+		shadowed = matching[1:]
+		e.ConfiguredBy = SourcePVCPolicy + " " + policy.Name
+		if hasEnabled {
+			e.Warnings = append(e.Warnings, ignoredAnnotationsWarning(enabled, annotationErr))
+		}
+		e.Fields = append(e.Fields, explainPolicyFields(policy, config)...)
+	}
+	if config != nil {
+		e.Fields = append(e.Fields, explainSuspension(config))
+		if config.LastExpansion != nil {
+			e.Fields = append(e.Fields, Field{Name: fieldName(AnnotationLastExpansion), Value: config.LastExpansion.UTC().Format(time.RFC3339), Source: SourceAnnotation, Object: AnnotationLastExpansion})
+		}
+	}
+
+	// Configuration annotations are only read together with enabled=true
+	if !hasEnabled {
+		for _, key := range configAnnotations {
+			if _, exists := pvc.Annotations[key]; exists {
+				e.Warnings = append(e.Warnings, fmt.Sprintf("annotation %s is ignored without %s=true", key, AnnotationEnabled))
+			}
+		}
+	}
+
+	for _, policy := range shadowed {
+		e.Shadowed = append(e.Shadowed, ShadowedPolicy{
+			Name:       policy.Name,
+			ShadowedBy: e.ConfiguredBy,
+			Conflicts:  policyConflicts(policy, e.Fields),
+		})
+	}
+	return e, nil
+}
+
+func ignoredAnnotationsWarning(enabled string, err error) string {
+	if lower := strings.ToLower(enabled); lower != "true" && lower != "false" {
+		return fmt.Sprintf("annotation %s=%q is neither true nor false, annotations are ignored", AnnotationEnabled, enabled)
+	}
+	return fmt.Sprintf("annotations are ignored: %v", err)
+}
+
+func fieldName(annotation string) string {
+	return strings.TrimPrefix(annotation, "pvc-chonker.io/")
+}
+
+// effectiveValues formats the configuration fields of the config by annotation.
+func effectiveValues(config *PVCConfig) map[string]string {
+	maxSize := "none"
+	if !config.MaxSize.IsZero() {
+		maxSize = config.MaxSize.String()
+	}
+	return map[string]string{
+		AnnotationThreshold:       formatPercentage(config.Threshold),
+		AnnotationInodesThreshold: formatPercentage(config.InodesThreshold),
+		AnnotationIncrease:        config.Increase,
+		AnnotationMaxSize:         maxSize,
+		AnnotationMinScaleUp:      config.MinScaleUp.String(),
+		AnnotationCooldown:        config.Cooldown.String(),
+	}
+}
+
+func formatPercentage(p float64) string {
+	return strconv.FormatFloat(p, 'f', -1, 64) + "%"
+}
+
+func explainGlobalFields(config *PVCConfig) []Field {
+	values := effectiveValues(config)
+	fields := make([]Field, 0, len(configAnnotations))
+	for _, key := range configAnnotations {
+		fields = append(fields, Field{Name: fieldName(key), Value: values[key], Source: SourceGlobal})
+	}
+	return fields
+}
+
+// explainAnnotationFields attributes annotations matching the template of the
+// PVCGroup to the group, since the webhook writes them on admission.
+func explainAnnotationFields(pvc *corev1.PersistentVolumeClaim, config *PVCConfig, group *pvcchonkerv1alpha1.PVCGroup) []Field {
+	var template map[string]string
+	if group != nil {
+		template = GroupTemplateAnnotations(group.Spec.Template)
+	}
+	values := effectiveValues(config)
+	fields := make([]Field, 0, len(configAnnotations))
+	for _, key := range configAnnotations {
+		field := Field{Name: fieldName(key), Value: values[key], Source: SourceGlobal}
+		if value, exists := pvc.Annotations[key]; exists {
+			field.Source, field.Object = SourceAnnotation, key
+			if templateValue, fromTemplate := template[key]; fromTemplate && templateValue == value {
+				field.Source, field.Object = SourcePVCGroup, group.Name
+				field.Note = "template applied by the webhook as annotation " + key
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func explainPolicyFields(policy *pvcchonkerv1alpha1.PVCPolicy, config *PVCConfig) []Field {
+	enabled := Field{Name: fieldName(AnnotationEnabled), Value: strconv.FormatBool(config.Enabled), Source: SourcePVCPolicy, Object: policy.Name}
+	if policy.Spec.Template.Enabled == nil {
+		enabled.Note = "selected by the policy"
+	}
+	fields := []Field{enabled}
+
+	template := PolicyTemplateAnnotations(policy.Spec.Template)
+	values := effectiveValues(config)
+	for _, key := range configAnnotations {
+		field := Field{Name: fieldName(key), Value: values[key], Source: SourceGlobal}
+		if value, exists := template[key]; exists {
+			if err := validateTemplateValue(key, value); err != nil {
+				field.Note = fmt.Sprintf("invalid PVCPolicy value %q ignored: %v", value, err)
+			} else {
+				field.Source, field.Object = SourcePVCPolicy, policy.Name
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func explainSuspension(config *PVCConfig) Field {
+	field := Field{Name: "suspended", Value: "false", Source: SourceGlobal}
+	if config.SuspendedBy != "" {
+		// SuspendedBy is the kind or "annotation" followed by the name
+		field.Source, field.Object, _ = strings.Cut(config.SuspendedBy, " ")
+	}
+	switch {
+	case config.Suspended:
+		field.Value = "true"
+	case config.SuspendedUntil != nil && config.IsSuspended():
+		field.Value = "until " + config.SuspendedUntil.UTC().Format(time.RFC3339)
+	case config.SuspendedUntil != nil:
+		field.Note = "expired " + config.SuspendedUntil.UTC().Format(time.RFC3339)
+	}
+	return field
+}
+
+// policyConflicts lists the template fields of a shadowed policy that differ
+// from the effective fields.
+func policyConflicts(policy *pvcchonkerv1alpha1.PVCPolicy, fields []Field) []string {
+	effective := make(map[string]string, len(fields))
+	for _, f := range fields {
+		effective[f.Name] = f.Value
+	}
+	template := PolicyTemplateAnnotations(policy.Spec.Template)
+	var conflicts []string
+	for _, key := range configAnnotations {
+		value, exists := template[key]
+		if !exists {
+			continue
+		}
+		if key == AnnotationThreshold || key == AnnotationInodesThreshold {
+			if p, err := parsePercentage(value); err == nil {
+				value = formatPercentage(p)
+			}
+		}
+		if value != effective[fieldName(key)] {
+			conflicts = append(conflicts, fmt.Sprintf("%s=%s (effective %s)", fieldName(key), value, effective[fieldName(key)]))
+		}
+	}
+	return conflicts
+}
+
+func validateTemplateValue(key, value string) error {
+	if key == AnnotationThreshold || key == AnnotationInodesThreshold {
+		_, err := parsePercentage(value)
+		return err
+	}
+	return nil
+}
+
+// PolicyTemplateAnnotations returns the fields set in the template keyed by the
+// annotation configuring the same field.
+func PolicyTemplateAnnotations(template pvcchonkerv1alpha1.PVCPolicyTemplate) map[string]string {
+	return templateValues(template.Threshold, template.InodesThreshold, template.Increase, template.MaxSize, template.MinScaleUp, template.Cooldown)
+}
+
+// GroupTemplateAnnotations returns the annotations the PVCGroup webhook writes
+// for the fields set in the template.
+func GroupTemplateAnnotations(template pvcchonkerv1alpha1.PVCGroupTemplate) map[string]string {
+	return templateValues(template.Threshold, template.InodesThreshold, template.Increase, template.MaxSize, template.MinScaleUp, template.Cooldown)
+}
+
+func templateValues(threshold, inodesThreshold, increase *string, maxSize, minScaleUp *resource.Quantity, cooldown *metav1.Duration) map[string]string {
+	values := make(map[string]string)
+	if threshold != nil {
+		values[AnnotationThreshold] = *threshold
+	}
+	if inodesThreshold != nil {
+		values[AnnotationInodesThreshold] = *inodesThreshold
+	}
+	if increase != nil {
+		values[AnnotationIncrease] = *increase
+	}
+	if maxSize != nil {
+		values[AnnotationMaxSize] = maxSize.String()
+	}
+	if minScaleUp != nil {
+		values[AnnotationMinScaleUp] = minScaleUp.String()
+	}
+	if cooldown != nil {
+		values[AnnotationCooldown] = cooldown.Duration.String()
+	}
+	return values
+}
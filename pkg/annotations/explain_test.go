@@ -0,0 +1,188 @@
+package annotations
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	pvcchonkerv1alpha1 "github.com/logicIQ/pvc-chonker/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPolicyResolver_Explain(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := pvcchonkerv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add pvcchonker scheme: %v", err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add corev1 scheme: %v", err)
+	}
+	globalConfig := NewGlobalConfig(0, 0, "", 0, resource.Quantity{}, resource.Quantity{})
+
+	policy := func(name, threshold string, suspend bool) *pvcchonkerv1alpha1.PVCPolicy {
+		return &pvcchonkerv1alpha1.PVCPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: pvcchonkerv1alpha1.PVCPolicySpec{
+				Selector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+				Template: pvcchonkerv1alpha1.PVCPolicyTemplate{Threshold: ptr.To(threshold), MaxSize: ptr.To(resource.MustParse("100Gi"))},
+				Suspend:  suspend,
+			},
+		}
+	}
+	group := &pvcchonkerv1alpha1.PVCGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default"},
+		Spec: pvcchonkerv1alpha1.PVCGroupSpec{
+			Template: pvcchonkerv1alpha1.PVCGroupTemplate{Increase: ptr.To("20%"), Cooldown: &metav1.Duration{Duration: time.Hour}},
+		},
+	}
+	newPVC := func(pvcAnnotations map[string]string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "default", Labels: map[string]string{"app": "db"}, Annotations: pvcAnnotations},
+		}
+	}
+	fieldsByName := func(e *Explanation) map[string]Field {
+		fields := make(map[string]Field)
+		for _, f := range e.Fields {
+			fields[f.Name] = f
+		}
+		return fields
+	}
+
+	tests := []struct {
+		name    string
+		objects []client.Object
+		pvc     *corev1.PersistentVolumeClaim
+		check   func(t *testing.T, e *Explanation)
+	}{
+		{
+			name:    "first matching policy configures the PVC",
+			objects: []client.Object{policy("a-db", "90%", false), policy("b-db", "70%", false)},
+			pvc:     newPVC(map[string]string{AnnotationThreshold: "50%"}),
+			check: func(t *testing.T, e *Explanation) {
+				fields := fieldsByName(e)
+				if e.ConfiguredBy != "PVCPolicy a-db" {
+					t.Errorf("expected PVCPolicy a-db, got %q", e.ConfiguredBy)
+				}
+				if f := fields["threshold"]; f.Value != "90%" || f.Source != SourcePVCPolicy || f.Object != "a-db" {
+					t.Errorf("unexpected threshold field %+v", f)
+				}
+				if f := fields["increase"]; f.Value != "10%" || f.Source != SourceGlobal {
+					t.Errorf("unexpected increase field %+v", f)
+				}
+				if len(e.Shadowed) != 1 || e.Shadowed[0].Name != "b-db" || len(e.Shadowed[0].Conflicts) != 1 || e.Shadowed[0].Conflicts[0] != "threshold=70% (effective 90%)" {
+					t.Errorf("expected b-db shadowed with a threshold conflict, got %+v", e.Shadowed)
+				}
+				if len(e.Warnings) != 1 || !strings.Contains(e.Warnings[0], "pvc-chonker.io/threshold is ignored") {
+					t.Errorf("expected the threshold annotation to be ignored, got %v", e.Warnings)
+				}
+			},
+		},
+		{
+			name:    "annotations shadow policies and group templates are attributed to the group",
+			objects: []client.Object{policy("db", "90%", true), group},
+			pvc: newPVC(map[string]string{
+				AnnotationEnabled:  "true",
+				AnnotationGroup:    "kafka",
+				AnnotationIncrease: "20%",
+				AnnotationCooldown: "30m",
+			}),
+			check: func(t *testing.T, e *Explanation) {
+				fields := fieldsByName(e)
+				if e.ConfiguredBy != "annotations" || e.Group != "kafka" {
+					t.Errorf("expected annotations in group kafka, got %+v", e)
+				}
+				if f := fields["increase"]; f.Source != SourcePVCGroup || f.Object != "kafka" {
+					t.Errorf("expected increase from the kafka template, got %+v", f)
+				}
+				if f := fields["cooldown"]; f.Value != "30m0s" || f.Source != SourceAnnotation {
+					t.Errorf("expected cooldown from the annotation, got %+v", f)
+				}
+				if f := fields["suspended"]; f.Value != "false" {
+					t.Errorf("expected the suspended policy not to apply, got %+v", f)
+				}
+				if len(e.Shadowed) != 1 || e.Shadowed[0].ShadowedBy != "annotations" {
+					t.Errorf("expected the policy shadowed by annotations, got %+v", e.Shadowed)
+				}
+				if len(e.Shadowed[0].Conflicts) != 2 {
+					t.Errorf("expected threshold and max-size conflicts, got %v", e.Shadowed[0].Conflicts)
+				}
+			},
+		},
+		{
+			name:    "invalid annotations fall back to the policy",
+			objects: []client.Object{policy("db", "80", true)},
+			pvc:     newPVC(map[string]string{AnnotationEnabled: "true", AnnotationThreshold: "80"}),
+			check: func(t *testing.T, e *Explanation) {
+				fields := fieldsByName(e)
+				if e.ConfiguredBy != "PVCPolicy db" {
+					t.Errorf("expected PVCPolicy db, got %q", e.ConfiguredBy)
+				}
+				if f := fields["threshold"]; f.Source != SourceGlobal || !strings.Contains(f.Note, "invalid PVCPolicy value") {
+					t.Errorf("expected the invalid policy threshold to be ignored, got %+v", f)
+				}
+				if f := fields["suspended"]; f.Value != "true" || f.Source != SourcePVCPolicy || f.Object != "db" {
+					t.Errorf("expected the policy to suspend the PVC, got %+v", f)
+				}
+				if len(e.Warnings) != 1 || !strings.Contains(e.Warnings[0], "invalid threshold") {
+					t.Errorf("expected a warning for the invalid annotation, got %v", e.Warnings)
+				}
+			},
+		},
+		{
+			name:    "unmanaged PVC in a missing group",
+			objects: nil,
+			pvc:     newPVC(map[string]string{AnnotationGroup: "missing"}),
+			check: func(t *testing.T, e *Explanation) {
+				if e.Managed || e.Config != nil || len(e.Fields) != 0 {
+					t.Errorf("expected an unmanaged PVC, got %+v", e)
+				}
+				if len(e.Warnings) != 1 || e.Warnings[0] != "PVCGroup missing does not exist" {
+					t.Errorf("expected a missing group warning, got %v", e.Warnings)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.objects...).WithInterceptorFuncs(reversedPolicyLists).Build()
+			e, err := NewPolicyResolver(fakeClient).Explain(context.Background(), tt.pvc, globalConfig)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tt.check(t, e)
+		})
+	}
+}
+
+func TestGroupTemplateAnnotations(t *testing.T) {
+	template := pvcchonkerv1alpha1.PVCGroupTemplate{
+		Enabled:    ptr.To(true),
+		Threshold:  ptr.To("85%"),
+		MaxSize:    ptr.To(resource.MustParse("1Ti")),
+		MinScaleUp: ptr.To(resource.MustParse("5Gi")),
+		Cooldown:   &metav1.Duration{Duration: 10 * time.Minute},
+	}
+	got := GroupTemplateAnnotations(template)
+	want := map[string]string{
+		AnnotationThreshold:  "85%",
+		AnnotationMaxSize:    "1Ti",
+		AnnotationMinScaleUp: "5Gi",
+		AnnotationCooldown:   "10m0s",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("expected %s=%s, got %q", key, value, got[key])
+		}
+	}
+}
@@ -2,6 +2,7 @@ package annotations
 
 import (
 	"context"
+	"sort"
 	"strings"
 	"time"
 
@@ -10,11 +11,16 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 type PolicyResolver struct {
 	client.Client
+	// Clock is set on every resolved PVCConfig. Real time is used when nil.
+	Clock clock.PassiveClock
 }
 
 func NewPolicyResolver(client client.Client) *PolicyResolver {
@@ -22,6 +28,15 @@ func NewPolicyResolver(client client.Client) *PolicyResolver {
 }
 
 func (r *PolicyResolver) ResolvePVCConfig(ctx context.Context, pvc *corev1.PersistentVolumeClaim, globalConfig *GlobalConfig) (*PVCConfig, error) {
+	config, err := r.resolvePVCConfig(ctx, pvc, globalConfig)
+	if err != nil {
+		return nil, err
+	}
+	config.Clock = r.Clock
+	return config, nil
+}
+
+func (r *PolicyResolver) resolvePVCConfig(ctx context.Context, pvc *corev1.PersistentVolumeClaim, globalConfig *GlobalConfig) (*PVCConfig, error) {
 	if pvc.Annotations != nil {
 		if enabled, exists := pvc.Annotations[AnnotationEnabled]; exists {
 			if strings.ToLower(enabled) == "false" {
@@ -40,6 +55,7 @@ func (r *PolicyResolver) ResolvePVCConfig(ctx context.Context, pvc *corev1.Persi
 
 	config, err := ParsePVCAnnotations(pvc, globalConfig)
 	if err == nil {
+		r.applyGroupSuspension(ctx, pvc, config)
 		return config, nil
 	}
 
@@ -47,6 +63,7 @@ func (r *PolicyResolver) ResolvePVCConfig(ctx context.Context, pvc *corev1.Persi
 	if err := r.List(ctx, &policies, client.InNamespace(pvc.Namespace)); err != nil {
 		return nil, err
 	}
+	sortPoliciesByName(policies.Items)
 
 	for _, policy := range policies.Items {
 		selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.Selector)
@@ -55,13 +72,24 @@ func (r *PolicyResolver) ResolvePVCConfig(ctx context.Context, pvc *corev1.Persi
 		}
 
 		if selector.Matches(labels.Set(pvc.Labels)) {
-			return r.buildConfigFromPolicy(&policy, globalConfig), nil
+			config := r.buildConfigFromPolicy(&policy, globalConfig)
+			applyPausedUntil(pvc, config)
+			r.applyGroupSuspension(ctx, pvc, config)
+			return config, nil
 		}
 	}
 
 	return nil, ErrPVCNotManaged
 }
 
+// sortPoliciesByName orders policies by name, the order in which the first
+// matching policy is chosen. Lists from a cache have no defined order.
+func sortPoliciesByName(policies []pvcchonkerv1alpha1.PVCPolicy) {
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].Name < policies[j].Name
+	})
+}
+
 func (r *PolicyResolver) buildConfigFromPolicy(policy *pvcchonkerv1alpha1.PVCPolicy, globalConfig *GlobalConfig) *PVCConfig {
 	config := &PVCConfig{
 		Enabled:         getBoolValue(policy.Spec.Template.Enabled, true),
@@ -72,9 +100,38 @@ func (r *PolicyResolver) buildConfigFromPolicy(policy *pvcchonkerv1alpha1.PVCPol
 		MinScaleUp:      getQuantityValue(policy.Spec.Template.MinScaleUp, globalConfig.MinScaleUp),
 		Cooldown:        getDurationValue(policy.Spec.Template.Cooldown, globalConfig.Cooldown),
 	}
+	if policy.Spec.Suspend {
+		config.Suspend(nil, "PVCPolicy "+policy.Name)
+	} else if policy.Spec.SuspendUntil != nil {
+		config.Suspend(&policy.Spec.SuspendUntil.Time, "PVCPolicy "+policy.Name)
+	}
 	return config
 }
 
+// applyGroupSuspension suspends the PVC when its PVCGroup is suspended. A
+// PVCGroup that cannot be read suspends the PVC, as its suspension is unknown.
+func (r *PolicyResolver) applyGroupSuspension(ctx context.Context, pvc *corev1.PersistentVolumeClaim, config *PVCConfig) {
+	groupName := pvc.Annotations[AnnotationGroup]
+	if groupName == "" {
+		return
+	}
+
+	var group pvcchonkerv1alpha1.PVCGroup
+	if err := r.Get(ctx, types.NamespacedName{Namespace: pvc.Namespace, Name: groupName}, &group); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			log.FromContext(ctx).Error(err, "Failed to get PVCGroup, suspending PVC", "pvc", pvc.Name, "namespace", pvc.Namespace, "group", groupName)
+			config.Suspend(nil, "PVCGroup "+groupName+" (unreadable)")
+		}
+		return
+	}
+
+	if group.Spec.Suspend {
+		config.Suspend(nil, "PVCGroup "+group.Name)
+	} else if group.Spec.SuspendUntil != nil {
+		config.Suspend(&group.Spec.SuspendUntil.Time, "PVCGroup "+group.Name)
+	}
+}
+
 func getBoolValue(ptr *bool, defaultVal bool) bool {
 	if ptr != nil {
 		return *ptr
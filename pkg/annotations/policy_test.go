@@ -2,6 +2,7 @@ package annotations
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -11,7 +12,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 )
 
 func TestPolicyResolver_ResolvePVCConfig(t *testing.T) {
@@ -465,3 +468,210 @@ func TestHelperFunctions(t *testing.T) {
 		t.Error("getDurationValue with value should return value")
 	}
 }
+
+func TestPolicyResolver_Suspension(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := pvcchonkerv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add pvcchonker scheme: %v", err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add corev1 scheme: %v", err)
+	}
+
+	globalConfig := &GlobalConfig{Threshold: 80.0, InodesThreshold: 80.0, Increase: "10%"}
+	future := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	past := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+
+	policy := func(spec pvcchonkerv1alpha1.PVCPolicySpec) *pvcchonkerv1alpha1.PVCPolicy {
+		spec.Selector = metav1.LabelSelector{MatchLabels: map[string]string{"app": "database"}}
+		spec.Template = pvcchonkerv1alpha1.PVCPolicyTemplate{Threshold: ptr.To("85%")}
+		return &pvcchonkerv1alpha1.PVCPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "default"},
+			Spec:       spec,
+		}
+	}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		policy      *pvcchonkerv1alpha1.PVCPolicy
+		group       *pvcchonkerv1alpha1.PVCGroup
+		expected    bool
+		expectedBy  string
+	}{
+		{
+			name:     "not suspended",
+			policy:   policy(pvcchonkerv1alpha1.PVCPolicySpec{}),
+			expected: false,
+		},
+		{
+			name:       "policy suspended",
+			policy:     policy(pvcchonkerv1alpha1.PVCPolicySpec{Suspend: true}),
+			expected:   true,
+			expectedBy: "PVCPolicy database",
+		},
+		{
+			name:       "policy suspended until future",
+			policy:     policy(pvcchonkerv1alpha1.PVCPolicySpec{SuspendUntil: &metav1.Time{Time: future}}),
+			expected:   true,
+			expectedBy: "PVCPolicy database",
+		},
+		{
+			name:     "policy suspension expired",
+			policy:   policy(pvcchonkerv1alpha1.PVCPolicySpec{SuspendUntil: &metav1.Time{Time: past}}),
+			expected: false,
+		},
+		{
+			name:        "annotation paused with policy",
+			annotations: map[string]string{AnnotationPausedUntil: future.Format(time.RFC3339)},
+			policy:      policy(pvcchonkerv1alpha1.PVCPolicySpec{}),
+			expected:    true,
+			expectedBy:  "annotation " + AnnotationPausedUntil,
+		},
+		{
+			name:        "annotation paused without policy",
+			annotations: map[string]string{AnnotationEnabled: "true", AnnotationPausedUntil: future.Format(time.RFC3339)},
+			expected:    true,
+			expectedBy:  "annotation " + AnnotationPausedUntil,
+		},
+		{
+			name:        "invalid paused-until with policy",
+			annotations: map[string]string{AnnotationPausedUntil: "tomorrow"},
+			policy:      policy(pvcchonkerv1alpha1.PVCPolicySpec{}),
+			expected:    true,
+			expectedBy:  "annotation " + AnnotationPausedUntil,
+		},
+		{
+			name:        "invalid paused-until without policy",
+			annotations: map[string]string{AnnotationEnabled: "true", AnnotationPausedUntil: "tomorrow"},
+			expected:    true,
+			expectedBy:  "annotation " + AnnotationPausedUntil,
+		},
+		{
+			name:        "group suspended",
+			annotations: map[string]string{AnnotationEnabled: "true", AnnotationGroup: "replicas"},
+			group: &pvcchonkerv1alpha1.PVCGroup{
+				ObjectMeta: metav1.ObjectMeta{Name: "replicas", Namespace: "default"},
+				Spec:       pvcchonkerv1alpha1.PVCGroupSpec{Suspend: true},
+			},
+			expected:   true,
+			expectedBy: "PVCGroup replicas",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := fake.NewClientBuilder().WithScheme(scheme)
+			if tt.policy != nil {
+				builder = builder.WithObjects(tt.policy)
+			}
+			if tt.group != nil {
+				builder = builder.WithObjects(tt.group)
+			}
+			resolver := NewPolicyResolver(builder.Build())
+
+			pvc := &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "data",
+					Namespace:   "default",
+					Labels:      map[string]string{"app": "database"},
+					Annotations: tt.annotations,
+				},
+			}
+
+			config, err := resolver.ResolvePVCConfig(context.Background(), pvc, globalConfig)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if config.IsSuspended() != tt.expected {
+				t.Errorf("expected IsSuspended() = %v, got %v", tt.expected, config.IsSuspended())
+			}
+			if tt.expectedBy != "" && config.SuspendedBy != tt.expectedBy {
+				t.Errorf("expected suspension by %q, got %q", tt.expectedBy, config.SuspendedBy)
+			}
+			if invalid := tt.annotations[AnnotationPausedUntil] == "tomorrow"; invalid != (config.PausedUntilErr != nil) {
+				t.Errorf("expected PausedUntilErr set = %v, got %v", invalid, config.PausedUntilErr)
+			}
+		})
+	}
+}
+
+func TestPolicyResolver_UnreadableGroup(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := pvcchonkerv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add pvcchonker scheme: %v", err)
+	}
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			if _, ok := obj.(*pvcchonkerv1alpha1.PVCGroup); ok {
+				return errors.New("connection refused")
+			}
+			return c.Get(ctx, key, obj, opts...)
+		},
+	}).Build()
+	resolver := NewPolicyResolver(fakeClient)
+
+	pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Name:        "data",
+		Namespace:   "default",
+		Annotations: map[string]string{AnnotationEnabled: "true", AnnotationGroup: "replicas"},
+	}}
+	config, err := resolver.ResolvePVCConfig(context.Background(), pvc, &GlobalConfig{Threshold: 80.0, InodesThreshold: 80.0, Increase: "10%"})
+	if err != nil {
+		t.Fatalf("expected the PVC to stay managed, got %v", err)
+	}
+	if !config.IsSuspended() || config.SuspendedBy != "PVCGroup replicas (unreadable)" {
+		t.Errorf("expected suspension by the unreadable group, got suspended=%v by %q", config.IsSuspended(), config.SuspendedBy)
+	}
+}
+
+// reversedPolicyLists returns PVCPolicies in reverse name order. A cache
+// returns lists in no defined order, so nothing may depend on it.
+var reversedPolicyLists = interceptor.Funcs{
+	List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+		if err := c.List(ctx, list, opts...); err != nil {
+			return err
+		}
+		if policies, ok := list.(*pvcchonkerv1alpha1.PVCPolicyList); ok {
+			for i, j := 0, len(policies.Items)-1; i < j; i, j = i+1, j-1 {
+				policies.Items[i], policies.Items[j] = policies.Items[j], policies.Items[i]
+			}
+		}
+		return nil
+	},
+}
+
+func TestPolicyResolver_PolicyOrder(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := pvcchonkerv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add pvcchonker scheme: %v", err)
+	}
+
+	policy := func(name, threshold string) *pvcchonkerv1alpha1.PVCPolicy {
+		return &pvcchonkerv1alpha1.PVCPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: pvcchonkerv1alpha1.PVCPolicySpec{
+				Selector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "database"}},
+				Template: pvcchonkerv1alpha1.PVCPolicyTemplate{Threshold: ptr.To(threshold)},
+			},
+		}
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(policy("a-database", "70%"), policy("b-database", "90%")).
+		WithInterceptorFuncs(reversedPolicyLists).
+		Build()
+
+	pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Name:      "data",
+		Namespace: "default",
+		Labels:    map[string]string{"app": "database"},
+	}}
+	config, err := NewPolicyResolver(fakeClient).ResolvePVCConfig(context.Background(), pvc, &GlobalConfig{Threshold: 80.0, InodesThreshold: 80.0, Increase: "10%"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Threshold != 70 {
+		t.Errorf("expected the first policy by name to apply, got threshold %v", config.Threshold)
+	}
+}
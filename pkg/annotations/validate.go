@@ -0,0 +1,63 @@
+package annotations
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ErrUnknownAnnotation is returned for pvc-chonker.io annotations the operator
+// does not read, usually typos.
+var ErrUnknownAnnotation = errors.New("unknown pvc-chonker annotation")
+
+// ValidateAnnotation checks the value of a PVC or StorageClass annotation with
+// the parsers the operator uses. Annotations outside pvc-chonker.io are valid.
+func ValidateAnnotation(key, value string) error {
+	switch key {
+	case AnnotationEnabled:
+		if lower := strings.ToLower(value); lower != "true" && lower != "false" {
+			return fmt.Errorf("must be true or false (got: %q)", value)
+		}
+	case AnnotationThreshold, AnnotationInodesThreshold:
+		_, err := parsePercentage(value)
+		return err
+	case AnnotationIncrease:
+		_, err := (&PVCConfig{Increase: value}).CalculateNewSize(resource.Quantity{})
+		return err
+	case AnnotationMaxSize, AnnotationMinScaleUp:
+		_, err := resource.ParseQuantity(value)
+		return err
+	case AnnotationCooldown:
+		_, err := time.ParseDuration(value)
+		return err
+	case AnnotationLastExpansion, AnnotationPausedUntil:
+		_, err := time.Parse(time.RFC3339, value)
+		return err
+	case AnnotationGroup:
+		if value == "" {
+			return fmt.Errorf("group name cannot be empty")
+		}
+	case AnnotationMaxInFlightResizes, AnnotationMaxExpansionsPerMinute:
+		_, _, err := ParseStorageClassLimits(map[string]string{key: value})
+		return err
+	default:
+		if strings.HasPrefix(key, "pvc-chonker.io/") {
+			return ErrUnknownAnnotation
+		}
+	}
+	return nil
+}
+
+// IsConfigAnnotation reports whether the annotation configures expansion and
+// is therefore ignored unless the PVC is enabled by annotation.
+func IsConfigAnnotation(key string) bool {
+	for _, configKey := range configAnnotations {
+		if key == configKey {
+			return true
+		}
+	}
+	return false
+}
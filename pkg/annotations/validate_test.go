@@ -0,0 +1,40 @@
+package annotations
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateAnnotation(t *testing.T) {
+	tests := []struct {
+		key     string
+		value   string
+		wantErr bool
+	}{
+		{AnnotationEnabled, "True", false},
+		{AnnotationEnabled, "yes", true},
+		{AnnotationThreshold, "80%", false},
+		{AnnotationThreshold, "80", true},
+		{AnnotationInodesThreshold, "101%", true},
+		{AnnotationIncrease, "10Gi", false},
+		{AnnotationIncrease, "10GB", true},
+		{AnnotationMaxSize, "1Ti", false},
+		{AnnotationMinScaleUp, "lots", true},
+		{AnnotationCooldown, "15", true},
+		{AnnotationPausedUntil, "2026-01-01T00:00:00Z", false},
+		{AnnotationLastExpansion, "yesterday", true},
+		{AnnotationGroup, "", true},
+		{AnnotationMaxInFlightResizes, "-1", true},
+		{AnnotationMaxExpansionsPerMinute, "10", false},
+		{"example.com/threshold", "80", false},
+	}
+	for _, tt := range tests {
+		if err := ValidateAnnotation(tt.key, tt.value); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateAnnotation(%s, %q) error = %v, wantErr %v", tt.key, tt.value, err, tt.wantErr)
+		}
+	}
+
+	if err := ValidateAnnotation("pvc-chonker.io/treshold", "80%"); !errors.Is(err, ErrUnknownAnnotation) {
+		t.Errorf("expected ErrUnknownAnnotation, got %v", err)
+	}
+}
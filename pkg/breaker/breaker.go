@@ -0,0 +1,234 @@
+package breaker
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// Settings configures when a circuit opens and for how long.
+type Settings struct {
+	// FailureThreshold is the number of failures within Window that opens the
+	// circuit. Zero disables the breaker.
+	FailureThreshold int
+	Window           time.Duration
+	// CoolOff is how long an open circuit rejects expansions before a single
+	// probe expansion is let through.
+	CoolOff time.Duration
+}
+
+type circuit struct {
+	state         State
+	failures      []time.Time
+	openedAt      time.Time
+	probeInFlight bool
+}
+
+// Breaker keeps an independent circuit per key, typically a StorageClass
+// provisioner, so a failing CSI driver does not pause expansions on others.
+type Breaker struct {
+	Settings
+	circuits map[string]*circuit
+	// episodes holds when each failure episode was last reported
+	episodes map[string]time.Time
+	mutex    sync.Mutex
+}
+
+func New(settings Settings) *Breaker {
+	return &Breaker{
+		Settings: settings,
+		circuits: make(map[string]*circuit),
+		episodes: make(map[string]time.Time),
+	}
+}
+
+func (b *Breaker) enabled() bool {
+	return b != nil && b.FailureThreshold > 0
+}
+
+func (b *Breaker) get(key string) *circuit {
+	c, exists := b.circuits[key]
+	if !exists {
+		c = &circuit{}
+		b.circuits[key] = c
+	}
+	return c
+}
+
+// Blocked reports whether expansions for the key are rejected at the given time
+// without changing the circuit state.
+func (b *Breaker) Blocked(key string, now time.Time) bool {
+	if !b.enabled() {
+		return false
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	c, exists := b.circuits[key]
+	if !exists {
+		return false
+	}
+	switch c.state {
+	case StateOpen:
+		return now.Before(c.openedAt.Add(b.CoolOff))
+	case StateHalfOpen:
+		return c.probeInFlight
+	}
+	return false
+}
+
+// Allow reports whether an expansion for the key may proceed. Once the cool-off
+// of an open circuit has elapsed the circuit becomes half-open and exactly one
+// probe is allowed until its outcome is recorded.
+func (b *Breaker) Allow(key string, now time.Time) (bool, State) {
+	if !b.enabled() {
+		return true, StateClosed
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	c := b.get(key)
+	switch c.state {
+	case StateOpen:
+		if now.Before(c.openedAt.Add(b.CoolOff)) {
+			return false, c.state
+		}
+		c.state = StateHalfOpen
+		c.probeInFlight = true
+		return true, c.state
+	case StateHalfOpen:
+		if c.probeInFlight {
+			return false, c.state
+		}
+		c.probeInFlight = true
+		return true, c.state
+	}
+	return true, c.state
+}
+
+// RecordSuccess closes a half-open circuit. It returns the new state and
+// whether the state changed.
+func (b *Breaker) RecordSuccess(key string) (State, bool) {
+	if !b.enabled() {
+		return StateClosed, false
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	c := b.get(key)
+	if c.state != StateHalfOpen {
+		return c.state, false
+	}
+	c.state = StateClosed
+	c.failures = nil
+	c.probeInFlight = false
+	return c.state, true
+}
+
+// RecordFailure counts a failure for the key. The circuit opens when the
+// failures within Window reach FailureThreshold, or immediately when the
+// half-open probe fails. It returns the new state and whether it changed.
+func (b *Breaker) RecordFailure(key string, now time.Time) (State, bool) {
+	if !b.enabled() {
+		return StateClosed, false
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	c := b.get(key)
+
+	switch c.state {
+	case StateOpen:
+		return c.state, false
+	case StateHalfOpen:
+		c.state = StateOpen
+		c.openedAt = now
+		c.probeInFlight = false
+		return c.state, true
+	}
+
+	cutoff := now.Add(-b.Window)
+	recent := c.failures[:0]
+	for _, t := range c.failures {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	c.failures = append(recent, now)
+
+	if len(c.failures) >= b.FailureThreshold {
+		c.state = StateOpen
+		c.openedAt = now
+		c.failures = nil
+		return c.state, true
+	}
+	return c.state, false
+}
+
+// FirstSeen reports whether the failure episode with the given ID is new.
+// Failures that persist across cycles, such as a resize error condition on a
+// PVC, keep their ID and are therefore counted once. IDs not reported for
+// longer than Window are forgotten.
+func (b *Breaker) FirstSeen(id string, now time.Time) bool {
+	if b == nil {
+		return true
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.episodes == nil {
+		b.episodes = make(map[string]time.Time)
+	}
+	for episode, lastSeen := range b.episodes {
+		if now.Sub(lastSeen) > b.Window {
+			delete(b.episodes, episode)
+		}
+	}
+	_, seen := b.episodes[id]
+	b.episodes[id] = now
+	return !seen
+}
+
+// State returns the current state of the circuit for the key.
+func (b *Breaker) State(key string) State {
+	if !b.enabled() {
+		return StateClosed
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if c, exists := b.circuits[key]; exists {
+		return c.state
+	}
+	return StateClosed
+}
+
+// Tripped returns the sorted keys of all circuits that are not closed.
+func (b *Breaker) Tripped() []string {
+	if !b.enabled() {
+		return nil
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	var keys []string
+	for key, c := range b.circuits {
+		if c.state != StateClosed {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,130 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestBreaker() *Breaker {
+	return New(Settings{FailureThreshold: 3, Window: 10 * time.Minute, CoolOff: 5 * time.Minute})
+}
+
+func TestBreaker_OpensAfterThreshold(t *testing.T) {
+	b := newTestBreaker()
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if _, changed := b.RecordFailure("ebs.csi.aws.com", now); changed {
+			t.Fatalf("expected circuit to stay closed after %d failures", i+1)
+		}
+	}
+	state, changed := b.RecordFailure("ebs.csi.aws.com", now)
+	if !changed || state != StateOpen {
+		t.Fatalf("expected circuit to open, got %s (changed=%v)", state, changed)
+	}
+
+	if allowed, _ := b.Allow("ebs.csi.aws.com", now.Add(time.Minute)); allowed {
+		t.Error("expected open circuit to reject expansions")
+	}
+	if allowed, _ := b.Allow("pd.csi.storage.gke.io", now); !allowed {
+		t.Error("expected other provisioners to be unaffected")
+	}
+	if tripped := b.Tripped(); len(tripped) != 1 || tripped[0] != "ebs.csi.aws.com" {
+		t.Errorf("expected only the ebs.csi.aws.com circuit to be tripped, got %v", tripped)
+	}
+}
+
+func TestBreaker_FailuresOutsideWindowIgnored(t *testing.T) {
+	b := newTestBreaker()
+	now := time.Now()
+
+	b.RecordFailure("csi", now)
+	b.RecordFailure("csi", now.Add(time.Minute))
+	if state, _ := b.RecordFailure("csi", now.Add(15*time.Minute)); state != StateClosed {
+		t.Errorf("expected stale failures to be ignored, got %s", state)
+	}
+}
+
+func TestBreaker_HalfOpenProbe(t *testing.T) {
+	tests := []struct {
+		name     string
+		succeed  bool
+		expected State
+	}{
+		{name: "probe succeeds", succeed: true, expected: StateClosed},
+		{name: "probe fails", succeed: false, expected: StateOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestBreaker()
+			now := time.Now()
+			for i := 0; i < 3; i++ {
+				b.RecordFailure("csi", now)
+			}
+
+			probeTime := now.Add(5 * time.Minute)
+			if b.Blocked("csi", probeTime) {
+				t.Error("expected circuit not to block once the cool-off elapsed")
+			}
+			allowed, state := b.Allow("csi", probeTime)
+			if !allowed || state != StateHalfOpen {
+				t.Fatalf("expected half-open probe to be allowed, got %v %s", allowed, state)
+			}
+			if allowed, _ := b.Allow("csi", probeTime); allowed {
+				t.Error("expected only one probe while half-open")
+			}
+
+			if tt.succeed {
+				b.RecordSuccess("csi")
+			} else {
+				b.RecordFailure("csi", probeTime)
+			}
+			if state := b.State("csi"); state != tt.expected {
+				t.Errorf("expected state %s, got %s", tt.expected, state)
+			}
+		})
+	}
+}
+
+func TestBreaker_DisabledAndNil(t *testing.T) {
+	var nilBreaker *Breaker
+	disabled := New(Settings{})
+	for _, b := range []*Breaker{nilBreaker, disabled} {
+		for i := 0; i < 10; i++ {
+			b.RecordFailure("csi", time.Now())
+		}
+		if allowed, _ := b.Allow("csi", time.Now()); !allowed {
+			t.Error("expected disabled breaker to allow expansions")
+		}
+		if tripped := b.Tripped(); len(tripped) != 0 {
+			t.Errorf("expected disabled breaker to have no tripped circuits, got %v", tripped)
+		}
+	}
+}
+
+func TestBreaker_FirstSeen(t *testing.T) {
+	b := newTestBreaker()
+	now := time.Now()
+
+	if !b.FirstSeen("default/data/resize_error", now) {
+		t.Fatal("expected a new episode")
+	}
+	// Reported on every cycle, the episode is never forgotten
+	for i := 1; i <= 30; i++ {
+		if b.FirstSeen("default/data/resize_error", now.Add(time.Duration(i)*time.Minute)) {
+			t.Fatalf("expected the episode to be known after %d minutes", i)
+		}
+	}
+	if !b.FirstSeen("default/logs/resize_error", now) {
+		t.Error("expected episodes of other PVCs to be new")
+	}
+	if !b.FirstSeen("default/data/resize_error", now.Add(45*time.Minute)) {
+		t.Error("expected the episode to be forgotten once not reported for longer than the window")
+	}
+
+	var nilBreaker *Breaker
+	if !nilBreaker.FirstSeen("default/data/resize_error", now) {
+		t.Error("expected a nil breaker to report every episode as new")
+	}
+}
@@ -5,15 +5,17 @@ import (
 )
 
 type StorageClassCache struct {
-	cache   map[string]bool
-	fsTypes map[string]string
-	mutex   sync.RWMutex
+	cache        map[string]bool
+	fsTypes      map[string]string
+	provisioners map[string]string
+	mutex        sync.RWMutex
 }
 
 func NewStorageClassCache() *StorageClassCache {
 	return &StorageClassCache{
-		cache:   make(map[string]bool),
-		fsTypes: make(map[string]string),
+		cache:        make(map[string]bool),
+		fsTypes:      make(map[string]string),
+		provisioners: make(map[string]string),
 	}
 }
 
@@ -43,9 +45,23 @@ func (c *StorageClassCache) SetFsType(name string, fsType string) {
 	c.fsTypes[name] = fsType
 }
 
+func (c *StorageClassCache) GetProvisioner(name string) (string, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	provisioner, exists := c.provisioners[name]
+	return provisioner, exists
+}
+
+func (c *StorageClassCache) SetProvisioner(name string, provisioner string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.provisioners[name] = provisioner
+}
+
 func (c *StorageClassCache) Clear() {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 	c.cache = make(map[string]bool)
 	c.fsTypes = make(map[string]string)
+	c.provisioners = make(map[string]string)
 }
@@ -0,0 +1,108 @@
+package control
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Keys of the control ConfigMap read by the reconciler on every cycle.
+const (
+	KeyAcknowledgedPlan = "acknowledged-plan"
+	KeyPaused           = "paused"
+	KeyPausedUntil      = "paused-until"
+
+	DefaultConfigMapName = "pvc-chonker-control"
+	DefaultNamespace     = "pvc-chonker-system"
+)
+
+// Settings holds the operator controls read from the control ConfigMap.
+type Settings struct {
+	// AcknowledgedPlan is the ID of an expansion plan an operator approved
+	// even though it exceeds the per-cycle safety caps.
+	AcknowledgedPlan string
+	// Paused stops all automatic expansions, PausedUntil until the given time.
+	Paused      bool
+	PausedUntil *time.Time
+}
+
+// IsPaused reports whether the cluster-wide kill switch is active at now.
+func (s *Settings) IsPaused(now time.Time) bool {
+	if s == nil {
+		return false
+	}
+	if s.Paused {
+		return true
+	}
+	return s.PausedUntil != nil && now.Before(*s.PausedUntil)
+}
+
+// Loader reads the control ConfigMap. A reader that bypasses the informer cache
+// should be used so the operator does not cache every ConfigMap in the cluster.
+type Loader struct {
+	Reader client.Reader
+	Key    types.NamespacedName
+}
+
+func NewLoader(reader client.Reader, namespace, name string) *Loader {
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+	if name == "" {
+		name = DefaultConfigMapName
+	}
+	return &Loader{
+		Reader: reader,
+		Key:    types.NamespacedName{Namespace: namespace, Name: name},
+	}
+}
+
+// Load returns the current settings. A missing ConfigMap yields empty settings.
+func (l *Loader) Load(ctx context.Context) (*Settings, error) {
+	if l == nil || l.Reader == nil {
+		return &Settings{}, nil
+	}
+
+	var cm corev1.ConfigMap
+	if err := l.Reader.Get(ctx, l.Key, &cm); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			return &Settings{}, nil
+		}
+		return nil, fmt.Errorf("failed to get control ConfigMap %s: %w", l.Key, err)
+	}
+
+	return ParseSettings(cm.Data)
+}
+
+func ParseSettings(data map[string]string) (*Settings, error) {
+	settings := &Settings{}
+	if data == nil {
+		return settings, nil
+	}
+
+	settings.AcknowledgedPlan = strings.TrimSpace(data[KeyAcknowledgedPlan])
+
+	if paused, exists := data[KeyPaused]; exists {
+		value, err := strconv.ParseBool(strings.TrimSpace(paused))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %q: %w", KeyPaused, paused, err)
+		}
+		settings.Paused = value
+	}
+
+	if pausedUntil, exists := data[KeyPausedUntil]; exists && strings.TrimSpace(pausedUntil) != "" {
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(pausedUntil))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %q: %w", KeyPausedUntil, pausedUntil, err)
+		}
+		settings.PausedUntil = &t
+	}
+
+	return settings, nil
+}
@@ -0,0 +1,101 @@
+package control
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestLoader_Load(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+
+	tests := []struct {
+		name     string
+		objects  []corev1.ConfigMap
+		expected string
+	}{
+		{
+			name: "missing ConfigMap",
+		},
+		{
+			name: "acknowledged plan",
+			objects: []corev1.ConfigMap{{
+				ObjectMeta: metav1.ObjectMeta{Name: DefaultConfigMapName, Namespace: "ops"},
+				Data:       map[string]string{KeyAcknowledgedPlan: " abc123 \n"},
+			}},
+			expected: "abc123",
+		},
+		{
+			name: "ConfigMap in other namespace ignored",
+			objects: []corev1.ConfigMap{{
+				ObjectMeta: metav1.ObjectMeta{Name: DefaultConfigMapName, Namespace: DefaultNamespace},
+				Data:       map[string]string{KeyAcknowledgedPlan: "abc123"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := fake.NewClientBuilder().WithScheme(scheme)
+			for i := range tt.objects {
+				builder = builder.WithObjects(&tt.objects[i])
+			}
+
+			settings, err := NewLoader(builder.Build(), "ops", "").Load(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if settings.AcknowledgedPlan != tt.expected {
+				t.Errorf("expected acknowledged plan %q, got %q", tt.expected, settings.AcknowledgedPlan)
+			}
+		})
+	}
+}
+
+func TestLoader_NilIsSafe(t *testing.T) {
+	var loader *Loader
+	settings, err := loader.Load(context.Background())
+	if err != nil || settings == nil {
+		t.Fatalf("expected empty settings from nil loader, got %v, %v", settings, err)
+	}
+}
+
+func TestParseSettings_Paused(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		data     map[string]string
+		expected bool
+		wantErr  bool
+	}{
+		{name: "no keys", expected: false},
+		{name: "paused", data: map[string]string{KeyPaused: "true"}, expected: true},
+		{name: "unpaused", data: map[string]string{KeyPaused: "false"}, expected: false},
+		{name: "paused until future", data: map[string]string{KeyPausedUntil: "2025-01-01T13:00:00Z"}, expected: true},
+		{name: "paused until past", data: map[string]string{KeyPausedUntil: "2025-01-01T11:00:00Z"}, expected: false},
+		{name: "invalid paused", data: map[string]string{KeyPaused: "maybe"}, wantErr: true},
+		{name: "invalid paused-until", data: map[string]string{KeyPausedUntil: "soon"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings, err := ParseSettings(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSettings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if paused := settings.IsPaused(now); paused != tt.expected {
+				t.Errorf("expected IsPaused() = %v, got %v", tt.expected, paused)
+			}
+		})
+	}
+}
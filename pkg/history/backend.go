@@ -0,0 +1,197 @@
+package history
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ConfigMapKey holds the gzipped history in the ConfigMap binary data.
+	ConfigMapKey         = "history.json.gz"
+	DefaultConfigMapName = "pvc-chonker-history"
+
+	// maxConfigMapBytes leaves headroom below the 1MiB object size limit for
+	// the rest of the ConfigMap.
+	maxConfigMapBytes = 900 * 1024
+)
+
+// Backend persists the encoded history so it survives restarts and leader
+// changes. Load returns nil data when nothing has been persisted yet.
+type Backend interface {
+	Load(ctx context.Context) ([]byte, error)
+	Save(ctx context.Context, data []byte) error
+}
+
+// Load replaces the history with the persisted one, if any.
+func (s *Store) Load(ctx context.Context, backend Backend) error {
+	data, err := backend.Load(ctx)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return nil
+	}
+	return s.UnmarshalJSON(data)
+}
+
+// Save persists the history when it changed since the last load or save.
+func (s *Store) Save(ctx context.Context, backend Backend) error {
+	s.mutex.RLock()
+	dirty := s.dirty
+	s.mutex.RUnlock()
+	if !dirty {
+		return nil
+	}
+
+	data, err := s.MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("failed to encode history: %w", err)
+	}
+	if err := backend.Save(ctx, data); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	s.dirty = false
+	s.mutex.Unlock()
+	return nil
+}
+
+// FileBackend stores the history as JSON in a local file, e.g. on a
+// PersistentVolume mounted into the operator pod.
+type FileBackend struct {
+	Path string
+}
+
+func (b *FileBackend) Load(ctx context.Context) ([]byte, error) {
+	data, err := os.ReadFile(b.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+	return data, nil
+}
+
+// Save writes to a temporary file first so a crash never leaves a truncated
+// history behind.
+func (b *FileBackend) Save(ctx context.Context, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(b.Path), filepath.Base(b.Path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create history file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), b.Path); err != nil {
+		return fmt.Errorf("failed to replace history file: %w", err)
+	}
+	return nil
+}
+
+// ConfigMapBackend stores the gzipped history in a ConfigMap. Reader should
+// bypass the informer cache, like the control ConfigMap loader.
+type ConfigMapBackend struct {
+	Reader client.Reader
+	Writer client.Writer
+	Key    types.NamespacedName
+}
+
+func NewConfigMapBackend(reader client.Reader, writer client.Writer, namespace, name string) *ConfigMapBackend {
+	if name == "" {
+		name = DefaultConfigMapName
+	}
+	return &ConfigMapBackend{
+		Reader: reader,
+		Writer: writer,
+		Key:    types.NamespacedName{Namespace: namespace, Name: name},
+	}
+}
+
+func (b *ConfigMapBackend) Load(ctx context.Context) ([]byte, error) {
+	var cm corev1.ConfigMap
+	if err := b.Reader.Get(ctx, b.Key, &cm); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get history ConfigMap %s: %w", b.Key, err)
+	}
+
+	compressed, exists := cm.BinaryData[ConfigMapKey]
+	if !exists {
+		return nil, nil
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress history: %w", err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress history: %w", err)
+	}
+	return data, nil
+}
+
+func (b *ConfigMapBackend) Save(ctx context.Context, data []byte) error {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return fmt.Errorf("failed to compress history: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to compress history: %w", err)
+	}
+	if buf.Len() > maxConfigMapBytes {
+		return fmt.Errorf("compressed history of %d bytes exceeds the ConfigMap limit of %d bytes, reduce the samples kept per PVC", buf.Len(), maxConfigMapBytes)
+	}
+
+	var cm corev1.ConfigMap
+	err := b.Reader.Get(ctx, b.Key, &cm)
+	if client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed to get history ConfigMap %s: %w", b.Key, err)
+	}
+	if err != nil {
+		cm = corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      b.Key.Name,
+				Namespace: b.Key.Namespace,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "pvc-chonker"},
+			},
+			BinaryData: map[string][]byte{ConfigMapKey: buf.Bytes()},
+		}
+		if err := b.Writer.Create(ctx, &cm); err != nil {
+			return fmt.Errorf("failed to create history ConfigMap %s: %w", b.Key, err)
+		}
+		return nil
+	}
+
+	if cm.BinaryData == nil {
+		cm.BinaryData = make(map[string][]byte)
+	}
+	cm.BinaryData[ConfigMapKey] = buf.Bytes()
+	if err := b.Writer.Update(ctx, &cm); err != nil {
+		return fmt.Errorf("failed to update history ConfigMap %s: %w", b.Key, err)
+	}
+	return nil
+}
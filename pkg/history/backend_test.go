@@ -0,0 +1,123 @@
+package history
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestFileBackend_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	backend := &FileBackend{Path: filepath.Join(t.TempDir(), "history.json")}
+
+	empty := NewStore(Config{})
+	if err := empty.Load(ctx, backend); err != nil {
+		t.Fatalf("expected a missing file to load as empty history, got %v", err)
+	}
+
+	store := NewStore(Config{})
+	store.Record("ns/data", sampleAt(0, 100))
+	store.Record("ns/data", sampleAt(1, 200))
+	if err := store.Save(ctx, backend); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	restored := NewStore(Config{})
+	if err := restored.Load(ctx, backend); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if latest, ok := restored.Latest("ns/data"); !ok || latest.UsedBytes != 200 {
+		t.Errorf("expected latest sample with 200 used bytes, got %+v", latest)
+	}
+}
+
+// countingBackend counts saves to check that clean history is not rewritten.
+type countingBackend struct {
+	data  []byte
+	saves int
+}
+
+func (b *countingBackend) Load(ctx context.Context) ([]byte, error) {
+	return b.data, nil
+}
+
+func (b *countingBackend) Save(ctx context.Context, data []byte) error {
+	b.data = data
+	b.saves++
+	return nil
+}
+
+func TestStore_SaveOnlyWhenDirty(t *testing.T) {
+	ctx := context.Background()
+	backend := &countingBackend{}
+	store := NewStore(Config{})
+
+	if err := store.Save(ctx, backend); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	store.Record("ns/data", sampleAt(0, 100))
+	for i := 0; i < 2; i++ {
+		if err := store.Save(ctx, backend); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if backend.saves != 1 {
+		t.Errorf("expected 1 save, got %d", backend.saves)
+	}
+}
+
+func TestConfigMapBackend_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	backend := NewConfigMapBackend(c, c, "ops", "")
+
+	store := NewStore(Config{})
+	store.Record("ns/data", sampleAt(0, 100))
+	// Save twice to exercise both create and update
+	if err := store.Save(ctx, backend); err != nil {
+		t.Fatalf("unexpected error creating ConfigMap: %v", err)
+	}
+	store.Record("ns/data", sampleAt(1, 200))
+	if err := store.Save(ctx, backend); err != nil {
+		t.Fatalf("unexpected error updating ConfigMap: %v", err)
+	}
+
+	var cm corev1.ConfigMap
+	if err := c.Get(ctx, backend.Key, &cm); err != nil {
+		t.Fatalf("expected history ConfigMap %s: %v", backend.Key, err)
+	}
+	if cm.Name != DefaultConfigMapName || len(cm.BinaryData[ConfigMapKey]) == 0 {
+		t.Errorf("expected gzipped history in %s, got %+v", DefaultConfigMapName, cm)
+	}
+
+	restored := NewStore(Config{})
+	if err := restored.Load(ctx, backend); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if samples := restored.Query("ns/data", sampleAt(0, 0).Time); len(samples) != 2 {
+		t.Errorf("expected 2 samples, got %d", len(samples))
+	}
+}
+
+func TestConfigMapBackend_RejectsOversizedHistory(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	backend := NewConfigMapBackend(c, c, "ops", "history")
+
+	// Random bytes do not compress
+	noise := make([]byte, 2*maxConfigMapBytes)
+	_, _ = rand.Read(noise)
+	data, _ := json.Marshal(noise)
+	if err := backend.Save(context.Background(), data); err == nil {
+		t.Error("expected an error for history above the ConfigMap limit")
+	}
+}
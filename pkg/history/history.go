@@ -0,0 +1,327 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Defaults keep two hours of raw samples at a one minute check interval and
+// a day of 30 minute downsampled samples per PVC.
+const (
+	DefaultRawSamples         = 120
+	DefaultDownsampledSamples = 48
+	DefaultResolution         = 30 * time.Minute
+
+	snapshotVersion = 1
+)
+
+// Sample is a single usage observation of a PVC.
+type Sample struct {
+	Time          time.Time
+	UsedBytes     int64
+	CapacityBytes int64
+	InodesUsed    int64
+	InodesTotal   int64
+}
+
+// UsagePercent returns the storage usage of the sample.
+func (s Sample) UsagePercent() float64 {
+	if s.CapacityBytes <= 0 {
+		return 0
+	}
+	return float64(s.UsedBytes) / float64(s.CapacityBytes) * 100
+}
+
+// MarshalJSON encodes a sample as a compact array, since persisted history is
+// bounded by the size of a ConfigMap.
+func (s Sample) MarshalJSON() ([]byte, error) {
+	return json.Marshal([5]int64{s.Time.Unix(), s.UsedBytes, s.CapacityBytes, s.InodesUsed, s.InodesTotal})
+}
+
+func (s *Sample) UnmarshalJSON(data []byte) error {
+	var fields [5]int64
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("invalid sample: %w", err)
+	}
+	*s = Sample{
+		Time:          time.Unix(fields[0], 0),
+		UsedBytes:     fields[1],
+		CapacityBytes: fields[2],
+		InodesUsed:    fields[3],
+		InodesTotal:   fields[4],
+	}
+	return nil
+}
+
+// ring is a bounded buffer of samples in insertion order. Storage grows up
+// to the capacity, so large bounds cost nothing until they are used.
+type ring struct {
+	capacity int
+	samples  []Sample
+	start    int
+}
+
+func newRing(capacity int) *ring {
+	return &ring{capacity: capacity}
+}
+
+func (r *ring) len() int {
+	return len(r.samples)
+}
+
+func (r *ring) push(s Sample) {
+	if r.capacity <= 0 {
+		return
+	}
+	if len(r.samples) < r.capacity {
+		r.samples = append(r.samples, s)
+		return
+	}
+	r.samples[r.start] = s
+	r.start = (r.start + 1) % len(r.samples)
+}
+
+func (r *ring) last() (Sample, bool) {
+	if len(r.samples) == 0 {
+		return Sample{}, false
+	}
+	return r.samples[(r.start+len(r.samples)-1)%len(r.samples)], true
+}
+
+func (r *ring) replaceLast(s Sample) {
+	r.samples[(r.start+len(r.samples)-1)%len(r.samples)] = s
+}
+
+func (r *ring) list() []Sample {
+	result := make([]Sample, 0, len(r.samples))
+	for i := range r.samples {
+		result = append(result, r.samples[(r.start+i)%len(r.samples)])
+	}
+	return result
+}
+
+// series holds the raw samples of a PVC and a downsampled copy keeping one
+// sample per resolution bucket, so older history survives raw evictions.
+type series struct {
+	raw         *ring
+	downsampled *ring
+}
+
+// Config bounds the history kept per PVC.
+type Config struct {
+	RawSamples         int
+	DownsampledSamples int
+	// Resolution is the bucket width of downsampled samples. The most
+	// pessimistic sample, the one with the highest usage, is kept per bucket.
+	Resolution time.Duration
+}
+
+// Store is a bounded in-memory time series of per-PVC usage samples, keyed by
+// "namespace/name". It is safe for concurrent use.
+type Store struct {
+	config Config
+	mutex  sync.RWMutex
+	series map[string]*series
+	dirty  bool
+}
+
+func NewStore(config Config) *Store {
+	if config.RawSamples <= 0 {
+		config.RawSamples = DefaultRawSamples
+	}
+	if config.DownsampledSamples < 0 {
+		config.DownsampledSamples = 0
+	}
+	if config.Resolution <= 0 {
+		config.Resolution = DefaultResolution
+	}
+	return &Store{config: config, series: make(map[string]*series)}
+}
+
+// Record appends a sample. Samples older than the latest one of the PVC are
+// ignored so replayed or lagging data cannot reorder the history.
+func (s *Store) Record(key string, sample Sample) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.record(key, sample)
+}
+
+func (s *Store) record(key string, sample Sample) {
+	sr := s.series[key]
+	if sr == nil {
+		sr = &series{raw: newRing(s.config.RawSamples), downsampled: newRing(s.config.DownsampledSamples)}
+		s.series[key] = sr
+	}
+	if last, ok := sr.raw.last(); ok && !sample.Time.After(last.Time) {
+		return
+	}
+	sr.raw.push(sample)
+
+	bucket := sample.Time.Truncate(s.config.Resolution)
+	if last, ok := sr.downsampled.last(); ok && last.Time.Truncate(s.config.Resolution).Equal(bucket) {
+		if sample.UsagePercent() >= last.UsagePercent() {
+			sr.downsampled.replaceLast(sample)
+		}
+	} else {
+		sr.downsampled.push(sample)
+	}
+	s.dirty = true
+}
+
+// Query returns the samples of a PVC since the given time, oldest first.
+// Downsampled samples cover the time before the oldest raw sample.
+func (s *Store) Query(key string, since time.Time) []Sample {
+	if s == nil {
+		return nil
+	}
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	sr := s.series[key]
+	if sr == nil {
+		return nil
+	}
+	raw := sr.raw.list()
+	var result []Sample
+	for _, sample := range sr.downsampled.list() {
+		if len(raw) > 0 && !sample.Time.Before(raw[0].Time) {
+			break
+		}
+		if !sample.Time.Before(since) {
+			result = append(result, sample)
+		}
+	}
+	for _, sample := range raw {
+		if !sample.Time.Before(since) {
+			result = append(result, sample)
+		}
+	}
+	return result
+}
+
+// Latest returns the most recent sample of a PVC.
+func (s *Store) Latest(key string) (Sample, bool) {
+	if s == nil {
+		return Sample{}, false
+	}
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if sr := s.series[key]; sr != nil {
+		return sr.raw.last()
+	}
+	return Sample{}, false
+}
+
+// GrowthRate returns the average growth of used bytes per second over the
+// samples within window before now. It reports false without two samples.
+func (s *Store) GrowthRate(key string, window time.Duration, now time.Time) (float64, bool) {
+	samples := s.Query(key, now.Add(-window))
+	if len(samples) < 2 {
+		return 0, false
+	}
+	first, last := samples[0], samples[len(samples)-1]
+	elapsed := last.Time.Sub(first.Time).Seconds()
+	if elapsed <= 0 {
+		return 0, false
+	}
+	return float64(last.UsedBytes-first.UsedBytes) / elapsed, true
+}
+
+// Keys returns the PVCs with history, sorted.
+func (s *Store) Keys() []string {
+	if s == nil {
+		return nil
+	}
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	keys := make([]string, 0, len(s.series))
+	for key := range s.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Len returns the number of PVCs and samples held.
+func (s *Store) Len() (series, samples int) {
+	if s == nil {
+		return 0, 0
+	}
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for _, sr := range s.series {
+		samples += sr.raw.len() + sr.downsampled.len()
+	}
+	return len(s.series), samples
+}
+
+// Retain drops the history of PVCs not in keep, e.g. deleted PVCs.
+func (s *Store) Retain(keep map[string]struct{}) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for key := range s.series {
+		if _, ok := keep[key]; !ok {
+			delete(s.series, key)
+			s.dirty = true
+		}
+	}
+}
+
+// snapshot is the persisted form of a Store.
+type snapshot struct {
+	Version int                       `json:"version"`
+	Series  map[string]snapshotSeries `json:"series"`
+}
+
+type snapshotSeries struct {
+	Raw         []Sample `json:"raw"`
+	Downsampled []Sample `json:"downsampled,omitempty"`
+}
+
+// MarshalJSON encodes the store for persistence.
+func (s *Store) MarshalJSON() ([]byte, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	snap := snapshot{Version: snapshotVersion, Series: make(map[string]snapshotSeries, len(s.series))}
+	for key, sr := range s.series {
+		snap.Series[key] = snapshotSeries{Raw: sr.raw.list(), Downsampled: sr.downsampled.list()}
+	}
+	return json.Marshal(snap)
+}
+
+// UnmarshalJSON replaces the history with a persisted snapshot. Samples beyond
+// the configured bounds are dropped.
+func (s *Store) UnmarshalJSON(data []byte) error {
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("failed to decode history: %w", err)
+	}
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("unsupported history version %d", snap.Version)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.series = make(map[string]*series, len(snap.Series))
+	for key, ss := range snap.Series {
+		sr := &series{raw: newRing(s.config.RawSamples), downsampled: newRing(s.config.DownsampledSamples)}
+		for _, sample := range ss.Downsampled {
+			sr.downsampled.push(sample)
+		}
+		for _, sample := range ss.Raw {
+			sr.raw.push(sample)
+		}
+		s.series[key] = sr
+	}
+	s.dirty = false
+	return nil
+}
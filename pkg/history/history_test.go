@@ -0,0 +1,161 @@
+package history
+
+import (
+	"testing"
+	"time"
+)
+
+var baseTime = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func sampleAt(minutes int, used int64) Sample {
+	return Sample{Time: baseTime.Add(time.Duration(minutes) * time.Minute), UsedBytes: used, CapacityBytes: 1000, InodesUsed: 10, InodesTotal: 100}
+}
+
+func TestStore_RecordEvictsOldestRawSamples(t *testing.T) {
+	store := NewStore(Config{RawSamples: 3, DownsampledSamples: 0})
+	for i := 0; i < 5; i++ {
+		store.Record("ns/data", sampleAt(i, int64(i)))
+	}
+
+	samples := store.Query("ns/data", time.Time{})
+	if len(samples) != 3 {
+		t.Fatalf("expected 3 samples, got %d", len(samples))
+	}
+	for i, s := range samples {
+		if s.UsedBytes != int64(i+2) {
+			t.Errorf("sample %d: expected used bytes %d, got %d", i, i+2, s.UsedBytes)
+		}
+	}
+}
+
+func TestStore_RecordIgnoresOlderSamples(t *testing.T) {
+	store := NewStore(Config{})
+	store.Record("ns/data", sampleAt(5, 500))
+	store.Record("ns/data", sampleAt(5, 600))
+	store.Record("ns/data", sampleAt(1, 100))
+
+	samples := store.Query("ns/data", time.Time{})
+	if len(samples) != 1 || samples[0].UsedBytes != 500 {
+		t.Errorf("expected only the first sample, got %+v", samples)
+	}
+}
+
+func TestStore_DownsampledSamplesOutliveRawSamples(t *testing.T) {
+	store := NewStore(Config{RawSamples: 2, DownsampledSamples: 10, Resolution: 30 * time.Minute})
+	// Two buckets of three samples each; the highest usage wins a bucket
+	store.Record("ns/data", sampleAt(0, 100))
+	store.Record("ns/data", sampleAt(10, 300))
+	store.Record("ns/data", sampleAt(20, 200))
+	store.Record("ns/data", sampleAt(30, 400))
+	store.Record("ns/data", sampleAt(40, 500))
+	store.Record("ns/data", sampleAt(50, 450))
+
+	samples := store.Query("ns/data", time.Time{})
+	var used []int64
+	for _, s := range samples {
+		used = append(used, s.UsedBytes)
+	}
+	// The downsampled sample of the second bucket is at minute 40, after the
+	// oldest raw sample, so only the first bucket precedes the raw samples
+	expected := []int64{300, 500, 450}
+	if len(used) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, used)
+	}
+	for i := range expected {
+		if used[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, used)
+		}
+	}
+
+	recent := store.Query("ns/data", baseTime.Add(45*time.Minute))
+	if len(recent) != 1 || recent[0].UsedBytes != 450 {
+		t.Errorf("expected only the newest sample since minute 45, got %+v", recent)
+	}
+}
+
+func TestStore_GrowthRate(t *testing.T) {
+	store := NewStore(Config{})
+	now := baseTime.Add(90 * time.Minute)
+
+	if _, ok := store.GrowthRate("ns/data", time.Hour, now); ok {
+		t.Error("expected no growth rate without history")
+	}
+
+	store.Record("ns/data", sampleAt(0, 0))
+	store.Record("ns/data", sampleAt(30, 3600))
+	if _, ok := store.GrowthRate("ns/data", time.Hour, now); ok {
+		t.Error("expected no growth rate with a single sample in the window")
+	}
+
+	store.Record("ns/data", sampleAt(90, 10800))
+	rate, ok := store.GrowthRate("ns/data", time.Hour, now)
+	if !ok {
+		t.Fatal("expected a growth rate")
+	}
+	if rate != 2 {
+		t.Errorf("expected 2 bytes per second, got %v", rate)
+	}
+}
+
+func TestStore_RetainAndLen(t *testing.T) {
+	store := NewStore(Config{RawSamples: 10, DownsampledSamples: 10})
+	store.Record("ns/a", sampleAt(0, 1))
+	store.Record("ns/a", sampleAt(1, 2))
+	store.Record("ns/b", sampleAt(0, 1))
+
+	if series, samples := store.Len(); series != 2 || samples != 5 {
+		t.Errorf("expected 2 series and 5 samples, got %d and %d", series, samples)
+	}
+
+	store.Retain(map[string]struct{}{"ns/a": {}})
+	keys := store.Keys()
+	if len(keys) != 1 || keys[0] != "ns/a" {
+		t.Errorf("expected only ns/a to be retained, got %v", keys)
+	}
+	if latest, ok := store.Latest("ns/a"); !ok || latest.UsedBytes != 2 {
+		t.Errorf("expected latest sample of ns/a with 2 used bytes, got %+v", latest)
+	}
+}
+
+func TestStore_JSONRoundTrip(t *testing.T) {
+	store := NewStore(Config{RawSamples: 3, DownsampledSamples: 3, Resolution: time.Hour})
+	for i := 0; i < 5; i++ {
+		store.Record("ns/data", sampleAt(i*40, int64(i*100)))
+	}
+
+	data, err := store.MarshalJSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	restored := NewStore(Config{RawSamples: 3, DownsampledSamples: 3, Resolution: time.Hour})
+	if err := restored.UnmarshalJSON(data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	original := store.Query("ns/data", time.Time{})
+	loaded := restored.Query("ns/data", time.Time{})
+	if len(original) != len(loaded) {
+		t.Fatalf("expected %d samples, got %d", len(original), len(loaded))
+	}
+	for i := range original {
+		loaded[i].Time = loaded[i].Time.UTC()
+		if original[i] != loaded[i] {
+			t.Errorf("sample %d: expected %+v, got %+v", i, original[i], loaded[i])
+		}
+	}
+
+	if err := restored.UnmarshalJSON([]byte(`{"version":2}`)); err == nil {
+		t.Error("expected an error for an unsupported version")
+	}
+}
+
+func TestStore_NilIsEmpty(t *testing.T) {
+	var store *Store
+	store.Record("ns/data", sampleAt(0, 1))
+	if samples := store.Query("ns/data", time.Time{}); samples != nil {
+		t.Errorf("expected no samples, got %v", samples)
+	}
+	if _, ok := store.Latest("ns/data"); ok {
+		t.Error("expected no latest sample")
+	}
+}
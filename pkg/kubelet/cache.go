@@ -1,36 +1,55 @@
 package kubelet
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
 
 	"github.com/logicIQ/pvc-chonker/pkg/metrics"
-	dto "github.com/prometheus/client_model/go"
-	"github.com/prometheus/common/expfmt"
 	"golang.org/x/sync/errgroup"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type MetricsCache struct {
-	data  map[string]*VolumeMetrics
-	mutex sync.RWMutex
+	data map[string]*VolumeMetrics
+	// samples holds the samples of each volume per reporting node until they
+	// are merged into data.
+	samples     map[string]map[string]*VolumeMetrics
+	failedNodes map[string]*NodeScrapeError
+	pvcNodes    map[string][]string
+	mutex       sync.RWMutex
 }
 
+// Kubelet endpoints volume stats can be read from.
+const (
+	EndpointMetrics = "metrics"
+	EndpointSummary = "stats/summary"
+)
+
 type MetricsCollector struct {
-	client      client.Client
-	clientset   *kubernetes.Clientset
-	kubeletURL  string
-	httpTimeout time.Duration
+	client             client.Client
+	clientset          *kubernetes.Clientset
+	kubeletURL         string
+	kubeletURLTemplate *template.Template
+	endpoint           string
+	nodeTimeout        time.Duration
+	httpClient         *http.Client
+	httpConfig         HTTPConfig
+	maxConcurrency     int
+	podsIndexed        bool
+	dedupeStrategy     string
+	recorder           *Recorder
 }
 
 func NewMetricsCache() *MetricsCache {
@@ -39,16 +58,31 @@ func NewMetricsCache() *MetricsCache {
 	}
 }
 
+// NewMetricsCollector returns a collector that reads volume stats through the
+// API server node proxy or, when kubeletURL is set, directly from a URL
+// template rendered per node.
 func NewMetricsCollector(kubeletURL string) (*MetricsCollector, error) {
+	mc := &MetricsCollector{
+		kubeletURL:     kubeletURL,
+		endpoint:       EndpointMetrics,
+		nodeTimeout:    DefaultNodeTimeout,
+		maxConcurrency: DefaultMaxConcurrentScrapes,
+		dedupeStrategy: DefaultDedupeStrategy,
+	}
 	if kubeletURL != "" {
-		if err := validateKubeletURL(kubeletURL); err != nil {
+		tmpl, err := parseURLTemplate(kubeletURL)
+		if err != nil {
 			return nil, fmt.Errorf("invalid kubelet URL: %w", err)
 		}
+		mc.kubeletURLTemplate = tmpl
 	}
-	return &MetricsCollector{
-		kubeletURL:  kubeletURL,
-		httpTimeout: 30 * time.Second,
-	}, nil
+
+	// Shared across nodes and cycles so connections to kubelets are reused
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = 100
+	transport.MaxIdleConnsPerHost = 2
+	mc.httpClient = &http.Client{Transport: transport}
+	return mc, nil
 }
 
 func validateKubeletURL(kubeletURL string) error {
@@ -81,6 +115,17 @@ func (mc *MetricsCollector) SetClient(client client.Client, clientset *kubernete
 	mc.clientset = clientset
 }
 
+// SetEndpoint selects the kubelet endpoint volume stats are read from, either
+// the Prometheus text exposition or the JSON summary API.
+func (mc *MetricsCollector) SetEndpoint(endpoint string) error {
+	switch endpoint {
+	case EndpointMetrics, EndpointSummary:
+		mc.endpoint = endpoint
+		return nil
+	}
+	return fmt.Errorf("unsupported kubelet endpoint %q, expected %q or %q", endpoint, EndpointMetrics, EndpointSummary)
+}
+
 func (mc *MetricsCollector) GetAllVolumeMetrics(ctx context.Context) (*MetricsCache, error) {
 	startTime := time.Now()
 	defer func() {
@@ -88,15 +133,19 @@ func (mc *MetricsCollector) GetAllVolumeMetrics(ctx context.Context) (*MetricsCa
 	}()
 
 	cache := NewMetricsCache()
-	if err := mc.fetchAllNodeMetrics(ctx, cache); err != nil {
+	rec := mc.recorder.begin(mc.endpoint, startTime)
+	err := mc.fetchAllNodeMetrics(ctx, cache, rec)
+	mc.finishRecording(ctx, rec, cache)
+	if err != nil {
 		return nil, err
 	}
 
+	cache.mergeSamples(mc.dedupeStrategy)
 	cache.calculateUsagePercentages()
 	return cache, nil
 }
 
-func (mc *MetricsCollector) fetchAllNodeMetrics(ctx context.Context, cache *MetricsCache) error {
+func (mc *MetricsCollector) fetchAllNodeMetrics(ctx context.Context, cache *MetricsCache, rec *recording) error {
 	var nodes corev1.NodeList
 	if err := mc.client.List(ctx, &nodes); err != nil {
 		return fmt.Errorf("failed to list nodes: %w", err)
@@ -106,116 +155,173 @@ func (mc *MetricsCollector) fetchAllNodeMetrics(ctx context.Context, cache *Metr
 		return fmt.Errorf("no nodes found")
 	}
 
-	eg, ectx := errgroup.WithContext(ctx)
+	nodeNames := make([]string, 0, len(nodes.Items))
 	for _, node := range nodes.Items {
-		nodeName := node.Name
-		eg.Go(func() error {
-			return mc.fetchNodeMetrics(ectx, nodeName, cache)
-		})
+		nodeNames = append(nodeNames, node.Name)
 	}
 
-	return eg.Wait()
-}
+	if err := mc.fetchNodesMetrics(ctx, nodeNames, cache, rec); err != nil {
+		return err
+	}
 
-func (mc *MetricsCollector) fetchNodeMetrics(ctx context.Context, nodeName string, cache *MetricsCache) error {
-	var respBody []byte
-	var err error
+	// Only needed to attribute missing metrics to failed nodes
+	if len(cache.FailedNodes()) > 0 {
+		var pods corev1.PodList
+		if err := mc.client.List(ctx, &pods); err != nil {
+			return fmt.Errorf("failed to list pods: %w", err)
+		}
+		cache.setPVCNodes(BuildPVCNodeMap(pods.Items))
+	}
+	return nil
+}
 
-	if mc.kubeletURL != "" {
-		respBody, err = mc.fetchFromCustomURL(ctx)
-	} else {
-		req := mc.clientset.
-			CoreV1().
-			RESTClient().
-			Get().
-			Resource("nodes").
-			Name(nodeName).
-			SubResource("proxy").
-			Suffix("metrics")
+// fetchNodesMetrics scrapes the given nodes, at most maxConcurrency at a time.
+// A node that cannot be scraped is recorded in the cache rather than failing
+// the whole scrape; an error is only returned when no node could be scraped.
+func (mc *MetricsCollector) fetchNodesMetrics(ctx context.Context, nodeNames []string, cache *MetricsCache, rec *recording) error {
+	metrics.KubeletClientScrapedNodes.Set(float64(len(nodeNames)))
 
-		respBody, err = req.DoRaw(ctx)
+	var eg errgroup.Group
+	if mc.maxConcurrency > 0 {
+		eg.SetLimit(mc.maxConcurrency)
+	}
+	for _, nodeName := range nodeNames {
+		eg.Go(func() error {
+			if err := mc.fetchNodeMetrics(ctx, nodeName, cache, rec); err != nil {
+				failure, ok := err.(*NodeScrapeError)
+				if !ok {
+					failure = &NodeScrapeError{Node: nodeName, Reason: classifyScrapeError(err), Err: err}
+				}
+				metrics.RecordKubeletNodeScrapeFailure(nodeName, failure.Reason)
+				cache.recordFailure(failure)
+				rec.recordFailure(failure)
+			}
+			return nil
+		})
+	}
+	_ = eg.Wait()
+
+	failures := cache.FailedNodes()
+	metrics.KubeletClientFailedNodes.Set(float64(len(failures)))
+	if len(nodeNames) > 0 && len(failures) == len(nodeNames) {
+		errs := make([]error, 0, len(failures))
+		for _, failure := range failures {
+			errs = append(errs, failure)
+		}
+		return fmt.Errorf("failed to scrape all %d nodes: %w", len(nodeNames), errors.Join(errs...))
 	}
+	return nil
+}
 
-	if err != nil {
-		return fmt.Errorf("failed to get metrics from node %s: %w", nodeName, err)
+func (mc *MetricsCollector) fetchNodeMetrics(ctx context.Context, nodeName string, cache *MetricsCache, rec *recording) error {
+	endpoint := mc.endpoint
+	if endpoint == "" {
+		endpoint = EndpointMetrics
 	}
 
-	parser := expfmt.TextParser{}
-	metricFamilies, err := parser.TextToMetricFamilies(bytes.NewReader(respBody))
-	if err != nil {
-		return fmt.Errorf("failed to parse metrics from node %s: %w", nodeName, err)
+	if mc.nodeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, mc.nodeTimeout)
+		defer cancel()
 	}
 
-	// Process metrics using ava-labs constants
-	if gauge, ok := metricFamilies["kubelet_volume_stats_capacity_bytes"]; ok {
-		for _, m := range gauge.Metric {
-			pvcName, value := mc.parseMetric(m)
-			if pvcName.Name != "" && pvcName.Namespace != "" {
-				cache.setCapacity(pvcName, value)
-			}
-		}
+	accept := metricsAcceptHeader
+	if endpoint == EndpointSummary {
+		accept = summaryAcceptHeader
 	}
 
-	if gauge, ok := metricFamilies["kubelet_volume_stats_available_bytes"]; ok {
-		for _, m := range gauge.Metric {
-			pvcName, value := mc.parseMetric(m)
-			if pvcName.Name != "" && pvcName.Namespace != "" {
-				cache.setAvailable(pvcName, value)
-			}
+	var body io.ReadCloser
+	var contentType string
+	var err error
+	if mc.kubeletURL != "" {
+		body, contentType, err = mc.fetchFromCustomURL(ctx, nodeName, endpoint, accept)
+	} else {
+		body, contentType, err = mc.fetchFromProxy(ctx, nodeName, endpoint, accept)
+	}
+	if err != nil {
+		return &NodeScrapeError{Node: nodeName, Reason: classifyScrapeError(err), Err: err}
+	}
+	defer body.Close()
+
+	scrapedAt := time.Now()
+	payload, recorded := rec.record(nodeName, contentType, scrapedAt, body)
+	// Parsed separately so volumes reported by several nodes are not mixed
+	nodeCache := NewMetricsCache()
+	err = parseNodePayload(payload, endpoint, contentType, nodeName, scrapedAt, nodeCache)
+	recorded(err)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return &NodeScrapeError{Node: nodeName, Reason: ScrapeReasonTimeout, Err: err}
 		}
+		return &NodeScrapeError{Node: nodeName, Reason: ScrapeReasonParseError, Err: err}
 	}
+	cache.addNodeSamples(nodeName, nodeCache)
+	return nil
+}
 
-	if gauge, ok := metricFamilies["kubelet_volume_stats_inodes"]; ok {
-		for _, m := range gauge.Metric {
-			pvcName, value := mc.parseMetric(m)
-			if pvcName.Name != "" && pvcName.Namespace != "" {
-				cache.setInodesTotal(pvcName, value)
-			}
-		}
+// fetchFromProxy opens a kubelet endpoint through the API server node proxy.
+// The response is streamed rather than buffered.
+func (mc *MetricsCollector) fetchFromProxy(ctx context.Context, nodeName, endpoint, accept string) (io.ReadCloser, string, error) {
+	req := mc.clientset.
+		CoreV1().
+		RESTClient().
+		Get().
+		Resource("nodes").
+		Name(nodeName).
+		SubResource("proxy").
+		Suffix(endpoint).
+		SetHeader("Accept", accept)
+
+	// The typed clientset's REST client exposes its authenticated HTTP client,
+	// which is needed to read the negotiated content type
+	restClient, ok := mc.clientset.CoreV1().RESTClient().(*rest.RESTClient)
+	if !ok || restClient.Client == nil {
+		body, err := req.Stream(ctx)
+		return body, "", err
 	}
+	return doStreamingRequest(ctx, restClient.Client, req.URL().String(), accept, nil)
+}
 
-	if gauge, ok := metricFamilies["kubelet_volume_stats_inodes_used"]; ok {
-		for _, m := range gauge.Metric {
-			pvcName, value := mc.parseMetric(m)
-			if pvcName.Name != "" && pvcName.Namespace != "" {
-				cache.setInodesUsed(pvcName, value)
-			}
-		}
+func (mc *MetricsCollector) fetchFromCustomURL(ctx context.Context, nodeName, endpoint, accept string) (io.ReadCloser, string, error) {
+	var node corev1.Node
+	if err := mc.client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
+		return nil, "", fmt.Errorf("failed to get node: %w", err)
 	}
 
-	return nil
+	nodeURL, err := mc.renderKubeletURL(NewURLTemplateData(&node), endpoint)
+	if err != nil {
+		return nil, "", err
+	}
+	return doStreamingRequest(ctx, mc.httpClient, nodeURL, accept, mc.authorize)
 }
 
-func (mc *MetricsCollector) fetchFromCustomURL(ctx context.Context) ([]byte, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", mc.kubeletURL+"/metrics", nil)
+// doStreamingRequest returns the body and content type of a successful
+// response. The caller must close the body.
+func doStreamingRequest(ctx context.Context, httpClient *http.Client, url, accept string, authorize func(*http.Request) error) (io.ReadCloser, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", accept)
+	if authorize != nil {
+		if err := authorize(req); err != nil {
+			return nil, "", err
+		}
 	}
 
-	client := &http.Client{Timeout: mc.httpTimeout}
-	resp, err := client.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch metrics: %w", err)
+		return nil, "", fmt.Errorf("failed to fetch metrics: %w", err)
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		// Drain a bounded amount so the connection can be reused
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		return nil, "", &statusCodeError{StatusCode: resp.StatusCode}
 	}
 
-	return io.ReadAll(resp.Body)
-}
-
-func (mc *MetricsCollector) parseMetric(m *dto.Metric) (pvcName types.NamespacedName, value int64) {
-	for _, label := range m.GetLabel() {
-		if label.GetName() == "namespace" {
-			pvcName.Namespace = label.GetValue()
-		} else if label.GetName() == "persistentvolumeclaim" {
-			pvcName.Name = label.GetValue()
-		}
-	}
-	value = int64(m.GetGauge().GetValue())
-	return pvcName, value
+	return resp.Body, resp.Header.Get("Content-Type"), nil
 }
 
 func (cache *MetricsCache) keyFromNamespacedName(nn types.NamespacedName) string {
@@ -284,6 +390,51 @@ func (cache *MetricsCache) setInodesUsed(pvcName types.NamespacedName, value int
 	cache.data[key].InodesUsed = value
 }
 
+func (cache *MetricsCache) setSource(pvcName types.NamespacedName, nodeName, podName, source string, timestamp time.Time) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	key := cache.keyFromNamespacedName(pvcName)
+	if cache.data[key] == nil {
+		cache.data[key] = &VolumeMetrics{}
+	}
+	cache.data[key].NodeName = nodeName
+	cache.data[key].PodName = podName
+	cache.data[key].Source = source
+	cache.data[key].Timestamp = timestamp
+}
+
+// setTimestamp sets the collection time of a sample from Unix seconds.
+func (cache *MetricsCache) setTimestamp(pvcName types.NamespacedName, unixSeconds int64) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	key := cache.keyFromNamespacedName(pvcName)
+	if cache.data[key] == nil {
+		cache.data[key] = &VolumeMetrics{}
+	}
+	cache.data[key].Timestamp = time.Unix(unixSeconds, 0)
+}
+
+// setDefaultSource fills in the source and collection time of samples that do
+// not have them yet.
+func (cache *MetricsCache) setDefaultSource(source string, timestamp time.Time) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	for _, vm := range cache.data {
+		if vm.Source == "" {
+			vm.Source = source
+		}
+		if vm.Timestamp.IsZero() {
+			vm.Timestamp = timestamp
+		}
+	}
+}
+
+func (cache *MetricsCache) set(pvcName types.NamespacedName, vm *VolumeMetrics) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.data[cache.keyFromNamespacedName(pvcName)] = vm
+}
+
 func (cache *MetricsCache) calculateUsagePercentages() {
 	cache.mutex.Lock()
 	defer cache.mutex.Unlock()
@@ -0,0 +1,101 @@
+package kubelet
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Strategies merging the samples of a volume reported by several nodes, as
+// happens for ReadWriteMany volumes. A strategy always picks one whole sample,
+// so capacity and availability come from the same node.
+const (
+	// DedupeFreshest picks the most recently collected sample.
+	DedupeFreshest = "freshest"
+	// DedupePessimistic picks the sample with the highest usage.
+	DedupePessimistic = "pessimistic"
+	// DedupeSameNode always picks the sample of the first node by name, so the
+	// usage of a volume is tracked from a stable source across cycles.
+	DedupeSameNode = "same-node"
+
+	DefaultDedupeStrategy = DedupeFreshest
+)
+
+// SetDedupeStrategy selects how samples of a volume reported by several nodes
+// are merged.
+func (mc *MetricsCollector) SetDedupeStrategy(strategy string) error {
+	switch strategy {
+	case DedupeFreshest, DedupePessimistic, DedupeSameNode:
+		mc.dedupeStrategy = strategy
+		return nil
+	}
+	return fmt.Errorf("unsupported dedupe strategy %q, expected %q, %q or %q", strategy, DedupeFreshest, DedupePessimistic, DedupeSameNode)
+}
+
+// addNodeSamples records the samples scraped from a single node.
+func (cache *MetricsCache) addNodeSamples(nodeName string, nodeCache *MetricsCache) {
+	nodeCache.calculateUsagePercentages()
+	samples := nodeCache.GetAll()
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if cache.samples == nil {
+		cache.samples = make(map[string]map[string]*VolumeMetrics)
+	}
+	for key, vm := range samples {
+		if vm.NodeName == "" {
+			vm.NodeName = nodeName
+		}
+		if cache.samples[key] == nil {
+			cache.samples[key] = make(map[string]*VolumeMetrics)
+		}
+		cache.samples[key][nodeName] = vm
+	}
+}
+
+// mergeSamples picks one sample per volume with the given strategy and
+// records the nodes the volume is mounted on.
+func (cache *MetricsCache) mergeSamples(strategy string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	for key, byNode := range cache.samples {
+		nodes := make(map[string]struct{}, len(byNode))
+		candidates := make([]*VolumeMetrics, 0, len(byNode))
+		for node, vm := range byNode {
+			nodes[node] = struct{}{}
+			candidates = append(candidates, vm)
+		}
+		for _, node := range cache.pvcNodes[key] {
+			nodes[node] = struct{}{}
+		}
+
+		vm := pickSample(candidates, strategy)
+		vm.MountedNodes = sortedKeys(nodes)
+		cache.data[key] = vm
+	}
+}
+
+func pickSample(candidates []*VolumeMetrics, strategy string) *VolumeMetrics {
+	// Sorting by node name first makes every strategy deterministic on ties
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].NodeName < candidates[j].NodeName
+	})
+
+	best := candidates[0]
+	for _, vm := range candidates[1:] {
+		switch strategy {
+		case DedupePessimistic:
+			if vm.UsagePercent > best.UsagePercent ||
+				(vm.UsagePercent == best.UsagePercent && vm.InodesUsagePercent > best.InodesUsagePercent) {
+				best = vm
+			}
+		case DedupeSameNode:
+			return best
+		default:
+			if vm.Timestamp.After(best.Timestamp) {
+				best = vm
+			}
+		}
+	}
+	return best
+}
@@ -0,0 +1,124 @@
+package kubelet
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestPickSample(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	samples := func() []*VolumeMetrics {
+		return []*VolumeMetrics{
+			{NodeName: "node-c", UsagePercent: 50, Timestamp: now},
+			{NodeName: "node-a", UsagePercent: 40, Timestamp: now.Add(-time.Minute)},
+			{NodeName: "node-b", UsagePercent: 80, Timestamp: now.Add(-2 * time.Minute)},
+		}
+	}
+
+	tests := []struct {
+		strategy string
+		expected string
+	}{
+		{DedupeFreshest, "node-c"},
+		{DedupePessimistic, "node-b"},
+		{DedupeSameNode, "node-a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			if got := pickSample(samples(), tt.strategy); got.NodeName != tt.expected {
+				t.Errorf("expected sample of %s, got %s", tt.expected, got.NodeName)
+			}
+		})
+	}
+}
+
+func TestPickSample_TiesAreDeterministic(t *testing.T) {
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		candidates := []*VolumeMetrics{
+			{NodeName: "node-b", UsagePercent: 50, Timestamp: now},
+			{NodeName: "node-a", UsagePercent: 50, Timestamp: now},
+		}
+		if i%2 == 1 {
+			candidates[0], candidates[1] = candidates[1], candidates[0]
+		}
+		for _, strategy := range []string{DedupeFreshest, DedupePessimistic, DedupeSameNode} {
+			if got := pickSample(candidates, strategy); got.NodeName != "node-a" {
+				t.Errorf("%s: expected node-a on ties, got %s", strategy, got.NodeName)
+			}
+		}
+	}
+}
+
+func TestGetAllVolumeMetrics_RWXVolume(t *testing.T) {
+	// Both nodes report the same volume with different usage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		available := "536870912"
+		if r.URL.Path == "/node-b" {
+			available = "107374182"
+		}
+		_, _ = w.Write([]byte(strings.ReplaceAll(testNodeMetrics, "536870912", available)))
+	}))
+	defer server.Close()
+
+	pvc := types.NamespacedName{Namespace: "test-ns", Name: "test-pvc"}
+	for _, tt := range []struct {
+		strategy     string
+		expectedNode string
+	}{
+		{DedupePessimistic, "node-b"},
+		{DedupeSameNode, "node-a"},
+	} {
+		t.Run(tt.strategy, func(t *testing.T) {
+			mc := newTestCollector(t, server.URL+"/{{.NodeName}}",
+				&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}},
+				&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-b"}},
+			)
+			if err := mc.SetDedupeStrategy(tt.strategy); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			cache, err := mc.GetAllVolumeMetrics(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			vm, exists := cache.Get(pvc)
+			if !exists {
+				t.Fatal("expected metrics for test-pvc")
+			}
+			if vm.NodeName != tt.expectedNode {
+				t.Errorf("expected sample of %s, got %s", tt.expectedNode, vm.NodeName)
+			}
+			// Capacity and availability must come from the same sample
+			expectedAvailable := int64(536870912)
+			if tt.expectedNode == "node-b" {
+				expectedAvailable = 107374182
+			}
+			if vm.AvailableBytes != expectedAvailable || vm.UsedBytes != vm.CapacityBytes-expectedAvailable {
+				t.Errorf("expected a consistent sample from %s, got %+v", tt.expectedNode, vm)
+			}
+			if strings.Join(vm.MountedNodes, ",") != "node-a,node-b" {
+				t.Errorf("expected volume to be mounted on both nodes, got %v", vm.MountedNodes)
+			}
+		})
+	}
+}
+
+func TestSetDedupeStrategy_Invalid(t *testing.T) {
+	mc, err := NewMetricsCollector("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mc.SetDedupeStrategy("average"); err == nil {
+		t.Error("expected error for unknown strategy")
+	}
+}
@@ -0,0 +1,141 @@
+package kubelet
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func writeTestFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestMetricsCollector_DirectTLS(t *testing.T) {
+	var authorization string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(testNodeMetrics))
+	}))
+	defer server.Close()
+
+	caFile := writeTestFile(t, "ca.crt", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	tokenFile := writeTestFile(t, "token", []byte("rotated-token\n"))
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}}
+	pvc := types.NamespacedName{Namespace: "test-ns", Name: "test-pvc"}
+
+	tests := []struct {
+		name    string
+		config  HTTPConfig
+		wantErr bool
+	}{
+		{"CA bundle and token", HTTPConfig{CAFile: caFile, BearerTokenFile: tokenFile}, false},
+		{"insecure skip verify", HTTPConfig{InsecureSkipVerify: true, BearerToken: "static-token"}, false},
+		{"unknown CA", HTTPConfig{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authorization = ""
+			mc := newTestCollector(t, server.URL, node)
+			if err := mc.SetHTTPConfig(tt.config); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			cache, err := mc.GetAllVolumeMetrics(context.Background())
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected certificate verification error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, exists := cache.Get(pvc); !exists {
+				t.Error("expected metrics for test-pvc")
+			}
+
+			expected := "Bearer static-token"
+			if tt.config.BearerTokenFile != "" {
+				expected = "Bearer rotated-token"
+			}
+			if authorization != expected {
+				t.Errorf("expected Authorization %q, got %q", expected, authorization)
+			}
+		})
+	}
+}
+
+func TestSetHTTPConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config HTTPConfig
+	}{
+		{"cert without key", HTTPConfig{CertFile: "client.crt"}},
+		{"missing CA file", HTTPConfig{CAFile: filepath.Join(t.TempDir(), "missing.crt")}},
+		{"CA file without certificates", HTTPConfig{CAFile: writeTestFile(t, "empty.crt", []byte("not a certificate"))}},
+		{"missing client certificate", HTTPConfig{CertFile: "missing.crt", KeyFile: "missing.key"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mc, err := NewMetricsCollector("https://{{.NodeIP}}:10250")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := mc.SetHTTPConfig(tt.config); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestMetricsCollector_NodeTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		_, _ = w.Write([]byte(testNodeMetrics))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	mc := newTestCollector(t, server.URL+"/{{.NodeName}}",
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "fast"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "slow"}},
+	)
+	if err := mc.SetNodeTimeout(50 * time.Millisecond); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cache, err := mc.GetAllVolumeMetrics(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	failures := cache.FailedNodes()
+	if len(failures) != 1 || failures[0].Node != "slow" || failures[0].Reason != ScrapeReasonTimeout {
+		t.Errorf("expected slow node to time out, got %v", failures)
+	}
+	if err := mc.SetNodeTimeout(0); err == nil {
+		t.Error("expected error for zero timeout")
+	}
+}
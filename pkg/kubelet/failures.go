@@ -0,0 +1,130 @@
+package kubelet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Reasons a node scrape failed, reported in logs and metrics.
+const (
+	ScrapeReasonTimeout      = "timeout"
+	ScrapeReasonUnauthorized = "unauthorized"
+	ScrapeReasonNotFound     = "not_found"
+	ScrapeReasonUnavailable  = "unavailable"
+	ScrapeReasonParseError   = "parse_error"
+	ScrapeReasonError        = "error"
+)
+
+// NodeScrapeError records why the volume stats of a node could not be read.
+type NodeScrapeError struct {
+	Node   string
+	Reason string
+	Err    error
+}
+
+func (e *NodeScrapeError) Error() string {
+	return fmt.Sprintf("failed to get metrics from node %s (%s): %v", e.Node, e.Reason, e.Err)
+}
+
+func (e *NodeScrapeError) Unwrap() error {
+	return e.Err
+}
+
+// statusCodeError is returned for non-200 responses of a custom kubelet URL.
+type statusCodeError struct {
+	StatusCode int
+}
+
+func (e *statusCodeError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// classifyScrapeError maps a kubelet request error to a scrape failure reason.
+func classifyScrapeError(err error) string {
+	var netErr net.Error
+	var statusErr *statusCodeError
+	if errors.As(err, &statusErr) {
+		switch {
+		case statusErr.StatusCode == http.StatusUnauthorized, statusErr.StatusCode == http.StatusForbidden:
+			return ScrapeReasonUnauthorized
+		case statusErr.StatusCode == http.StatusNotFound:
+			return ScrapeReasonNotFound
+		case statusErr.StatusCode == http.StatusTooManyRequests, statusErr.StatusCode >= http.StatusInternalServerError:
+			return ScrapeReasonUnavailable
+		}
+		return ScrapeReasonError
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), apierrors.IsTimeout(err), apierrors.IsServerTimeout(err):
+		return ScrapeReasonTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ScrapeReasonTimeout
+	case apierrors.IsUnauthorized(err), apierrors.IsForbidden(err):
+		return ScrapeReasonUnauthorized
+	case apierrors.IsNotFound(err):
+		return ScrapeReasonNotFound
+	case apierrors.IsServiceUnavailable(err), apierrors.IsInternalError(err), apierrors.IsTooManyRequests(err):
+		return ScrapeReasonUnavailable
+	case errors.As(err, &netErr):
+		return ScrapeReasonUnavailable
+	}
+	return ScrapeReasonError
+}
+
+func (cache *MetricsCache) recordFailure(failure *NodeScrapeError) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if cache.failedNodes == nil {
+		cache.failedNodes = make(map[string]*NodeScrapeError)
+	}
+	cache.failedNodes[failure.Node] = failure
+}
+
+// setPVCNodes records which nodes host each PVC, so PVCs on failed nodes can be
+// told apart from PVCs that are not mounted anywhere.
+func (cache *MetricsCache) setPVCNodes(pvcNodes map[string][]string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.pvcNodes = pvcNodes
+}
+
+// FailedNodes returns the nodes that could not be scraped, sorted by name.
+func (cache *MetricsCache) FailedNodes() []*NodeScrapeError {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	failures := make([]*NodeScrapeError, 0, len(cache.failedNodes))
+	for _, failure := range cache.failedNodes {
+		failures = append(failures, failure)
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		return failures[i].Node < failures[j].Node
+	})
+	return failures
+}
+
+// MetricsUnavailable returns the scrape failure of a node hosting the PVC when
+// no metrics were collected for it.
+func (cache *MetricsCache) MetricsUnavailable(namespacedName types.NamespacedName) (*NodeScrapeError, bool) {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	key := cache.keyFromNamespacedName(namespacedName)
+	if _, exists := cache.data[key]; exists || len(cache.failedNodes) == 0 {
+		return nil, false
+	}
+	for _, node := range cache.pvcNodes[key] {
+		if failure, failed := cache.failedNodes[node]; failed {
+			return failure, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,149 @@
+package kubelet
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testNodeMetrics = `# HELP kubelet_volume_stats_capacity_bytes Capacity in bytes of the volume
+# TYPE kubelet_volume_stats_capacity_bytes gauge
+kubelet_volume_stats_capacity_bytes{namespace="test-ns",persistentvolumeclaim="test-pvc"} 1073741824
+# HELP kubelet_volume_stats_available_bytes Number of available bytes in the volume
+# TYPE kubelet_volume_stats_available_bytes gauge
+kubelet_volume_stats_available_bytes{namespace="test-ns",persistentvolumeclaim="test-pvc"} 536870912
+`
+
+func newPodWithPVC(name, node, claim string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-ns"},
+		Spec: corev1.PodSpec{
+			NodeName: node,
+			Volumes: []corev1.Volume{{
+				Name: "data",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim},
+				},
+			}},
+		},
+	}
+}
+
+func newFailureTestCollector(t *testing.T, handler http.HandlerFunc, objects ...client.Object) *MetricsCollector {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return newTestCollector(t, server.URL+"/{{.NodeName}}", objects...)
+}
+
+func newTestCollector(t *testing.T, kubeletURL string, objects ...client.Object) *MetricsCollector {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+	mc, err := NewMetricsCollector(kubeletURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mc.SetClient(fakeClient, nil)
+	return mc
+}
+
+func TestGetAllVolumeMetrics_PartialFailure(t *testing.T) {
+	mc := newFailureTestCollector(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/node-b" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(testNodeMetrics))
+	},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-b"}},
+		newPodWithPVC("reader-a", "node-a", "shared-pvc"),
+		newPodWithPVC("reader-b", "node-b", "shared-pvc"),
+	)
+
+	cache, err := mc.GetAllVolumeMetrics(context.Background())
+	if err != nil {
+		t.Fatalf("expected partial results, got error: %v", err)
+	}
+
+	if _, exists := cache.Get(types.NamespacedName{Namespace: "test-ns", Name: "test-pvc"}); !exists {
+		t.Error("expected metrics from the healthy node")
+	}
+
+	failures := cache.FailedNodes()
+	if len(failures) != 1 || failures[0].Node != "node-b" {
+		t.Fatalf("expected node-b to fail, got %v", failures)
+	}
+	if failures[0].Reason != ScrapeReasonUnavailable {
+		t.Errorf("expected reason %s, got %s", ScrapeReasonUnavailable, failures[0].Reason)
+	}
+
+	failure, unavailable := cache.MetricsUnavailable(types.NamespacedName{Namespace: "test-ns", Name: "shared-pvc"})
+	if !unavailable {
+		t.Fatal("expected PVC mounted on the failed node to be unavailable")
+	}
+	if failure.Node != "node-b" {
+		t.Errorf("expected failure of node-b, got %s", failure.Node)
+	}
+	if _, unavailable := cache.MetricsUnavailable(types.NamespacedName{Namespace: "test-ns", Name: "test-pvc"}); unavailable {
+		t.Error("expected PVC with metrics not to be unavailable")
+	}
+	if _, unavailable := cache.MetricsUnavailable(types.NamespacedName{Namespace: "test-ns", Name: "unmounted-pvc"}); unavailable {
+		t.Error("expected unmounted PVC not to be unavailable")
+	}
+}
+
+func TestGetAllVolumeMetrics_AllNodesFail(t *testing.T) {
+	mc := newFailureTestCollector(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-b"}},
+	)
+
+	if _, err := mc.GetAllVolumeMetrics(context.Background()); err == nil {
+		t.Error("expected error when no node could be scraped")
+	}
+}
+
+func TestClassifyScrapeError(t *testing.T) {
+	resource := schema.GroupResource{Resource: "nodes"}
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"deadline", fmt.Errorf("request: %w", context.DeadlineExceeded), ScrapeReasonTimeout},
+		{"api timeout", apierrors.NewTimeoutError("slow", 1), ScrapeReasonTimeout},
+		{"forbidden", apierrors.NewForbidden(resource, "node-a", fmt.Errorf("denied")), ScrapeReasonUnauthorized},
+		{"not found", apierrors.NewNotFound(resource, "node-a"), ScrapeReasonNotFound},
+		{"service unavailable", apierrors.NewServiceUnavailable("down"), ScrapeReasonUnavailable},
+		{"status 401", &statusCodeError{StatusCode: http.StatusUnauthorized}, ScrapeReasonUnauthorized},
+		{"status 502", &statusCodeError{StatusCode: http.StatusBadGateway}, ScrapeReasonUnavailable},
+		{"status 400", &statusCodeError{StatusCode: http.StatusBadRequest}, ScrapeReasonError},
+		{"other", fmt.Errorf("boom"), ScrapeReasonError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyScrapeError(tt.err); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
@@ -10,4 +10,11 @@ type MetricsCollectorInterface interface {
 	GetAllVolumeMetrics(ctx context.Context) (*MetricsCache, error)
 }
 
+// NodeScopedMetricsCollector is implemented by collectors that can limit
+// scraping to the nodes hosting a given set of PVCs.
+type NodeScopedMetricsCollector interface {
+	GetVolumeMetricsForPVCs(ctx context.Context, pvcs []types.NamespacedName) (*MetricsCache, error)
+}
+
 var _ MetricsCollectorInterface = (*MetricsCollector)(nil)
+var _ NodeScopedMetricsCollector = (*MetricsCollector)(nil)
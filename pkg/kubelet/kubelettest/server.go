@@ -0,0 +1,395 @@
+// Package kubelettest provides an in-process fake kubelet for tests. It serves
+// the volume stats of several nodes in the kubelet /metrics and /stats/summary
+// formats, with scripted volume growth and per-node faults.
+package kubelettest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/proto"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Endpoints served for every node, below /<node name>/.
+const (
+	EndpointMetrics = "metrics"
+	EndpointSummary = "stats/summary"
+)
+
+// GrowthFunc returns the used bytes of a volume, given the time elapsed since
+// the volume was added and the number of times its node was scraped before.
+type GrowthFunc func(elapsed time.Duration, scrape int) int64
+
+// Linear grows from startBytes by bytesPerHour.
+func Linear(startBytes, bytesPerHour int64) GrowthFunc {
+	return func(elapsed time.Duration, _ int) int64 {
+		return startBytes + int64(elapsed.Hours()*float64(bytesPerHour))
+	}
+}
+
+// Steps reports one value per scrape and keeps the last one afterwards.
+func Steps(usedBytes ...int64) GrowthFunc {
+	return func(_ time.Duration, scrape int) int64 {
+		if len(usedBytes) == 0 {
+			return 0
+		}
+		return usedBytes[min(scrape, len(usedBytes)-1)]
+	}
+}
+
+// Volume is a PVC mounted on a node.
+type Volume struct {
+	Namespace string
+	PVC       string
+	// PodName defaults to "<PVC>-pod".
+	PodName       string
+	CapacityBytes int64
+	UsedBytes     int64
+	// Growth overrides UsedBytes when set. The result is capped at
+	// CapacityBytes.
+	Growth      GrowthFunc
+	InodesTotal int64
+	InodesUsed  int64
+
+	addedAt time.Time
+}
+
+// NamespacedName returns the PVC of the volume.
+func (v *Volume) NamespacedName() types.NamespacedName {
+	return types.NamespacedName{Namespace: v.Namespace, Name: v.PVC}
+}
+
+func (v *Volume) podName() string {
+	if v.PodName != "" {
+		return v.PodName
+	}
+	return v.PVC + "-pod"
+}
+
+// Fault changes how a node responds.
+type Fault struct {
+	// Latency delays every response, honouring request cancellation.
+	Latency time.Duration
+	// StatusCode responds with this status and no body when non-zero.
+	StatusCode int
+	// Malformed responds with a payload that cannot be parsed.
+	Malformed bool
+}
+
+type node struct {
+	volumes []*Volume
+	fault   Fault
+	scrapes int
+}
+
+// Server is a fake kubelet for any number of nodes. The zero value is not
+// usable, create servers with NewServer.
+type Server struct {
+	*httptest.Server
+
+	// Now returns the time used for growth and sample timestamps.
+	Now func() time.Time
+
+	mutex sync.Mutex
+	nodes map[string]*node
+}
+
+// NewServer starts a fake kubelet for the given nodes. The caller should call
+// Close when finished.
+func NewServer(nodeNames ...string) *Server {
+	s := &Server{Now: time.Now, nodes: make(map[string]*node, len(nodeNames))}
+	for _, name := range nodeNames {
+		s.nodes[name] = &node{}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{node}/"+EndpointMetrics, s.serveMetrics)
+	mux.HandleFunc("GET /{node}/"+EndpointSummary, s.serveSummary)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// URLTemplate returns the kubelet URL template of an endpoint, for
+// kubelet.NewMetricsCollector.
+func (s *Server) URLTemplate(endpoint string) string {
+	return s.URL + "/{{.NodeName}}/" + endpoint
+}
+
+// AddNode adds a node without volumes.
+func (s *Server) AddNode(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.nodes[name]; !ok {
+		s.nodes[name] = &node{}
+	}
+}
+
+// AddVolume mounts a volume on a node, adding the node when needed. A PVC
+// added to several nodes is reported by each of them.
+func (s *Server) AddVolume(nodeName string, volume Volume) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	n, ok := s.nodes[nodeName]
+	if !ok {
+		n = &node{}
+		s.nodes[nodeName] = n
+	}
+	volume.addedAt = s.Now()
+	n.volumes = append(n.volumes, &volume)
+}
+
+// SetUsage changes the used bytes of a volume on every node and drops its
+// growth function.
+func (s *Server) SetUsage(pvc types.NamespacedName, usedBytes int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, n := range s.nodes {
+		for _, v := range n.volumes {
+			if v.NamespacedName() == pvc {
+				v.UsedBytes = usedBytes
+				v.Growth = nil
+			}
+		}
+	}
+}
+
+// SetCapacity changes the capacity of a volume on every node, e.g. once the
+// PVC was expanded.
+func (s *Server) SetCapacity(pvc types.NamespacedName, capacityBytes int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, n := range s.nodes {
+		for _, v := range n.volumes {
+			if v.NamespacedName() == pvc {
+				v.CapacityBytes = capacityBytes
+			}
+		}
+	}
+}
+
+// SetFault changes how a node responds. The zero Fault clears it.
+func (s *Server) SetFault(nodeName string, fault Fault) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if n, ok := s.nodes[nodeName]; ok {
+		n.fault = fault
+	}
+}
+
+// Scrapes returns how often a node was scraped, including failed scrapes.
+func (s *Server) Scrapes(nodeName string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if n, ok := s.nodes[nodeName]; ok {
+		return n.scrapes
+	}
+	return 0
+}
+
+// Objects returns the nodes and the pods mounting their volumes, for a fake
+// client or an envtest API server.
+func (s *Server) Objects() []client.Object {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var objects []client.Object
+	for _, name := range s.nodeNames() {
+		objects = append(objects, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}})
+		for _, v := range s.nodes[name].volumes {
+			objects = append(objects, &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: v.podName(), Namespace: v.Namespace},
+				Spec: corev1.PodSpec{
+					NodeName:   name,
+					Containers: []corev1.Container{{Name: "app", Image: "busybox"}},
+					Volumes: []corev1.Volume{{
+						Name: "data",
+						VolumeSource: corev1.VolumeSource{
+							PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: v.PVC},
+						},
+					}},
+				},
+			})
+		}
+	}
+	return objects
+}
+
+func (s *Server) nodeNames() []string {
+	names := make([]string, 0, len(s.nodes))
+	for name := range s.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sample is the state of a volume at scrape time.
+type sample struct {
+	volume    *Volume
+	usedBytes int64
+}
+
+// scrape applies the fault of a node and returns its samples. It returns false
+// when the response was already written.
+func (s *Server) scrape(w http.ResponseWriter, r *http.Request) ([]sample, time.Time, bool) {
+	s.mutex.Lock()
+	n, ok := s.nodes[r.PathValue("node")]
+	if !ok {
+		s.mutex.Unlock()
+		http.NotFound(w, r)
+		return nil, time.Time{}, false
+	}
+	scrape := n.scrapes
+	n.scrapes++
+	fault := n.fault
+	now := s.Now()
+	samples := make([]sample, 0, len(n.volumes))
+	for _, v := range n.volumes {
+		used := v.UsedBytes
+		if v.Growth != nil {
+			used = v.Growth(now.Sub(v.addedAt), scrape)
+		}
+		samples = append(samples, sample{volume: v, usedBytes: min(max(used, 0), v.CapacityBytes)})
+	}
+	s.mutex.Unlock()
+
+	if fault.Latency > 0 {
+		select {
+		case <-time.After(fault.Latency):
+		case <-r.Context().Done():
+			return nil, now, false
+		}
+	}
+	if fault.StatusCode != 0 {
+		w.WriteHeader(fault.StatusCode)
+		return nil, now, false
+	}
+	if fault.Malformed {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_, _ = fmt.Fprintln(w, "kubelet_volume_stats_capacity_bytes{namespace=")
+		return nil, now, false
+	}
+	return samples, now, true
+}
+
+func (s *Server) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	samples, _, ok := s.scrape(w, r)
+	if !ok {
+		return
+	}
+
+	families := map[string]*dto.MetricFamily{}
+	add := func(name string, v *Volume, value int64) {
+		family, ok := families[name]
+		if !ok {
+			family = &dto.MetricFamily{Name: proto.String(name), Type: dto.MetricType_GAUGE.Enum()}
+			families[name] = family
+		}
+		family.Metric = append(family.Metric, &dto.Metric{
+			Label: []*dto.LabelPair{
+				{Name: proto.String("namespace"), Value: proto.String(v.Namespace)},
+				{Name: proto.String("persistentvolumeclaim"), Value: proto.String(v.PVC)},
+			},
+			Gauge: &dto.Gauge{Value: proto.Float64(float64(value))},
+		})
+	}
+	for _, sample := range samples {
+		v := sample.volume
+		add("kubelet_volume_stats_capacity_bytes", v, v.CapacityBytes)
+		add("kubelet_volume_stats_available_bytes", v, v.CapacityBytes-sample.usedBytes)
+		add("kubelet_volume_stats_used_bytes", v, sample.usedBytes)
+		if v.InodesTotal > 0 {
+			add("kubelet_volume_stats_inodes", v, v.InodesTotal)
+			add("kubelet_volume_stats_inodes_free", v, v.InodesTotal-v.InodesUsed)
+			add("kubelet_volume_stats_inodes_used", v, v.InodesUsed)
+		}
+	}
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	format := expfmt.Negotiate(r.Header)
+	w.Header().Set("Content-Type", string(format))
+	encoder := expfmt.NewEncoder(w, format)
+	for _, name := range names {
+		if err := encoder.Encode(families[name]); err != nil {
+			return
+		}
+	}
+}
+
+type summaryResponse struct {
+	Node struct {
+		NodeName string `json:"nodeName"`
+	} `json:"node"`
+	Pods []summaryPod `json:"pods"`
+}
+
+type summaryPod struct {
+	PodRef struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"podRef"`
+	Volumes []summaryVolume `json:"volume"`
+}
+
+type summaryVolume struct {
+	Time           time.Time `json:"time"`
+	AvailableBytes uint64    `json:"availableBytes"`
+	CapacityBytes  uint64    `json:"capacityBytes"`
+	UsedBytes      uint64    `json:"usedBytes"`
+	InodesFree     *uint64   `json:"inodesFree,omitempty"`
+	Inodes         *uint64   `json:"inodes,omitempty"`
+	InodesUsed     *uint64   `json:"inodesUsed,omitempty"`
+	Name           string    `json:"name"`
+	PVCRef         struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"pvcRef"`
+}
+
+func (s *Server) serveSummary(w http.ResponseWriter, r *http.Request) {
+	samples, now, ok := s.scrape(w, r)
+	if !ok {
+		return
+	}
+
+	var response summaryResponse
+	response.Node.NodeName = r.PathValue("node")
+	response.Pods = []summaryPod{}
+	for _, sample := range samples {
+		v := sample.volume
+		volume := summaryVolume{
+			Time:           now.UTC().Truncate(time.Second),
+			AvailableBytes: uint64(v.CapacityBytes - sample.usedBytes),
+			CapacityBytes:  uint64(v.CapacityBytes),
+			UsedBytes:      uint64(sample.usedBytes),
+			Name:           "data",
+		}
+		if v.InodesTotal > 0 {
+			inodes, used, free := uint64(v.InodesTotal), uint64(v.InodesUsed), uint64(v.InodesTotal-v.InodesUsed)
+			volume.Inodes, volume.InodesUsed, volume.InodesFree = &inodes, &used, &free
+		}
+		volume.PVCRef.Name, volume.PVCRef.Namespace = v.PVC, v.Namespace
+
+		var pod summaryPod
+		pod.PodRef.Name, pod.PodRef.Namespace = v.podName(), v.Namespace
+		pod.Volumes = []summaryVolume{volume}
+		response.Pods = append(response.Pods, pod)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
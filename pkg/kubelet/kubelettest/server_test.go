@@ -0,0 +1,165 @@
+package kubelettest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/logicIQ/pvc-chonker/pkg/kubelet"
+)
+
+const gib = int64(1 << 30)
+
+func newCollector(t *testing.T, server *Server, endpoint string) *kubelet.MetricsCollector {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(server.Objects()...).Build()
+
+	mc, err := kubelet.NewMetricsCollector(server.URLTemplate(endpoint))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mc.SetClient(fakeClient, nil)
+	if err := mc.SetEndpoint(endpoint); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return mc
+}
+
+func TestServer_Endpoints(t *testing.T) {
+	data := types.NamespacedName{Namespace: "default", Name: "data"}
+	for _, endpoint := range []string{EndpointMetrics, EndpointSummary} {
+		t.Run(endpoint, func(t *testing.T) {
+			server := NewServer("node-a", "node-b")
+			defer server.Close()
+			server.AddVolume("node-a", Volume{Namespace: "default", PVC: "data", CapacityBytes: 10 * gib, UsedBytes: 8 * gib, InodesTotal: 1000, InodesUsed: 250})
+			mc := newCollector(t, server, endpoint)
+
+			cache, err := mc.GetAllVolumeMetrics(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			vm, ok := cache.Get(data)
+			if !ok {
+				t.Fatal("expected metrics for default/data")
+			}
+			if vm.CapacityBytes != 10*gib || vm.UsedBytes != 8*gib || vm.UsagePercent != 80 || vm.InodesUsagePercent != 25 || vm.NodeName != "node-a" {
+				t.Errorf("unexpected metrics %+v", vm)
+			}
+			if server.Scrapes("node-b") != 1 {
+				t.Errorf("expected node-b to be scraped once, got %d", server.Scrapes("node-b"))
+			}
+		})
+	}
+}
+
+func TestServer_TextFormat(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.AddVolume("node-a", Volume{Namespace: "default", PVC: "data", CapacityBytes: 100, UsedBytes: 40})
+
+	resp, err := http.Get(server.URL + "/node-a/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), `kubelet_volume_stats_available_bytes{namespace="default",persistentvolumeclaim="data"} 60`) {
+		t.Errorf("expected text exposition format, got:\n%s", body)
+	}
+
+	resp, err = http.Get(server.URL + "/node-c/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown node, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_Growth(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	server := NewServer()
+	defer server.Close()
+	server.Now = func() time.Time { return now }
+	server.AddVolume("node-a", Volume{Namespace: "default", PVC: "steps", CapacityBytes: 100, Growth: Steps(10, 50, 120)})
+	server.AddVolume("node-a", Volume{Namespace: "default", PVC: "linear", CapacityBytes: 100, Growth: Linear(20, 10)})
+	mc := newCollector(t, server, EndpointMetrics)
+
+	steps := types.NamespacedName{Namespace: "default", Name: "steps"}
+	linear := types.NamespacedName{Namespace: "default", Name: "linear"}
+	expected := []struct {
+		steps, linear int64
+	}{{10, 20}, {50, 40}, {100, 60}, {100, 80}}
+	for i, want := range expected {
+		cache, err := mc.GetAllVolumeMetrics(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if vm, _ := cache.Get(steps); vm == nil || vm.UsedBytes != want.steps {
+			t.Errorf("scrape %d: expected %d used bytes for steps, got %+v", i, want.steps, vm)
+		}
+		if vm, _ := cache.Get(linear); vm == nil || vm.UsedBytes != want.linear {
+			t.Errorf("scrape %d: expected %d used bytes for linear, got %+v", i, want.linear, vm)
+		}
+		now = now.Add(2 * time.Hour)
+	}
+
+	server.SetCapacity(steps, 200)
+	server.SetUsage(steps, 150)
+	cache, err := mc.GetAllVolumeMetrics(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vm, _ := cache.Get(steps); vm == nil || vm.UsagePercent != 75 {
+		t.Errorf("expected 75%% usage after expansion, got %+v", vm)
+	}
+}
+
+func TestServer_Faults(t *testing.T) {
+	tests := []struct {
+		name   string
+		fault  Fault
+		reason string
+	}{
+		{"status code", Fault{StatusCode: http.StatusServiceUnavailable}, kubelet.ScrapeReasonUnavailable},
+		{"latency", Fault{Latency: time.Second}, kubelet.ScrapeReasonTimeout},
+		{"malformed", Fault{Malformed: true}, kubelet.ScrapeReasonParseError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer()
+			defer server.Close()
+			server.AddVolume("node-a", Volume{Namespace: "default", PVC: "data", CapacityBytes: 100, UsedBytes: 40})
+			server.AddVolume("node-b", Volume{Namespace: "default", PVC: "logs", CapacityBytes: 100, UsedBytes: 40})
+			server.SetFault("node-b", tt.fault)
+			mc := newCollector(t, server, EndpointMetrics)
+			if err := mc.SetNodeTimeout(100 * time.Millisecond); err != nil {
+				t.Fatal(err)
+			}
+
+			cache, err := mc.GetAllVolumeMetrics(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, ok := cache.Get(types.NamespacedName{Namespace: "default", Name: "data"}); !ok {
+				t.Error("expected metrics of the healthy node")
+			}
+			failure, unavailable := cache.MetricsUnavailable(types.NamespacedName{Namespace: "default", Name: "logs"})
+			if !unavailable || failure.Reason != tt.reason {
+				t.Errorf("expected failure reason %q, got %+v", tt.reason, failure)
+			}
+		})
+	}
+}
@@ -22,6 +22,33 @@ type VolumeMetrics struct {
 	InodesUsed         int64
 	InodesFree         int64
 	InodesUsagePercent float64
+	// NodeName and PodName attribute the sample to the node it was read from
+	// and, when known, a pod mounting the volume. Timestamp is when the kubelet
+	// collected the sample, or the scrape time when it does not report one.
+	// Source is one of the Source constants.
+	NodeName  string
+	PodName   string
+	Timestamp time.Time
+	Source    string
+	// MountedNodes lists the nodes the volume is mounted on, more than one
+	// for ReadWriteMany volumes. Empty when the source does not report nodes.
+	MountedNodes []string
+}
+
+// Sources volume metrics are read from.
+const (
+	SourceKubeletMetrics = "kubelet_metrics"
+	SourceKubeletSummary = "kubelet_summary"
+	SourcePrometheus     = "prometheus"
+)
+
+// Age returns how old the sample is at now, or zero when its collection time
+// is unknown.
+func (vm *VolumeMetrics) Age(now time.Time) time.Duration {
+	if vm == nil || vm.Timestamp.IsZero() || now.Before(vm.Timestamp) {
+		return 0
+	}
+	return now.Sub(vm.Timestamp)
 }
 
 func (mc *MetricsCollector) GetVolumeMetrics(ctx context.Context, namespacedName types.NamespacedName) (*VolumeMetrics, error) {
@@ -2,6 +2,7 @@ package kubelet
 
 import (
 	"testing"
+	"time"
 
 	"k8s.io/apimachinery/pkg/types"
 )
@@ -70,3 +71,25 @@ kubelet_volume_stats_available_bytes{namespace="test-ns",persistentvolumeclaim="
 		t.Errorf("expected inodes usage percent 0, got %f", metrics.InodesUsagePercent)
 	}
 }
+
+func TestVolumeMetricsAge(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		vm       *VolumeMetrics
+		expected time.Duration
+	}{
+		{"nil metrics", nil, 0},
+		{"unknown timestamp", &VolumeMetrics{}, 0},
+		{"old sample", &VolumeMetrics{Timestamp: now.Add(-3 * time.Minute)}, 3 * time.Minute},
+		{"clock skew", &VolumeMetrics{Timestamp: now.Add(time.Minute)}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.vm.Age(now); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package kubelet
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/logicIQ/pvc-chonker/pkg/metrics"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// GetVolumeMetricsForPVCs scrapes only the nodes running pods that mount one of
+// the given PVCs. Kubelet only reports stats for mounted volumes, so PVCs that
+// are not mounted anywhere are simply absent from the returned cache.
+func (mc *MetricsCollector) GetVolumeMetricsForPVCs(ctx context.Context, pvcs []types.NamespacedName) (*MetricsCache, error) {
+	startTime := time.Now()
+	defer func() {
+		metrics.KubeletClientResponseTime.Observe(time.Since(startTime).Seconds())
+	}()
+
+	pods, err := mc.podsForPVCs(ctx, pvcs)
+	if err != nil {
+		return nil, err
+	}
+
+	pvcNodes := BuildPVCNodeMap(pods)
+	nodeNames := NodesForPVCs(pvcNodes, pvcs)
+
+	cache := NewMetricsCache()
+	cache.setPVCNodes(pvcNodes)
+	if len(nodeNames) == 0 {
+		metrics.KubeletClientScrapedNodes.Set(0)
+		return cache, nil
+	}
+
+	rec := mc.recorder.begin(mc.endpoint, startTime)
+	err = mc.fetchNodesMetrics(ctx, nodeNames, cache, rec)
+	mc.finishRecording(ctx, rec, cache)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.mergeSamples(mc.dedupeStrategy)
+	cache.calculateUsagePercentages()
+	return cache, nil
+}
+
+// BuildPVCNodeMap maps each PVC key ("namespace/name") to the sorted names of
+// the nodes running non-terminated pods that mount it.
+func BuildPVCNodeMap(pods []corev1.Pod) map[string][]string {
+	nodeSets := make(map[string]map[string]struct{})
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim == nil {
+				continue
+			}
+			key := types.NamespacedName{Namespace: pod.Namespace, Name: volume.PersistentVolumeClaim.ClaimName}.String()
+			if nodeSets[key] == nil {
+				nodeSets[key] = make(map[string]struct{})
+			}
+			nodeSets[key][pod.Spec.NodeName] = struct{}{}
+		}
+	}
+
+	result := make(map[string][]string, len(nodeSets))
+	for key, nodes := range nodeSets {
+		result[key] = sortedKeys(nodes)
+	}
+	return result
+}
+
+// NodesForPVCs returns the sorted, de-duplicated nodes hosting any of the PVCs.
+func NodesForPVCs(pvcNodes map[string][]string, pvcs []types.NamespacedName) []string {
+	nodes := make(map[string]struct{})
+	for _, pvc := range pvcs {
+		for _, node := range pvcNodes[pvc.String()] {
+			nodes[node] = struct{}{}
+		}
+	}
+	return sortedKeys(nodes)
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,58 @@
+package kubelet
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func newPVCPod(namespace, name, nodeName string, phase corev1.PodPhase, claims ...string) corev1.Pod {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       corev1.PodSpec{NodeName: nodeName},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+	for _, claim := range claims {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name: claim,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim},
+			},
+		})
+	}
+	return pod
+}
+
+func TestBuildPVCNodeMap(t *testing.T) {
+	pods := []corev1.Pod{
+		newPVCPod("default", "db-0", "node-b", corev1.PodRunning, "data-db-0"),
+		newPVCPod("default", "shared-1", "node-a", corev1.PodRunning, "shared"),
+		newPVCPod("default", "shared-2", "node-c", corev1.PodRunning, "shared"),
+		newPVCPod("default", "job", "node-d", corev1.PodSucceeded, "scratch"),
+		newPVCPod("default", "pending", "", corev1.PodPending, "pending-data"),
+		newPVCPod("other", "db-0", "node-e", corev1.PodRunning, "data-db-0"),
+	}
+
+	pvcNodes := BuildPVCNodeMap(pods)
+
+	expected := map[string][]string{
+		"default/data-db-0": {"node-b"},
+		"default/shared":    {"node-a", "node-c"},
+		"other/data-db-0":   {"node-e"},
+	}
+	if !reflect.DeepEqual(pvcNodes, expected) {
+		t.Errorf("expected %v, got %v", expected, pvcNodes)
+	}
+
+	nodes := NodesForPVCs(pvcNodes, []types.NamespacedName{
+		{Namespace: "default", Name: "shared"},
+		{Namespace: "other", Name: "data-db-0"},
+		{Namespace: "default", Name: "not-mounted"},
+	})
+	if expectedNodes := []string{"node-a", "node-c", "node-e"}; !reflect.DeepEqual(nodes, expectedNodes) {
+		t.Errorf("expected nodes %v, got %v", expectedNodes, nodes)
+	}
+}
@@ -0,0 +1,101 @@
+package kubelet
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PodPVCIndex indexes scheduled, non-terminated pods by the names of the PVCs
+// they mount.
+const PodPVCIndex = "spec.volumes.persistentVolumeClaim.claimName"
+
+// IndexPodPVCs is the index function for PodPVCIndex.
+func IndexPodPVCs(obj client.Object) []string {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return nil
+	}
+
+	var claims []string
+	for _, volume := range pod.Spec.Volumes {
+		if volume.PersistentVolumeClaim != nil {
+			claims = append(claims, volume.PersistentVolumeClaim.ClaimName)
+		}
+	}
+	return claims
+}
+
+// TrimPod is a cache transform that keeps only the pod fields needed to map
+// PVCs to nodes, so caching every pod in a large cluster stays cheap.
+func TrimPod(obj interface{}) (interface{}, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return obj, nil
+	}
+
+	var volumes []corev1.Volume
+	for _, volume := range pod.Spec.Volumes {
+		if volume.PersistentVolumeClaim != nil {
+			volumes = append(volumes, corev1.Volume{
+				Name:         volume.Name,
+				VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: volume.PersistentVolumeClaim},
+			})
+		}
+	}
+
+	return &corev1.Pod{
+		TypeMeta: pod.TypeMeta,
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            pod.Name,
+			Namespace:       pod.Namespace,
+			UID:             pod.UID,
+			ResourceVersion: pod.ResourceVersion,
+		},
+		Spec:   corev1.PodSpec{NodeName: pod.Spec.NodeName, Volumes: volumes},
+		Status: corev1.PodStatus{Phase: pod.Status.Phase},
+	}, nil
+}
+
+// IndexPods registers PodPVCIndex so the collector looks up the pods of each
+// PVC instead of listing every pod in the cluster.
+func (mc *MetricsCollector) IndexPods(ctx context.Context, indexer client.FieldIndexer) error {
+	if err := indexer.IndexField(ctx, &corev1.Pod{}, PodPVCIndex, IndexPodPVCs); err != nil {
+		return fmt.Errorf("failed to index pods by PVC: %w", err)
+	}
+	mc.podsIndexed = true
+	return nil
+}
+
+// podsForPVCs returns the pods mounting any of the given PVCs, using the pod
+// index when it is registered.
+func (mc *MetricsCollector) podsForPVCs(ctx context.Context, pvcs []types.NamespacedName) ([]corev1.Pod, error) {
+	if !mc.podsIndexed {
+		var pods corev1.PodList
+		if err := mc.client.List(ctx, &pods); err != nil {
+			return nil, fmt.Errorf("failed to list pods: %w", err)
+		}
+		return pods.Items, nil
+	}
+
+	seen := make(map[string]struct{})
+	var result []corev1.Pod
+	for _, pvc := range pvcs {
+		var pods corev1.PodList
+		if err := mc.client.List(ctx, &pods, client.InNamespace(pvc.Namespace), client.MatchingFields{PodPVCIndex: pvc.Name}); err != nil {
+			return nil, fmt.Errorf("failed to list pods of PVC %s/%s: %w", pvc.Namespace, pvc.Name, err)
+		}
+		for _, pod := range pods.Items {
+			key := pod.Namespace + "/" + pod.Name
+			if _, exists := seen[key]; !exists {
+				seen[key] = struct{}{}
+				result = append(result, pod)
+			}
+		}
+	}
+	return result, nil
+}
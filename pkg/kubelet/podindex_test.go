@@ -0,0 +1,151 @@
+package kubelet
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestIndexPodPVCs(t *testing.T) {
+	running := newPodWithPVC("app", "node-a", "data")
+	running.Spec.Volumes = append(running.Spec.Volumes, corev1.Volume{Name: "config", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}})
+	unscheduled := newPodWithPVC("pending", "", "data")
+	completed := newPodWithPVC("job", "node-a", "data")
+	completed.Status.Phase = corev1.PodSucceeded
+
+	tests := []struct {
+		name     string
+		pod      client.Object
+		expected []string
+	}{
+		{"running pod", running, []string{"data"}},
+		{"unscheduled pod", unscheduled, nil},
+		{"completed pod", completed, nil},
+		{"not a pod", &corev1.Node{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := IndexPodPVCs(tt.pod)
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTrimPod(t *testing.T) {
+	pod := newPodWithPVC("app", "node-a", "data")
+	pod.Labels = map[string]string{"app": "web"}
+	pod.Spec.Containers = []corev1.Container{{Name: "web", Image: "nginx"}}
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{Name: "config", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}})
+	pod.Status.Phase = corev1.PodRunning
+
+	obj, err := TrimPod(pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	trimmed := obj.(*corev1.Pod)
+
+	if trimmed.Name != "app" || trimmed.Namespace != "test-ns" || trimmed.Spec.NodeName != "node-a" || trimmed.Status.Phase != corev1.PodRunning {
+		t.Errorf("expected identifying fields to be kept, got %+v", trimmed)
+	}
+	if len(trimmed.Labels) != 0 || len(trimmed.Spec.Containers) != 0 {
+		t.Error("expected labels and containers to be dropped")
+	}
+	if len(trimmed.Spec.Volumes) != 1 || trimmed.Spec.Volumes[0].PersistentVolumeClaim.ClaimName != "data" {
+		t.Errorf("expected only the PVC volume, got %v", trimmed.Spec.Volumes)
+	}
+}
+
+func TestGetVolumeMetricsForPVCs_ScrapesOnlyHostingNodes(t *testing.T) {
+	var mutex sync.Mutex
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requested = append(requested, r.URL.Path)
+		mutex.Unlock()
+		_, _ = w.Write([]byte(testNodeMetrics))
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithIndex(&corev1.Pod{}, PodPVCIndex, IndexPodPVCs).
+		WithObjects(
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}},
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-b"}},
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-c"}},
+			newPodWithPVC("app", "node-a", "test-pvc"),
+			newPodWithPVC("other", "node-b", "unmanaged-pvc"),
+		).
+		Build()
+
+	mc, err := NewMetricsCollector(server.URL + "/{{.NodeName}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mc.SetClient(fakeClient, nil)
+	mc.podsIndexed = true
+
+	cache, err := mc.GetVolumeMetricsForPVCs(context.Background(), []types.NamespacedName{{Namespace: "test-ns", Name: "test-pvc"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requested) != 1 || requested[0] != "/node-a" {
+		t.Errorf("expected only node-a to be scraped, got %v", requested)
+	}
+	if _, exists := cache.Get(types.NamespacedName{Namespace: "test-ns", Name: "test-pvc"}); !exists {
+		t.Error("expected metrics for test-pvc")
+	}
+}
+
+func TestFetchNodesMetrics_BoundedConcurrency(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			old := peak.Load()
+			if current <= old || peak.CompareAndSwap(old, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte(testNodeMetrics))
+	}))
+	defer server.Close()
+
+	var nodes []client.Object
+	for _, name := range []string{"n1", "n2", "n3", "n4", "n5", "n6"} {
+		nodes = append(nodes, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+	mc := newTestCollector(t, server.URL+"/{{.NodeName}}", nodes...)
+	if err := mc.SetMaxConcurrency(2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := mc.GetAllVolumeMetrics(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := peak.Load(); got > 2 {
+		t.Errorf("expected at most 2 concurrent scrapes, got %d", got)
+	}
+	if err := mc.SetMaxConcurrency(0); err == nil {
+		t.Error("expected error for zero concurrency")
+	}
+}
@@ -0,0 +1,289 @@
+package kubelet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/logicIQ/pvc-chonker/pkg/metrics"
+	"golang.org/x/sync/errgroup"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// PrometheusQueries holds the PromQL queries returning one series per PVC.
+// Queries left empty are skipped, for example when inode metrics are not
+// collected.
+type PrometheusQueries struct {
+	CapacityBytes  string
+	AvailableBytes string
+	InodesTotal    string
+	InodesUsed     string
+	// Timestamp returns the Unix time of the newest underlying sample, since
+	// instant query results only carry the evaluation time.
+	Timestamp string
+}
+
+// DefaultPrometheusQueries read the kubelet volume stats scraped by Prometheus.
+// The max aggregation removes duplicates when a volume is reported by several
+// scrape targets.
+var DefaultPrometheusQueries = PrometheusQueries{
+	CapacityBytes:  "max by (namespace, persistentvolumeclaim) (kubelet_volume_stats_capacity_bytes)",
+	AvailableBytes: "max by (namespace, persistentvolumeclaim) (kubelet_volume_stats_available_bytes)",
+	InodesTotal:    "max by (namespace, persistentvolumeclaim) (kubelet_volume_stats_inodes)",
+	InodesUsed:     "max by (namespace, persistentvolumeclaim) (kubelet_volume_stats_inodes_used)",
+	Timestamp:      "max by (namespace, persistentvolumeclaim) (timestamp(kubelet_volume_stats_capacity_bytes))",
+}
+
+type PrometheusConfig struct {
+	URL     string
+	Queries PrometheusQueries
+	// NamespaceLabel and PVCLabel name the series labels holding the PVC
+	// namespace and name.
+	NamespaceLabel string
+	PVCLabel       string
+	// BearerTokenFile is read on every request so rotated tokens are picked up.
+	BearerToken     string
+	BearerTokenFile string
+	Username        string
+	Password        string
+	Timeout         time.Duration
+}
+
+// PrometheusMetricsCollector reads volume metrics from the Prometheus HTTP API
+// instead of scraping kubelets.
+type PrometheusMetricsCollector struct {
+	config     PrometheusConfig
+	httpClient *http.Client
+}
+
+var _ MetricsCollectorInterface = (*PrometheusMetricsCollector)(nil)
+
+func NewPrometheusMetricsCollector(config PrometheusConfig) (*PrometheusMetricsCollector, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("prometheus URL is required")
+	}
+	if err := validatePrometheusURL(config.URL); err != nil {
+		return nil, fmt.Errorf("invalid prometheus URL: %w", err)
+	}
+	if config.Queries.CapacityBytes == "" || config.Queries.AvailableBytes == "" {
+		return nil, fmt.Errorf("capacity and available bytes queries are required")
+	}
+	if config.NamespaceLabel == "" {
+		config.NamespaceLabel = "namespace"
+	}
+	if config.PVCLabel == "" {
+		config.PVCLabel = "persistentvolumeclaim"
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	config.URL = strings.TrimSuffix(config.URL, "/")
+
+	return &PrometheusMetricsCollector{
+		config:     config,
+		httpClient: &http.Client{Timeout: config.Timeout},
+	}, nil
+}
+
+func (pc *PrometheusMetricsCollector) GetVolumeMetrics(ctx context.Context, namespacedName types.NamespacedName) (*VolumeMetrics, error) {
+	cache, err := pc.GetAllVolumeMetrics(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	vm, exists := cache.Get(namespacedName)
+	if !exists {
+		return nil, fmt.Errorf("volume metrics not found for %s/%s", namespacedName.Namespace, namespacedName.Name)
+	}
+
+	return vm, nil
+}
+
+func (pc *PrometheusMetricsCollector) GetAllVolumeMetrics(ctx context.Context) (*MetricsCache, error) {
+	startTime := time.Now()
+	defer func() {
+		metrics.KubeletClientResponseTime.Observe(time.Since(startTime).Seconds())
+	}()
+
+	queries := []string{
+		pc.config.Queries.CapacityBytes,
+		pc.config.Queries.AvailableBytes,
+		pc.config.Queries.InodesTotal,
+		pc.config.Queries.InodesUsed,
+		pc.config.Queries.Timestamp,
+	}
+	results := make([]map[types.NamespacedName]int64, len(queries))
+
+	eg, ectx := errgroup.WithContext(ctx)
+	for i, query := range queries {
+		if query == "" {
+			continue
+		}
+		eg.Go(func() error {
+			samples, err := pc.query(ectx, query)
+			if err != nil {
+				return err
+			}
+			results[i] = samples
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	// Usage is derived from both series, so a volume missing one would look
+	// full or empty and is skipped instead
+	capacity, available, inodesTotal, inodesUsed, timestamps := results[0], results[1], results[2], results[3], results[4]
+	cache := NewMetricsCache()
+	var incomplete []string
+	for pvcName, capacityBytes := range capacity {
+		availableBytes, ok := available[pvcName]
+		if !ok {
+			incomplete = append(incomplete, pvcName.String())
+			continue
+		}
+		cache.setCapacity(pvcName, capacityBytes)
+		cache.setAvailable(pvcName, availableBytes)
+		total, hasTotal := inodesTotal[pvcName]
+		used, hasUsed := inodesUsed[pvcName]
+		if hasTotal && hasUsed {
+			cache.setInodesTotal(pvcName, total)
+			cache.setInodesUsed(pvcName, used)
+		}
+		if timestamp, ok := timestamps[pvcName]; ok {
+			cache.setTimestamp(pvcName, timestamp)
+		}
+	}
+	for pvcName := range available {
+		if _, ok := capacity[pvcName]; !ok {
+			incomplete = append(incomplete, pvcName.String())
+		}
+	}
+	if len(incomplete) > 0 {
+		log.FromContext(ctx).V(1).Info("Skipping volumes missing the capacity or available bytes series", "pvcs", incomplete)
+	}
+
+	cache.setDefaultSource(SourcePrometheus, startTime)
+	cache.calculateUsagePercentages()
+	return cache, nil
+}
+
+// validatePrometheusURL checks the Prometheus server URL. Unlike kubelet URLs,
+// Prometheus commonly runs in-cluster or behind a localhost sidecar, so only
+// cloud metadata endpoints are refused.
+func validatePrometheusURL(prometheusURL string) error {
+	parsed, err := url.Parse(prometheusURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse URL: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("only http and https schemes are allowed")
+	}
+	host := strings.ToLower(parsed.Hostname())
+	if host == "" {
+		return fmt.Errorf("URL must have a valid host")
+	}
+	if host == "169.254.169.254" || host == "metadata.google.internal" {
+		return fmt.Errorf("blocked host: %s", host)
+	}
+	return nil
+}
+
+type prometheusResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Metric map[string]string `json:"metric"`
+			Value  [2]any            `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// query runs an instant query and returns the sample value per PVC.
+func (pc *PrometheusMetricsCollector) query(ctx context.Context, query string) (map[types.NamespacedName]int64, error) {
+	form := url.Values{"query": {query}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pc.config.URL+"/api/v1/query", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := pc.authenticate(req); err != nil {
+		return nil, err
+	}
+
+	resp, err := pc.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query prometheus: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prometheus response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("prometheus query %q returned status %d: %s", query, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result prometheusResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode prometheus response: %w", err)
+	}
+	if result.Status != "success" {
+		return nil, fmt.Errorf("prometheus query %q failed: %s: %s", query, result.ErrorType, result.Error)
+	}
+	if result.Data.ResultType != "vector" {
+		return nil, fmt.Errorf("prometheus query %q returned %s, expected vector", query, result.Data.ResultType)
+	}
+
+	samples := make(map[types.NamespacedName]int64, len(result.Data.Result))
+	for _, series := range result.Data.Result {
+		pvcName := types.NamespacedName{
+			Namespace: series.Metric[pc.config.NamespaceLabel],
+			Name:      series.Metric[pc.config.PVCLabel],
+		}
+		if pvcName.Namespace == "" || pvcName.Name == "" {
+			continue
+		}
+		raw, ok := series.Value[1].(string)
+		if !ok {
+			continue
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) || value < 0 {
+			continue
+		}
+		samples[pvcName] = int64(value)
+	}
+	return samples, nil
+}
+
+func (pc *PrometheusMetricsCollector) authenticate(req *http.Request) error {
+	switch {
+	case pc.config.BearerTokenFile != "":
+		token, err := os.ReadFile(pc.config.BearerTokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read bearer token file: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	case pc.config.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+pc.config.BearerToken)
+	case pc.config.Username != "":
+		req.SetBasicAuth(pc.config.Username, pc.config.Password)
+	}
+	return nil
+}
@@ -0,0 +1,324 @@
+package kubelet
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type fakeSeries struct {
+	labels map[string]string
+	value  string
+}
+
+// newFakePrometheus serves instant queries from a fixed query to series map and
+// records the Authorization header of the last request.
+func newFakePrometheus(t *testing.T, results map[string][]fakeSeries, authHeader *string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" {
+			http.NotFound(w, r)
+			return
+		}
+		if authHeader != nil {
+			*authHeader = r.Header.Get("Authorization")
+		}
+
+		query := r.FormValue("query")
+		series, ok := results[query]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"status":    "error",
+				"errorType": "bad_data",
+				"error":     "unknown query " + query,
+			})
+			return
+		}
+
+		result := make([]map[string]any, 0, len(series))
+		for _, s := range series {
+			result = append(result, map[string]any{
+				"metric": s.labels,
+				"value":  []any{1700000000.0, s.value},
+			})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"status": "success",
+			"data": map[string]any{
+				"resultType": "vector",
+				"result":     result,
+			},
+		})
+	}))
+}
+
+func TestPrometheusMetricsCollector_GetAllVolumeMetrics(t *testing.T) {
+	pvc := map[string]string{"namespace": "default", "persistentvolumeclaim": "data"}
+	server := newFakePrometheus(t, map[string][]fakeSeries{
+		DefaultPrometheusQueries.CapacityBytes:  {{pvc, "1073741824"}},
+		DefaultPrometheusQueries.AvailableBytes: {{pvc, "268435456"}, {map[string]string{"namespace": "default"}, "1"}},
+		DefaultPrometheusQueries.InodesTotal:    {{pvc, "1000"}},
+		DefaultPrometheusQueries.InodesUsed:     {{pvc, "900"}},
+		DefaultPrometheusQueries.Timestamp:      {{pvc, "1699999940.5"}},
+	}, nil)
+	defer server.Close()
+
+	collector, err := NewPrometheusMetricsCollector(PrometheusConfig{URL: server.URL, Queries: DefaultPrometheusQueries})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cache, err := collector.GetAllVolumeMetrics(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(cache.GetAll()) != 1 {
+		t.Fatalf("expected series without PVC labels to be skipped, got %v", cache.GetAll())
+	}
+	vm, exists := cache.Get(types.NamespacedName{Namespace: "default", Name: "data"})
+	if !exists {
+		t.Fatal("expected metrics for default/data")
+	}
+	if vm.UsagePercent != 75 {
+		t.Errorf("expected usage 75%%, got %f", vm.UsagePercent)
+	}
+	if vm.InodesUsagePercent != 90 {
+		t.Errorf("expected inodes usage 90%%, got %f", vm.InodesUsagePercent)
+	}
+	if vm.Source != SourcePrometheus {
+		t.Errorf("expected source %s, got %s", SourcePrometheus, vm.Source)
+	}
+	if !vm.Timestamp.Equal(time.Unix(1699999940, 0)) {
+		t.Errorf("expected sample timestamp from the timestamp query, got %s", vm.Timestamp)
+	}
+}
+
+func TestPrometheusMetricsCollector_LabelMapping(t *testing.T) {
+	queries := PrometheusQueries{
+		CapacityBytes:  `csi_volume_capacity_bytes`,
+		AvailableBytes: `csi_volume_available_bytes`,
+	}
+	labels := map[string]string{"exported_namespace": "db", "claim": "postgres"}
+	server := newFakePrometheus(t, map[string][]fakeSeries{
+		queries.CapacityBytes:  {{labels, "100"}},
+		queries.AvailableBytes: {{labels, "40"}},
+	}, nil)
+	defer server.Close()
+
+	collector, err := NewPrometheusMetricsCollector(PrometheusConfig{
+		URL:            server.URL,
+		Queries:        queries,
+		NamespaceLabel: "exported_namespace",
+		PVCLabel:       "claim",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	vm, err := collector.GetVolumeMetrics(context.Background(), types.NamespacedName{Namespace: "db", Name: "postgres"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vm.UsagePercent != 60 {
+		t.Errorf("expected usage 60%%, got %f", vm.UsagePercent)
+	}
+	if vm.InodesTotal != 0 {
+		t.Errorf("expected no inode metrics when inode queries are empty, got %d", vm.InodesTotal)
+	}
+}
+
+func TestPrometheusMetricsCollector_Auth(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("file-token\n"), 0o600); err != nil {
+		t.Fatalf("failed to write token file: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		config   PrometheusConfig
+		expected string
+	}{
+		{name: "no auth", expected: ""},
+		{name: "bearer token", config: PrometheusConfig{BearerToken: "secret"}, expected: "Bearer secret"},
+		{name: "bearer token file", config: PrometheusConfig{BearerTokenFile: tokenFile}, expected: "Bearer file-token"},
+		{name: "basic auth", config: PrometheusConfig{Username: "user", Password: "pass"}, expected: "Basic dXNlcjpwYXNz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var authHeader string
+			server := newFakePrometheus(t, map[string][]fakeSeries{
+				"capacity":  {},
+				"available": {},
+			}, &authHeader)
+			defer server.Close()
+
+			config := tt.config
+			config.URL = server.URL
+			config.Queries = PrometheusQueries{CapacityBytes: "capacity", AvailableBytes: "available"}
+			collector, err := NewPrometheusMetricsCollector(config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if _, err := collector.GetAllVolumeMetrics(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if authHeader != tt.expected {
+				t.Errorf("expected Authorization %q, got %q", tt.expected, authHeader)
+			}
+		})
+	}
+}
+
+func TestPrometheusMetricsCollector_QueryError(t *testing.T) {
+	server := newFakePrometheus(t, map[string][]fakeSeries{}, nil)
+	defer server.Close()
+
+	collector, err := NewPrometheusMetricsCollector(PrometheusConfig{URL: server.URL, Queries: DefaultPrometheusQueries})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := collector.GetAllVolumeMetrics(context.Background()); err == nil {
+		t.Error("expected error for failing query")
+	}
+}
+
+func TestPrometheusMetricsCollector_ErrorStatus(t *testing.T) {
+	// A proxy answering with an error status and a well-formed body
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"status": "success",
+			"data":   map[string]any{"resultType": "vector", "result": []any{}},
+		})
+	}))
+	defer server.Close()
+
+	collector, err := NewPrometheusMetricsCollector(PrometheusConfig{URL: server.URL, Queries: DefaultPrometheusQueries})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := collector.GetAllVolumeMetrics(context.Background()); err == nil {
+		t.Error("expected error for non-2xx response")
+	}
+}
+
+func TestPrometheusMetricsCollector_InvalidValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"NaN", "NaN"},
+		{"positive infinity", "+Inf"},
+		{"negative infinity", "-Inf"},
+		{"negative", "-1000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := map[string]string{"namespace": "default", "persistentvolumeclaim": "data"}
+			invalid := map[string]string{"namespace": "default", "persistentvolumeclaim": "invalid"}
+			server := newFakePrometheus(t, map[string][]fakeSeries{
+				DefaultPrometheusQueries.CapacityBytes:  {{data, "1000"}, {invalid, tt.value}},
+				DefaultPrometheusQueries.AvailableBytes: {{data, "500"}, {invalid, "500"}},
+				DefaultPrometheusQueries.InodesTotal:    {},
+				DefaultPrometheusQueries.InodesUsed:     {},
+				DefaultPrometheusQueries.Timestamp:      {},
+			}, nil)
+			defer server.Close()
+
+			collector, err := NewPrometheusMetricsCollector(PrometheusConfig{URL: server.URL, Queries: DefaultPrometheusQueries})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			cache, err := collector.GetAllVolumeMetrics(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if _, exists := cache.Get(types.NamespacedName{Namespace: "default", Name: "invalid"}); exists {
+				t.Errorf("expected series with value %s to be skipped", tt.value)
+			}
+			if _, exists := cache.Get(types.NamespacedName{Namespace: "default", Name: "data"}); !exists {
+				t.Error("expected default/data to be collected")
+			}
+		})
+	}
+}
+
+func TestPrometheusMetricsCollector_MissingSeries(t *testing.T) {
+	data := map[string]string{"namespace": "default", "persistentvolumeclaim": "data"}
+	noAvailable := map[string]string{"namespace": "default", "persistentvolumeclaim": "no-available"}
+	noCapacity := map[string]string{"namespace": "default", "persistentvolumeclaim": "no-capacity"}
+	server := newFakePrometheus(t, map[string][]fakeSeries{
+		DefaultPrometheusQueries.CapacityBytes:  {{data, "1000"}, {noAvailable, "1000"}},
+		DefaultPrometheusQueries.AvailableBytes: {{data, "500"}, {noCapacity, "500"}},
+		DefaultPrometheusQueries.InodesTotal:    {{data, "100"}},
+		DefaultPrometheusQueries.InodesUsed:     {},
+		DefaultPrometheusQueries.Timestamp:      {},
+	}, nil)
+	defer server.Close()
+
+	collector, err := NewPrometheusMetricsCollector(PrometheusConfig{URL: server.URL, Queries: DefaultPrometheusQueries})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cache, err := collector.GetAllVolumeMetrics(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Without the available series the volume would look 100% full
+	if len(cache.GetAll()) != 1 {
+		t.Fatalf("expected volumes missing a series to be skipped, got %v", cache.GetAll())
+	}
+	vm, exists := cache.Get(types.NamespacedName{Namespace: "default", Name: "data"})
+	if !exists || vm.UsagePercent != 50 {
+		t.Fatalf("expected 50%% usage for default/data, got %+v", vm)
+	}
+	if vm.InodesTotal != 0 || vm.InodesUsagePercent != 0 {
+		t.Errorf("expected inode stats to be skipped without the inodes used series, got %+v", vm)
+	}
+}
+
+func TestNewPrometheusMetricsCollector_Validation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config PrometheusConfig
+	}{
+		{name: "missing URL", config: PrometheusConfig{Queries: DefaultPrometheusQueries}},
+		{name: "invalid scheme", config: PrometheusConfig{URL: "ftp://prometheus:9090", Queries: DefaultPrometheusQueries}},
+		{name: "missing capacity query", config: PrometheusConfig{URL: "http://prometheus:9090", Queries: PrometheusQueries{AvailableBytes: "x"}}},
+		{name: "metadata endpoint", config: PrometheusConfig{URL: "http://169.254.169.254", Queries: DefaultPrometheusQueries}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPrometheusMetricsCollector(tt.config); err == nil {
+				t.Error("expected validation error")
+			}
+		})
+	}
+
+	for _, allowed := range []string{
+		"http://localhost:9090",
+		"http://prometheus-operated.monitoring.svc.cluster.local:9090",
+		"https://10.96.0.20:9091",
+	} {
+		if _, err := NewPrometheusMetricsCollector(PrometheusConfig{URL: allowed, Queries: DefaultPrometheusQueries}); err != nil {
+			t.Errorf("expected %s to be allowed, got %v", allowed, err)
+		}
+	}
+}
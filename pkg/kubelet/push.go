@@ -0,0 +1,277 @@
+package kubelet
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// SourcePush marks samples pushed by agents running next to the workload.
+const SourcePush = "push"
+
+// How pushed samples are combined with samples of the wrapped collector.
+const (
+	// PushModeMerge keeps the most recently collected sample of a PVC, so
+	// pushed samples fill in volumes the kubelet does not report.
+	PushModeMerge = "merge"
+	// PushModeOverride always prefers a pushed sample over a collected one.
+	PushModeOverride = "override"
+
+	DefaultPushMode = PushModeMerge
+	DefaultPushTTL  = 5 * time.Minute
+)
+
+// PushSample is a usage sample of a PVC pushed by an agent. Either UsedBytes
+// or AvailableBytes is required. Timestamp defaults to the time the sample is
+// received.
+type PushSample struct {
+	Namespace             string     `json:"namespace"`
+	PersistentVolumeClaim string     `json:"persistentVolumeClaim"`
+	CapacityBytes         int64      `json:"capacityBytes"`
+	UsedBytes             *int64     `json:"usedBytes,omitempty"`
+	AvailableBytes        *int64     `json:"availableBytes,omitempty"`
+	InodesTotal           int64      `json:"inodesTotal,omitempty"`
+	InodesUsed            int64      `json:"inodesUsed,omitempty"`
+	Timestamp             *time.Time `json:"timestamp,omitempty"`
+	NodeName              string     `json:"nodeName,omitempty"`
+	PodName               string     `json:"podName,omitempty"`
+}
+
+// maxPushClockSkew bounds how far in the future a pushed timestamp may be.
+const maxPushClockSkew = time.Minute
+
+// volumeMetrics validates the sample and converts it at now.
+func (s *PushSample) volumeMetrics(now time.Time) (types.NamespacedName, *VolumeMetrics, error) {
+	nn := types.NamespacedName{Namespace: s.Namespace, Name: s.PersistentVolumeClaim}
+	if s.Namespace == "" || s.PersistentVolumeClaim == "" {
+		return nn, nil, fmt.Errorf("namespace and persistentVolumeClaim are required")
+	}
+	if s.CapacityBytes <= 0 {
+		return nn, nil, fmt.Errorf("capacityBytes of %s must be positive", nn)
+	}
+
+	var available int64
+	switch {
+	case s.AvailableBytes != nil:
+		available = *s.AvailableBytes
+	case s.UsedBytes != nil:
+		available = s.CapacityBytes - *s.UsedBytes
+	default:
+		return nn, nil, fmt.Errorf("usedBytes or availableBytes of %s is required", nn)
+	}
+	if available < 0 || available > s.CapacityBytes {
+		return nn, nil, fmt.Errorf("usage of %s must be within capacityBytes", nn)
+	}
+	if s.InodesTotal < 0 || s.InodesUsed < 0 || s.InodesUsed > s.InodesTotal {
+		return nn, nil, fmt.Errorf("inodesUsed of %s must be within inodesTotal", nn)
+	}
+
+	timestamp := now
+	if s.Timestamp != nil && !s.Timestamp.IsZero() {
+		if s.Timestamp.After(now.Add(maxPushClockSkew)) {
+			return nn, nil, fmt.Errorf("timestamp of %s is in the future", nn)
+		}
+		timestamp = *s.Timestamp
+	}
+
+	vm := &VolumeMetrics{
+		CapacityBytes:  s.CapacityBytes,
+		AvailableBytes: available,
+		UsedBytes:      s.CapacityBytes - available,
+		UsagePercent:   float64(s.CapacityBytes-available) / float64(s.CapacityBytes) * 100,
+		InodesTotal:    s.InodesTotal,
+		InodesUsed:     s.InodesUsed,
+		NodeName:       s.NodeName,
+		PodName:        s.PodName,
+		Timestamp:      timestamp,
+		Source:         SourcePush,
+	}
+	if s.InodesTotal > 0 {
+		vm.InodesFree = s.InodesTotal - s.InodesUsed
+		vm.InodesUsagePercent = float64(s.InodesUsed) / float64(s.InodesTotal) * 100
+	}
+	return nn, vm, nil
+}
+
+// PushStore holds the latest pushed sample of each PVC until it is older
+// than the TTL. It is safe for concurrent use.
+type PushStore struct {
+	ttl     time.Duration
+	mutex   sync.RWMutex
+	samples map[string]*VolumeMetrics
+	now     func() time.Time
+}
+
+func NewPushStore(ttl time.Duration) *PushStore {
+	if ttl <= 0 {
+		ttl = DefaultPushTTL
+	}
+	return &PushStore{ttl: ttl, samples: make(map[string]*VolumeMetrics), now: time.Now}
+}
+
+// Add validates and stores samples. Samples older than the stored one of the
+// same PVC are ignored. It returns the number of samples stored and the
+// errors of rejected ones.
+func (ps *PushStore) Add(samples []PushSample) (int, []error) {
+	now := ps.now()
+	var errs []error
+	accepted := 0
+
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	for i := range samples {
+		nn, vm, err := samples[i].volumeMetrics(now)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if vm.Age(now) > ps.ttl {
+			errs = append(errs, fmt.Errorf("sample of %s is older than the TTL of %s", nn, ps.ttl))
+			continue
+		}
+		accepted++
+		if existing, ok := ps.samples[nn.String()]; ok && existing.Timestamp.After(vm.Timestamp) {
+			continue
+		}
+		ps.samples[nn.String()] = vm
+	}
+	return accepted, errs
+}
+
+// Get returns a copy of the pushed sample of a PVC if it has not expired.
+func (ps *PushStore) Get(namespacedName types.NamespacedName) (*VolumeMetrics, bool) {
+	if ps == nil {
+		return nil, false
+	}
+	ps.mutex.RLock()
+	defer ps.mutex.RUnlock()
+	vm, ok := ps.samples[namespacedName.String()]
+	if !ok || vm.Age(ps.now()) > ps.ttl {
+		return nil, false
+	}
+	sample := *vm
+	return &sample, true
+}
+
+// Has reports whether an unexpired pushed sample exists for the PVC.
+func (ps *PushStore) Has(namespacedName types.NamespacedName) bool {
+	_, ok := ps.Get(namespacedName)
+	return ok
+}
+
+// Expire drops samples older than the TTL and returns the number left.
+func (ps *PushStore) Expire() int {
+	now := ps.now()
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	for key, vm := range ps.samples {
+		if vm.Age(now) > ps.ttl {
+			delete(ps.samples, key)
+		}
+	}
+	return len(ps.samples)
+}
+
+// snapshot returns copies of the unexpired samples keyed by namespace/name.
+func (ps *PushStore) snapshot() map[string]*VolumeMetrics {
+	now := ps.now()
+	ps.mutex.RLock()
+	defer ps.mutex.RUnlock()
+	result := make(map[string]*VolumeMetrics, len(ps.samples))
+	for key, vm := range ps.samples {
+		if vm.Age(now) <= ps.ttl {
+			sample := *vm
+			result[key] = &sample
+		}
+	}
+	return result
+}
+
+// PushMetricsCollector combines pushed samples with the samples of another
+// collector. Without a wrapped collector only pushed samples are used.
+type PushMetricsCollector struct {
+	collector MetricsCollectorInterface
+	store     *PushStore
+	mode      string
+}
+
+var _ MetricsCollectorInterface = (*PushMetricsCollector)(nil)
+var _ NodeScopedMetricsCollector = (*PushMetricsCollector)(nil)
+
+func NewPushMetricsCollector(collector MetricsCollectorInterface, store *PushStore, mode string) (*PushMetricsCollector, error) {
+	switch mode {
+	case "":
+		mode = DefaultPushMode
+	case PushModeMerge, PushModeOverride:
+	default:
+		return nil, fmt.Errorf("unsupported push mode %q, expected %q or %q", mode, PushModeMerge, PushModeOverride)
+	}
+	return &PushMetricsCollector{collector: collector, store: store, mode: mode}, nil
+}
+
+func (pc *PushMetricsCollector) GetVolumeMetrics(ctx context.Context, namespacedName types.NamespacedName) (*VolumeMetrics, error) {
+	cache, err := pc.GetVolumeMetricsForPVCs(ctx, []types.NamespacedName{namespacedName})
+	if err != nil {
+		return nil, err
+	}
+	vm, exists := cache.Get(namespacedName)
+	if !exists {
+		return nil, fmt.Errorf("volume metrics not found for %s/%s", namespacedName.Namespace, namespacedName.Name)
+	}
+	return vm, nil
+}
+
+func (pc *PushMetricsCollector) GetAllVolumeMetrics(ctx context.Context) (*MetricsCache, error) {
+	cache := NewMetricsCache()
+	if pc.collector != nil {
+		var err error
+		if cache, err = pc.collector.GetAllVolumeMetrics(ctx); err != nil {
+			return nil, err
+		}
+	}
+	pc.mergePushed(cache)
+	return cache, nil
+}
+
+func (pc *PushMetricsCollector) GetVolumeMetricsForPVCs(ctx context.Context, pvcs []types.NamespacedName) (*MetricsCache, error) {
+	scoped, ok := pc.collector.(NodeScopedMetricsCollector)
+	if !ok {
+		return pc.GetAllVolumeMetrics(ctx)
+	}
+
+	// Volumes fully covered by pushed samples need no scrape in override mode
+	remaining := pvcs
+	if pc.mode == PushModeOverride {
+		remaining = make([]types.NamespacedName, 0, len(pvcs))
+		for _, nn := range pvcs {
+			if !pc.store.Has(nn) {
+				remaining = append(remaining, nn)
+			}
+		}
+	}
+
+	cache := NewMetricsCache()
+	if len(remaining) > 0 {
+		var err error
+		if cache, err = scoped.GetVolumeMetricsForPVCs(ctx, remaining); err != nil {
+			return nil, err
+		}
+	}
+	pc.mergePushed(cache)
+	return cache, nil
+}
+
+func (pc *PushMetricsCollector) mergePushed(cache *MetricsCache) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	for key, pushed := range pc.store.snapshot() {
+		collected, exists := cache.data[key]
+		if exists && pc.mode == PushModeMerge && !pushed.Timestamp.After(collected.Timestamp) {
+			continue
+		}
+		cache.data[key] = pushed
+	}
+}
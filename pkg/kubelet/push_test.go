@@ -0,0 +1,278 @@
+package kubelet
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func int64Ptr(v int64) *int64 {
+	return &v
+}
+
+func newTestPushStore(now time.Time) *PushStore {
+	store := NewPushStore(5 * time.Minute)
+	store.now = func() time.Time { return now }
+	return store
+}
+
+func TestPushStore_Add(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-10 * time.Minute)
+	future := now.Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		sample   PushSample
+		accepted bool
+	}{
+		{"used bytes", PushSample{Namespace: "default", PersistentVolumeClaim: "data", CapacityBytes: 100, UsedBytes: int64Ptr(40)}, true},
+		{"available bytes", PushSample{Namespace: "default", PersistentVolumeClaim: "data", CapacityBytes: 100, AvailableBytes: int64Ptr(60)}, true},
+		{"missing name", PushSample{Namespace: "default", CapacityBytes: 100, UsedBytes: int64Ptr(40)}, false},
+		{"missing usage", PushSample{Namespace: "default", PersistentVolumeClaim: "data", CapacityBytes: 100}, false},
+		{"usage above capacity", PushSample{Namespace: "default", PersistentVolumeClaim: "data", CapacityBytes: 100, UsedBytes: int64Ptr(140)}, false},
+		{"inodes above total", PushSample{Namespace: "default", PersistentVolumeClaim: "data", CapacityBytes: 100, UsedBytes: int64Ptr(40), InodesTotal: 10, InodesUsed: 11}, false},
+		{"older than TTL", PushSample{Namespace: "default", PersistentVolumeClaim: "data", CapacityBytes: 100, UsedBytes: int64Ptr(40), Timestamp: &old}, false},
+		{"in the future", PushSample{Namespace: "default", PersistentVolumeClaim: "data", CapacityBytes: 100, UsedBytes: int64Ptr(40), Timestamp: &future}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestPushStore(now)
+			accepted, errs := store.Add([]PushSample{tt.sample})
+			if (accepted == 1) != tt.accepted {
+				t.Errorf("expected accepted %v, got %d with errors %v", tt.accepted, accepted, errs)
+			}
+			vm, ok := store.Get(types.NamespacedName{Namespace: "default", Name: "data"})
+			if ok != tt.accepted {
+				t.Fatalf("expected stored sample %v, got %v", tt.accepted, ok)
+			}
+			if ok && (vm.UsedBytes != 40 || vm.UsagePercent != 40 || vm.Source != SourcePush || !vm.Timestamp.Equal(now)) {
+				t.Errorf("unexpected sample %+v", vm)
+			}
+		})
+	}
+}
+
+func TestPushStore_Expiry(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := newTestPushStore(now)
+	nn := types.NamespacedName{Namespace: "default", Name: "data"}
+	store.Add([]PushSample{{Namespace: "default", PersistentVolumeClaim: "data", CapacityBytes: 100, UsedBytes: int64Ptr(40)}})
+
+	// An older sample never replaces a newer one
+	older := now.Add(-time.Minute)
+	store.Add([]PushSample{{Namespace: "default", PersistentVolumeClaim: "data", CapacityBytes: 100, UsedBytes: int64Ptr(10), Timestamp: &older}})
+	if vm, _ := store.Get(nn); vm.UsedBytes != 40 {
+		t.Errorf("expected the newest sample to be kept, got %d used bytes", vm.UsedBytes)
+	}
+
+	store.now = func() time.Time { return now.Add(6 * time.Minute) }
+	if store.Has(nn) {
+		t.Error("expected sample to expire after the TTL")
+	}
+	if left := store.Expire(); left != 0 {
+		t.Errorf("expected expired samples to be dropped, %d left", left)
+	}
+}
+
+// stubCollector returns a fixed cache and records the PVCs it was asked for.
+type stubCollector struct {
+	data      map[string]*VolumeMetrics
+	requested []types.NamespacedName
+}
+
+func (s *stubCollector) cache() *MetricsCache {
+	cache := NewMetricsCache()
+	for key, vm := range s.data {
+		sample := *vm
+		cache.data[key] = &sample
+	}
+	return cache
+}
+
+func (s *stubCollector) GetVolumeMetrics(ctx context.Context, nn types.NamespacedName) (*VolumeMetrics, error) {
+	vm, _ := s.cache().Get(nn)
+	return vm, nil
+}
+
+func (s *stubCollector) GetAllVolumeMetrics(ctx context.Context) (*MetricsCache, error) {
+	return s.cache(), nil
+}
+
+func (s *stubCollector) GetVolumeMetricsForPVCs(ctx context.Context, pvcs []types.NamespacedName) (*MetricsCache, error) {
+	s.requested = pvcs
+	return s.cache(), nil
+}
+
+func TestPushMetricsCollector_Modes(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	data := types.NamespacedName{Namespace: "default", Name: "data"}
+	block := types.NamespacedName{Namespace: "default", Name: "block"}
+
+	tests := []struct {
+		name          string
+		mode          string
+		collectedAt   time.Time
+		expectedUsed  int64
+		expectedScope int
+	}{
+		{"merge prefers fresher pushed sample", PushModeMerge, now.Add(-time.Minute), 40, 2},
+		{"merge prefers fresher collected sample", PushModeMerge, now.Add(time.Second), 70, 2},
+		{"override always prefers pushed sample", PushModeOverride, now.Add(time.Second), 40, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &stubCollector{data: map[string]*VolumeMetrics{
+				data.String(): {CapacityBytes: 100, UsedBytes: 70, Timestamp: tt.collectedAt, Source: SourceKubeletSummary},
+			}}
+			store := newTestPushStore(now)
+			store.Add([]PushSample{
+				{Namespace: "default", PersistentVolumeClaim: "data", CapacityBytes: 100, UsedBytes: int64Ptr(40)},
+				{Namespace: "default", PersistentVolumeClaim: "block", CapacityBytes: 100, UsedBytes: int64Ptr(90)},
+			})
+			collector, err := NewPushMetricsCollector(inner, store, tt.mode)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			cache, err := collector.GetVolumeMetricsForPVCs(context.Background(), []types.NamespacedName{data, block})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if vm, _ := cache.Get(data); vm == nil || vm.UsedBytes != tt.expectedUsed {
+				t.Errorf("expected %d used bytes, got %+v", tt.expectedUsed, vm)
+			}
+			if vm, _ := cache.Get(block); vm == nil || vm.Source != SourcePush {
+				t.Errorf("expected pushed sample for a volume the kubelet does not report, got %+v", vm)
+			}
+			if len(inner.requested) != tt.expectedScope {
+				t.Errorf("expected %d PVCs to be scraped, got %v", tt.expectedScope, inner.requested)
+			}
+		})
+	}
+
+	if _, err := NewPushMetricsCollector(nil, NewPushStore(0), "replace"); err == nil {
+		t.Error("expected an error for an unsupported mode")
+	}
+}
+
+func TestPushHandler(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store := NewPushStore(time.Minute)
+	handler := &PushHandler{Store: store, TokenFile: tokenFile}
+
+	tests := []struct {
+		name     string
+		method   string
+		token    string
+		body     string
+		expected int
+	}{
+		{"accepted", http.MethodPost, "secret", `{"samples":[{"namespace":"default","persistentVolumeClaim":"data","capacityBytes":100,"usedBytes":40}]}`, http.StatusAccepted},
+		{"partially accepted", http.MethodPost, "secret", `{"samples":[{"namespace":"default","persistentVolumeClaim":"data","capacityBytes":100,"usedBytes":40},{"namespace":"default"}]}`, http.StatusAccepted},
+		{"all rejected", http.MethodPost, "secret", `{"samples":[{"namespace":"default"}]}`, http.StatusBadRequest},
+		{"unknown field", http.MethodPost, "secret", `{"samples":[],"extra":1}`, http.StatusBadRequest},
+		{"wrong token", http.MethodPost, "guess", `{"samples":[]}`, http.StatusUnauthorized},
+		{"missing token", http.MethodPost, "", `{"samples":[]}`, http.StatusUnauthorized},
+		{"wrong method", http.MethodGet, "secret", "", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, PushPath, strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+			if recorder.Code != tt.expected {
+				t.Errorf("expected status %d, got %d: %s", tt.expected, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+
+	if !store.Has(types.NamespacedName{Namespace: "default", Name: "data"}) {
+		t.Error("expected pushed sample to be stored")
+	}
+}
+
+func TestPushHandler_scopedTokens(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "tokens")
+	tokens := "# agents\nglobal\n\ndb-agent databases, analytics\n"
+	if err := os.WriteFile(tokenFile, []byte(tokens), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store := NewPushStore(time.Minute)
+	handler := &PushHandler{Store: store, TokenFile: tokenFile}
+
+	push := func(token, body string) (int, PushResponse) {
+		req := httptest.NewRequest(http.MethodPost, PushPath, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		var response PushResponse
+		_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+		return recorder.Code, response
+	}
+
+	body := `{"samples":[` +
+		`{"namespace":"databases","persistentVolumeClaim":"pg","capacityBytes":100,"usedBytes":40},` +
+		`{"namespace":"default","persistentVolumeClaim":"data","capacityBytes":100,"usedBytes":40}]}`
+	code, response := push("db-agent", body)
+	if code != http.StatusAccepted || response.Accepted != 1 || response.Rejected != 1 ||
+		len(response.Errors) != 1 || !strings.Contains(response.Errors[0], `namespace "default" is not allowed`) {
+		t.Errorf("unexpected response %d %+v", code, response)
+	}
+	if !store.Has(types.NamespacedName{Namespace: "databases", Name: "pg"}) || store.Has(types.NamespacedName{Namespace: "default", Name: "data"}) {
+		t.Error("expected only the sample in the token scope to be stored")
+	}
+
+	if code, _ := push("db-agent", `{"samples":[{"namespace":"default","persistentVolumeClaim":"data","capacityBytes":100,"usedBytes":40}]}`); code != http.StatusBadRequest {
+		t.Errorf("expected status %d for samples outside the scope, got %d", http.StatusBadRequest, code)
+	}
+	if code, response := push("global", body); code != http.StatusAccepted || response.Accepted != 2 {
+		t.Errorf("expected an unscoped token to push any namespace, got %d %+v", code, response)
+	}
+	if code, _ := push("agents", body); code != http.StatusUnauthorized {
+		t.Errorf("expected a comment not to be a token, got %d", code)
+	}
+
+	if err := os.WriteFile(tokenFile, []byte("db-agent databases extra\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if code, _ := push("db-agent", body); code != http.StatusUnauthorized {
+		t.Errorf("expected an invalid token file to reject pushes, got %d", code)
+	}
+}
+
+func TestPushServer_RequiresTLS(t *testing.T) {
+	server := &PushServer{
+		BindAddress: "127.0.0.1:0",
+		Handler:     &PushHandler{Store: NewPushStore(time.Minute), TokenFile: "tokens"},
+	}
+	if err := server.Start(context.Background()); err == nil {
+		t.Fatal("expected push endpoint without a certificate to be refused")
+	}
+
+	server.Insecure = true
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- server.Start(ctx) }()
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Fatalf("expected insecure push endpoint to start, got %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+package kubelet
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/logicIQ/pvc-chonker/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// PushPath is where agents POST usage samples.
+	PushPath = "/api/v1/samples"
+
+	maxPushBodyBytes = 1 << 20
+)
+
+// PushRequest is the body accepted by the push endpoint.
+type PushRequest struct {
+	Samples []PushSample `json:"samples"`
+}
+
+// PushResponse reports how many samples were stored and why others were not.
+type PushResponse struct {
+	Accepted int      `json:"accepted"`
+	Rejected int      `json:"rejected"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// PushHandler serves the push endpoint. Agents authenticate with a bearer
+// token listed in TokenFile, which is read on every request so rotated tokens
+// are picked up. Each line of TokenFile holds a token optionally followed by
+// a comma separated list of the namespaces it may push samples for:
+//
+//	# agents of the database nodes
+//	3f9c... databases,analytics
+//
+// A token without namespaces may push samples for any PVC. Blank lines and
+// lines starting with # are ignored.
+type PushHandler struct {
+	Store     *PushStore
+	TokenFile string
+}
+
+func (h *PushHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	scope, err := h.authenticate(req)
+	if err != nil {
+		log.FromContext(req.Context()).V(1).Info("Rejected push request", "remoteAddr", req.RemoteAddr, "error", err)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var body PushRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxPushBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	samples, errs := scope.filter(body.Samples)
+	accepted, addErrs := h.Store.Add(samples)
+	errs = append(errs, addErrs...)
+	metrics.RecordPushedSamples(accepted, len(errs))
+	metrics.PushPVCs.Set(float64(h.Store.Expire()))
+
+	response := PushResponse{Accepted: accepted, Rejected: len(errs)}
+	for _, err := range errs {
+		response.Errors = append(response.Errors, err.Error())
+	}
+	status := http.StatusAccepted
+	if accepted == 0 && len(errs) > 0 {
+		status = http.StatusBadRequest
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// pushScope is the set of namespaces a token may push samples for. A nil
+// scope allows every namespace.
+type pushScope map[string]bool
+
+// filter returns the samples in the scope and an error for each other one.
+func (s pushScope) filter(samples []PushSample) ([]PushSample, []error) {
+	if s == nil {
+		return samples, nil
+	}
+	var allowed []PushSample
+	var errs []error
+	for _, sample := range samples {
+		if !s[sample.Namespace] {
+			errs = append(errs, fmt.Errorf("sample of %s/%s: namespace %q is not allowed for this token",
+				sample.Namespace, sample.PersistentVolumeClaim, sample.Namespace))
+			continue
+		}
+		allowed = append(allowed, sample)
+	}
+	return allowed, errs
+}
+
+// parsePushTokens parses the token file into the scope of every token.
+func parsePushTokens(data string) (map[string]pushScope, error) {
+	tokens := make(map[string]pushScope)
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		token, namespaces, _ := strings.Cut(strings.ReplaceAll(line, "\t", " "), " ")
+		var scope pushScope
+		if namespaces = strings.TrimSpace(namespaces); namespaces != "" {
+			scope = make(pushScope)
+			for _, namespace := range strings.Split(namespaces, ",") {
+				namespace = strings.TrimSpace(namespace)
+				if strings.Contains(namespace, " ") {
+					return nil, fmt.Errorf("line %d: expected a token and an optional comma separated namespace list", i+1)
+				}
+				if namespace != "" {
+					scope[namespace] = true
+				}
+			}
+			if len(scope) == 0 {
+				return nil, fmt.Errorf("line %d: empty namespace list", i+1)
+			}
+		}
+		tokens[token] = scope
+	}
+	return tokens, nil
+}
+
+// authenticate returns the scope of the bearer token of the request.
+func (h *PushHandler) authenticate(req *http.Request) (pushScope, error) {
+	data, err := os.ReadFile(h.TokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read push token file: %w", err)
+	}
+	tokens, err := parsePushTokens(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid push token file %s: %w", h.TokenFile, err)
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("push token file %s is empty", h.TokenFile)
+	}
+	provided, found := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !found || provided == "" {
+		return nil, fmt.Errorf("missing bearer token")
+	}
+	// Compare against every token so the time taken does not reveal which
+	// one matched
+	var scope pushScope
+	matched := false
+	for token, tokenScope := range tokens {
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1 {
+			scope, matched = tokenScope, true
+		}
+	}
+	if !matched {
+		return nil, fmt.Errorf("invalid bearer token")
+	}
+	return scope, nil
+}
+
+// PushServer serves the push endpoint on every replica, not only the leader.
+// Only the leader uses the samples, so agents push to every replica through a
+// headless Service, and a new leader already holds recent samples.
+type PushServer struct {
+	BindAddress string
+	Handler     *PushHandler
+	// CertFile and KeyFile enable TLS.
+	CertFile string
+	KeyFile  string
+	// Insecure allows serving without TLS, which sends bearer tokens in
+	// plain text. It is meant for a TLS-terminating proxy or service mesh.
+	Insecure bool
+}
+
+func (s *PushServer) NeedLeaderElection() bool {
+	return false
+}
+
+// Start serves until the context is cancelled.
+func (s *PushServer) Start(ctx context.Context) error {
+	log := log.FromContext(ctx).WithName("pushServer")
+	if s.Handler == nil || s.Handler.TokenFile == "" {
+		return fmt.Errorf("push endpoint requires a token file")
+	}
+	if s.CertFile == "" {
+		if !s.Insecure {
+			return fmt.Errorf("push endpoint requires a TLS certificate, bearer tokens would be sent in plain text")
+		}
+		log.Info("WARNING: serving push endpoint without TLS, bearer tokens are sent in plain text")
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(PushPath, s.Handler)
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	listener, err := net.Listen("tcp", s.BindAddress)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.BindAddress, err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.Info("Serving push endpoint", "address", listener.Addr().String(), "path", PushPath, "tls", s.CertFile != "")
+		if s.CertFile != "" {
+			errCh <- server.ServeTLS(listener, s.CertFile, s.KeyFile)
+		} else {
+			errCh <- server.Serve(listener)
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return fmt.Errorf("push endpoint failed: %w", err)
+	}
+}
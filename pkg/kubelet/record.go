@@ -0,0 +1,270 @@
+package kubelet
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// ManifestFile describes a recorded scrape cycle and its node payloads.
+	ManifestFile = "manifest.json"
+
+	DefaultMaxRecordings = 100
+
+	recordingVersion = 1
+	// recordingDirFormat sorts recordings chronologically by name.
+	recordingDirFormat = "20060102T150405.000000000Z"
+)
+
+// RecordedNode describes the scrape of a single node. Failed scrapes carry
+// the failure reason, and a payload when the response could not be parsed.
+type RecordedNode struct {
+	Name        string    `json:"name"`
+	File        string    `json:"file,omitempty"`
+	ContentType string    `json:"contentType,omitempty"`
+	ScrapedAt   time.Time `json:"scrapedAt"`
+	Reason      string    `json:"reason,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Recording is the manifest of a recorded scrape cycle.
+type Recording struct {
+	Version   int            `json:"version"`
+	StartedAt time.Time      `json:"startedAt"`
+	Endpoint  string         `json:"endpoint"`
+	Nodes     []RecordedNode `json:"nodes"`
+	// PVCNodes maps PVCs to the nodes hosting them, so failed scrapes are
+	// attributed to PVCs on replay.
+	PVCNodes map[string][]string `json:"pvcNodes,omitempty"`
+}
+
+// Recorder writes the raw payload of every node scrape to a directory per
+// scrape cycle, keeping the most recent MaxRecordings cycles. Payloads are
+// gzipped as they are streamed to the parser.
+type Recorder struct {
+	Dir           string
+	MaxRecordings int
+}
+
+func NewRecorder(dir string, maxRecordings int) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create recording directory: %w", err)
+	}
+	if maxRecordings <= 0 {
+		maxRecordings = DefaultMaxRecordings
+	}
+	return &Recorder{Dir: dir, MaxRecordings: maxRecordings}, nil
+}
+
+// SetRecorder records the raw payload of every scrape.
+func (mc *MetricsCollector) SetRecorder(recorder *Recorder) {
+	mc.recorder = recorder
+}
+
+// recording collects a scrape cycle while it runs. A nil recording records
+// nothing.
+type recording struct {
+	dir      string
+	manifest Recording
+	mutex    sync.Mutex
+	errs     []error
+}
+
+func (r *Recorder) begin(endpoint string, startedAt time.Time) *recording {
+	if r == nil {
+		return nil
+	}
+	rec := &recording{
+		dir:      filepath.Join(r.Dir, startedAt.UTC().Format(recordingDirFormat)),
+		manifest: Recording{Version: recordingVersion, StartedAt: startedAt, Endpoint: endpoint},
+	}
+	if err := os.MkdirAll(rec.dir, 0o750); err != nil {
+		rec.errs = append(rec.errs, fmt.Errorf("failed to create recording: %w", err))
+	}
+	return rec
+}
+
+// payloadWriter returns a writer storing the payload of a node.
+func (rec *recording) payloadWriter(nodeName, contentType string) (io.WriteCloser, string, error) {
+	ext := ".txt"
+	switch {
+	case rec.manifest.Endpoint == EndpointSummary:
+		ext = ".json"
+	case isProtobuf(contentType):
+		ext = ".pb"
+	}
+	file := nodeName + ext + ".gz"
+	f, err := os.Create(filepath.Join(rec.dir, file))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to record payload of node %s: %w", nodeName, err)
+	}
+	return &gzipFile{Writer: gzip.NewWriter(f), file: f}, file, nil
+}
+
+// record wraps the payload of a node so it is written as it is read, and
+// returns a function completing the node entry once the scrape finished.
+func (rec *recording) record(nodeName, contentType string, scrapedAt time.Time, body io.Reader) (io.Reader, func(error)) {
+	entry := RecordedNode{Name: nodeName, ContentType: contentType, ScrapedAt: scrapedAt}
+	if rec == nil {
+		return body, func(error) {}
+	}
+	w, file, err := rec.payloadWriter(nodeName, contentType)
+	if err != nil {
+		rec.addError(err)
+		return body, func(error) { rec.addNode(entry) }
+	}
+	entry.File = file
+	return io.TeeReader(body, w), func(scrapeErr error) {
+		// Parsers may stop before the end of the payload
+		if _, err := io.Copy(io.Discard, io.TeeReader(body, w)); err != nil && scrapeErr == nil {
+			rec.addError(fmt.Errorf("failed to record payload of node %s: %w", nodeName, err))
+		}
+		if err := w.Close(); err != nil {
+			rec.addError(fmt.Errorf("failed to record payload of node %s: %w", nodeName, err))
+		}
+		rec.addNode(entry)
+	}
+}
+
+// recordFailure records a node that could not be scraped.
+func (rec *recording) recordFailure(failure *NodeScrapeError) {
+	if rec == nil {
+		return
+	}
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+	for i := range rec.manifest.Nodes {
+		if rec.manifest.Nodes[i].Name == failure.Node {
+			rec.manifest.Nodes[i].Reason = failure.Reason
+			rec.manifest.Nodes[i].Error = failure.Err.Error()
+			return
+		}
+	}
+	rec.manifest.Nodes = append(rec.manifest.Nodes, RecordedNode{Name: failure.Node, ScrapedAt: time.Now(), Reason: failure.Reason, Error: failure.Err.Error()})
+}
+
+func (rec *recording) addNode(entry RecordedNode) {
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+	rec.manifest.Nodes = append(rec.manifest.Nodes, entry)
+}
+
+func (rec *recording) addError(err error) {
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+	rec.errs = append(rec.errs, err)
+}
+
+// finish writes the manifest and drops the oldest recordings. It returns the
+// errors met while recording, which never fail the scrape itself.
+func (rec *recording) finish(recorder *Recorder, cache *MetricsCache) error {
+	if rec == nil {
+		return nil
+	}
+	cache.mutex.RLock()
+	if len(cache.pvcNodes) > 0 {
+		rec.manifest.PVCNodes = make(map[string][]string, len(cache.pvcNodes))
+		for key, nodes := range cache.pvcNodes {
+			rec.manifest.PVCNodes[key] = nodes
+		}
+	}
+	cache.mutex.RUnlock()
+
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+	sort.Slice(rec.manifest.Nodes, func(i, j int) bool {
+		return rec.manifest.Nodes[i].Name < rec.manifest.Nodes[j].Name
+	})
+	data, err := json.MarshalIndent(rec.manifest, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(rec.dir, ManifestFile), data, 0o640)
+	}
+	if err != nil {
+		rec.errs = append(rec.errs, fmt.Errorf("failed to write recording manifest: %w", err))
+	}
+	if err := recorder.rotate(); err != nil {
+		rec.errs = append(rec.errs, err)
+	}
+	return errors.Join(rec.errs...)
+}
+
+// rotate removes the oldest recordings beyond MaxRecordings.
+func (r *Recorder) rotate() error {
+	recordings, err := listRecordings(r.Dir)
+	if err != nil {
+		return err
+	}
+	for len(recordings) > r.MaxRecordings {
+		if err := os.RemoveAll(recordings[0]); err != nil {
+			return fmt.Errorf("failed to remove old recording: %w", err)
+		}
+		recordings = recordings[1:]
+	}
+	return nil
+}
+
+// listRecordings returns the recording directories below dir, oldest first.
+// dir itself is returned when it is a single recording.
+func listRecordings(dir string) ([]string, error) {
+	if _, err := os.Stat(filepath.Join(dir, ManifestFile)); err == nil {
+		return []string{dir}, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recordings: %w", err)
+	}
+	var recordings []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(recordingDirFormat, entry.Name()); err != nil {
+			continue
+		}
+		recordings = append(recordings, filepath.Join(dir, entry.Name()))
+	}
+	sort.Strings(recordings)
+	return recordings, nil
+}
+
+// gzipFile closes the gzip stream and the file under it.
+type gzipFile struct {
+	*gzip.Writer
+	file *os.File
+}
+
+func (g *gzipFile) Close() error {
+	err := g.Writer.Close()
+	if closeErr := g.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// finishRecording completes a recording. Recording errors are logged rather
+// than failing the scrape.
+func (mc *MetricsCollector) finishRecording(ctx context.Context, rec *recording, cache *MetricsCache) {
+	if err := rec.finish(mc.recorder, cache); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to record kubelet scrape", "recording", rec.dir)
+	}
+}
+
+// parseNodePayload parses the response of a kubelet endpoint scraped at
+// scrapedAt into the cache.
+func parseNodePayload(r io.Reader, endpoint, contentType, nodeName string, scrapedAt time.Time, cache *MetricsCache) error {
+	if endpoint == EndpointSummary {
+		return parseSummaryAt(r, nodeName, scrapedAt, cache)
+	}
+	return parseVolumeStatsAt(r, contentType, nodeName, scrapedAt, cache)
+}
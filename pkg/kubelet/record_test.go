@@ -0,0 +1,121 @@
+package kubelet
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	mc := newFailureTestCollector(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/node-b" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(testNodeMetrics))
+	},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-b"}},
+		newPodWithPVC("reader-b", "node-b", "shared-pvc"),
+	)
+	dir := t.TempDir()
+	recorder, err := NewRecorder(dir, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mc.SetRecorder(recorder)
+
+	live, err := mc.GetAllVolumeMetrics(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	replay, err := NewReplayCollector(dir, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if replay.Len() != 1 {
+		t.Fatalf("expected 1 recording, got %d", replay.Len())
+	}
+	replayed, err := replay.GetAllVolumeMetrics(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	nn := types.NamespacedName{Namespace: "test-ns", Name: "test-pvc"}
+	expected, _ := live.Get(nn)
+	actual, ok := replayed.Get(nn)
+	if !ok {
+		t.Fatal("expected replayed metrics for test-pvc")
+	}
+	if actual.UsedBytes != expected.UsedBytes || actual.UsagePercent != expected.UsagePercent || actual.NodeName != "node-a" {
+		t.Errorf("expected replayed metrics %+v, got %+v", expected, actual)
+	}
+	if !actual.Timestamp.Equal(expected.Timestamp) {
+		t.Errorf("expected scrape time %v to be replayed, got %v", expected.Timestamp, actual.Timestamp)
+	}
+
+	failures := replayed.FailedNodes()
+	if len(failures) != 1 || failures[0].Node != "node-b" || failures[0].Reason != live.FailedNodes()[0].Reason {
+		t.Errorf("expected the node-b failure to be replayed, got %v", failures)
+	}
+	if _, unavailable := replayed.MetricsUnavailable(types.NamespacedName{Namespace: "test-ns", Name: "shared-pvc"}); !unavailable {
+		t.Error("expected shared-pvc to be attributed to the failed node")
+	}
+
+	if _, err := replay.GetAllVolumeMetrics(context.Background()); !errors.Is(err, ErrReplayExhausted) {
+		t.Errorf("expected replay to be exhausted, got %v", err)
+	}
+}
+
+func TestRecorder_Rotate(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := NewRecorder(dir, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		rec := recorder.begin(EndpointMetrics, start.Add(time.Duration(i)*time.Minute))
+		if err := rec.finish(recorder, NewMetricsCache()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	recordings, err := listRecordings(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recordings) != 2 || filepath.Base(recordings[0]) != start.Add(time.Minute).Format(recordingDirFormat) {
+		t.Errorf("expected the oldest recording to be removed, got %v", recordings)
+	}
+
+	// A single recording can be replayed on its own
+	replay, err := NewReplayCollector(recordings[1], true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for range 2 {
+		if _, recording, err := replay.Next(); err != nil || !recording.StartedAt.Equal(start.Add(2*time.Minute)) {
+			t.Errorf("expected looped replay of the newest recording, got %v, %v", recording, err)
+		}
+	}
+
+	if _, err := NewReplayCollector(filepath.Join(dir, "missing"), false); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+	if err := os.Mkdir(filepath.Join(dir, "empty"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewReplayCollector(filepath.Join(dir, "empty"), false); err == nil {
+		t.Error("expected an error for a directory without recordings")
+	}
+}
@@ -0,0 +1,157 @@
+package kubelet
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ErrReplayExhausted is returned once every recording has been replayed.
+var ErrReplayExhausted = errors.New("all recordings replayed")
+
+// ReplayCollector feeds recorded scrapes back through the parsers, one
+// recording per GetAllVolumeMetrics call, oldest first. Failed node scrapes
+// are replayed as failures.
+type ReplayCollector struct {
+	recordings     []string
+	next           int
+	loop           bool
+	dedupeStrategy string
+	mutex          sync.Mutex
+}
+
+var _ MetricsCollectorInterface = (*ReplayCollector)(nil)
+
+// NewReplayCollector replays the recordings below dir, or dir itself when it
+// is a single recording. With loop set, replay restarts at the oldest
+// recording instead of returning ErrReplayExhausted.
+func NewReplayCollector(dir string, loop bool) (*ReplayCollector, error) {
+	all, err := listRecordings(dir)
+	if err != nil {
+		return nil, err
+	}
+	// Recordings still being written have no manifest yet
+	var recordings []string
+	for _, recording := range all {
+		if _, err := os.Stat(filepath.Join(recording, ManifestFile)); err == nil {
+			recordings = append(recordings, recording)
+		}
+	}
+	if len(recordings) == 0 {
+		return nil, fmt.Errorf("no recordings found in %s", dir)
+	}
+	return &ReplayCollector{recordings: recordings, loop: loop, dedupeStrategy: DefaultDedupeStrategy}, nil
+}
+
+// SetDedupeStrategy selects how samples of a volume reported by several nodes
+// are merged, which may differ from the strategy used when recording.
+func (rc *ReplayCollector) SetDedupeStrategy(strategy string) error {
+	switch strategy {
+	case DedupeFreshest, DedupePessimistic, DedupeSameNode:
+		rc.dedupeStrategy = strategy
+		return nil
+	}
+	return fmt.Errorf("unsupported dedupe strategy %q, expected %q, %q or %q", strategy, DedupeFreshest, DedupePessimistic, DedupeSameNode)
+}
+
+// Len returns the number of recordings.
+func (rc *ReplayCollector) Len() int {
+	return len(rc.recordings)
+}
+
+func (rc *ReplayCollector) GetVolumeMetrics(ctx context.Context, namespacedName types.NamespacedName) (*VolumeMetrics, error) {
+	cache, err := rc.GetAllVolumeMetrics(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vm, exists := cache.Get(namespacedName)
+	if !exists {
+		return nil, fmt.Errorf("volume metrics not found for %s/%s", namespacedName.Namespace, namespacedName.Name)
+	}
+	return vm, nil
+}
+
+// GetAllVolumeMetrics replays the next recording.
+func (rc *ReplayCollector) GetAllVolumeMetrics(ctx context.Context) (*MetricsCache, error) {
+	cache, _, err := rc.Next()
+	return cache, err
+}
+
+// Next replays the next recording and returns its manifest, so callers can
+// align their clock with the time of the recording.
+func (rc *ReplayCollector) Next() (*MetricsCache, *Recording, error) {
+	rc.mutex.Lock()
+	if rc.next >= len(rc.recordings) {
+		if !rc.loop {
+			rc.mutex.Unlock()
+			return nil, nil, ErrReplayExhausted
+		}
+		rc.next = 0
+	}
+	dir := rc.recordings[rc.next]
+	rc.next++
+	rc.mutex.Unlock()
+
+	return ReplayRecording(dir, rc.dedupeStrategy)
+}
+
+// ReplayRecording parses a single recording into a cache like the scrape that
+// produced it. Like a live scrape, it fails when every node failed.
+func ReplayRecording(dir, dedupeStrategy string) (*MetricsCache, *Recording, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read recording manifest: %w", err)
+	}
+	var recording Recording
+	if err := json.Unmarshal(data, &recording); err != nil {
+		return nil, nil, fmt.Errorf("invalid recording manifest %s: %w", dir, err)
+	}
+	if recording.Version != recordingVersion {
+		return nil, nil, fmt.Errorf("unsupported recording version %d in %s", recording.Version, dir)
+	}
+
+	cache := NewMetricsCache()
+	cache.setPVCNodes(recording.PVCNodes)
+	var errs []error
+	for _, node := range recording.Nodes {
+		if node.Reason != "" {
+			failure := &NodeScrapeError{Node: node.Name, Reason: node.Reason, Err: errors.New(node.Error)}
+			cache.recordFailure(failure)
+			errs = append(errs, failure)
+			continue
+		}
+		nodeCache := NewMetricsCache()
+		if err := replayPayload(filepath.Join(dir, node.File), recording.Endpoint, node, nodeCache); err != nil {
+			return nil, nil, err
+		}
+		cache.addNodeSamples(node.Name, nodeCache)
+	}
+	if len(recording.Nodes) > 0 && len(errs) == len(recording.Nodes) {
+		return nil, &recording, fmt.Errorf("failed to scrape all %d nodes: %w", len(recording.Nodes), errors.Join(errs...))
+	}
+
+	cache.mergeSamples(dedupeStrategy)
+	cache.calculateUsagePercentages()
+	return cache, &recording, nil
+}
+
+func replayPayload(path, endpoint string, node RecordedNode, cache *MetricsCache) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open recorded payload of node %s: %w", node.Name, err)
+	}
+	defer f.Close()
+	reader, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("failed to decompress recorded payload of node %s: %w", node.Name, err)
+	}
+	defer reader.Close()
+	return parseNodePayload(reader, endpoint, node.ContentType, node.Name, node.ScrapedAt, cache)
+}
@@ -0,0 +1,311 @@
+package kubelet
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strconv"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Volume stats families exposed by the kubelet. Every other family on the
+// node is skipped without being decoded.
+const (
+	familyCapacityBytes  = "kubelet_volume_stats_capacity_bytes"
+	familyAvailableBytes = "kubelet_volume_stats_available_bytes"
+	familyInodes         = "kubelet_volume_stats_inodes"
+	familyInodesUsed     = "kubelet_volume_stats_inodes_used"
+
+	volumeStatsPrefix = "kubelet_volume_stats_"
+
+	// metricsAcceptHeader prefers the delimited protobuf exposition format and
+	// falls back to the text format.
+	metricsAcceptHeader = "application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited;q=0.7,text/plain;version=0.0.4;q=0.3"
+	summaryAcceptHeader = "application/json"
+
+	maxMetricLineBytes   = 1 << 20
+	maxMetricFamilyBytes = 64 << 20
+)
+
+// parseVolumeStats streams a kubelet metrics response into the cache, decoding
+// only the volume stats families.
+func parseVolumeStats(r io.Reader, contentType, nodeName string, cache *MetricsCache) error {
+	return parseVolumeStatsAt(r, contentType, nodeName, time.Now(), cache)
+}
+
+// parseVolumeStatsAt is parseVolumeStats for a response scraped at scrapedAt,
+// which is the timestamp of samples without their own.
+func parseVolumeStatsAt(r io.Reader, contentType, nodeName string, scrapedAt time.Time, cache *MetricsCache) error {
+	var err error
+	if isProtobuf(contentType) {
+		err = parseVolumeStatsProto(r, nodeName, cache, scrapedAt)
+	} else {
+		err = parseVolumeStatsText(r, nodeName, cache, scrapedAt)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse metrics from node %s: %w", nodeName, err)
+	}
+	return nil
+}
+
+func isProtobuf(contentType string) bool {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == expfmt.ProtoType && params["proto"] == expfmt.ProtoProtocol && params["encoding"] == "delimited"
+}
+
+// applyVolumeStat stores a sample. timestamp is the sample's own timestamp
+// when the exposition carries one, otherwise the scrape time.
+func applyVolumeStat(cache *MetricsCache, family string, pvc types.NamespacedName, value int64, nodeName string, timestamp time.Time) {
+	if pvc.Name == "" || pvc.Namespace == "" {
+		return
+	}
+	switch family {
+	case familyCapacityBytes:
+		cache.setCapacity(pvc, value)
+		cache.setSource(pvc, nodeName, "", SourceKubeletMetrics, timestamp)
+	case familyAvailableBytes:
+		cache.setAvailable(pvc, value)
+	case familyInodes:
+		cache.setInodesTotal(pvc, value)
+	case familyInodesUsed:
+		cache.setInodesUsed(pvc, value)
+	}
+}
+
+// volumeStatsFamily returns the volume stats family named by b, or "" for any
+// other family, without allocating.
+func volumeStatsFamily(b []byte) string {
+	switch string(b) {
+	case familyCapacityBytes:
+		return familyCapacityBytes
+	case familyAvailableBytes:
+		return familyAvailableBytes
+	case familyInodes:
+		return familyInodes
+	case familyInodesUsed:
+		return familyInodesUsed
+	}
+	return ""
+}
+
+func isVolumeStatsFamily(name string) bool {
+	switch name {
+	case familyCapacityBytes, familyAvailableBytes, familyInodes, familyInodesUsed:
+		return true
+	}
+	return false
+}
+
+// parseVolumeStatsProto reads length-delimited MetricFamily messages. Only
+// the family name, the first field of each message, is inspected before a
+// message is skipped, so unrelated families are never unmarshalled.
+func parseVolumeStatsProto(r io.Reader, nodeName string, cache *MetricsCache, scrapedAt time.Time) error {
+	reader := bufio.NewReader(r)
+	var buf []byte
+	for {
+		size, err := binary.ReadUvarint(reader)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if size > maxMetricFamilyBytes {
+			return fmt.Errorf("metric family of %d bytes exceeds the limit of %d", size, maxMetricFamilyBytes)
+		}
+		if uint64(cap(buf)) < size {
+			buf = make([]byte, size)
+		}
+		buf = buf[:size]
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return err
+		}
+
+		if !isVolumeStatsFamily(string(protoFamilyName(buf))) {
+			continue
+		}
+		var family dto.MetricFamily
+		if err := proto.Unmarshal(buf, &family); err != nil {
+			return err
+		}
+		for _, m := range family.Metric {
+			pvc, value := parseMetric(m)
+			timestamp := scrapedAt
+			if m.TimestampMs != nil {
+				timestamp = time.UnixMilli(m.GetTimestampMs())
+			}
+			applyVolumeStat(cache, family.GetName(), pvc, value, nodeName, timestamp)
+		}
+	}
+}
+
+// protoFamilyName returns the name field of an encoded MetricFamily when it is
+// the first field, as written by the Prometheus client libraries, or nil.
+func protoFamilyName(msg []byte) []byte {
+	num, typ, n := protowire.ConsumeTag(msg)
+	if n < 0 || num != 1 || typ != protowire.BytesType {
+		return nil
+	}
+	name, m := protowire.ConsumeBytes(msg[n:])
+	if m < 0 {
+		return nil
+	}
+	return name
+}
+
+func parseMetric(m *dto.Metric) (pvcName types.NamespacedName, value int64) {
+	for _, label := range m.GetLabel() {
+		if label.GetName() == "namespace" {
+			pvcName.Namespace = label.GetValue()
+		} else if label.GetName() == "persistentvolumeclaim" {
+			pvcName.Name = label.GetValue()
+		}
+	}
+	value = int64(m.GetGauge().GetValue())
+	return pvcName, value
+}
+
+func parseVolumeStatsText(r io.Reader, nodeName string, cache *MetricsCache, scrapedAt time.Time) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMetricLineBytes)
+	prefix := []byte(volumeStatsPrefix)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if !bytes.HasPrefix(line, prefix) {
+			continue
+		}
+		family, pvc, value, timestamp, err := parseVolumeStatsLine(line)
+		if err != nil {
+			return err
+		}
+		if timestamp.IsZero() {
+			timestamp = scrapedAt
+		}
+		applyVolumeStat(cache, family, pvc, value, nodeName, timestamp)
+	}
+	return scanner.Err()
+}
+
+// parseVolumeStatsLine parses a text exposition sample such as
+// kubelet_volume_stats_capacity_bytes{namespace="ns",persistentvolumeclaim="pvc"} 1.073741824e+09
+// with an optional millisecond timestamp. Families other than the volume stats
+// gauges yield an empty family name.
+func parseVolumeStatsLine(line []byte) (string, types.NamespacedName, int64, time.Time, error) {
+	var pvc types.NamespacedName
+	var timestamp time.Time
+
+	end := bytes.IndexAny(line, "{ \t")
+	if end < 0 {
+		return "", pvc, 0, timestamp, fmt.Errorf("invalid sample line %q", line)
+	}
+	name := volumeStatsFamily(line[:end])
+	if name == "" {
+		return "", pvc, 0, timestamp, nil
+	}
+	rest := line[end:]
+
+	if len(rest) > 0 && rest[0] == '{' {
+		var err error
+		rest, err = parseLabels(rest[1:], func(key, value []byte) {
+			switch string(key) {
+			case "namespace":
+				pvc.Namespace = string(value)
+			case "persistentvolumeclaim":
+				pvc.Name = string(value)
+			}
+		})
+		if err != nil {
+			return "", pvc, 0, timestamp, fmt.Errorf("invalid labels in %q: %w", line, err)
+		}
+	}
+
+	fields := bytes.Fields(rest)
+	if len(fields) == 0 {
+		return "", pvc, 0, timestamp, fmt.Errorf("missing value in %q", line)
+	}
+	value, err := strconv.ParseFloat(string(fields[0]), 64)
+	if err != nil {
+		return "", pvc, 0, timestamp, fmt.Errorf("invalid value in %q: %w", line, err)
+	}
+	if len(fields) > 1 {
+		ms, err := strconv.ParseInt(string(fields[1]), 10, 64)
+		if err != nil {
+			return "", pvc, 0, timestamp, fmt.Errorf("invalid timestamp in %q: %w", line, err)
+		}
+		timestamp = time.UnixMilli(ms)
+	}
+	return name, pvc, int64(value), timestamp, nil
+}
+
+// parseLabels parses label pairs up to the closing brace and returns the rest
+// of the line.
+func parseLabels(b []byte, fn func(key, value []byte)) ([]byte, error) {
+	for {
+		b = bytes.TrimLeft(b, " \t,")
+		if len(b) == 0 {
+			return nil, fmt.Errorf("unterminated label set")
+		}
+		if b[0] == '}' {
+			return b[1:], nil
+		}
+
+		eq := bytes.IndexByte(b, '=')
+		if eq < 0 || eq+1 >= len(b) || b[eq+1] != '"' {
+			return nil, fmt.Errorf("malformed label")
+		}
+		key := bytes.TrimSpace(b[:eq])
+		b = b[eq+2:]
+
+		value, n, err := unquoteLabelValue(b)
+		if err != nil {
+			return nil, err
+		}
+		fn(key, value)
+		b = b[n:]
+	}
+}
+
+// unquoteLabelValue returns the value up to the closing quote and the number
+// of bytes consumed. Values without escapes are returned without copying.
+func unquoteLabelValue(b []byte) ([]byte, int, error) {
+	var buf []byte
+	for i := 0; i < len(b); i++ {
+		switch b[i] {
+		case '"':
+			if buf == nil {
+				return b[:i], i + 1, nil
+			}
+			return buf, i + 1, nil
+		case '\\':
+			if buf == nil {
+				buf = append([]byte{}, b[:i]...)
+			}
+			i++
+			if i >= len(b) {
+				return nil, 0, fmt.Errorf("unterminated escape")
+			}
+			switch b[i] {
+			case 'n':
+				buf = append(buf, '\n')
+			default:
+				buf = append(buf, b[i])
+			}
+		default:
+			if buf != nil {
+				buf = append(buf, b[i])
+			}
+		}
+	}
+	return nil, 0, fmt.Errorf("unterminated label value")
+}
@@ -0,0 +1,221 @@
+package kubelet
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+)
+
+func TestParseVolumeStatsText(t *testing.T) {
+	body := `# HELP kubelet_volume_stats_capacity_bytes [ALPHA] Capacity in bytes of the volume
+# TYPE kubelet_volume_stats_capacity_bytes gauge
+kubelet_volume_stats_capacity_bytes{namespace="db",persistentvolumeclaim="data"} 1.073741824e+09
+kubelet_volume_stats_capacity_bytes{persistentvolumeclaim="quoted \"pvc\"",namespace="odd\\ns"} 2048 1700000000000
+kubelet_volume_stats_available_bytes{namespace="db",persistentvolumeclaim="data"} 536870912
+kubelet_volume_stats_inodes{namespace="db",persistentvolumeclaim="data"} 65536
+kubelet_volume_stats_inodes_free{namespace="db",persistentvolumeclaim="data"} 1
+kubelet_volume_stats_inodes_used{namespace="db",persistentvolumeclaim="data"} 32768
+kubelet_volume_stats_health_status_abnormal{namespace="db",persistentvolumeclaim="data",status="normal"} 0
+apiserver_request_duration_seconds_bucket{le="0.1"} 42
+`
+	cache := NewMetricsCache()
+	if err := parseVolumeStats(strings.NewReader(body), "text/plain; version=0.0.4", "worker-1", cache); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cache.calculateUsagePercentages()
+
+	vm, exists := cache.Get(types.NamespacedName{Namespace: "db", Name: "data"})
+	if !exists {
+		t.Fatal("expected metrics for db/data")
+	}
+	if vm.CapacityBytes != 1073741824 || vm.AvailableBytes != 536870912 || vm.InodesTotal != 65536 || vm.InodesUsed != 32768 {
+		t.Errorf("unexpected metrics: %+v", vm)
+	}
+	if vm.NodeName != "worker-1" || vm.Source != SourceKubeletMetrics {
+		t.Errorf("expected worker-1 and %s as source, got %s and %s", SourceKubeletMetrics, vm.NodeName, vm.Source)
+	}
+	if vm.Timestamp.IsZero() {
+		t.Error("expected scrape time for samples without a timestamp")
+	}
+
+	if vm, exists := cache.Get(types.NamespacedName{Namespace: `odd\ns`, Name: `quoted "pvc"`}); !exists || vm.CapacityBytes != 2048 {
+		t.Errorf("expected escaped labels to be decoded, got %+v", cache.GetAll())
+	} else if !vm.Timestamp.Equal(time.UnixMilli(1700000000000)) {
+		t.Errorf("expected sample timestamp to be used, got %s", vm.Timestamp)
+	}
+	if len(cache.GetAll()) != 2 {
+		t.Errorf("expected 2 PVCs, got %d", len(cache.GetAll()))
+	}
+}
+
+func TestParseVolumeStatsText_Invalid(t *testing.T) {
+	tests := []string{
+		`kubelet_volume_stats_capacity_bytes{namespace="db",persistentvolumeclaim="data"`,
+		`kubelet_volume_stats_capacity_bytes{namespace="db} 1`,
+		`kubelet_volume_stats_capacity_bytes{namespace="db",persistentvolumeclaim="data"}`,
+		`kubelet_volume_stats_capacity_bytes{namespace="db",persistentvolumeclaim="data"} many`,
+	}
+
+	for _, line := range tests {
+		if err := parseVolumeStats(strings.NewReader(line+"\n"), "", "worker-1", NewMetricsCache()); err == nil {
+			t.Errorf("expected error for %q", line)
+		}
+	}
+}
+
+func gaugeFamily(name string, samples map[types.NamespacedName]float64) *dto.MetricFamily {
+	family := &dto.MetricFamily{Name: ptr.To(name), Type: dto.MetricType_GAUGE.Enum()}
+	for pvc, value := range samples {
+		family.Metric = append(family.Metric, &dto.Metric{
+			Label: []*dto.LabelPair{
+				{Name: ptr.To("namespace"), Value: ptr.To(pvc.Namespace)},
+				{Name: ptr.To("persistentvolumeclaim"), Value: ptr.To(pvc.Name)},
+			},
+			Gauge: &dto.Gauge{Value: ptr.To(value)},
+		})
+	}
+	return family
+}
+
+func encodeFamilies(t testing.TB, format expfmt.Format, families ...*dto.MetricFamily) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	encoder := expfmt.NewEncoder(&buf, format)
+	for _, family := range families {
+		if err := encoder.Encode(family); err != nil {
+			t.Fatalf("failed to encode %s: %v", family.GetName(), err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestMetricsCollector_ProtobufNegotiation(t *testing.T) {
+	pvc := types.NamespacedName{Namespace: "db", Name: "data"}
+	protoFormat := expfmt.NewFormat(expfmt.TypeProtoDelim)
+	body := encodeFamilies(t, protoFormat,
+		&dto.MetricFamily{
+			Name:   ptr.To("process_cpu_seconds_total"),
+			Type:   dto.MetricType_COUNTER.Enum(),
+			Metric: []*dto.Metric{{Counter: &dto.Counter{Value: ptr.To(1.0)}}},
+		},
+		gaugeFamily(familyCapacityBytes, map[types.NamespacedName]float64{pvc: 1000}),
+		gaugeFamily(familyAvailableBytes, map[types.NamespacedName]float64{pvc: 250}),
+	)
+
+	var accept string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept")
+		w.Header().Set("Content-Type", string(protoFormat))
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	mc := newTestCollector(t, server.URL, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}})
+	cache, err := mc.GetAllVolumeMetrics(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(accept, "application/vnd.google.protobuf") {
+		t.Errorf("expected protobuf to be requested, got Accept %q", accept)
+	}
+
+	vm, exists := cache.Get(pvc)
+	if !exists {
+		t.Fatal("expected metrics for db/data")
+	}
+	if vm.UsagePercent != 75 {
+		t.Errorf("expected 75%% usage, got %.1f", vm.UsagePercent)
+	}
+}
+
+// largeNodeFamilies approximates a busy node: a few volumes among thousands of
+// unrelated histogram samples.
+func largeNodeFamilies() []*dto.MetricFamily {
+	var families []*dto.MetricFamily
+	for i := 0; i < 200; i++ {
+		family := &dto.MetricFamily{
+			Name: ptr.To(fmt.Sprintf("apiserver_request_duration_%d_seconds", i)),
+			Type: dto.MetricType_HISTOGRAM.Enum(),
+		}
+		for j := 0; j < 10; j++ {
+			histogram := &dto.Histogram{SampleCount: ptr.To(uint64(100)), SampleSum: ptr.To(12.5)}
+			for _, bound := range []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10} {
+				histogram.Bucket = append(histogram.Bucket, &dto.Bucket{UpperBound: ptr.To(bound), CumulativeCount: ptr.To(uint64(10))})
+			}
+			family.Metric = append(family.Metric, &dto.Metric{
+				Label:     []*dto.LabelPair{{Name: ptr.To("verb"), Value: ptr.To(fmt.Sprintf("verb-%d", j))}},
+				Histogram: histogram,
+			})
+		}
+		families = append(families, family)
+	}
+
+	samples := make(map[types.NamespacedName]float64)
+	for i := 0; i < 50; i++ {
+		samples[types.NamespacedName{Namespace: "ns", Name: fmt.Sprintf("pvc-%d", i)}] = float64(1 << 30)
+	}
+	for _, name := range []string{familyCapacityBytes, familyAvailableBytes, familyInodes, familyInodesUsed} {
+		families = append(families, gaugeFamily(name, samples))
+	}
+	return families
+}
+
+func BenchmarkParseVolumeStats(b *testing.B) {
+	families := largeNodeFamilies()
+	textFormat := expfmt.NewFormat(expfmt.TypeTextPlain)
+	protoFormat := expfmt.NewFormat(expfmt.TypeProtoDelim)
+	text := encodeFamilies(b, textFormat, families...)
+	proto := encodeFamilies(b, protoFormat, families...)
+
+	b.Run("text-to-metric-families", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(text)))
+		for i := 0; i < b.N; i++ {
+			// The previous approach: decode every family on the node
+			var parser expfmt.TextParser
+			parsed, err := parser.TextToMetricFamilies(bytes.NewReader(text))
+			if err != nil {
+				b.Fatal(err)
+			}
+			cache := NewMetricsCache()
+			for _, name := range []string{familyCapacityBytes, familyAvailableBytes, familyInodes, familyInodesUsed} {
+				for _, m := range parsed[name].GetMetric() {
+					pvc, value := parseMetric(m)
+					applyVolumeStat(cache, name, pvc, value, "node", time.Time{})
+				}
+			}
+		}
+	})
+
+	b.Run("streaming-text", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(text)))
+		for i := 0; i < b.N; i++ {
+			if err := parseVolumeStats(bytes.NewReader(text), string(textFormat), "node", NewMetricsCache()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("protobuf", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(proto)))
+		for i := 0; i < b.N; i++ {
+			if err := parseVolumeStats(bytes.NewReader(proto), string(protoFormat), "node", NewMetricsCache()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
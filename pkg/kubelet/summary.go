@@ -0,0 +1,94 @@
+package kubelet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// summary is the subset of the kubelet /stats/summary response describing
+// pod volumes.
+type summary struct {
+	Node struct {
+		NodeName string `json:"nodeName"`
+	} `json:"node"`
+	Pods []struct {
+		PodRef struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"podRef"`
+		Volumes []summaryVolume `json:"volume"`
+	} `json:"pods"`
+}
+
+type summaryVolume struct {
+	Time           time.Time `json:"time"`
+	AvailableBytes *uint64   `json:"availableBytes"`
+	CapacityBytes  *uint64   `json:"capacityBytes"`
+	UsedBytes      *uint64   `json:"usedBytes"`
+	InodesFree     *uint64   `json:"inodesFree"`
+	Inodes         *uint64   `json:"inodes"`
+	InodesUsed     *uint64   `json:"inodesUsed"`
+	Name           string    `json:"name"`
+	PVCRef         *struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"pvcRef"`
+}
+
+// parseSummary adds the PVC volumes of a kubelet summary to the cache.
+func parseSummary(body io.Reader, nodeName string, cache *MetricsCache) error {
+	return parseSummaryAt(body, nodeName, time.Now(), cache)
+}
+
+// parseSummaryAt is parseSummary for a response scraped at scrapedAt, which
+// is the timestamp of volumes the kubelet reports no time for.
+func parseSummaryAt(body io.Reader, nodeName string, scrapedAt time.Time, cache *MetricsCache) error {
+	var s summary
+	if err := json.NewDecoder(body).Decode(&s); err != nil {
+		return fmt.Errorf("failed to parse stats summary from node %s: %w", nodeName, err)
+	}
+	if s.Node.NodeName != "" {
+		nodeName = s.Node.NodeName
+	}
+
+	for _, pod := range s.Pods {
+		for _, volume := range pod.Volumes {
+			if volume.PVCRef == nil || volume.PVCRef.Name == "" || volume.CapacityBytes == nil {
+				continue
+			}
+
+			vm := &VolumeMetrics{
+				CapacityBytes:  toInt64(volume.CapacityBytes),
+				AvailableBytes: toInt64(volume.AvailableBytes),
+				InodesTotal:    toInt64(volume.Inodes),
+				InodesUsed:     toInt64(volume.InodesUsed),
+				NodeName:       nodeName,
+				PodName:        pod.PodRef.Name,
+				Timestamp:      volume.Time,
+				Source:         SourceKubeletSummary,
+			}
+			if vm.Timestamp.IsZero() {
+				vm.Timestamp = scrapedAt
+			}
+
+			namespace := volume.PVCRef.Namespace
+			if namespace == "" {
+				namespace = pod.PodRef.Namespace
+			}
+			cache.set(types.NamespacedName{Namespace: namespace, Name: volume.PVCRef.Name}, vm)
+		}
+	}
+
+	return nil
+}
+
+func toInt64(v *uint64) int64 {
+	if v == nil {
+		return 0
+	}
+	return int64(*v)
+}
@@ -0,0 +1,119 @@
+package kubelet
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testSummary = `{
+  "node": {"nodeName": "worker-1"},
+  "pods": [
+    {
+      "podRef": {"name": "postgres-0", "namespace": "db"},
+      "volume": [
+        {
+          "time": "2025-01-01T12:00:00Z",
+          "availableBytes": 268435456,
+          "capacityBytes": 1073741824,
+          "usedBytes": 805306368,
+          "inodesFree": 100,
+          "inodes": 1000,
+          "inodesUsed": 900,
+          "name": "data",
+          "pvcRef": {"name": "data-postgres-0", "namespace": "db"}
+        },
+        {
+          "time": "2025-01-01T12:00:00Z",
+          "availableBytes": 1024,
+          "capacityBytes": 2048,
+          "name": "kube-api-access"
+        }
+      ]
+    }
+  ]
+}`
+
+func TestParseSummary(t *testing.T) {
+	cache := NewMetricsCache()
+	if err := parseSummary(strings.NewReader(testSummary), "ignored", cache); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cache.calculateUsagePercentages()
+
+	if len(cache.GetAll()) != 1 {
+		t.Fatalf("expected only PVC volumes to be cached, got %v", cache.GetAll())
+	}
+
+	vm, exists := cache.Get(types.NamespacedName{Namespace: "db", Name: "data-postgres-0"})
+	if !exists {
+		t.Fatal("expected metrics for db/data-postgres-0")
+	}
+	if vm.UsagePercent != 75 {
+		t.Errorf("expected usage 75%%, got %f", vm.UsagePercent)
+	}
+	if vm.InodesUsagePercent != 90 {
+		t.Errorf("expected inodes usage 90%%, got %f", vm.InodesUsagePercent)
+	}
+	if vm.NodeName != "worker-1" || vm.PodName != "postgres-0" {
+		t.Errorf("expected attribution to worker-1/postgres-0, got %s/%s", vm.NodeName, vm.PodName)
+	}
+	if expected := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC); !vm.Timestamp.Equal(expected) {
+		t.Errorf("expected timestamp %v, got %v", expected, vm.Timestamp)
+	}
+}
+
+func TestParseSummary_Invalid(t *testing.T) {
+	if err := parseSummary(strings.NewReader("not json"), "worker-1", NewMetricsCache()); err == nil {
+		t.Error("expected error for invalid summary")
+	}
+}
+
+func TestMetricsCollector_SummaryEndpoint(t *testing.T) {
+	var requestedPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedPath = r.URL.Path
+		_, _ = w.Write([]byte(testSummary))
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}}).
+		Build()
+
+	mc, err := NewMetricsCollector(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mc.SetClient(fakeClient, nil)
+	if err := mc.SetEndpoint(EndpointSummary); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	vm, err := mc.GetVolumeMetrics(context.Background(), types.NamespacedName{Namespace: "db", Name: "data-postgres-0"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requestedPath != "/stats/summary" {
+		t.Errorf("expected summary endpoint to be requested, got %s", requestedPath)
+	}
+	if vm.UsagePercent != 75 {
+		t.Errorf("expected usage 75%%, got %f", vm.UsagePercent)
+	}
+
+	if err := mc.SetEndpoint("stats"); err == nil {
+		t.Error("expected error for unsupported endpoint")
+	}
+}
@@ -0,0 +1,101 @@
+package kubelet
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// URLTemplateData holds the per-node fields available to a custom kubelet URL
+// template, e.g. "https://{{.NodeIP}}:10250/metrics".
+type URLTemplateData struct {
+	NodeName     string
+	NodeIP       string
+	NodeHostname string
+	KubeletPort  int32
+}
+
+// NewURLTemplateData resolves the addresses of a node, preferring the
+// InternalIP over the ExternalIP for NodeIP, and its advertised kubelet port.
+func NewURLTemplateData(node *corev1.Node) URLTemplateData {
+	data := URLTemplateData{NodeName: node.Name, KubeletPort: node.Status.DaemonEndpoints.KubeletEndpoint.Port}
+	if data.KubeletPort == 0 {
+		data.KubeletPort = DefaultKubeletPort
+	}
+	var externalIP string
+	for _, addr := range node.Status.Addresses {
+		switch addr.Type {
+		case corev1.NodeInternalIP:
+			if data.NodeIP == "" {
+				data.NodeIP = addr.Address
+			}
+		case corev1.NodeExternalIP:
+			if externalIP == "" {
+				externalIP = addr.Address
+			}
+		case corev1.NodeHostName:
+			if data.NodeHostname == "" {
+				data.NodeHostname = addr.Address
+			}
+		}
+	}
+	if data.NodeIP == "" {
+		data.NodeIP = externalIP
+	}
+	return data
+}
+
+// parseURLTemplate parses a custom kubelet URL and validates it against sample
+// node data, so invalid templates are rejected at startup.
+func parseURLTemplate(rawURL string) (*template.Template, error) {
+	tmpl, err := template.New("kubelet-url").Option("missingkey=error").Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse URL template: %w", err)
+	}
+
+	sample, err := executeURLTemplate(tmpl, URLTemplateData{NodeName: "node", NodeIP: "10.0.0.1", NodeHostname: "node", KubeletPort: DefaultKubeletPort})
+	if err != nil {
+		return nil, err
+	}
+	if err := validateKubeletURL(sample); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+func executeURLTemplate(tmpl *template.Template, data URLTemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render URL template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// renderKubeletURL renders the URL for a node. The kubelet endpoint is appended
+// unless the template already specifies a path.
+func (mc *MetricsCollector) renderKubeletURL(data URLTemplateData, endpoint string) (string, error) {
+	if data.NodeIP == "" && strings.Contains(mc.kubeletURL, ".NodeIP") {
+		return "", fmt.Errorf("node %s has no InternalIP or ExternalIP address", data.NodeName)
+	}
+
+	rendered, err := executeURLTemplate(mc.kubeletURLTemplate, data)
+	if err != nil {
+		return "", err
+	}
+	if err := validateKubeletURL(rendered); err != nil {
+		return "", fmt.Errorf("invalid kubelet URL for node %s: %w", data.NodeName, err)
+	}
+
+	parsed, err := url.Parse(rendered)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse URL: %w", err)
+	}
+	if parsed.Path == "" || parsed.Path == "/" {
+		parsed.Path = "/" + endpoint
+	}
+	return parsed.String(), nil
+}
@@ -0,0 +1,143 @@
+package kubelet
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestNewURLTemplateData(t *testing.T) {
+	tests := []struct {
+		name       string
+		addresses  []corev1.NodeAddress
+		expectedIP string
+	}{
+		{
+			name: "prefers internal IP",
+			addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeExternalIP, Address: "203.0.113.10"},
+				{Type: corev1.NodeInternalIP, Address: "10.0.0.5"},
+			},
+			expectedIP: "10.0.0.5",
+		},
+		{
+			name:       "falls back to external IP",
+			addresses:  []corev1.NodeAddress{{Type: corev1.NodeExternalIP, Address: "203.0.113.10"}},
+			expectedIP: "203.0.113.10",
+		},
+		{
+			name:       "no addresses",
+			expectedIP: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker-1"},
+				Status:     corev1.NodeStatus{Addresses: tt.addresses},
+			}
+			data := NewURLTemplateData(node)
+			if data.NodeName != "worker-1" {
+				t.Errorf("expected node name worker-1, got %s", data.NodeName)
+			}
+			if data.NodeIP != tt.expectedIP {
+				t.Errorf("expected IP %q, got %q", tt.expectedIP, data.NodeIP)
+			}
+		})
+	}
+}
+
+func TestRenderKubeletURL(t *testing.T) {
+	data := URLTemplateData{NodeName: "worker-1", NodeIP: "10.0.0.5", KubeletPort: 10250}
+	tests := []struct {
+		name       string
+		kubeletURL string
+		expected   string
+		wantErr    bool
+	}{
+		{"plain URL appends endpoint", "http://mock-service:8080", "http://mock-service:8080/metrics", false},
+		{"node IP with path", "https://{{.NodeIP}}:10250/metrics", "https://10.0.0.5:10250/metrics", false},
+		{"direct template", DirectURLTemplate, "https://10.0.0.5:10250/metrics", false},
+		{"node name without path", "http://agent-{{.NodeName}}:9100", "http://agent-worker-1:9100/metrics", false},
+		{"unknown field", "http://{{.Zone}}:9100", "", true},
+		{"blocked host", "http://169.254.169.254/{{.NodeName}}", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mc, err := NewMetricsCollector(tt.kubeletURL)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got, err := mc.renderKubeletURL(data, EndpointMetrics)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRenderKubeletURL_MissingNodeIP(t *testing.T) {
+	mc, err := NewMetricsCollector("https://{{.NodeIP}}:10250")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := mc.renderKubeletURL(URLTemplateData{NodeName: "worker-1"}, EndpointMetrics); err == nil {
+		t.Error("expected error for node without address")
+	}
+}
+
+func TestGetAllVolumeMetrics_PerNodeURL(t *testing.T) {
+	var mutex sync.Mutex
+	requested := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requested[r.URL.Path] = true
+		mutex.Unlock()
+		// Each node reports its own PVC
+		pvc := strings.TrimPrefix(r.URL.Path, "/nodes/")
+		_, _ = w.Write([]byte(strings.ReplaceAll(testNodeMetrics, "test-pvc", pvc+"-pvc")))
+	}))
+	defer server.Close()
+
+	mc := newTestCollector(t, server.URL+"/nodes/{{.NodeName}}",
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-b"}},
+	)
+
+	cache, err := mc.GetAllVolumeMetrics(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requested) != 2 || !requested["/nodes/node-a"] || !requested["/nodes/node-b"] {
+		t.Errorf("expected one request per node, got %v", requested)
+	}
+	for _, name := range []string{"node-a-pvc", "node-b-pvc"} {
+		vm, exists := cache.Get(types.NamespacedName{Namespace: "test-ns", Name: name})
+		if !exists {
+			t.Errorf("expected metrics for %s", name)
+			continue
+		}
+		if !strings.HasPrefix(name, vm.NodeName) {
+			t.Errorf("expected %s to be attributed to its node, got %s", name, vm.NodeName)
+		}
+	}
+}
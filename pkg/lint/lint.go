@@ -0,0 +1,504 @@
+// Package lint checks pvc-chonker annotations, PVCPolicies and PVCGroups in
+// manifests before they reach a cluster, with the parsers the operator uses.
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	pvcchonkerv1alpha1 "github.com/logicIQ/pvc-chonker/api/v1alpha1"
+	"github.com/logicIQ/pvc-chonker/pkg/annotations"
+)
+
+// Severity of an issue. Errors are values the operator rejects or ignores,
+// warnings are valid configurations that likely do not do what was intended.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Document is a manifest document decoded to JSON. Index is its 1-based
+// position in File.
+type Document struct {
+	File  string
+	Index int
+	Data  []byte
+}
+
+// Issue is a problem found in a manifest document.
+type Issue struct {
+	File      string   `json:"file"`
+	Document  int      `json:"document"`
+	Kind      string   `json:"kind,omitempty"`
+	Namespace string   `json:"namespace,omitempty"`
+	Name      string   `json:"name,omitempty"`
+	Field     string   `json:"field,omitempty"`
+	Severity  Severity `json:"severity"`
+	Message   string   `json:"message"`
+}
+
+// templateFields maps configuration annotations to the template fields of
+// PVCPolicies and PVCGroups.
+var templateFields = map[string]string{
+	annotations.AnnotationThreshold:       "threshold",
+	annotations.AnnotationInodesThreshold: "inodesThreshold",
+	annotations.AnnotationIncrease:        "increase",
+	annotations.AnnotationMaxSize:         "maxSize",
+	annotations.AnnotationMinScaleUp:      "minScaleUp",
+	annotations.AnnotationCooldown:        "cooldown",
+}
+
+// object is a decoded manifest object issues are reported on.
+type object struct {
+	doc       Document
+	kind      string
+	namespace string
+	name      string
+}
+
+type policyEntry struct {
+	object
+	selector labels.Selector
+}
+
+type claimEntry struct {
+	object
+	labels labels.Set
+	// annotated claims are configured by annotations, so PVCPolicies do not
+	// apply to them
+	annotated bool
+}
+
+type linter struct {
+	issues   []Issue
+	policies []policyEntry
+	claims   []claimEntry
+}
+
+// Lint checks every document and returns the issues found, in document order
+// followed by the issues spanning several objects.
+func Lint(docs []Document) []Issue {
+	l := &linter{issues: []Issue{}}
+	for _, doc := range docs {
+		l.document(doc)
+	}
+	l.checkOverlappingPolicies()
+	l.checkClaimPolicies()
+	return l.issues
+}
+
+// HasErrors reports whether any issue is an error.
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *linter) report(obj object, field string, severity Severity, format string, args ...any) {
+	l.issues = append(l.issues, Issue{
+		File:      obj.doc.File,
+		Document:  obj.doc.Index,
+		Kind:      obj.kind,
+		Namespace: obj.namespace,
+		Name:      obj.name,
+		Field:     field,
+		Severity:  severity,
+		Message:   fmt.Sprintf(format, args...),
+	})
+}
+
+func (l *linter) document(doc Document) {
+	var meta struct {
+		metav1.TypeMeta
+		Metadata metav1.ObjectMeta `json:"metadata"`
+	}
+	if err := json.Unmarshal(doc.Data, &meta); err != nil {
+		l.report(object{doc: doc}, "", SeverityError, "not a Kubernetes object: %v", err)
+		return
+	}
+	obj := object{doc: doc, kind: meta.Kind, namespace: meta.Metadata.Namespace, name: meta.Metadata.Name}
+
+	switch meta.Kind {
+	case "List":
+		var list struct {
+			Items []runtime.RawExtension `json:"items"`
+		}
+		if err := json.Unmarshal(doc.Data, &list); err != nil {
+			l.report(obj, "items", SeverityError, "invalid List: %v", err)
+			return
+		}
+		for _, item := range list.Items {
+			l.document(Document{File: doc.File, Index: doc.Index, Data: item.Raw})
+		}
+	case "PersistentVolumeClaim":
+		var pvc corev1.PersistentVolumeClaim
+		if err := json.Unmarshal(doc.Data, &pvc); err != nil {
+			l.report(obj, "", SeverityError, "invalid PersistentVolumeClaim: %v", err)
+			return
+		}
+		l.claim(obj, "metadata.annotations", pvc.ObjectMeta, pvc.Labels, pvc.Spec.Resources.Requests)
+	case "StatefulSet":
+		var sts appsv1.StatefulSet
+		if err := json.Unmarshal(doc.Data, &sts); err != nil {
+			l.report(obj, "", SeverityError, "invalid StatefulSet: %v", err)
+			return
+		}
+		for i, template := range sts.Spec.VolumeClaimTemplates {
+			// The StatefulSet controller adds the selector labels to its PVCs
+			claimLabels := labels.Set{}
+			for key, value := range template.Labels {
+				claimLabels[key] = value
+			}
+			if sts.Spec.Selector != nil {
+				for key, value := range sts.Spec.Selector.MatchLabels {
+					claimLabels[key] = value
+				}
+			}
+			l.claim(obj, fmt.Sprintf("spec.volumeClaimTemplates[%d].metadata.annotations", i), template.ObjectMeta, claimLabels, template.Spec.Resources.Requests)
+		}
+	case "StorageClass":
+		var sc storagev1.StorageClass
+		if err := json.Unmarshal(doc.Data, &sc); err != nil {
+			l.report(obj, "", SeverityError, "invalid StorageClass: %v", err)
+			return
+		}
+		l.storageClass(obj, sc.Annotations)
+	case "PVCPolicy":
+		var policy pvcchonkerv1alpha1.PVCPolicy
+		if err := decodeStrict(doc.Data, &policy); err != nil {
+			l.report(obj, "", SeverityError, "invalid PVCPolicy: %v", err)
+			return
+		}
+		l.policy(obj, &policy)
+	case "PVCGroup":
+		var group pvcchonkerv1alpha1.PVCGroup
+		if err := decodeStrict(doc.Data, &group); err != nil {
+			l.report(obj, "", SeverityError, "invalid PVCGroup: %v", err)
+			return
+		}
+		l.group(obj, &group)
+	}
+}
+
+// decodeStrict decodes the custom resource and rejects unknown fields, which
+// are usually misspelled template fields.
+func decodeStrict(data []byte, into any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(into)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (l *linter) claim(obj object, field string, meta metav1.ObjectMeta, claimLabels labels.Set, requests corev1.ResourceList) {
+	enabled, hasEnabled := meta.Annotations[annotations.AnnotationEnabled]
+	enabledByAnnotation := hasEnabled && strings.ToLower(enabled) == "true"
+	disabledByAnnotation := hasEnabled && strings.ToLower(enabled) == "false"
+
+	valid := map[string]string{}
+	annotationsValid := true
+	for _, key := range sortedKeys(meta.Annotations) {
+		value := meta.Annotations[key]
+		keyField := field + "[" + key + "]"
+		err := annotations.ValidateAnnotation(key, value)
+		switch {
+		case errors.Is(err, annotations.ErrUnknownAnnotation):
+			l.report(obj, keyField, SeverityWarning, "unknown annotation, it is ignored")
+		case err != nil:
+			l.report(obj, keyField, SeverityError, "invalid value %q: %v%s", value, err, claimConsequence(key, enabledByAnnotation))
+			if key != annotations.AnnotationIncrease && key != annotations.AnnotationPausedUntil {
+				annotationsValid = false
+			}
+		case annotations.IsConfigAnnotation(key):
+			valid[key] = value
+			if !hasEnabled {
+				l.report(obj, keyField, SeverityWarning, "ignored without %s=true", annotations.AnnotationEnabled)
+			}
+		}
+	}
+
+	if enabledByAnnotation && annotationsValid {
+		var requested *resource.Quantity
+		if size, exists := requests[corev1.ResourceStorage]; exists {
+			requested = &size
+		}
+		l.checkUnsafe(obj, func(key string) string { return field + "[" + key + "]" }, valid, requested)
+	}
+
+	l.claims = append(l.claims, claimEntry{
+		object: obj,
+		labels: claimLabels,
+		// PVCs with invalid annotations fall back to PVCPolicies
+		annotated: disabledByAnnotation || (enabledByAnnotation && annotationsValid),
+	})
+}
+
+// claimConsequence describes what the operator does with an invalid PVC
+// annotation.
+func claimConsequence(key string, enabledByAnnotation bool) string {
+	switch key {
+	case annotations.AnnotationEnabled:
+		return "; the PVC is not configured by annotations and only a matching PVCPolicy applies"
+	case annotations.AnnotationIncrease:
+		return "; expansions of the PVC fail"
+	case annotations.AnnotationPausedUntil:
+		return "; expansions of the PVC are suspended until it is fixed"
+	case annotations.AnnotationMaxInFlightResizes, annotations.AnnotationMaxExpansionsPerMinute:
+		return "; it is only read from StorageClasses"
+	}
+	if enabledByAnnotation {
+		return "; all annotations of the PVC are ignored and only a matching PVCPolicy applies"
+	}
+	return ""
+}
+
+func (l *linter) storageClass(obj object, scAnnotations map[string]string) {
+	for _, key := range sortedKeys(scAnnotations) {
+		if !strings.HasPrefix(key, "pvc-chonker.io/") {
+			continue
+		}
+		field := "metadata.annotations[" + key + "]"
+		if key != annotations.AnnotationMaxInFlightResizes && key != annotations.AnnotationMaxExpansionsPerMinute {
+			l.report(obj, field, SeverityWarning, "not read from StorageClasses, it is ignored")
+			continue
+		}
+		if err := annotations.ValidateAnnotation(key, scAnnotations[key]); err != nil {
+			l.report(obj, field, SeverityError, "%v; the limit is ignored", err)
+		}
+	}
+}
+
+func (l *linter) policy(obj object, policy *pvcchonkerv1alpha1.PVCPolicy) {
+	selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.Selector)
+	if err != nil {
+		l.report(obj, "spec.selector", SeverityError, "invalid selector: %v; PVCs of the namespace not configured by annotations or an earlier PVCPolicy are not managed", err)
+	} else {
+		if selector.Empty() {
+			l.report(obj, "spec.selector", SeverityWarning, "empty selector, the policy applies to every PVC in the namespace")
+		}
+		l.policies = append(l.policies, policyEntry{object: obj, selector: selector})
+	}
+
+	valid := l.checkTemplate(obj, annotations.PolicyTemplateAnnotations(policy.Spec.Template), func(key string, err error) string {
+		if key == annotations.AnnotationIncrease {
+			return "; expansions of matching PVCs fail"
+		}
+		return "; the global default is used instead"
+	})
+	l.checkUnsafe(obj, templateField, valid, nil)
+}
+
+func (l *linter) group(obj object, group *pvcchonkerv1alpha1.PVCGroup) {
+	if group.Spec.Template.Enabled != nil {
+		l.report(obj, "spec.template.enabled", SeverityWarning, "not applied to members, annotate member PVCs with %s instead", annotations.AnnotationEnabled)
+	}
+	valid := l.checkTemplate(obj, annotations.GroupTemplateAnnotations(group.Spec.Template), func(key string, err error) string {
+		if key == annotations.AnnotationIncrease {
+			return "; the webhook copies it to member PVCs, whose expansions then fail"
+		}
+		return "; the webhook copies it to member PVCs, whose annotations are then ignored"
+	})
+	l.checkUnsafe(obj, templateField, valid, nil)
+}
+
+func templateField(key string) string {
+	return "spec.template." + templateFields[key]
+}
+
+// checkTemplate validates the template values as annotations, since the
+// operator parses both the same way. It returns the valid values.
+func (l *linter) checkTemplate(obj object, values map[string]string, consequence func(key string, err error) string) map[string]string {
+	valid := map[string]string{}
+	for _, key := range sortedKeys(values) {
+		if err := annotations.ValidateAnnotation(key, values[key]); err != nil {
+			l.report(obj, templateField(key), SeverityError, "invalid value %q: %v%s", values[key], err, consequence(key, err))
+			continue
+		}
+		valid[key] = values[key]
+	}
+	return valid
+}
+
+// checkUnsafe flags valid configurations that expand too often, never or not
+// as much as intended.
+func (l *linter) checkUnsafe(obj object, field func(key string) string, values map[string]string, requested *resource.Quantity) {
+	if value, exists := values[annotations.AnnotationThreshold]; exists {
+		switch percent, _ := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "%"), 64); percent {
+		case 0:
+			l.report(obj, field(annotations.AnnotationThreshold), SeverityWarning, "threshold of %s expands the volume on every cycle once the cooldown has passed", value)
+		case 100:
+			l.report(obj, field(annotations.AnnotationThreshold), SeverityWarning, "threshold of %s only expands a full volume", value)
+		}
+	}
+
+	if value, exists := values[annotations.AnnotationCooldown]; exists {
+		if cooldown, _ := time.ParseDuration(value); cooldown <= 0 {
+			l.report(obj, field(annotations.AnnotationCooldown), SeverityWarning, "cooldown of %s allows an expansion on every cycle", value)
+		}
+	}
+
+	if value, exists := values[annotations.AnnotationIncrease]; exists {
+		increase := strings.TrimSpace(value)
+		positive := true
+		if strings.HasSuffix(increase, "%") {
+			percent, _ := strconv.ParseFloat(strings.TrimSuffix(increase, "%"), 64)
+			positive = percent > 0
+		} else {
+			quantity := resource.MustParse(increase)
+			positive = quantity.Sign() > 0
+		}
+		if !positive {
+			l.report(obj, field(annotations.AnnotationIncrease), SeverityWarning, "increase of %s only grows the volume by min-scale-up", value)
+		}
+	}
+
+	maxValue, hasMax := values[annotations.AnnotationMaxSize]
+	if !hasMax {
+		return
+	}
+	maxSize := resource.MustParse(maxValue)
+	if maxSize.IsZero() {
+		return
+	}
+	if value, exists := values[annotations.AnnotationMinScaleUp]; exists {
+		if minScaleUp := resource.MustParse(value); minScaleUp.Cmp(maxSize) > 0 {
+			l.report(obj, field(annotations.AnnotationMinScaleUp), SeverityWarning, "min-scale-up of %s exceeds the max size of %s, every expansion is refused", value, maxValue)
+		}
+	}
+	if requested != nil && requested.Cmp(maxSize) >= 0 {
+		l.report(obj, field(annotations.AnnotationMaxSize), SeverityWarning, "max size of %s is not above the requested size of %s, the PVC is never expanded", maxValue, requested.String())
+	}
+}
+
+// checkOverlappingPolicies flags PVCPolicies in the same namespace whose
+// selectors can match the same PVC. Only the first by name applies to it.
+func (l *linter) checkOverlappingPolicies() {
+	for i := range l.policies {
+		for j := i + 1; j < len(l.policies); j++ {
+			a, b := l.policies[i], l.policies[j]
+			if a.namespace != b.namespace || !selectorsOverlap(a.selector, b.selector) {
+				continue
+			}
+			first, shadowed := a, b
+			if b.name < a.name {
+				first, shadowed = b, a
+			}
+			l.report(shadowed.object, "spec.selector", SeverityWarning,
+				"selector overlaps with PVCPolicy %s, which applies to PVCs matching both since PVCPolicies are evaluated by name", first.name)
+		}
+	}
+}
+
+// checkClaimPolicies flags PVCs in the manifests matched by several
+// PVCPolicies.
+func (l *linter) checkClaimPolicies() {
+	for _, claim := range l.claims {
+		if claim.annotated {
+			continue
+		}
+		var matching []string
+		for _, policy := range l.policies {
+			if policy.namespace == claim.namespace && policy.selector.Matches(claim.labels) {
+				matching = append(matching, policy.name)
+			}
+		}
+		if len(matching) < 2 {
+			continue
+		}
+		sort.Strings(matching)
+		l.report(claim.object, "metadata.labels", SeverityWarning,
+			"matched by PVCPolicies %s, only %s applies", strings.Join(matching, ", "), matching[0])
+	}
+}
+
+// selectorsOverlap reports whether some label set matches both selectors.
+func selectorsOverlap(a, b labels.Selector) bool {
+	requirementsA, _ := a.Requirements()
+	requirementsB, _ := b.Requirements()
+
+	type constraint struct {
+		in        map[string]bool // nil allows any value
+		notIn     map[string]bool
+		exists    bool
+		notExists bool
+	}
+	constraints := map[string]*constraint{}
+	for _, r := range append(requirementsA, requirementsB...) {
+		c, ok := constraints[r.Key()]
+		if !ok {
+			c = &constraint{notIn: map[string]bool{}}
+			constraints[r.Key()] = c
+		}
+		values := r.Values()
+		switch r.Operator() {
+		case "in", "=", "==":
+			c.exists = true
+			if c.in == nil {
+				c.in = map[string]bool{}
+				for value := range values {
+					c.in[value] = true
+				}
+			} else {
+				for value := range c.in {
+					if !values.Has(value) {
+						delete(c.in, value)
+					}
+				}
+			}
+		case "notin", "!=":
+			for value := range values {
+				c.notIn[value] = true
+			}
+		case "exists":
+			c.exists = true
+		case "!":
+			c.notExists = true
+		}
+	}
+
+	for _, c := range constraints {
+		if c.exists && c.notExists {
+			return false
+		}
+		if c.in == nil {
+			continue
+		}
+		satisfiable := false
+		for value := range c.in {
+			if !c.notIn[value] {
+				satisfiable = true
+				break
+			}
+		}
+		if !satisfiable {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,250 @@
+package lint
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/labels"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+func documents(t *testing.T, manifests ...string) []Document {
+	t.Helper()
+	docs := make([]Document, 0, len(manifests))
+	for i, manifest := range manifests {
+		data, err := utilyaml.ToJSON([]byte(manifest))
+		if err != nil {
+			t.Fatalf("invalid manifest %d: %v", i+1, err)
+		}
+		docs = append(docs, Document{File: "test.yaml", Index: i + 1, Data: data})
+	}
+	return docs
+}
+
+func pvc(name, labels, annotations string) string {
+	return `
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: ` + name + `
+  namespace: default
+  labels: ` + labels + `
+  annotations: ` + annotations + `
+spec:
+  resources:
+    requests:
+      storage: 10Gi
+`
+}
+
+func policy(name, selector, template string) string {
+	return `
+apiVersion: pvc-chonker.io/v1alpha1
+kind: PVCPolicy
+metadata:
+  name: ` + name + `
+  namespace: default
+spec:
+  selector: ` + selector + `
+  template: ` + template + `
+`
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name      string
+		manifests []string
+		// want holds "severity field substring" of the expected issues
+		want []string
+	}{
+		{
+			name: "valid annotations",
+			manifests: []string{
+				pvc("data", "{}", `{pvc-chonker.io/enabled: "true", pvc-chonker.io/threshold: "80%", pvc-chonker.io/max-size: 100Gi}`),
+			},
+		},
+		{
+			name: "invalid and unknown annotations",
+			manifests: []string{
+				pvc("data", "{}", `{pvc-chonker.io/enabled: "true", pvc-chonker.io/threshold: "80", pvc-chonker.io/cooldown: "15", pvc-chonker.io/increse: 10%}`),
+			},
+			want: []string{
+				"error metadata.annotations[pvc-chonker.io/cooldown] all annotations of the PVC are ignored",
+				"warning metadata.annotations[pvc-chonker.io/increse] unknown annotation",
+				"error metadata.annotations[pvc-chonker.io/threshold] percentage must end with %",
+			},
+		},
+		{
+			name: "config annotations without enabled",
+			manifests: []string{
+				pvc("data", "{}", `{pvc-chonker.io/threshold: "80%"}`),
+			},
+			want: []string{"warning metadata.annotations[pvc-chonker.io/threshold] ignored without pvc-chonker.io/enabled=true"},
+		},
+		{
+			name: "unsafe annotations",
+			manifests: []string{
+				pvc("data", "{}", `{pvc-chonker.io/enabled: "true", pvc-chonker.io/threshold: "0%", pvc-chonker.io/max-size: 10Gi}`),
+			},
+			want: []string{
+				"warning metadata.annotations[pvc-chonker.io/threshold] expands the volume on every cycle",
+				"warning metadata.annotations[pvc-chonker.io/max-size] the PVC is never expanded",
+			},
+		},
+		{
+			name: "statefulset volume claim templates",
+			manifests: []string{`
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+  namespace: default
+spec:
+  selector:
+    matchLabels: {app: db}
+  volumeClaimTemplates:
+  - metadata:
+      name: data
+      annotations: {pvc-chonker.io/enabled: "true", pvc-chonker.io/increase: 10GB}
+`},
+			want: []string{"error spec.volumeClaimTemplates[0].metadata.annotations[pvc-chonker.io/increase] expansions of the PVC fail"},
+		},
+		{
+			name: "invalid policy template",
+			manifests: []string{
+				policy("db", "{matchLabels: {app: db}}", `{threshold: "80", increase: 10GB, cooldown: 0s}`),
+			},
+			want: []string{
+				"error spec.template.increase expansions of matching PVCs fail",
+				"error spec.template.threshold the global default is used",
+				"warning spec.template.cooldown allows an expansion on every cycle",
+			},
+		},
+		{
+			name: "unknown policy field",
+			manifests: []string{
+				policy("db", "{matchLabels: {app: db}}", `{treshold: "80%"}`),
+			},
+			want: []string{`error  unknown field "treshold"`},
+		},
+		{
+			name: "invalid and empty selectors",
+			manifests: []string{
+				policy("a", "{matchExpressions: [{key: app, operator: Equals}]}", "{}"),
+				policy("b", "{}", "{}"),
+			},
+			want: []string{
+				"error spec.selector invalid selector",
+				"warning spec.selector every PVC in the namespace",
+			},
+		},
+		{
+			name: "overlapping policies",
+			manifests: []string{
+				policy("b-db", "{matchLabels: {app: db}}", "{}"),
+				policy("a-db", "{matchExpressions: [{key: tier, operator: In, values: [hot]}]}", "{}"),
+				policy("c-web", "{matchLabels: {app: web, tier: cold}}", "{}"),
+				pvc("data", "{app: db, tier: hot}", "{}"),
+				pvc("annotated", "{app: db, tier: hot}", `{pvc-chonker.io/enabled: "false"}`),
+			},
+			want: []string{
+				"warning spec.selector overlaps with PVCPolicy a-db",
+				"warning metadata.labels matched by PVCPolicies a-db, b-db, only a-db applies",
+			},
+		},
+		{
+			name: "group template",
+			manifests: []string{`
+apiVersion: pvc-chonker.io/v1alpha1
+kind: PVCGroup
+metadata:
+  name: kafka
+  namespace: default
+spec:
+  template: {enabled: true, maxSize: 10Gi, minScaleUp: 20Gi, increase: ten}
+`},
+			want: []string{
+				"warning spec.template.enabled not applied to members",
+				"error spec.template.increase the webhook copies it to member PVCs",
+				"warning spec.template.minScaleUp every expansion is refused",
+			},
+		},
+		{
+			name: "storage class limits",
+			manifests: []string{`
+apiVersion: storage.k8s.io/v1
+kind: StorageClass
+metadata:
+  name: standard
+  annotations: {pvc-chonker.io/max-in-flight-resizes: "x", pvc-chonker.io/threshold: "80%"}
+provisioner: ebs.csi.aws.com
+`},
+			want: []string{
+				"error metadata.annotations[pvc-chonker.io/max-in-flight-resizes] the limit is ignored",
+				"warning metadata.annotations[pvc-chonker.io/threshold] not read from StorageClasses",
+			},
+		},
+		{
+			name: "list items",
+			manifests: []string{`
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: PersistentVolumeClaim
+  metadata:
+    name: data
+    annotations: {pvc-chonker.io/enabled: "yes"}
+`},
+			want: []string{"error metadata.annotations[pvc-chonker.io/enabled] only a matching PVCPolicy applies"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := Lint(documents(t, tt.manifests...))
+			if len(issues) != len(tt.want) {
+				t.Fatalf("expected %d issues, got %+v", len(tt.want), issues)
+			}
+			for i, want := range tt.want {
+				severity, rest, _ := strings.Cut(want, " ")
+				field, message, _ := strings.Cut(rest, " ")
+				issue := issues[i]
+				if string(issue.Severity) != severity || issue.Field != field || !strings.Contains(issue.Message, message) {
+					t.Errorf("issue %d: expected %q, got %+v", i, want, issue)
+				}
+			}
+			if HasErrors(issues) != strings.Contains(strings.Join(tt.want, "\n"), "error ") {
+				t.Errorf("unexpected HasErrors for %+v", issues)
+			}
+		})
+	}
+}
+
+func TestSelectorsOverlap(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"app=db", "app=db", true},
+		{"app=db", "app=web", false},
+		{"app=db", "tier=hot", true},
+		{"app in (db,web)", "app notin (db)", true},
+		{"app in (db)", "app notin (db)", false},
+		{"app", "!app", false},
+		{"app!=db", "app=web", true},
+	}
+	for _, tt := range tests {
+		a, err := labels.Parse(tt.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := labels.Parse(tt.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := selectorsOverlap(a, b); got != tt.want {
+			t.Errorf("selectorsOverlap(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -10,6 +10,10 @@ const (
 	ResizerSubsystem          = "resizer"
 	KubernetesClientSubsystem = "kubernetes_client"
 	KubeletClientSubsystem    = "kubelet_client"
+	SchedulerSubsystem        = "scheduler"
+	CircuitBreakerSubsystem   = "circuit_breaker"
+	HistorySubsystem          = "history"
+	PushSubsystem             = "push"
 )
 
 var (
@@ -81,6 +85,72 @@ var (
 		},
 		[]string{"persistentvolumeclaim", "namespace"},
 	)
+
+	DecisionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: ResizerSubsystem,
+			Name:      "decisions_total",
+			Help:      "Counter of planning decisions made for PVCs by reason",
+		},
+		[]string{"reason"},
+	)
+
+	GlobalPaused = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: ResizerSubsystem,
+			Name:      "paused",
+			Help:      "Whether all automatic expansions are paused by the cluster-wide kill switch (1 = paused)",
+		},
+	)
+
+	PlannedExpansions = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: ResizerSubsystem,
+			Name:      "planned_expansions",
+			Help:      "Number of expansions in the plan of the last reconciliation cycle",
+		},
+	)
+
+	PlanHeld = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: ResizerSubsystem,
+			Name:      "plan_held",
+			Help:      "Whether the last expansion plan is held for exceeding a safety cap (1 = held, 0 = applied)",
+		},
+	)
+
+	DeferredPVCs = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: ResizerSubsystem,
+			Name:      "deferred_pvcs",
+			Help:      "Number of planned expansions deferred by concurrency or rate limits in the last reconciliation cycle",
+		},
+	)
+
+	DeferredTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: ResizerSubsystem,
+			Name:      "deferred_total",
+			Help:      "Counter of expansions deferred by concurrency or rate limits by reason",
+		},
+		[]string{"reason"},
+	)
+
+	PlanHeldTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: ResizerSubsystem,
+			Name:      "plan_held_total",
+			Help:      "Counter of expansion plans held by the safety cap that was exceeded",
+		},
+		[]string{"cap"},
+	)
 )
 
 var (
@@ -134,6 +204,98 @@ var (
 			Buckets:   prometheus.DefBuckets,
 		},
 	)
+
+	KubeletClientScrapedNodes = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: KubeletClientSubsystem,
+			Name:      "scraped_nodes",
+			Help:      "Number of nodes scraped for volume metrics in the last reconciliation",
+		},
+	)
+
+	KubeletClientSampleStaleness = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: KubeletClientSubsystem,
+			Name:      "sample_staleness_seconds",
+			Help:      "Age of volume metrics samples when they are evaluated",
+			Buckets:   []float64{5, 15, 30, 60, 120, 300, 600, 1800, 3600},
+		},
+		[]string{"source"},
+	)
+
+	KubeletClientFailedNodes = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: KubeletClientSubsystem,
+			Name:      "failed_nodes",
+			Help:      "Number of nodes that could not be scraped in the last reconciliation",
+		},
+	)
+
+	KubeletClientNodeScrapeFailuresTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: KubeletClientSubsystem,
+			Name:      "node_scrape_failures_total",
+			Help:      "Total number of failed node scrapes by node and reason",
+		},
+		[]string{"node", "reason"},
+	)
+)
+
+var (
+	SchedulerDuePVCs = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: SchedulerSubsystem,
+			Name:      "due_pvcs",
+			Help:      "Number of managed PVCs that were due for a usage check in the last reconciliation",
+		},
+	)
+
+	SchedulerNextCheckSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: SchedulerSubsystem,
+			Name:      "next_check_seconds",
+			Help:      "Delay in seconds until the next usage check of a managed PVC",
+		},
+		[]string{"persistentvolumeclaim", "namespace"},
+	)
+)
+
+var (
+	CircuitBreakerState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: CircuitBreakerSubsystem,
+			Name:      "state",
+			Help:      "Circuit breaker state per provisioner (0 = closed, 1 = open, 2 = half-open)",
+		},
+		[]string{"provisioner"},
+	)
+
+	CircuitBreakerFailuresTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: CircuitBreakerSubsystem,
+			Name:      "failures_total",
+			Help:      "Counter of resize failures counted by the circuit breaker per provisioner and source",
+		},
+		[]string{"provisioner", "source"},
+	)
+
+	CircuitBreakerTransitionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: CircuitBreakerSubsystem,
+			Name:      "transitions_total",
+			Help:      "Counter of circuit breaker state transitions per provisioner and new state",
+		},
+		[]string{"provisioner", "state"},
+	)
 )
 
 var (
@@ -197,6 +359,65 @@ var (
 		},
 		[]string{"persistentvolumeclaim", "namespace"},
 	)
+
+	PVCGrowthBytesPerHour = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Name:      "pvc_growth_bytes_per_hour",
+			Help:      "Average growth of used bytes per hour of managed PVCs over the last hour of history",
+		},
+		[]string{"persistentvolumeclaim", "namespace"},
+	)
+)
+
+var (
+	HistorySeries = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: HistorySubsystem,
+			Name:      "series",
+			Help:      "Number of PVCs with usage history",
+		},
+	)
+
+	HistorySamples = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: HistorySubsystem,
+			Name:      "samples",
+			Help:      "Number of usage samples held in the history store",
+		},
+	)
+
+	HistorySaveFailuresTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: HistorySubsystem,
+			Name:      "save_failures_total",
+			Help:      "Total number of failed attempts to persist the usage history",
+		},
+	)
+)
+
+var (
+	PushSamplesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: PushSubsystem,
+			Name:      "samples_total",
+			Help:      "Total number of usage samples pushed by agents",
+		},
+		[]string{"result"},
+	)
+
+	PushPVCs = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: PushSubsystem,
+			Name:      "pvcs",
+			Help:      "Number of PVCs with an unexpired pushed usage sample",
+		},
+	)
 )
 
 func RecordSuccessfulResize(pvcName, namespace string) {
@@ -223,6 +444,27 @@ func RecordResizeInProgress(pvcName, namespace string) {
 	ResizeInProgressTotal.WithLabelValues(pvcName, namespace).Inc()
 }
 
+func RecordDecision(reason string) {
+	DecisionsTotal.WithLabelValues(reason).Inc()
+}
+
+func RecordDeferred(reason string) {
+	DeferredTotal.WithLabelValues(reason).Inc()
+}
+
+func RecordPlanHeld(capName string) {
+	PlanHeldTotal.WithLabelValues(capName).Inc()
+}
+
+func RecordCircuitBreakerFailure(provisioner, source string) {
+	CircuitBreakerFailuresTotal.WithLabelValues(provisioner, source).Inc()
+}
+
+func RecordCircuitBreakerTransition(provisioner, state string, stateValue int) {
+	CircuitBreakerTransitionsTotal.WithLabelValues(provisioner, state).Inc()
+	CircuitBreakerState.WithLabelValues(provisioner).Set(float64(stateValue))
+}
+
 func RecordKubernetesClientRequest(operation, status string) {
 	KubernetesClientRequestsTotal.WithLabelValues(operation, status).Inc()
 	if status == "failed" {
@@ -237,6 +479,14 @@ func RecordKubeletClientRequest(status string) {
 	}
 }
 
+func ObserveSampleStaleness(source string, seconds float64) {
+	KubeletClientSampleStaleness.WithLabelValues(source).Observe(seconds)
+}
+
+func RecordKubeletNodeScrapeFailure(node, reason string) {
+	KubeletClientNodeScrapeFailuresTotal.WithLabelValues(node, reason).Inc()
+}
+
 func RecordLoopDuration(seconds float64) {
 	LoopSecondsTotal.Add(seconds)
 }
@@ -256,6 +506,49 @@ func UpdatePVCInodesMetrics(pvcName, namespace string, inodesUsagePercent float6
 	}
 }
 
+func UpdatePVCGrowthRate(pvcName, namespace string, bytesPerHour float64) {
+	PVCGrowthBytesPerHour.WithLabelValues(pvcName, namespace).Set(bytesPerHour)
+}
+
+func RecordPushedSamples(accepted, rejected int) {
+	PushSamplesTotal.WithLabelValues("accepted").Add(float64(accepted))
+	PushSamplesTotal.WithLabelValues("rejected").Add(float64(rejected))
+}
+
+func UpdateHistorySize(series, samples int) {
+	HistorySeries.Set(float64(series))
+	HistorySamples.Set(float64(samples))
+}
+
+func UpdatePVCNextCheck(pvcName, namespace string, delaySeconds float64) {
+	SchedulerNextCheckSeconds.WithLabelValues(pvcName, namespace).Set(delaySeconds)
+}
+
+// NewCircuitBreakerDegraded returns a gauge that is 1 while tripped reports
+// an open or half-open circuit. It is the degraded signal of the operator,
+// which stays ready while circuits are open so the webhook keeps serving.
+func NewCircuitBreakerDegraded(tripped func() []string) prometheus.GaugeFunc {
+	return prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: CircuitBreakerSubsystem,
+			Name:      "degraded",
+			Help:      "1 while any circuit breaker is open or half-open, 0 otherwise",
+		},
+		func() float64 {
+			if len(tripped()) > 0 {
+				return 1
+			}
+			return 0
+		},
+	)
+}
+
+// RegisterCircuitBreakerDegraded registers the degraded gauge of a breaker.
+func RegisterCircuitBreakerDegraded(tripped func() []string) {
+	metrics.Registry.MustRegister(NewCircuitBreakerDegraded(tripped))
+}
+
 func init() {
 	metrics.Registry.MustRegister(
 		// Resizer metrics
@@ -266,12 +559,30 @@ func init() {
 		ThresholdReachedTotal,
 		CooldownSkippedTotal,
 		ResizeInProgressTotal,
+		DecisionsTotal,
+		GlobalPaused,
+		PlannedExpansions,
+		PlanHeld,
+		PlanHeldTotal,
+		DeferredPVCs,
+		DeferredTotal,
 		// Client metrics
 		KubernetesClientFailTotal,
 		KubernetesClientRequestsTotal,
 		KubeletClientFailTotal,
 		KubeletClientRequestsTotal,
 		KubeletClientResponseTime,
+		KubeletClientScrapedNodes,
+		KubeletClientFailedNodes,
+		KubeletClientSampleStaleness,
+		KubeletClientNodeScrapeFailuresTotal,
+		// Scheduler metrics
+		SchedulerDuePVCs,
+		SchedulerNextCheckSeconds,
+		// Circuit breaker metrics
+		CircuitBreakerState,
+		CircuitBreakerFailuresTotal,
+		CircuitBreakerTransitionsTotal,
 		// Operational metrics
 		LastReconciliationTime,
 		ReconciliationStatus,
@@ -280,5 +591,13 @@ func init() {
 		PVCCapacityBytes,
 		PVCInodesUsagePercent,
 		PVCInodesTotal,
+		PVCGrowthBytesPerHour,
+		// History metrics
+		HistorySeries,
+		HistorySamples,
+		HistorySaveFailuresTotal,
+		// Push metrics
+		PushSamplesTotal,
+		PushPVCs,
 	)
 }
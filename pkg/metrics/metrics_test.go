@@ -195,3 +195,15 @@ func TestGoRuntimeMetrics(t *testing.T) {
 
 	t.Logf("Found %d Go runtime metric families", len(metricFamilies))
 }
+
+func TestCircuitBreakerDegraded(t *testing.T) {
+	var tripped []string
+	gauge := NewCircuitBreakerDegraded(func() []string { return tripped })
+	if value := testutil.ToFloat64(gauge); value != 0 {
+		t.Errorf("expected 0 without tripped circuits, got %v", value)
+	}
+	tripped = []string{"ebs.csi.aws.com"}
+	if value := testutil.ToFloat64(gauge); value != 1 {
+		t.Errorf("expected 1 with a tripped circuit, got %v", value)
+	}
+}
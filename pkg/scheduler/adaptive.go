@@ -0,0 +1,165 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+)
+
+// growthSmoothing is the weight given to the newest growth observation when
+// updating the exponentially weighted growth rate of a PVC.
+const growthSmoothing = 0.5
+
+type entry struct {
+	usagePercent float64
+	observedAt   time.Time
+	growthRate   float64 // usage percentage points per second
+	nextCheck    time.Time
+}
+
+// AdaptiveScheduler tracks when each PVC is next due for a usage check. PVCs
+// with plenty of headroom are checked rarely, PVCs close to their threshold or
+// growing quickly are checked often.
+type AdaptiveScheduler struct {
+	MinInterval time.Duration
+	MaxInterval time.Duration
+	entries     map[string]*entry
+	mutex       sync.RWMutex
+}
+
+func NewAdaptiveScheduler(minInterval, maxInterval time.Duration) *AdaptiveScheduler {
+	if maxInterval < minInterval {
+		maxInterval = minInterval
+	}
+	return &AdaptiveScheduler{
+		MinInterval: minInterval,
+		MaxInterval: maxInterval,
+		entries:     make(map[string]*entry),
+	}
+}
+
+// IsDue reports whether the PVC should be checked at the given time. Unknown
+// PVCs are always due.
+func (s *AdaptiveScheduler) IsDue(key string, now time.Time) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	e, exists := s.entries[key]
+	if !exists {
+		return true
+	}
+	return !now.Before(e.nextCheck)
+}
+
+// NextCheck returns the time the PVC is next due and whether it is tracked.
+func (s *AdaptiveScheduler) NextCheck(key string) (time.Time, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	e, exists := s.entries[key]
+	if !exists {
+		return time.Time{}, false
+	}
+	return e.nextCheck, true
+}
+
+// GrowthRate returns the smoothed growth of the PVC in usage percentage points
+// per hour.
+func (s *AdaptiveScheduler) GrowthRate(key string) float64 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if e, exists := s.entries[key]; exists {
+		return e.growthRate * time.Hour.Seconds()
+	}
+	return 0
+}
+
+// Observe records a usage sample for the PVC and schedules its next check.
+// usagePercent and thresholdPercent should describe the resource (storage or
+// inodes) with the least headroom. The chosen delay is returned.
+func (s *AdaptiveScheduler) Observe(key string, usagePercent, thresholdPercent float64, now time.Time) time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	e, exists := s.entries[key]
+	if !exists {
+		e = &entry{}
+		s.entries[key] = e
+	}
+	if elapsed := now.Sub(e.observedAt).Seconds(); !e.observedAt.IsZero() && elapsed > 0 {
+		rate := (usagePercent - e.usagePercent) / elapsed
+		if rate < 0 {
+			// Usage dropped, most likely after an expansion or cleanup
+			rate = 0
+		}
+		e.growthRate = growthSmoothing*rate + (1-growthSmoothing)*e.growthRate
+	}
+
+	e.usagePercent = usagePercent
+	e.observedAt = now
+
+	delay := s.calculateDelay(usagePercent, thresholdPercent, e.growthRate)
+	e.nextCheck = now.Add(delay)
+	return delay
+}
+
+// DeferUntil pushes the next check of the PVC to the given time, for example
+// until the end of its cooldown period. The delay is still bounded by
+// MaxInterval.
+func (s *AdaptiveScheduler) DeferUntil(key string, until time.Time, now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if latest := now.Add(s.MaxInterval); until.After(latest) {
+		until = latest
+	}
+
+	e, exists := s.entries[key]
+	if !exists {
+		e = &entry{}
+		s.entries[key] = e
+	}
+	e.nextCheck = until
+}
+
+// Forget drops all state for the PVC so it is due on the next cycle.
+func (s *AdaptiveScheduler) Forget(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.entries, key)
+}
+
+// Retain drops state for every PVC whose key is not in keys.
+func (s *AdaptiveScheduler) Retain(keys map[string]struct{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for key := range s.entries {
+		if _, ok := keys[key]; !ok {
+			delete(s.entries, key)
+		}
+	}
+}
+
+func (s *AdaptiveScheduler) calculateDelay(usagePercent, thresholdPercent, growthRate float64) time.Duration {
+	headroom := thresholdPercent - usagePercent
+	if headroom <= 0 || thresholdPercent <= 0 {
+		return s.MinInterval
+	}
+
+	// Scale linearly with the remaining headroom when nothing is known about growth
+	span := float64(s.MaxInterval - s.MinInterval)
+	delay := s.MinInterval + time.Duration(span*headroom/thresholdPercent)
+
+	// Check at least twice before a growing volume is expected to cross the threshold
+	if growthRate > 0 {
+		timeToThreshold := time.Duration(headroom / growthRate * float64(time.Second))
+		if half := timeToThreshold / 2; half < delay {
+			delay = half
+		}
+	}
+
+	if delay < s.MinInterval {
+		delay = s.MinInterval
+	}
+	if delay > s.MaxInterval {
+		delay = s.MaxInterval
+	}
+	return delay
+}
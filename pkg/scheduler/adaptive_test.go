@@ -0,0 +1,127 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveScheduler_UnknownPVCIsDue(t *testing.T) {
+	s := NewAdaptiveScheduler(time.Minute, time.Hour)
+	if !s.IsDue("default/data", time.Now()) {
+		t.Error("expected unknown PVC to be due")
+	}
+}
+
+func TestAdaptiveScheduler_DelayScalesWithHeadroom(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		usage     float64
+		threshold float64
+		expected  time.Duration
+	}{
+		{
+			name:      "empty volume waits max interval",
+			usage:     0,
+			threshold: 80,
+			expected:  time.Hour,
+		},
+		{
+			name:      "half of headroom left",
+			usage:     40,
+			threshold: 80,
+			expected:  time.Minute + (time.Hour-time.Minute)/2,
+		},
+		{
+			name:      "threshold reached",
+			usage:     85,
+			threshold: 80,
+			expected:  time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewAdaptiveScheduler(time.Minute, time.Hour)
+			delay := s.Observe("default/data", tt.usage, tt.threshold, now)
+			if delay != tt.expected {
+				t.Errorf("expected delay %v, got %v", tt.expected, delay)
+			}
+			if s.IsDue("default/data", now.Add(delay-time.Second)) {
+				t.Error("expected PVC not to be due before its delay elapsed")
+			}
+			if !s.IsDue("default/data", now.Add(delay)) {
+				t.Error("expected PVC to be due once its delay elapsed")
+			}
+		})
+	}
+}
+
+func TestAdaptiveScheduler_FastGrowthShortensDelay(t *testing.T) {
+	s := NewAdaptiveScheduler(time.Minute, time.Hour)
+	now := time.Now()
+
+	s.Observe("default/data", 20, 80, now)
+	delay := s.Observe("default/data", 60, 80, now.Add(10*time.Minute))
+
+	// 40 points in 10 minutes, smoothed to 20 points per 10 minutes, leaves
+	// 10 minutes until the threshold is crossed; the check happens halfway
+	if expected := 5 * time.Minute; delay != expected {
+		t.Errorf("expected delay %v, got %v", expected, delay)
+	}
+
+	headroomOnly := NewAdaptiveScheduler(time.Minute, time.Hour).Observe("default/data", 60, 80, now)
+	if delay >= headroomOnly {
+		t.Errorf("expected growth to shorten the delay below %v, got %v", headroomOnly, delay)
+	}
+}
+
+func TestAdaptiveScheduler_ShrinkingUsageIgnored(t *testing.T) {
+	s := NewAdaptiveScheduler(time.Minute, time.Hour)
+	now := time.Now()
+
+	s.Observe("default/data", 70, 80, now)
+	s.Observe("default/data", 10, 80, now.Add(time.Minute))
+
+	if rate := s.GrowthRate("default/data"); rate != 0 {
+		t.Errorf("expected zero growth after usage dropped, got %f", rate)
+	}
+}
+
+func TestAdaptiveScheduler_DeferUntil(t *testing.T) {
+	s := NewAdaptiveScheduler(time.Minute, time.Hour)
+	now := time.Now()
+
+	s.DeferUntil("default/data", now.Add(10*time.Minute), now)
+	if s.IsDue("default/data", now.Add(5*time.Minute)) {
+		t.Error("expected deferred PVC not to be due")
+	}
+	if !s.IsDue("default/data", now.Add(10*time.Minute)) {
+		t.Error("expected deferred PVC to be due after the deferral")
+	}
+
+	s.DeferUntil("default/data", now.Add(24*time.Hour), now)
+	next, _ := s.NextCheck("default/data")
+	if !next.Equal(now.Add(time.Hour)) {
+		t.Errorf("expected deferral to be capped at max interval, got %v", next.Sub(now))
+	}
+}
+
+func TestAdaptiveScheduler_RetainAndForget(t *testing.T) {
+	s := NewAdaptiveScheduler(time.Minute, time.Hour)
+	now := time.Now()
+
+	s.Observe("default/a", 10, 80, now)
+	s.Observe("default/b", 10, 80, now)
+
+	s.Retain(map[string]struct{}{"default/a": {}})
+	if _, exists := s.NextCheck("default/b"); exists {
+		t.Error("expected default/b to be dropped")
+	}
+
+	s.Forget("default/a")
+	if !s.IsDue("default/a", now) {
+		t.Error("expected forgotten PVC to be due")
+	}
+}
@@ -0,0 +1,285 @@
+// Package simulate replays a usage trace of a single PVC against the expansion
+// logic of the operator, so the effect of a policy or annotations can be seen
+// before it is rolled out.
+package simulate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	pvcchonkerv1alpha1 "github.com/logicIQ/pvc-chonker/api/v1alpha1"
+	"github.com/logicIQ/pvc-chonker/pkg/annotations"
+)
+
+// DefaultInterval matches the default watch interval of the operator.
+const DefaultInterval = 5 * time.Minute
+
+// Reasons a cycle did not expand the PVC.
+const (
+	SkipBelowThreshold = "BelowThreshold"
+	SkipCooldown       = "Cooldown"
+	SkipSuspended      = "Suspended"
+	SkipMaxSize        = "MaxSizeReached"
+	SkipNotManaged     = "NotManaged"
+)
+
+// Config describes the simulated PVC and the configuration it is resolved
+// against.
+type Config struct {
+	// PVC carries the annotations and labels the configuration is resolved
+	// from. Its capacity is the initial size.
+	PVC      *corev1.PersistentVolumeClaim
+	Policies []pvcchonkerv1alpha1.PVCPolicy
+	Groups   []pvcchonkerv1alpha1.PVCGroup
+	Global   *annotations.GlobalConfig
+	// Start is the time of the first cycle. Cooldowns and suspensions are
+	// evaluated against Start plus the offset of the cycle.
+	Start time.Time
+	// Interval between cycles, DefaultInterval when zero.
+	Interval time.Duration
+	// Duration of the simulation, the duration of the trace when zero.
+	Duration time.Duration
+}
+
+// Expansion is a simulated resize.
+type Expansion struct {
+	Offset             time.Duration
+	Time               time.Time
+	From               resource.Quantity
+	To                 resource.Quantity
+	UsedBytes          int64
+	UsagePercent       float64
+	InodesUsagePercent float64
+	// Trigger is storage or inodes.
+	Trigger string
+}
+
+// Result summarizes a simulation.
+type Result struct {
+	Start       time.Time
+	Duration    time.Duration
+	Cycles      int
+	InitialSize resource.Quantity
+	FinalSize   resource.Quantity
+	Expansions  []Expansion
+	// Skipped counts the cycles that did not expand by reason.
+	Skipped map[string]int
+	// TimeAboveThreshold adds up the intervals of the cycles in which usage
+	// was at or above the storage or inode threshold.
+	TimeAboveThreshold time.Duration
+	PeakUsagePercent   float64
+	// MaxSizeReachedAt is the offset of the first cycle an expansion was
+	// refused because it would exceed the max size.
+	MaxSizeReachedAt *time.Duration
+	// FullAt is the offset of the first cycle the volume was full.
+	FullAt *time.Duration
+}
+
+// simClock is the simulated time every resolved PVCConfig is evaluated at.
+type simClock struct {
+	now time.Time
+}
+
+func (c *simClock) Now() time.Time {
+	return c.now
+}
+
+func (c *simClock) Since(t time.Time) time.Duration {
+	return c.now.Sub(t)
+}
+
+// Run simulates one cycle per interval. Every cycle resolves the PVC
+// configuration the way the operator does, including PVCPolicy and PVCGroup
+// precedence, and expands the PVC when a threshold is reached. Resizes are
+// assumed to complete before the next cycle.
+func Run(ctx context.Context, config Config, trace Trace) (*Result, error) {
+	if config.PVC == nil {
+		return nil, fmt.Errorf("PVC cannot be nil")
+	}
+	if config.Global == nil {
+		return nil, fmt.Errorf("global config cannot be nil")
+	}
+	interval := config.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	duration := config.Duration
+	if duration <= 0 {
+		duration = trace.Duration()
+	}
+	if duration <= 0 {
+		return nil, fmt.Errorf("duration is required for an unbounded trace")
+	}
+	start := config.Start
+	if start.IsZero() {
+		start = time.Now()
+	}
+
+	pvc := config.PVC.DeepCopy()
+	if pvc.Namespace == "" {
+		pvc.Namespace = corev1.NamespaceDefault
+	}
+	size := pvc.Status.Capacity[corev1.ResourceStorage]
+	if size.IsZero() {
+		size = pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	}
+	if size.IsZero() {
+		return nil, fmt.Errorf("PVC %s has no capacity", pvc.Name)
+	}
+	setSize(pvc, size)
+
+	clk := &simClock{now: start}
+	resolver := &annotations.PolicyResolver{Client: newClient(pvc.Namespace, config.Policies, config.Groups), Clock: clk}
+
+	result := &Result{
+		Start:       start,
+		Duration:    duration,
+		InitialSize: size.DeepCopy(),
+		Expansions:  []Expansion{},
+		Skipped:     map[string]int{},
+	}
+	for offset := time.Duration(0); offset <= duration; offset += interval {
+		clk.now = start.Add(offset)
+		result.Cycles++
+		if err := cycle(ctx, resolver, config.Global, pvc, trace.At(offset), interval, result); err != nil {
+			return nil, fmt.Errorf("cycle at %s: %w", offset, err)
+		}
+	}
+	result.FinalSize = pvc.Status.Capacity[corev1.ResourceStorage]
+	return result, nil
+}
+
+func cycle(ctx context.Context, resolver *annotations.PolicyResolver, global *annotations.GlobalConfig, pvc *corev1.PersistentVolumeClaim, point Point, interval time.Duration, result *Result) error {
+	now := resolver.Clock.Now()
+	currentSize := pvc.Status.Capacity[corev1.ResourceStorage]
+	capacity := currentSize.Value()
+
+	// A full volume cannot hold more than its capacity
+	used := point.UsedBytes
+	if used >= capacity {
+		used = capacity
+		if result.FullAt == nil {
+			offset := point.Offset
+			result.FullAt = &offset
+		}
+	}
+	usage := float64(used) / float64(capacity) * 100
+	var inodesUsage float64
+	if point.InodesTotal > 0 {
+		inodesUsage = float64(point.InodesUsed) / float64(point.InodesTotal) * 100
+	}
+	if usage > result.PeakUsagePercent {
+		result.PeakUsagePercent = usage
+	}
+
+	config, err := resolver.ResolvePVCConfig(ctx, pvc, global)
+	if errors.Is(err, annotations.ErrPVCNotManaged) {
+		result.Skipped[SkipNotManaged]++
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !config.Enabled {
+		result.Skipped[SkipNotManaged]++
+		return nil
+	}
+
+	trigger := ""
+	if usage >= config.Threshold {
+		trigger = "storage"
+	} else if point.InodesTotal > 0 && inodesUsage >= config.InodesThreshold {
+		trigger = "inodes"
+	}
+	if trigger != "" {
+		result.TimeAboveThreshold += interval
+	}
+
+	// Same order of checks as the PVC reconciler
+	if config.IsInCooldown() {
+		result.Skipped[SkipCooldown]++
+		return nil
+	}
+	if config.IsSuspended() {
+		result.Skipped[SkipSuspended]++
+		return nil
+	}
+	if trigger == "" {
+		result.Skipped[SkipBelowThreshold]++
+		return nil
+	}
+
+	newSize, err := config.CalculateNewSize(currentSize)
+	if err != nil {
+		return err
+	}
+	if config.ExceedsMaxSize(newSize) {
+		result.Skipped[SkipMaxSize]++
+		if result.MaxSizeReachedAt == nil {
+			offset := point.Offset
+			result.MaxSizeReachedAt = &offset
+		}
+		return nil
+	}
+
+	result.Expansions = append(result.Expansions, Expansion{
+		Offset:             point.Offset,
+		Time:               now,
+		From:               currentSize.DeepCopy(),
+		To:                 newSize.DeepCopy(),
+		UsedBytes:          used,
+		UsagePercent:       usage,
+		InodesUsagePercent: inodesUsage,
+		Trigger:            trigger,
+	})
+	setSize(pvc, newSize)
+	annotations.UpdateLastExpansionAt(pvc, now)
+	return nil
+}
+
+func setSize(pvc *corev1.PersistentVolumeClaim, size resource.Quantity) {
+	if pvc.Spec.Resources.Requests == nil {
+		pvc.Spec.Resources.Requests = corev1.ResourceList{}
+	}
+	pvc.Spec.Resources.Requests[corev1.ResourceStorage] = size
+	if pvc.Status.Capacity == nil {
+		pvc.Status.Capacity = corev1.ResourceList{}
+	}
+	pvc.Status.Capacity[corev1.ResourceStorage] = size
+	pvc.Status.Phase = corev1.ClaimBound
+}
+
+// newClient returns an in-memory client holding the policies and groups the
+// resolver reads. They default to the namespace of the PVC.
+func newClient(namespace string, policies []pvcchonkerv1alpha1.PVCPolicy, groups []pvcchonkerv1alpha1.PVCGroup) client.Client {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(pvcchonkerv1alpha1.AddToScheme(scheme))
+
+	var objects []client.Object
+	for i := range policies {
+		policy := policies[i].DeepCopy()
+		if policy.Namespace == "" {
+			policy.Namespace = namespace
+		}
+		objects = append(objects, policy)
+	}
+	for i := range groups {
+		group := groups[i].DeepCopy()
+		if group.Namespace == "" {
+			group.Namespace = namespace
+		}
+		objects = append(objects, group)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+}
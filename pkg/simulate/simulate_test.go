@@ -0,0 +1,181 @@
+package simulate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	pvcchonkerv1alpha1 "github.com/logicIQ/pvc-chonker/api/v1alpha1"
+	"github.com/logicIQ/pvc-chonker/pkg/annotations"
+)
+
+const gib = int64(1 << 30)
+
+func newPVC(size string, labels, pvcAnnotations map[string]string) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "default", Labels: labels, Annotations: pvcAnnotations},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(size)},
+			},
+		},
+	}
+}
+
+func defaultGlobal() *annotations.GlobalConfig {
+	return annotations.NewGlobalConfig(0, 0, "", 0, resource.Quantity{}, resource.Quantity{})
+}
+
+func TestRunAnnotations(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	pvc := newPVC("10Gi", nil, map[string]string{
+		annotations.AnnotationEnabled:   "true",
+		annotations.AnnotationThreshold: "80%",
+		annotations.AnnotationIncrease:  "50%",
+		annotations.AnnotationCooldown:  "1h",
+		annotations.AnnotationMaxSize:   "20Gi",
+	})
+	// 1Gi per hour from 7Gi: reaches 80% of 10Gi after 1h and of 15Gi after 5h
+	trace := LinearGrowth{InitialBytes: 7 * gib, BytesPerHour: gib}
+
+	result, err := Run(context.Background(), Config{PVC: pvc, Global: defaultGlobal(), Start: start, Interval: 30 * time.Minute, Duration: 24 * time.Hour}, trace)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result.Expansions) != 1 {
+		t.Fatalf("expected 1 expansion, got %+v", result.Expansions)
+	}
+	expansion := result.Expansions[0]
+	if expansion.Offset != time.Hour || expansion.To.Cmp(resource.MustParse("15Gi")) != 0 || expansion.Trigger != "storage" {
+		t.Errorf("expected expansion to 15Gi after 1h on storage, got %+v", expansion)
+	}
+	if !expansion.Time.Equal(start.Add(time.Hour)) {
+		t.Errorf("expected expansion at %s, got %s", start.Add(time.Hour), expansion.Time)
+	}
+	if result.FinalSize.Cmp(resource.MustParse("15Gi")) != 0 {
+		t.Errorf("expected final size 15Gi, got %s", result.FinalSize.String())
+	}
+	// 15Gi to 23Gi exceeds the max size once usage reaches 12Gi after 5h
+	if result.MaxSizeReachedAt == nil || *result.MaxSizeReachedAt != 5*time.Hour {
+		t.Errorf("expected max size reached after 5h, got %v", result.MaxSizeReachedAt)
+	}
+	// Full at 15Gi after 8h
+	if result.FullAt == nil || *result.FullAt != 8*time.Hour {
+		t.Errorf("expected the volume full after 8h, got %v", result.FullAt)
+	}
+	if result.PeakUsagePercent != 100 {
+		t.Errorf("expected peak usage of 100%%, got %.1f", result.PeakUsagePercent)
+	}
+	if result.Cycles != 49 {
+		t.Errorf("expected 49 cycles, got %d", result.Cycles)
+	}
+	// 1h at 10Gi, then every cycle from 5h
+	if want := 40 * 30 * time.Minute; result.TimeAboveThreshold != want {
+		t.Errorf("expected %s above threshold, got %s", want, result.TimeAboveThreshold)
+	}
+	if result.Skipped[SkipCooldown] != 1 || result.Skipped[SkipMaxSize] != 39 {
+		t.Errorf("expected 1 cooldown and 39 max size skips, got %v", result.Skipped)
+	}
+}
+
+func TestRunCooldown(t *testing.T) {
+	pvc := newPVC("10Gi", nil, map[string]string{
+		annotations.AnnotationEnabled:   "true",
+		annotations.AnnotationIncrease:  "1Gi",
+		annotations.AnnotationCooldown:  "1h",
+		annotations.AnnotationThreshold: "50%",
+	})
+	trace := LinearGrowth{InitialBytes: 9 * gib}
+
+	result, err := Run(context.Background(), Config{PVC: pvc, Global: defaultGlobal(), Interval: 15 * time.Minute, Duration: 2 * time.Hour}, trace)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Expansions) != 3 {
+		t.Fatalf("expected expansions at 0, 1h and 2h, got %+v", result.Expansions)
+	}
+	for i, offset := range []time.Duration{0, time.Hour, 2 * time.Hour} {
+		if result.Expansions[i].Offset != offset {
+			t.Errorf("expected expansion %d at %s, got %s", i, offset, result.Expansions[i].Offset)
+		}
+	}
+	if result.Skipped[SkipCooldown] != 6 {
+		t.Errorf("expected 6 cooldown skips, got %v", result.Skipped)
+	}
+}
+
+func TestRunPolicy(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	threshold, increase := "90%", "5Gi"
+	policy := pvcchonkerv1alpha1.PVCPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "db"},
+		Spec: pvcchonkerv1alpha1.PVCPolicySpec{
+			Selector:     metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+			Template:     pvcchonkerv1alpha1.PVCPolicyTemplate{Threshold: &threshold, Increase: &increase},
+			SuspendUntil: &metav1.Time{Time: start.Add(time.Hour)},
+		},
+	}
+	trace, err := NewSampledTrace([]Point{{UsedBytes: 95 * gib / 10}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	config := Config{
+		PVC:      newPVC("10Gi", map[string]string{"app": "db"}, nil),
+		Policies: []pvcchonkerv1alpha1.PVCPolicy{policy},
+		Global:   defaultGlobal(),
+		Start:    start,
+		Duration: 2 * time.Hour,
+	}
+	result, err := Run(context.Background(), config, trace)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Expansions) != 1 || result.Expansions[0].Offset != time.Hour || result.Expansions[0].To.Cmp(resource.MustParse("15Gi")) != 0 {
+		t.Fatalf("expected expansion to 15Gi once the policy resumes after 1h, got %+v", result.Expansions)
+	}
+	if result.Skipped[SkipSuspended] != 12 {
+		t.Errorf("expected 12 suspended cycles, got %v", result.Skipped)
+	}
+
+	// Without a matching policy the PVC is not managed
+	config.PVC = newPVC("10Gi", map[string]string{"app": "web"}, nil)
+	result, err = Run(context.Background(), config, trace)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Expansions) != 0 || result.Skipped[SkipNotManaged] != result.Cycles {
+		t.Errorf("expected no expansions of an unmanaged PVC, got %+v", result)
+	}
+}
+
+func TestRunInodes(t *testing.T) {
+	pvc := newPVC("10Gi", nil, map[string]string{annotations.AnnotationEnabled: "true"})
+	trace, err := NewSampledTrace([]Point{{UsedBytes: gib, InodesUsed: 90, InodesTotal: 100}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := Run(context.Background(), Config{PVC: pvc, Global: defaultGlobal(), Duration: time.Minute}, trace)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Expansions) != 1 || result.Expansions[0].Trigger != "inodes" {
+		t.Errorf("expected an expansion on inode pressure, got %+v", result.Expansions)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	pvc := newPVC("10Gi", nil, map[string]string{annotations.AnnotationEnabled: "true"})
+	if _, err := Run(context.Background(), Config{PVC: pvc, Global: defaultGlobal()}, LinearGrowth{}); err == nil {
+		t.Error("expected an error for an unbounded trace without duration")
+	}
+	if _, err := Run(context.Background(), Config{PVC: newPVC("0", nil, nil), Global: defaultGlobal(), Duration: time.Hour}, LinearGrowth{}); err == nil {
+		t.Error("expected an error for a PVC without capacity")
+	}
+}
@@ -0,0 +1,298 @@
+package simulate
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/logicIQ/pvc-chonker/pkg/kubelet"
+)
+
+// Point is the usage of a volume at an offset from the start of a trace.
+type Point struct {
+	Offset      time.Duration
+	UsedBytes   int64
+	InodesUsed  int64
+	InodesTotal int64
+}
+
+// Trace returns the usage of a volume over simulated time. Usage is absolute,
+// so it does not change when the volume is expanded.
+type Trace interface {
+	At(offset time.Duration) Point
+	// Duration is the length of the trace, zero when it is unbounded.
+	Duration() time.Duration
+}
+
+// SampledTrace replays recorded samples. The usage between two samples is the
+// usage of the earlier one.
+type SampledTrace struct {
+	points []Point
+}
+
+// NewSampledTrace sorts the points by offset.
+func NewSampledTrace(points []Point) (*SampledTrace, error) {
+	if len(points) == 0 {
+		return nil, fmt.Errorf("trace has no samples")
+	}
+	sorted := append([]Point(nil), points...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Offset < sorted[j].Offset })
+	return &SampledTrace{points: sorted}, nil
+}
+
+func (t *SampledTrace) At(offset time.Duration) Point {
+	i := sort.Search(len(t.points), func(i int) bool { return t.points[i].Offset > offset })
+	if i == 0 {
+		return Point{Offset: offset, UsedBytes: t.points[0].UsedBytes, InodesUsed: t.points[0].InodesUsed, InodesTotal: t.points[0].InodesTotal}
+	}
+	p := t.points[i-1]
+	p.Offset = offset
+	return p
+}
+
+func (t *SampledTrace) Duration() time.Duration {
+	return t.points[len(t.points)-1].Offset
+}
+
+// LinearGrowth grows from InitialBytes by BytesPerHour.
+type LinearGrowth struct {
+	InitialBytes int64
+	BytesPerHour int64
+}
+
+func (g LinearGrowth) At(offset time.Duration) Point {
+	return Point{Offset: offset, UsedBytes: g.InitialBytes + int64(offset.Hours()*float64(g.BytesPerHour))}
+}
+
+func (g LinearGrowth) Duration() time.Duration {
+	return 0
+}
+
+// ExponentialGrowth grows from InitialBytes by PercentPerDay, compounded
+// continuously.
+type ExponentialGrowth struct {
+	InitialBytes  int64
+	PercentPerDay float64
+}
+
+func (g ExponentialGrowth) At(offset time.Duration) Point {
+	days := offset.Hours() / 24
+	return Point{Offset: offset, UsedBytes: int64(float64(g.InitialBytes) * math.Pow(1+g.PercentPerDay/100, days))}
+}
+
+func (g ExponentialGrowth) Duration() time.Duration {
+	return 0
+}
+
+// traceTime holds the time of a trace sample, either an absolute time or an
+// offset from the start of the trace.
+type traceTime struct {
+	absolute time.Time
+	offset   time.Duration
+}
+
+func parseTraceTime(s string) (traceTime, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return traceTime{absolute: t}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return traceTime{offset: d}, nil
+	}
+	if seconds, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return traceTime{absolute: time.Unix(seconds, 0)}, nil
+	}
+	return traceTime{}, fmt.Errorf("invalid time %q, expected RFC3339, a duration or Unix seconds", s)
+}
+
+// toPoints converts absolute sample times to offsets from the earliest one.
+// Times and offsets cannot be mixed.
+func toPoints(times []traceTime, points []Point) ([]Point, time.Time, error) {
+	var start time.Time
+	absolute := 0
+	for _, t := range times {
+		if !t.absolute.IsZero() {
+			absolute++
+			if start.IsZero() || t.absolute.Before(start) {
+				start = t.absolute
+			}
+		}
+	}
+	if absolute != 0 && absolute != len(times) {
+		return nil, time.Time{}, fmt.Errorf("trace mixes absolute times and offsets")
+	}
+	for i, t := range times {
+		if absolute > 0 {
+			points[i].Offset = t.absolute.Sub(start)
+		} else {
+			points[i].Offset = t.offset
+		}
+	}
+	return points, start, nil
+}
+
+func parseBytes(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	q, err := resource.ParseQuantity(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity %q: %w", s, err)
+	}
+	return q.Value(), nil
+}
+
+// ParseCSV reads a trace with a time,usedBytes[,inodesUsed,inodesTotal]
+// header. Times are RFC3339, Unix seconds or offsets such as 90m. Byte values
+// may be quantities such as 5Gi. It returns the time of the first sample when
+// times are absolute.
+func ParseCSV(r io.Reader) (Trace, time.Time, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to read CSV trace: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, time.Time{}, fmt.Errorf("trace has no samples")
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	timeColumn, hasTime := columns["time"]
+	usedColumn, hasUsed := columns["usedbytes"]
+	if !hasTime || !hasUsed {
+		return nil, time.Time{}, fmt.Errorf("CSV trace header must contain time and usedBytes columns")
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	times := make([]traceTime, 0, len(records)-1)
+	points := make([]Point, 0, len(records)-1)
+	for line, record := range records[1:] {
+		if timeColumn >= len(record) || usedColumn >= len(record) {
+			return nil, time.Time{}, fmt.Errorf("line %d: missing time or usedBytes", line+2)
+		}
+		t, err := parseTraceTime(record[timeColumn])
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("line %d: %w", line+2, err)
+		}
+		var p Point
+		if p.UsedBytes, err = parseBytes(record[usedColumn]); err != nil {
+			return nil, time.Time{}, fmt.Errorf("line %d: %w", line+2, err)
+		}
+		if p.InodesUsed, err = parseBytes(field(record, "inodesused")); err != nil {
+			return nil, time.Time{}, fmt.Errorf("line %d: %w", line+2, err)
+		}
+		if p.InodesTotal, err = parseBytes(field(record, "inodestotal")); err != nil {
+			return nil, time.Time{}, fmt.Errorf("line %d: %w", line+2, err)
+		}
+		times = append(times, t)
+		points = append(points, p)
+	}
+	return newTrace(times, points)
+}
+
+// jsonSample is a trace sample in a JSON trace.
+type jsonSample struct {
+	Time        string          `json:"time"`
+	UsedBytes   json.RawMessage `json:"usedBytes"`
+	InodesUsed  int64           `json:"inodesUsed,omitempty"`
+	InodesTotal int64           `json:"inodesTotal,omitempty"`
+}
+
+// ParseJSON reads a trace from an array of {"time", "usedBytes",
+// "inodesUsed", "inodesTotal"} objects. usedBytes is a number or a quantity
+// string.
+func ParseJSON(r io.Reader) (Trace, time.Time, error) {
+	var samples []jsonSample
+	if err := json.NewDecoder(r).Decode(&samples); err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to read JSON trace: %w", err)
+	}
+
+	times := make([]traceTime, 0, len(samples))
+	points := make([]Point, 0, len(samples))
+	for i, s := range samples {
+		t, err := parseTraceTime(s.Time)
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("sample %d: %w", i, err)
+		}
+		used := strings.Trim(string(s.UsedBytes), `"`)
+		p := Point{InodesUsed: s.InodesUsed, InodesTotal: s.InodesTotal}
+		if p.UsedBytes, err = parseBytes(used); err != nil {
+			return nil, time.Time{}, fmt.Errorf("sample %d: %w", i, err)
+		}
+		times = append(times, t)
+		points = append(points, p)
+	}
+	return newTrace(times, points)
+}
+
+func newTrace(times []traceTime, points []Point) (Trace, time.Time, error) {
+	points, start, err := toPoints(times, points)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	trace, err := NewSampledTrace(points)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return trace, start, nil
+}
+
+// LoadRecordings builds the trace of a PVC from kubelet scrapes recorded with
+// kubelet-record-dir. Recordings that do not contain the PVC are skipped.
+func LoadRecordings(dir string, pvc types.NamespacedName) (Trace, time.Time, error) {
+	collector, err := kubelet.NewReplayCollector(dir, false)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	var times []traceTime
+	var points []Point
+	for {
+		cache, recording, err := collector.Next()
+		if errors.Is(err, kubelet.ErrReplayExhausted) {
+			break
+		}
+		if err != nil {
+			// A cycle in which every node failed has no samples
+			if recording != nil {
+				continue
+			}
+			return nil, time.Time{}, err
+		}
+		vm, ok := cache.Get(pvc)
+		if !ok {
+			continue
+		}
+		sampledAt := vm.Timestamp
+		if sampledAt.IsZero() {
+			sampledAt = recording.StartedAt
+		}
+		times = append(times, traceTime{absolute: sampledAt})
+		points = append(points, Point{UsedBytes: vm.UsedBytes, InodesUsed: vm.InodesUsed, InodesTotal: vm.InodesTotal})
+	}
+	if len(points) == 0 {
+		return nil, time.Time{}, fmt.Errorf("no recorded samples of PVC %s in %s", pvc, dir)
+	}
+	return newTrace(times, points)
+}
@@ -0,0 +1,75 @@
+package simulate
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCSV(t *testing.T) {
+	input := "time,usedBytes,inodesUsed,inodesTotal\n" +
+		"2026-01-01T01:00:00Z,2Gi,20,100\n" +
+		"2026-01-01T00:00:00Z,1073741824,10,100\n"
+	trace, start, err := ParseCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC); !start.Equal(want) {
+		t.Errorf("expected start %s, got %s", want, start)
+	}
+	if trace.Duration() != time.Hour {
+		t.Errorf("expected duration 1h, got %s", trace.Duration())
+	}
+	if p := trace.At(30 * time.Minute); p.UsedBytes != gib || p.InodesUsed != 10 {
+		t.Errorf("expected the first sample at 30m, got %+v", p)
+	}
+	if p := trace.At(2 * time.Hour); p.UsedBytes != 2*gib || p.InodesUsed != 20 || p.Offset != 2*time.Hour {
+		t.Errorf("expected the last sample at 2h, got %+v", p)
+	}
+
+	offsets := "time,usedBytes\n0s,1Gi\n90m,3Gi\n"
+	trace, start, err = ParseCSV(strings.NewReader(offsets))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !start.IsZero() || trace.Duration() != 90*time.Minute {
+		t.Errorf("expected an offset trace of 90m, got start %s and duration %s", start, trace.Duration())
+	}
+
+	for name, input := range map[string]string{
+		"missing header": "0s,1Gi\n",
+		"invalid time":   "time,usedBytes\nyesterday,1Gi\n",
+		"invalid bytes":  "time,usedBytes\n0s,lots\n",
+		"mixed times":    "time,usedBytes\n0s,1Gi\n2026-01-01T00:00:00Z,2Gi\n",
+		"no samples":     "time,usedBytes\n",
+	} {
+		if _, _, err := ParseCSV(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	input := `[{"time": "0s", "usedBytes": "1Gi"}, {"time": "1h", "usedBytes": 2147483648, "inodesUsed": 5, "inodesTotal": 10}]`
+	trace, _, err := ParseJSON(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p := trace.At(0); p.UsedBytes != gib {
+		t.Errorf("expected 1Gi at 0s, got %+v", p)
+	}
+	if p := trace.At(time.Hour); p.UsedBytes != 2*gib || p.InodesTotal != 10 {
+		t.Errorf("expected 2Gi at 1h, got %+v", p)
+	}
+}
+
+func TestGrowthModels(t *testing.T) {
+	linear := LinearGrowth{InitialBytes: gib, BytesPerHour: gib}
+	if p := linear.At(90 * time.Minute); p.UsedBytes != gib*5/2 {
+		t.Errorf("expected 2.5Gi after 90m, got %d", p.UsedBytes)
+	}
+	exponential := ExponentialGrowth{InitialBytes: gib, PercentPerDay: 100}
+	if p := exponential.At(48 * time.Hour); p.UsedBytes != 4*gib {
+		t.Errorf("expected 4Gi after 2 days of doubling, got %d", p.UsedBytes)
+	}
+}
@@ -0,0 +1,257 @@
+package throttle
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reasons an expansion is deferred, used as metric labels.
+const (
+	ReasonStorageClassInFlight = "storageclass_in_flight"
+	ReasonStorageClassRate     = "storageclass_rate"
+	ReasonProvisionerInFlight  = "provisioner_in_flight"
+	ReasonProvisionerRate      = "provisioner_rate"
+)
+
+const rateWindow = time.Minute
+
+// Limits bounds the expansions of a StorageClass or provisioner. Zero values
+// disable the corresponding limit.
+type Limits struct {
+	// MaxInFlight is the number of PVCs that may be resizing at the same time,
+	// including PVCs still resizing from earlier cycles.
+	MaxInFlight int
+	// PerMinute is the number of expansions that may be started per minute.
+	PerMinute int
+}
+
+// start is an expansion counted against the per-minute limit.
+type start struct {
+	key string
+	at  time.Time
+}
+
+type bucket struct {
+	inFlight int
+	// acquired holds the PVCs counted in inFlight by Acquire this cycle.
+	acquired map[string]bool
+	started  []start
+}
+
+// Throttle limits in-flight resizes and expansion rate per StorageClass and
+// per provisioner.
+type Throttle struct {
+	ProvisionerLimits map[string]Limits
+	// Jitter is the upper bound of the random delay before each expansion, so
+	// expansions allowed in the same cycle do not hit the backend at once.
+	Jitter time.Duration
+
+	storageClassLimits map[string]Limits
+	storageClasses     map[string]*bucket
+	provisioners       map[string]*bucket
+	mutex              sync.Mutex
+}
+
+func NewThrottle(provisionerLimits map[string]Limits, jitter time.Duration) *Throttle {
+	if provisionerLimits == nil {
+		provisionerLimits = make(map[string]Limits)
+	}
+	return &Throttle{
+		ProvisionerLimits:  provisionerLimits,
+		Jitter:             jitter,
+		storageClassLimits: make(map[string]Limits),
+		storageClasses:     make(map[string]*bucket),
+		provisioners:       make(map[string]*bucket),
+	}
+}
+
+// SetStorageClassLimits sets the limits of a StorageClass, typically read from
+// its annotations.
+func (t *Throttle) SetStorageClassLimits(storageClass string, limits Limits) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.storageClassLimits[storageClass] = limits
+}
+
+// ResetInFlight clears the in-flight counts at the start of a cycle, before
+// the PVCs currently resizing are added back with AddInFlight.
+func (t *Throttle) ResetInFlight() {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, b := range t.storageClasses {
+		b.inFlight = 0
+		b.acquired = nil
+	}
+	for _, b := range t.provisioners {
+		b.inFlight = 0
+		b.acquired = nil
+	}
+}
+
+// AddInFlight counts a PVC that is currently resizing.
+func (t *Throttle) AddInFlight(storageClass, provisioner string) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	getBucket(t.storageClasses, storageClass).inFlight++
+	getBucket(t.provisioners, provisioner).inFlight++
+}
+
+// Acquire reserves an expansion slot for the PVC identified by key. When a
+// limit is reached it returns false together with the deferral reason.
+func (t *Throttle) Acquire(key, storageClass, provisioner string, now time.Time) (bool, string) {
+	if t == nil {
+		return true, ""
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	scBucket := getBucket(t.storageClasses, storageClass)
+	provBucket := getBucket(t.provisioners, provisioner)
+
+	if reason := check(scBucket, t.storageClassLimits[storageClass], now, ReasonStorageClassInFlight, ReasonStorageClassRate); reason != "" {
+		return false, reason
+	}
+	if reason := check(provBucket, t.ProvisionerLimits[provisioner], now, ReasonProvisionerInFlight, ReasonProvisionerRate); reason != "" {
+		return false, reason
+	}
+
+	for _, b := range []*bucket{scBucket, provBucket} {
+		if b.acquired == nil {
+			b.acquired = make(map[string]bool)
+		}
+		b.acquired[key] = true
+		b.inFlight++
+		b.started = append(b.started, start{key: key, at: now})
+	}
+	return true, ""
+}
+
+// Check reports whether Acquire would reserve a slot, without reserving one.
+// It lets a dry run report deferrals without using up per-minute slots.
+func (t *Throttle) Check(storageClass, provisioner string, now time.Time) (bool, string) {
+	if t == nil {
+		return true, ""
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if reason := check(getBucket(t.storageClasses, storageClass), t.storageClassLimits[storageClass], now, ReasonStorageClassInFlight, ReasonStorageClassRate); reason != "" {
+		return false, reason
+	}
+	if reason := check(getBucket(t.provisioners, provisioner), t.ProvisionerLimits[provisioner], now, ReasonProvisionerInFlight, ReasonProvisionerRate); reason != "" {
+		return false, reason
+	}
+	return true, ""
+}
+
+// Release returns the slot the PVC identified by key acquired for an
+// expansion that was not started.
+func (t *Throttle) Release(key, storageClass, provisioner string) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, b := range []*bucket{getBucket(t.storageClasses, storageClass), getBucket(t.provisioners, provisioner)} {
+		if b.acquired[key] {
+			delete(b.acquired, key)
+			if b.inFlight > 0 {
+				b.inFlight--
+			}
+		}
+		// The start may already have left the rate window
+		for i := len(b.started) - 1; i >= 0; i-- {
+			if b.started[i].key == key {
+				b.started = append(b.started[:i], b.started[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// Delay returns a random delay in [0, Jitter).
+func (t *Throttle) Delay() time.Duration {
+	if t == nil || t.Jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(t.Jitter)))
+}
+
+func getBucket(buckets map[string]*bucket, key string) *bucket {
+	b, exists := buckets[key]
+	if !exists {
+		b = &bucket{}
+		buckets[key] = b
+	}
+	return b
+}
+
+func check(b *bucket, limits Limits, now time.Time, inFlightReason, rateReason string) string {
+	cutoff := now.Add(-rateWindow)
+	recent := b.started[:0]
+	for _, started := range b.started {
+		if started.at.After(cutoff) {
+			recent = append(recent, started)
+		}
+	}
+	b.started = recent
+
+	if limits.MaxInFlight > 0 && b.inFlight >= limits.MaxInFlight {
+		return inFlightReason
+	}
+	if limits.PerMinute > 0 && len(b.started) >= limits.PerMinute {
+		return rateReason
+	}
+	return ""
+}
+
+// ParseLimits parses a comma-separated list of name=value pairs, for example
+// "nfs.csi.k8s.io=2,rbd.csi.ceph.com=4".
+func ParseLimits(s string) (map[string]int, error) {
+	limits := make(map[string]int)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, found := strings.Cut(pair, "=")
+		if !found || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid limit %q, expected name=value", pair)
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid limit value in %q", pair)
+		}
+		limits[strings.TrimSpace(name)] = limit
+	}
+	return limits, nil
+}
+
+// MergeLimits combines in-flight and per-minute limits keyed by the same names.
+func MergeLimits(maxInFlight, perMinute map[string]int) map[string]Limits {
+	limits := make(map[string]Limits)
+	for name, value := range maxInFlight {
+		l := limits[name]
+		l.MaxInFlight = value
+		limits[name] = l
+	}
+	for name, value := range perMinute {
+		l := limits[name]
+		l.PerMinute = value
+		limits[name] = l
+	}
+	return limits
+}
@@ -0,0 +1,142 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottle_InFlightLimit(t *testing.T) {
+	th := NewThrottle(map[string]Limits{"nfs.csi.k8s.io": {MaxInFlight: 2}}, 0)
+	now := time.Now()
+
+	th.AddInFlight("nfs", "nfs.csi.k8s.io")
+	if ok, _ := th.Acquire("default/nfs", "nfs", "nfs.csi.k8s.io", now); !ok {
+		t.Fatal("expected second in-flight resize to be allowed")
+	}
+	ok, reason := th.Acquire("default/nfs", "nfs", "nfs.csi.k8s.io", now)
+	if ok || reason != ReasonProvisionerInFlight {
+		t.Fatalf("expected third resize to be deferred by provisioner limit, got %v %q", ok, reason)
+	}
+	if ok, _ := th.Acquire("default/gp3", "gp3", "ebs.csi.aws.com", now); !ok {
+		t.Error("expected other provisioners to be unaffected")
+	}
+
+	th.ResetInFlight()
+	if ok, _ := th.Acquire("default/nfs", "nfs", "nfs.csi.k8s.io", now); !ok {
+		t.Error("expected in-flight count to reset between cycles")
+	}
+}
+
+func TestThrottle_StorageClassRateLimit(t *testing.T) {
+	th := NewThrottle(nil, 0)
+	th.SetStorageClassLimits("ceph-rbd", Limits{PerMinute: 2})
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := th.Acquire("default/rbd", "ceph-rbd", "rbd.csi.ceph.com", now); !ok {
+			t.Fatalf("expected expansion %d to be allowed", i+1)
+		}
+	}
+	if ok, reason := th.Acquire("default/rbd", "ceph-rbd", "rbd.csi.ceph.com", now.Add(30*time.Second)); ok || reason != ReasonStorageClassRate {
+		t.Fatalf("expected expansion to be rate limited, got %v %q", ok, reason)
+	}
+	if ok, _ := th.Acquire("default/rbd", "ceph-rbd", "rbd.csi.ceph.com", now.Add(61*time.Second)); !ok {
+		t.Error("expected expansion to be allowed once the minute passed")
+	}
+}
+
+func TestThrottle_Release(t *testing.T) {
+	th := NewThrottle(map[string]Limits{"csi": {MaxInFlight: 1, PerMinute: 1}}, 0)
+	now := time.Now()
+
+	th.Acquire("default/a", "sc", "csi", now)
+	th.Release("default/a", "sc", "csi")
+	if ok, reason := th.Acquire("default/a", "sc", "csi", now); !ok {
+		t.Errorf("expected released slot to be reusable, got %q", reason)
+	}
+}
+
+func TestThrottle_Check(t *testing.T) {
+	th := NewThrottle(map[string]Limits{"csi": {PerMinute: 1}}, 0)
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if ok, reason := th.Check("sc", "csi", now); !ok {
+			t.Fatalf("expected check %d to leave the slot free, got %q", i, reason)
+		}
+	}
+	th.Acquire("default/a", "sc", "csi", now)
+	if ok, reason := th.Check("sc", "csi", now); ok || reason != ReasonProvisionerRate {
+		t.Errorf("expected check to report the rate limit, got %v %q", ok, reason)
+	}
+}
+
+func TestThrottle_ReleaseOutOfOrder(t *testing.T) {
+	th := NewThrottle(map[string]Limits{"csi": {PerMinute: 2}}, 0)
+	now := time.Now()
+
+	th.Acquire("default/a", "sc", "csi", now)
+	th.Acquire("default/b", "sc", "csi", now.Add(50*time.Second))
+	// a failed, b started: only b still counts once a's minute has passed
+	th.Release("default/a", "sc", "csi")
+	if ok, reason := th.Acquire("default/c", "sc", "csi", now.Add(55*time.Second)); !ok {
+		t.Fatalf("expected the slot of the released expansion to be reusable, got %q", reason)
+	}
+	if ok, reason := th.Acquire("default/d", "sc", "csi", now.Add(65*time.Second)); ok || reason != ReasonProvisionerRate {
+		t.Errorf("expected b and c to still count, got %v %q", ok, reason)
+	}
+	if ok, _ := th.Acquire("default/d", "sc", "csi", now.Add(111*time.Second)); !ok {
+		t.Error("expected the expansion to be allowed once b's minute passed")
+	}
+
+	// Releasing a key that holds no slot changes nothing
+	th.Release("default/unknown", "sc", "csi")
+}
+
+func TestThrottle_Delay(t *testing.T) {
+	var nilThrottle *Throttle
+	if d := nilThrottle.Delay(); d != 0 {
+		t.Errorf("expected no delay from nil throttle, got %v", d)
+	}
+
+	th := NewThrottle(nil, time.Second)
+	for i := 0; i < 100; i++ {
+		if d := th.Delay(); d < 0 || d >= time.Second {
+			t.Fatalf("expected delay within jitter, got %v", d)
+		}
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected map[string]int
+		wantErr  bool
+	}{
+		{input: "", expected: map[string]int{}},
+		{input: "nfs.csi.k8s.io=2, rbd.csi.ceph.com=4", expected: map[string]int{"nfs.csi.k8s.io": 2, "rbd.csi.ceph.com": 4}},
+		{input: "nfs.csi.k8s.io", wantErr: true},
+		{input: "nfs.csi.k8s.io=-1", wantErr: true},
+		{input: "=2", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			limits, err := ParseLimits(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLimits() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(limits) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, limits)
+			}
+			for name, value := range tt.expected {
+				if limits[name] != value {
+					t.Errorf("expected %s=%d, got %d", name, value, limits[name])
+				}
+			}
+		})
+	}
+}